@@ -1,17 +1,20 @@
 package main
 
 import (
+	"context"
+	"crypto/subtle"
 	"math/rand/v2"
 	"net/http"
+	"net/url"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/caddyserver/certmagic"
 	"github.com/etzelm/blog-in-golang/src/handlers"
-	"github.com/gin-contrib/cache"
-	"github.com/gin-contrib/cache/persistence"
+	"github.com/etzelm/blog-in-golang/src/models"
 	"github.com/gin-contrib/gzip"
 	"github.com/gin-contrib/static"
 	"github.com/gin-gonic/gin"
@@ -43,6 +46,44 @@ var (
 		},
 		[]string{"method", "route", "status"},
 	)
+	// Page cache (the CachePage routes in LoadServerRoutes). Lookups are
+	// counted per request; entries is the number of pages currently held.
+	pageCacheHits = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "blog_page_cache_hits_total",
+			Help: "Page cache lookups answered from the cache.",
+		},
+	)
+	pageCacheMisses = prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "blog_page_cache_misses_total",
+			Help: "Page cache lookups that fell through to the handler.",
+		},
+	)
+	pageCacheEntries = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "blog_page_cache_entries",
+			Help: "Pages currently held in the page cache.",
+		},
+	)
+	// AWS SDK calls (models.AWSMetrics), labeled by service (DynamoDB, S3)
+	// and operation (Query, PutObject, …); errors also by API error code, or
+	// "transport" when AWS never answered. Latency includes retries.
+	awsRequestDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "blog_aws_request_duration_seconds",
+			Help:    "AWS SDK call duration in seconds, retries included, labeled by service and operation.",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"service", "operation"},
+	)
+	awsRequestErrors = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "blog_aws_request_errors_total",
+			Help: "AWS SDK calls that returned an error, labeled by service, operation, and error code.",
+		},
+		[]string{"service", "operation", "code"},
+	)
 )
 
 type envHook struct{ env string }
@@ -80,9 +121,16 @@ func init() {
 		log.AddHook(hook)
 	}
 
-	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration)
+	prometheus.MustRegister(httpRequestsTotal, httpRequestDuration, pageCacheHits, pageCacheMisses, pageCacheEntries,
+		awsRequestDuration, awsRequestErrors)
 }
 
+// panelRefreshInterval is how often the shared panel snapshot re-reads the
+// article store, to pick up posts published around the blog (the daemon's
+// publish). Scheduled posts don't wait for it: the watcher wakes at their
+// publish-at.
+const panelRefreshInterval = 5 * time.Minute
+
 var RandomOne int = randRange(1, 9)
 var RandomTwo int = randRange(1, 9)
 
@@ -99,6 +147,7 @@ func main() {
 	// and emits one JSON line per request via logrus.
 	httpServer := gin.New()
 	httpServer.Use(gin.Recovery())
+	httpServer.SetFuncMap(models.TemplateFuncs())
 	httpServer.LoadHTMLGlob("templates/*")
 	// Middlewares MUST be registered before routes — Gin snapshots
 	// engine.Handlers into each route's frozen handler chain at registration
@@ -109,9 +158,13 @@ func main() {
 	//
 	// metricsMiddleware is registered FIRST (in LoadMiddlewares) so it
 	// observes total end-to-end request time, including downstream middleware.
+	clients, err := newAWSClients()
+	if err != nil {
+		log.Fatal("Unable to configure AWS clients: ", err)
+	}
 	LoadMiddlewares(httpServer)
 	LoadStaticFileRoutes(httpServer)
-	LoadServerRoutes(httpServer)
+	LoadServerRoutes(httpServer, newArticleStore(clients), clients)
 	// Don't log the *gin.Engine as a structured field — it embeds
 	// `gin.HandlerFunc` slices that json.Marshal can't serialize, so
 	// JSONFormatter / lokirus drop the line with
@@ -143,7 +196,6 @@ func main() {
 func LoadStaticFileRoutes(server *gin.Engine) {
 
 	server.StaticFile("/robots.txt", "./public/robots.txt")
-	server.StaticFile("/sitemap.xml", "./public/sitemap.xml")
 	server.StaticFile("/favicon.ico", "./public/images/favicon.ico")
 	server.Use(static.Serve("/public", static.LocalFile("./public", true)))
 	server.Use(static.Serve("/realtor", static.LocalFile("./realtor/build", true)))
@@ -155,23 +207,158 @@ func LoadStaticFileRoutes(server *gin.Engine) {
 
 }
 
+// newAWSClients builds the process-wide AWS clients from the environment
+// (see models.AWSOptionsFromEnv), reporting to the blog_aws_* metrics.
+func newAWSClients() (*models.AWSClients, error) {
+	opts, err := models.AWSOptionsFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	log.WithFields(log.Fields{
+		"region":            opts.Region,
+		"dynamodb_endpoint": opts.DynamoDBEndpoint,
+		"s3_endpoint":       opts.S3Endpoint,
+	}).Info("Configuring AWS clients")
+	return models.NewAWSClients(context.Background(), opts, models.AWSMetrics{
+		Latency: awsRequestDuration,
+		Errors:  awsRequestErrors,
+	})
+}
+
+// newArticleStore picks the ArticleStore backend from ARTICLE_STORE:
+//   - "dynamodb" (default): the ARTICLES table, as in prod.
+//   - "file": article directories under ARTICLE_DIR (../daemon/articles by
+//     default), for running the blog without AWS.
+//   - "memory": an empty in-process store.
+func newArticleStore(clients *models.AWSClients) models.ArticleStore {
+	switch backend := os.Getenv("ARTICLE_STORE"); backend {
+	case "file":
+		dir := os.Getenv("ARTICLE_DIR")
+		if dir == "" {
+			dir = "../daemon/articles"
+		}
+		log.WithField("dir", dir).Info("Serving articles from local files")
+		return models.NewFileArticleStore(dir)
+	case "memory":
+		log.Info("Serving articles from an empty in-memory store")
+		return models.NewMemoryArticleStore()
+	case "", "dynamodb":
+		return models.NewDynamoArticleStore(clients.DynamoDB)
+	default:
+		log.WithField("backend", backend).Warn("Unknown ARTICLE_STORE; falling back to DynamoDB")
+		return models.NewDynamoArticleStore(clients.DynamoDB)
+	}
+}
+
+// listingGeocoder loads the ZIP table listings are placed with (see
+// models.ZIPCentroidsPath). Without one, listings are saved unplaced and
+// location searches are refused.
+func listingGeocoder() models.Geocoder {
+	path := models.ZIPCentroidsPath()
+	if path == "" {
+		log.Warn("ZIP_CENTROIDS is not set; listings won't be geocoded and location searches are off")
+		return nil
+	}
+	zips, err := models.OpenZIPCentroids(path)
+	if err != nil {
+		log.WithField("path", path).Warn("Unable to load the ZIP table; listings won't be geocoded and location searches are off: ", err)
+		return nil
+	}
+	log.WithFields(log.Fields{"path": path, "zips": len(zips)}).Info("Geocoding listings by ZIP code")
+	return zips
+}
+
 // LoadServerRoutes loads all the custom api calls I've written for the server.
-func LoadServerRoutes(server *gin.Engine) {
+func LoadServerRoutes(server *gin.Engine, articles models.ArticleStore, clients *models.AWSClients) {
 
-	store := persistence.NewInMemoryStore(365 * 24 * time.Hour)
-	server.GET("/", cache.CachePage(store, 365*24*time.Hour, handlers.AboutPage))
-	server.GET("/posts", cache.CachePage(store, 365*24*time.Hour, handlers.PostPage))
-	server.GET("/article/:article_id", cache.CachePage(store, 365*24*time.Hour, handlers.ArticlePage))
-	server.GET("/category/:category", cache.CachePage(store, 365*24*time.Hour, handlers.CategoryPage))
+	pages := handlers.NewPageCache(365*24*time.Hour, handlers.PageCacheMetrics{
+		Hits:    pageCacheHits,
+		Misses:  pageCacheMisses,
+		Entries: pageCacheEntries,
+	})
+	panels := models.NewPanelSnapshot(articles)
+	searchIndex := models.NewSearchIndex(panels)
+	go watchListedArticles(panels, panelRefreshInterval, func() {
+		log.Info("Listed articles changed; flushing the page cache")
+		pages.PurgeAll()
+		searchIndex.Refresh()
+	})
+	geocoder := listingGeocoder()
+	server.GET("/", pages.Page(handlers.AboutPage))
+	server.GET("/posts", pages.Page(handlers.PostPage(articles)))
+	server.GET("/posts/:slug", pages.Page(handlers.ArticlePage(articles)))
+	server.GET("/article/:article_id", pages.Page(handlers.ArticleRedirect(articles)))
+	server.GET("/category/:category", pages.Page(handlers.CategoryPage(articles)))
+	server.GET("/category/:category/feed.xml", pages.Page(handlers.CategoryRSSFeed(articles)))
+	server.GET("/feed.xml", pages.Page(handlers.RSSFeed(articles)))
+	server.GET("/atom.xml", pages.Page(handlers.AtomFeed(articles)))
+	server.GET("/sitemap.xml", pages.Page(handlers.Sitemap(articles)))
+	server.GET("/preview/:article_id", handlers.PreviewPage(articles))
+	server.GET("/search", handlers.SearchPage(searchIndex))
 	server.GET("/contact", handlers.ContactPage(&RandomOne, &RandomTwo))
-	server.POST("/contact", handlers.ContactResponse(&RandomOne, &RandomTwo))
-	server.GET("/listing/:listing", handlers.ListingGETAPI)
-	server.GET("/listings", handlers.ListingsGETAPI)
+	server.POST("/contact", handlers.ContactResponse(&RandomOne, &RandomTwo, clients))
+	server.GET("/listing/:listing", optionalUser(), handlers.ListingGETAPI(clients))
+	server.GET("/listings", optionalUser(), handlers.ListingsGETAPI(clients, geocoder))
 	server.GET("/auth", handlers.AuthPage)
-	server.POST("/auth", handlers.AuthResponse)
+	server.POST("/auth", handlers.AuthResponse(clients))
 	server.GET("/secure", handlers.SecurePage)
-	server.POST("/listings/add/:key", handlers.ListingPOSTAPI)
-	server.POST("/upload/image/:user", handlers.UploadImagePOSTAPI)
+	server.POST("/listings", userAuth(), handlers.ListingPOSTAPI(clients, geocoder))
+	server.DELETE("/listings/:mls", userAuth(), handlers.ListingDELETEAPI(clients))
+	server.POST("/listings/:mls/restore", userAuth(), handlers.ListingRestorePOSTAPI(clients))
+	server.POST("/upload/image", userAuth(), handlers.UploadImagePOSTAPI(clients))
+
+	// Realtor sign-in: the app trades its Google ID token for a user session.
+	var verifier models.IDTokenVerifier
+	if clientID := models.GoogleClientID(); clientID != "" {
+		verifier = models.NewGoogleIDTokenVerifier(clientID, &http.Client{Timeout: 10 * time.Second})
+	}
+	server.POST("/realtor/session", handlers.RealtorSessionAPI(verifier))
+	server.DELETE("/realtor/session", handlers.EndRealtorSessionAPI)
+	// A purge follows a publish made around the blog, so re-read the panels
+	// too rather than wait for the next refresh.
+	server.POST("/manage/cache/purge", adminAuth(), func(c *gin.Context) {
+		c.Next()
+		if c.Writer.Status() == http.StatusOK {
+			panels.Refresh()
+			go searchIndex.Refresh()
+		}
+	}, handlers.CachePurge(pages))
+	server.DELETE("/manage/listings/:mls", adminAuth(), handlers.ListingPurgeAPI(clients))
+
+	// Article editor. Saves go straight to the store, so re-read the panels,
+	// flush the cached pages and rebuild the search index (a same-day edit
+	// doesn't change the panel fingerprint Refresh looks at).
+	if editor, ok := articles.(models.ArticleEditor); ok {
+		saved := func() {
+			panels.Refresh()
+			pages.PurgeAll()
+			go searchIndex.Rebuild()
+		}
+		manage := server.Group("/manage", adminAuth())
+		manage.GET("/editor", handlers.EditorPage)
+		manage.GET("/articles", handlers.EditorArticlesAPI(editor))
+		manage.POST("/articles", handlers.CreateArticleAPI(editor, saved))
+		manage.POST("/articles/preview", handlers.PreviewArticleAPI)
+		manage.GET("/articles/:id", handlers.EditorArticleAPI(editor))
+		manage.PUT("/articles/:id", handlers.UpdateArticleAPI(editor, saved))
+		manage.DELETE("/articles/:id", handlers.DeleteArticleAPI(editor, saved))
+		manage.GET("/articles/:id/revisions", handlers.ArticleHistoryAPI(editor))
+		manage.GET("/articles/:id/revisions/:version", handlers.ArticleRevisionAPI(editor))
+		manage.GET("/articles/:id/diff", handlers.ArticleDiffAPI(editor))
+		manage.POST("/articles/:id/rollback", handlers.RollbackArticleAPI(editor, saved))
+	} else {
+		log.Info("The article store is read-only; the article editor is disabled")
+	}
+
+	// Read-only JSON API for the mobile reader and static mirrors. Not page
+	// cached: responses carry an ETag and clients revalidate instead. The
+	// list is served from the panel snapshot, so revalidating it reads
+	// nothing from the store.
+	api := server.Group("/api/v1", apiCORS())
+	api.GET("/articles", handlers.ArticlesAPI(panels))
+	api.GET("/articles/:id", handlers.ArticleAPI(articles))
+	api.OPTIONS("/articles", func(*gin.Context) {})
+	api.OPTIONS("/articles/:id", func(*gin.Context) {})
 
 	// Liveness probe — used by the container healthcheck and any external
 	// uptime monitors. Deliberately cheap: no DynamoDB / S3 / external calls.
@@ -187,6 +374,34 @@ func LoadServerRoutes(server *gin.Engine) {
 
 }
 
+// watchListedArticles calls onChange whenever the listed article panels
+// change, forever. It refreshes panels every interval and, in between,
+// wakes when the next scheduled post's publish-at passes, which needs no
+// read: the snapshot already holds the post. The pages behind CachePage are
+// cached for a year, so this is what lets a scheduled post appear on time
+// (and a newly published one without a restart).
+func watchListedArticles(panels *models.PanelSnapshot, interval time.Duration, onChange func()) {
+	last := models.PanelFingerprint(panels.GetArticlePanels())
+	refreshAt := time.Now().Add(interval)
+	for {
+		wake := refreshAt
+		if at, ok := panels.NextPublishAt(time.Now()); ok && at.Before(wake) {
+			wake = at
+		}
+		time.Sleep(time.Until(wake))
+
+		if !time.Now().Before(refreshAt) {
+			panels.Refresh()
+			refreshAt = time.Now().Add(interval)
+		}
+		current := models.PanelFingerprint(panels.GetArticlePanels())
+		if current != last {
+			last = current
+			onChange()
+		}
+	}
+}
+
 // LoadMiddlewares loads third party and custom gin middlewares the server uses.
 func LoadMiddlewares(server *gin.Engine) {
 
@@ -280,10 +495,152 @@ func metricsAuth() gin.HandlerFunc {
 	}
 }
 
+// apiCORS lets pages on the origins listed in API_CORS_ORIGINS (comma-
+// separated, or * for any) read the /api routes from the browser, and
+// answers their preflight requests. With it unset no CORS headers are sent
+// and only same-origin pages can. Read at construction time, like
+// metricsAuth.
+func apiCORS() gin.HandlerFunc {
+	origins := []string{}
+	for _, origin := range strings.Split(os.Getenv("API_CORS_ORIGINS"), ",") {
+		if origin = strings.TrimSpace(origin); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	anyOrigin := slices.Contains(origins, "*")
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		if len(origins) > 0 && !anyOrigin {
+			c.Header("Vary", "Origin")
+		}
+		if origin != "" && (anyOrigin || slices.Contains(origins, origin)) {
+			if anyOrigin {
+				c.Header("Access-Control-Allow-Origin", "*")
+			} else {
+				c.Header("Access-Control-Allow-Origin", origin)
+			}
+			c.Header("Access-Control-Expose-Headers", "ETag")
+			if c.Request.Method == http.MethodOptions {
+				c.Header("Access-Control-Allow-Methods", "GET, OPTIONS")
+				c.Header("Access-Control-Allow-Headers", "If-None-Match")
+				c.Header("Access-Control-Max-Age", "86400")
+			}
+		}
+		if c.Request.Method == http.MethodOptions {
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		c.Next()
+	}
+}
+
+// adminAuth gates the /manage endpoints. Scripts (the daemon's purge and
+// publish --purge) send ADMIN_TOKEN as a bearer token; browsers send the
+// session cookie the /auth login gives the accounts in ADMIN_EMAILS, which
+// is signed with the same token. Unlike metricsAuth it fails closed: with no
+// token set, every request is refused. Captured at construction time, like
+// metricsAuth.
+//
+// The session cookie is SameSite=Strict, and a cookie-authenticated POST or
+// PUT must be JSON: a cross-site form can't send that without a CORS
+// preflight, which /manage never answers.
+func adminAuth() gin.HandlerFunc {
+	expected := os.Getenv("ADMIN_TOKEN")
+	return func(c *gin.Context) {
+		got := c.Request.Header.Get("Authorization")
+		if expected != "" && subtle.ConstantTimeCompare([]byte(got), []byte("Bearer "+expected)) == 1 {
+			c.Next()
+			return
+		}
+
+		session, _ := c.Cookie(models.AdminSessionCookie)
+		if email, ok := models.ValidAdminSession(expected, session, time.Now()); ok {
+			if (c.Request.Method == http.MethodPost || c.Request.Method == http.MethodPut) && c.ContentType() != "application/json" {
+				c.AbortWithStatusJSON(http.StatusUnsupportedMediaType, gin.H{"error": "send application/json"})
+				return
+			}
+			c.Set(handlers.AdminEmailKey, email)
+			c.Next()
+			return
+		}
+
+		// Send people who open the editor without a session to the login.
+		if c.Request.Method == http.MethodGet && strings.Contains(c.GetHeader("Accept"), "text/html") {
+			c.Redirect(http.StatusFound, "/auth")
+			c.Abort()
+			return
+		}
+		c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "unauthorized"})
+	}
+}
+
+// userAuth gates the realtor write APIs. The realtor app and the /auth login
+// set a userSession cookie; scripts can send the same token as a bearer
+// token. Like adminAuth it fails closed: with no SESSION_SECRET set, every
+// request is refused.
+//
+// The cookie is SameSite=Strict, and a cookie-authenticated write must come
+// from this site: a browser's Origin header, when it sends one, has to name
+// this host. Uploads are multipart, so unlike /manage the content type alone
+// can't rule out a cross-site form.
+func userAuth() gin.HandlerFunc {
+	secret := models.UserSecret()
+	return func(c *gin.Context) {
+		email, crossSite := signedInUser(c, secret)
+		switch {
+		case crossSite:
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "cross-site request"})
+		case email == "":
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "sign in first"})
+		default:
+			c.Set(handlers.UserEmailKey, email)
+			c.Next()
+		}
+	}
+}
+
+// optionalUser notes who is signed in, as userAuth does, for the read APIs
+// that show owners more (their deleted listings), but refuses no one.
+func optionalUser() gin.HandlerFunc {
+	secret := models.UserSecret()
+	return func(c *gin.Context) {
+		if email, _ := signedInUser(c, secret); email != "" {
+			c.Set(handlers.UserEmailKey, email)
+		}
+		c.Next()
+	}
+}
+
+// signedInUser is the realtor user the request's bearer token or session
+// cookie names, if either is valid. A cookie sent from another site doesn't
+// count, and crossSite reports it.
+func signedInUser(c *gin.Context, secret string) (email string, crossSite bool) {
+	if token, ok := strings.CutPrefix(c.Request.Header.Get("Authorization"), "Bearer "); ok {
+		if email, ok := models.ValidUserSession(secret, token, time.Now()); ok {
+			return email, false
+		}
+	}
+
+	session, _ := c.Cookie(models.UserSessionCookie)
+	if email, ok := models.ValidUserSession(secret, session, time.Now()); ok {
+		if origin := c.GetHeader("Origin"); origin != "" && !sameHost(origin, c.Request.Host) {
+			return "", true
+		}
+		return email, false
+	}
+	return "", false
+}
+
+// sameHost reports whether origin (scheme://host[:port]) names host.
+func sameHost(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && strings.EqualFold(u.Host, host)
+}
+
 // staticCacheMiddleware adds optimized caching headers for static files with proper cache strategies
 func staticCacheMiddleware() gin.HandlerFunc {
 	staticPrefixes := []string{
-		"/public/", "/favicon.ico", "/robots.txt", "/sitemap.xml",
+		"/public/", "/favicon.ico", "/robots.txt",
 		"/realtor/js/", "/realtor/css/", "/realtor/images/", "/realtor/static/",
 	}
 	return func(c *gin.Context) {
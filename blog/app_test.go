@@ -2,6 +2,7 @@ package main
 
 import (
 	"bytes"
+	"html/template"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -13,6 +14,7 @@ import (
 	"time"
 
 	"github.com/caddyserver/certmagic"
+	"github.com/etzelm/blog-in-golang/src/models"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"go.uber.org/zap"
@@ -26,6 +28,26 @@ func silenceLogrus(t *testing.T) {
 	})
 }
 
+// testAWSClients builds the clients main would, but trying each call once so
+// routes that reach for AWS fail fast.
+func testAWSClients(t *testing.T) *models.AWSClients {
+	t.Helper()
+	t.Setenv("AWS_MAX_ATTEMPTS", "1")
+	clients, err := newAWSClients()
+	if err != nil {
+		t.Fatalf("newAWSClients: %v", err)
+	}
+	return clients
+}
+
+func TestNewAWSClients_BadEnvironment(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("AWS_HTTP_TIMEOUT", "forever")
+	if _, err := newAWSClients(); err == nil {
+		t.Error("Expected an error for an unparseable AWS_HTTP_TIMEOUT")
+	}
+}
+
 func TestRandRange(t *testing.T) {
 	silenceLogrus(t)
 	testCases := []struct {
@@ -128,16 +150,6 @@ func TestLoadServerRoutes(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
 
-	originalArticlesEnv, articlesEnvIsSet := os.LookupEnv("ARTICLES")
-	os.Setenv("ARTICLES", "dummy-test-articles-table")
-	defer func() {
-		if articlesEnvIsSet {
-			os.Setenv("ARTICLES", originalArticlesEnv)
-		} else {
-			os.Unsetenv("ARTICLES")
-		}
-	}()
-
 	tempTemplatesDir := t.TempDir()
 
 	dummyTemplateFiles := map[string]string{
@@ -156,12 +168,13 @@ func TestLoadServerRoutes(t *testing.T) {
 			t.Fatalf("Failed to create dummy template %s: %v", path, err)
 		}
 	}
+	router.SetFuncMap(models.TemplateFuncs())
 	router.LoadHTMLGlob(filepath.Join(tempTemplatesDir, "*.html"))
 
 	RandomOne = 1
 	RandomTwo = 2
 
-	LoadServerRoutes(router)
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
 
 	var uploadBody bytes.Buffer
 	mpWriter := multipart.NewWriter(&uploadBody)
@@ -181,10 +194,15 @@ func TestLoadServerRoutes(t *testing.T) {
 		{"RootGET", "/", http.MethodGet, nil, ""},
 		{"PostsGET", "/posts", http.MethodGet, nil, ""},
 		{"ArticleGET", "/article/3", http.MethodGet, nil, ""},
+		{"ArticleSlugGET", "/posts/some-slug", http.MethodGet, nil, ""},
+		{"PreviewGET", "/preview/3", http.MethodGet, nil, ""},
 		{"CategoryGET", "/category/somecategory", http.MethodGet, nil, ""},
 		{"ContactGET", "/contact", http.MethodGet, nil, ""},
 		{"ListingsGET", "/listings", http.MethodGet, nil, ""},
 		{"SpecificListingGET", "/listing/MLS123", http.MethodGet, nil, ""},
+		{"ListingDELETE", "/listings/MLS123", http.MethodDelete, nil, ""},
+		{"ListingRestorePOST", "/listings/MLS123/restore", http.MethodPost, nil, ""},
+		{"ListingPurge", "/manage/listings/MLS123", http.MethodDelete, nil, ""},
 		{"AuthGET", "/auth", http.MethodGet, nil, ""},
 		{"AuthPOST", "/auth", http.MethodPost, bytes.NewBufferString("email=test@example.com&password=p"), "application/x-www-form-urlencoded"},
 		{"SecureGET", "/secure", http.MethodGet, nil, ""},
@@ -232,6 +250,41 @@ func TestLoadServerRoutes(t *testing.T) {
 	}
 }
 
+func TestWatchListedArticles(t *testing.T) {
+	articles := models.NewMemoryArticleStore(models.Item{PostID: 0, PostTitle: "Live", PostType: "standard"})
+	changed := make(chan struct{}, 1)
+	go watchListedArticles(models.NewPanelSnapshot(articles), 10*time.Millisecond, func() { changed <- struct{}{} })
+
+	select {
+	case <-changed:
+		t.Fatal("onChange fired before anything changed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// A scheduled post whose publish-at passes while the watcher runs.
+	articles.Put(models.Item{PostID: 1, PostTitle: "Soon", PostType: "standard",
+		Status: models.StatusScheduled, PublishAt: time.Now().Add(100 * time.Millisecond).Format(time.RFC3339Nano)})
+	select {
+	case <-changed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("onChange never fired once the scheduled post went live")
+	}
+}
+
+func TestWatchListedArticlesWakesAtPublishAt(t *testing.T) {
+	articles := models.NewMemoryArticleStore(models.Item{PostID: 1, PostTitle: "Soon", PostType: "standard",
+		Status: models.StatusScheduled, PublishAt: time.Now().Add(100 * time.Millisecond).Format(time.RFC3339Nano)})
+	changed := make(chan struct{}, 1)
+	// Far longer than the test: only the publish-at can wake the watcher.
+	go watchListedArticles(models.NewPanelSnapshot(articles), time.Hour, func() { changed <- struct{}{} })
+
+	select {
+	case <-changed:
+	case <-time.After(3 * time.Second):
+		t.Fatal("onChange never fired at the scheduled post's publish-at")
+	}
+}
+
 func TestLoadMiddlewares(t *testing.T) {
 	silenceLogrus(t)
 	gin.SetMode(gin.TestMode)
@@ -239,6 +292,15 @@ func TestLoadMiddlewares(t *testing.T) {
 	LoadMiddlewares(router)
 }
 
+func TestTemplatesParse(t *testing.T) {
+	// main loads these with LoadHTMLGlob, which panics on a template that
+	// calls a helper missing from models.TemplateFuncs.
+	_, err := template.New("").Funcs(models.TemplateFuncs()).ParseGlob("templates/*")
+	if err != nil {
+		t.Fatalf("templates/*: %v", err)
+	}
+}
+
 func TestLoadStaticFileRoutes(t *testing.T) {
 	silenceLogrus(t)
 	gin.SetMode(gin.TestMode)
@@ -459,7 +521,7 @@ func TestHealthzHandler(t *testing.T) {
 	silenceLogrus(t)
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	LoadServerRoutes(router)
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
 
 	req, _ := http.NewRequest(http.MethodGet, "/healthz", nil)
 	rr := httptest.NewRecorder()
@@ -482,7 +544,7 @@ func TestMetricsAuth_OpenWhenTokenUnset(t *testing.T) {
 	t.Setenv("METRICS_TOKEN", "")
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	LoadServerRoutes(router)
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
 
 	req, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
 	rr := httptest.NewRecorder()
@@ -501,7 +563,7 @@ func TestMetricsAuth_TokenSet(t *testing.T) {
 	t.Setenv("METRICS_TOKEN", "s3cret-token")
 	gin.SetMode(gin.TestMode)
 	router := gin.New()
-	LoadServerRoutes(router)
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
 
 	// No header → 401
 	req1, _ := http.NewRequest(http.MethodGet, "/metrics", nil)
@@ -530,6 +592,278 @@ func TestMetricsAuth_TokenSet(t *testing.T) {
 	}
 }
 
+func TestAdminAuth_ClosedWhenTokenUnset(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("ADMIN_TOKEN", "")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
+
+	req, _ := http.NewRequest(http.MethodPost, "/manage/cache/purge", strings.NewReader(`{"all": true}`))
+	req.Header.Set("Authorization", "Bearer ")
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected /manage/cache/purge to return 401 when ADMIN_TOKEN unset, got %d", rr.Code)
+	}
+}
+
+func TestAdminAuth_TokenSet(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("ADMIN_TOKEN", "s3cret-token")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
+
+	// Wrong token → 401
+	req1, _ := http.NewRequest(http.MethodPost, "/manage/cache/purge", strings.NewReader(`{"all": true}`))
+	req1.Header.Set("Authorization", "Bearer wrong-token")
+	rr1 := httptest.NewRecorder()
+	router.ServeHTTP(rr1, req1)
+	if rr1.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with wrong token, got %d", rr1.Code)
+	}
+
+	// Correct token → 200
+	req2, _ := http.NewRequest(http.MethodPost, "/manage/cache/purge", strings.NewReader(`{"all": true}`))
+	req2.Header.Set("Authorization", "Bearer s3cret-token")
+	req2.Header.Set("Content-Type", "application/json")
+	rr2 := httptest.NewRecorder()
+	router.ServeHTTP(rr2, req2)
+	if rr2.Code != http.StatusOK {
+		t.Errorf("Expected 200 with correct token, got %d: %s", rr2.Code, rr2.Body.String())
+	}
+	if !strings.Contains(rr2.Body.String(), `"purged":0`) {
+		t.Errorf("Expected a purge count in the response, got %q", rr2.Body.String())
+	}
+}
+
+func TestAdminAuth_Session(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("ADMIN_TOKEN", "s3cret-token")
+	t.Setenv("ADMIN_EMAILS", "etzelm@live.com")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
+
+	session := &http.Cookie{
+		Name:  models.AdminSessionCookie,
+		Value: models.AdminSessionToken("s3cret-token", "etzelm@live.com", time.Now().Add(time.Hour)),
+	}
+	forged := &http.Cookie{
+		Name:  models.AdminSessionCookie,
+		Value: models.AdminSessionToken("guess", "etzelm@live.com", time.Now().Add(time.Hour)),
+	}
+
+	// Valid session → 200
+	req1, _ := http.NewRequest(http.MethodGet, "/manage/articles", nil)
+	req1.AddCookie(session)
+	rr1 := httptest.NewRecorder()
+	router.ServeHTTP(rr1, req1)
+	if rr1.Code != http.StatusOK {
+		t.Errorf("Expected 200 with a session, got %d: %s", rr1.Code, rr1.Body.String())
+	}
+
+	// Forged session → 401
+	req2, _ := http.NewRequest(http.MethodGet, "/manage/articles", nil)
+	req2.AddCookie(forged)
+	rr2 := httptest.NewRecorder()
+	router.ServeHTTP(rr2, req2)
+	if rr2.Code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 with a forged session, got %d", rr2.Code)
+	}
+
+	// No session, from a browser → login page
+	req3, _ := http.NewRequest(http.MethodGet, "/manage/editor", nil)
+	req3.Header.Set("Accept", "text/html,application/xhtml+xml")
+	rr3 := httptest.NewRecorder()
+	router.ServeHTTP(rr3, req3)
+	if rr3.Code != http.StatusFound || rr3.Header().Get("Location") != "/auth" {
+		t.Errorf("Expected a redirect to /auth, got %d to %q", rr3.Code, rr3.Header().Get("Location"))
+	}
+
+	// Session, but a form post → 415
+	req4, _ := http.NewRequest(http.MethodPost, "/manage/articles", strings.NewReader("post-id=1"))
+	req4.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req4.AddCookie(session)
+	rr4 := httptest.NewRecorder()
+	router.ServeHTTP(rr4, req4)
+	if rr4.Code != http.StatusUnsupportedMediaType {
+		t.Errorf("Expected 415 for a cookie-authenticated form post, got %d", rr4.Code)
+	}
+
+	// Session, JSON → created
+	req5, _ := http.NewRequest(http.MethodPost, "/manage/articles",
+		strings.NewReader(`{"post-id": 1, "post-title": "Hello", "post-type": "standard", "markdown": "hi"}`))
+	req5.Header.Set("Content-Type", "application/json")
+	req5.AddCookie(session)
+	rr5 := httptest.NewRecorder()
+	router.ServeHTTP(rr5, req5)
+	if rr5.Code != http.StatusCreated {
+		t.Errorf("Expected 201 for a JSON create, got %d: %s", rr5.Code, rr5.Body.String())
+	}
+}
+
+func TestUserAuth(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("SESSION_SECRET", "user-secret")
+	t.Setenv("ADMIN_TOKEN", "s3cret-token")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
+
+	token := models.UserSessionToken("user-secret", "seller@example.com", time.Now().Add(time.Hour))
+	upload := func(header http.Header, cookie *http.Cookie) int {
+		var body bytes.Buffer
+		mpWriter := multipart.NewWriter(&body)
+		mpWriter.Close()
+		req, _ := http.NewRequest(http.MethodPost, "http://blog.test/upload/image", &body)
+		req.Header.Set("Content-Type", mpWriter.FormDataContentType())
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	session := &http.Cookie{Name: models.UserSessionCookie, Value: token}
+
+	if code := upload(nil, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 without a session, got %d", code)
+	}
+	if code := upload(http.Header{"Authorization": {"Bearer " + token}}, nil); code != http.StatusOK {
+		t.Errorf("Expected 200 with a bearer session, got %d", code)
+	}
+	if code := upload(http.Header{"Authorization": {"Bearer s3cret-token"}}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for the admin token, which isn't a user session, got %d", code)
+	}
+	admin := models.AdminSessionToken("user-secret", "seller@example.com", time.Now().Add(time.Hour))
+	if code := upload(http.Header{"Authorization": {"Bearer " + admin}}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for an admin session signed with the user secret, got %d", code)
+	}
+	if code := upload(http.Header{"Origin": {"https://blog.test"}}, session); code != http.StatusOK {
+		t.Errorf("Expected 200 with a same-site session cookie, got %d", code)
+	}
+	if code := upload(http.Header{"Origin": {"https://evil.example"}}, session); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for a cross-site request riding the cookie, got %d", code)
+	}
+
+	t.Setenv("SESSION_SECRET", "")
+	router = gin.New()
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
+	if code := upload(http.Header{"Authorization": {"Bearer " + models.UserSessionToken("", "seller@example.com", time.Now().Add(time.Hour))}}, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for every request when SESSION_SECRET is unset, got %d", code)
+	}
+}
+
+func TestOptionalUser(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("SESSION_SECRET", "user-secret")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	LoadServerRoutes(router, models.NewMemoryArticleStore(), testAWSClients(t))
+
+	token := models.UserSessionToken("user-secret", "seller@example.com", time.Now().Add(time.Hour))
+	get := func(path string, header http.Header) int {
+		req, _ := http.NewRequest(http.MethodGet, "http://blog.test"+path, nil)
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr.Code
+	}
+	mine := "/listings?user=seller@example.com&include_deleted=true"
+
+	if code := get("/listings", nil); code != http.StatusOK {
+		t.Errorf("Expected 200 for an anonymous read, got %d", code)
+	}
+	if code := get(mine, nil); code != http.StatusUnauthorized {
+		t.Errorf("Expected 401 for deleted listings without a session, got %d", code)
+	}
+	if code := get(mine, http.Header{"Authorization": {"Bearer " + token}}); code != http.StatusOK {
+		t.Errorf("Expected 200 for the owner's deleted listings, got %d", code)
+	}
+	if code := get("/listings?user=other@example.com&include_deleted=true", http.Header{"Authorization": {"Bearer " + token}}); code != http.StatusForbidden {
+		t.Errorf("Expected 403 for someone else's deleted listings, got %d", code)
+	}
+	session := &http.Cookie{Name: models.UserSessionCookie, Value: token}
+	req, _ := http.NewRequest(http.MethodGet, "http://blog.test"+mine, nil)
+	req.Header.Set("Origin", "https://evil.example")
+	req.AddCookie(session)
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	if rr.Code != http.StatusUnauthorized {
+		t.Errorf("Expected a cross-site cookie not to count, got %d", rr.Code)
+	}
+}
+
+func TestAPICORS(t *testing.T) {
+	silenceLogrus(t)
+	t.Setenv("API_CORS_ORIGINS", "https://reader.example, https://mirror.example")
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	LoadMiddlewares(router)
+	LoadServerRoutes(router, models.NewMemoryArticleStore(models.Item{
+		HTMLHold: "<p>body</p>", PostID: 1, PostTitle: "One", PostType: "standard",
+	}), testAWSClients(t))
+
+	// Allowed origin → CORS headers, ETag exposed
+	req1, _ := http.NewRequest(http.MethodGet, "/api/v1/articles/1", nil)
+	req1.Header.Set("Origin", "https://reader.example")
+	req1.Header.Set("Accept-Encoding", "gzip")
+	rr1 := httptest.NewRecorder()
+	router.ServeHTTP(rr1, req1)
+	if rr1.Code != http.StatusOK {
+		t.Fatalf("Expected 200, got %d: %s", rr1.Code, rr1.Body.String())
+	}
+	if got := rr1.Header().Get("Access-Control-Allow-Origin"); got != "https://reader.example" {
+		t.Errorf("Expected the origin to be allowed, got %q", got)
+	}
+	if got := rr1.Header().Get("Access-Control-Expose-Headers"); got != "ETag" {
+		t.Errorf("Expected ETag to be exposed, got %q", got)
+	}
+	if !strings.Contains(rr1.Header().Get("Vary"), "Origin") {
+		t.Errorf("Expected Vary: Origin, got %q", rr1.Header().Get("Vary"))
+	}
+
+	// Revalidation through the gzip middleware → bare 304
+	req2, _ := http.NewRequest(http.MethodGet, "/api/v1/articles/1", nil)
+	req2.Header.Set("Accept-Encoding", "gzip")
+	req2.Header.Set("If-None-Match", rr1.Header().Get("ETag"))
+	rr2 := httptest.NewRecorder()
+	router.ServeHTTP(rr2, req2)
+	if rr2.Code != http.StatusNotModified {
+		t.Errorf("Expected 304 for a matching ETag, got %d", rr2.Code)
+	}
+
+	// Other origin → no CORS headers
+	req3, _ := http.NewRequest(http.MethodGet, "/api/v1/articles", nil)
+	req3.Header.Set("Origin", "https://evil.example")
+	rr3 := httptest.NewRecorder()
+	router.ServeHTTP(rr3, req3)
+	if got := rr3.Header().Get("Access-Control-Allow-Origin"); got != "" {
+		t.Errorf("Expected no Access-Control-Allow-Origin for an unlisted origin, got %q", got)
+	}
+
+	// Preflight → 204 with the allowed methods
+	req4, _ := http.NewRequest(http.MethodOptions, "/api/v1/articles", nil)
+	req4.Header.Set("Origin", "https://mirror.example")
+	req4.Header.Set("Access-Control-Request-Method", "GET")
+	rr4 := httptest.NewRecorder()
+	router.ServeHTTP(rr4, req4)
+	if rr4.Code != http.StatusNoContent {
+		t.Errorf("Expected 204 for a preflight, got %d", rr4.Code)
+	}
+	if got := rr4.Header().Get("Access-Control-Allow-Methods"); got != "GET, OPTIONS" {
+		t.Errorf("Expected GET, OPTIONS to be allowed, got %q", got)
+	}
+}
+
 // Helper function to check if a string contains a substring
 func contains(str, substr string) bool {
 	return len(str) >= len(substr) &&
@@ -0,0 +1,126 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ArticlesPage : the /api/v1/articles response, one page of article panels
+// and the token for the next one ("" on the last page).
+type ArticlesPage struct {
+	Articles      []models.Article `json:"articles"`
+	NextPageToken string           `json:"next-page-token,omitempty"`
+}
+
+// ArticlesAPI : Returns a Page of Listed Article Panels as JSON, Newest First (?category=&page_token=&limit=)
+//
+// Clients revalidate on every request, so give it a models.PanelSnapshot:
+// the page and its ETag are then worked out without a read of the store.
+func ArticlesAPI(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		req, err := models.ParsePageRequest(c.Query("page_token"), c.Query("limit"), models.DefaultPostsPageLimit)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var panels []models.Article
+		if category := c.Query("category"); category != "" {
+			// Category panels come oldest first; pages run newest first.
+			panels = slices.Clone(articles.GetCategoryPageArticlePanels(category))
+			slices.Reverse(panels)
+		} else {
+			panels = articles.GetArticlePanels()
+		}
+
+		page, next, err := models.PagePanels(panels, req)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		writeJSON(c, ArticlesPage{Articles: page, NextPageToken: next})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ArticleAPI : Returns One Public Article as JSON, its Body as Rendered HTML (?body=html, the Default) or as Written (?body=source)
+func ArticleAPI(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "article id must be a number")
+			return
+		}
+		body := c.DefaultQuery("body", "html")
+		if body != "html" && body != "source" {
+			apiError(c, http.StatusBadRequest, "body must be html or source")
+			return
+		}
+
+		article, err := articles.GetArticleByID(id)
+		if err != nil || !article.Public(time.Now()) {
+			apiError(c, http.StatusNotFound, "article not found")
+			return
+		}
+		if body == "html" {
+			article.Source, article.SourceFormat = "", ""
+		} else {
+			article.HTMLHold = ""
+		}
+		writeJSON(c, article)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// writeJSON sends v with an ETag of its encoding, or just 304 Not Modified
+// when If-None-Match already names it. Clients revalidate every time
+// (no-cache), so an edit shows up on their next request. The ETag is weak
+// because the gzip middleware may re-encode the body.
+func writeJSON(c *gin.Context, v any) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		log.WithError(err).Error("Failed to encode API response")
+		apiError(c, http.StatusInternalServerError, "internal error")
+		return
+	}
+	sum := sha256.Sum256(buf.Bytes())
+	etag := `W/"` + hex.EncodeToString(sum[:16]) + `"`
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("ETag", etag)
+	if etagMatches(c.GetHeader("If-None-Match"), etag) {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Data(http.StatusOK, "application/json; charset=utf-8", buf.Bytes())
+}
+
+// etagMatches reports whether an If-None-Match header names etag, using the
+// weak comparison RFC 9110 prescribes for it.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			return true
+		}
+	}
+	return false
+}
+
+func apiError(c *gin.Context, status int, message string) {
+	c.Header("Cache-Control", "no-cache")
+	c.AbortWithStatusJSON(status, gin.H{"error": message})
+}
@@ -0,0 +1,201 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupAPIRouter(t *testing.T) *gin.Engine {
+	silenceLogrus(t)
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+
+	articles := testArticleStore()
+	articles.Put(models.Item{Markdown: "# Hello\n\nWritten in *Markdown*.", PostID: 3, PostTitle: "Markdown", PostType: "standard", Categories: "Notes", CreatedDate: "2021-03-01T00:00:00Z"})
+	articles.Put(models.Item{HTMLHold: "<p>d</p>", PostID: 10, PostTitle: "Draft", PostType: "standard", Status: models.StatusDraft})
+	router.GET("/api/v1/articles", ArticlesAPI(articles))
+	router.GET("/api/v1/articles/:id", ArticleAPI(articles))
+	return router
+}
+
+func getAPI(router *gin.Engine, path string, header http.Header) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestArticlesAPI(t *testing.T) {
+	router := setupAPIRouter(t)
+
+	t.Run("Pages", func(t *testing.T) {
+		var ids []int
+		path := "/api/v1/articles?limit=1"
+		for range 10 {
+			recorder := getAPI(router, path, nil)
+			require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+			assert.Equal(t, "application/json; charset=utf-8", recorder.Header().Get("Content-Type"))
+
+			var page ArticlesPage
+			require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+			require.Len(t, page.Articles, 1)
+			ids = append(ids, page.Articles[0].PostID)
+			if page.NextPageToken == "" {
+				break
+			}
+			path = "/api/v1/articles?limit=1&page_token=" + page.NextPageToken
+		}
+		assert.NotContains(t, ids, 10, "Drafts aren't listed")
+		assert.Equal(t, 3, ids[0], "Newest first")
+	})
+
+	t.Run("Category", func(t *testing.T) {
+		recorder := getAPI(router, "/api/v1/articles?category=Distributed+Systems", nil)
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+
+		var page ArticlesPage
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &page))
+		require.Len(t, page.Articles, 2)
+		assert.Equal(t, 2, page.Articles[0].PostID, "Newest first")
+		assert.Equal(t, 0, page.Articles[1].PostID)
+		assert.Empty(t, page.NextPageToken)
+	})
+
+	t.Run("BadRequest", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/articles?limit=zero", "/api/v1/articles?page_token=%21%21"} {
+			recorder := getAPI(router, path, nil)
+			assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+			assert.Contains(t, recorder.Body.String(), `"error"`, path)
+		}
+	})
+}
+
+// countingStore counts the calls that reach the store it wraps.
+type countingStore struct {
+	models.ArticleStore
+	reads int
+}
+
+func (s *countingStore) GetArticlePanels() []models.Article {
+	s.reads++
+	return s.ArticleStore.GetArticlePanels()
+}
+
+func (s *countingStore) GetAllArticlePanels() []models.Article {
+	s.reads++
+	return s.ArticleStore.GetAllArticlePanels()
+}
+
+func (s *countingStore) GetCategoryPageArticlePanels(category string) []models.Article {
+	s.reads++
+	return s.ArticleStore.GetCategoryPageArticlePanels(category)
+}
+
+func TestArticlesAPIFromSnapshot(t *testing.T) {
+	silenceLogrus(t)
+	gin.SetMode(gin.TestMode)
+	store := &countingStore{ArticleStore: testArticleStore()}
+	router := gin.New()
+	router.GET("/api/v1/articles", ArticlesAPI(models.NewPanelSnapshot(store)))
+
+	first := getAPI(router, "/api/v1/articles", nil)
+	require.Equal(t, http.StatusOK, first.Code, first.Body.String())
+	etag := first.Header().Get("ETag")
+	for _, path := range []string{"/api/v1/articles", "/api/v1/articles?category=Distributed+Systems"} {
+		recorder := getAPI(router, path, nil)
+		require.Equal(t, http.StatusOK, recorder.Code, path)
+	}
+	revalidated := getAPI(router, "/api/v1/articles", http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusNotModified, revalidated.Code)
+	assert.Equal(t, 1, store.reads, "Only the snapshot's first read reaches the store")
+
+	recorder := getAPI(router, "/api/v1/articles?category=Distributed+Systems", nil)
+	want := getAPI(setupAPIRouter(t), "/api/v1/articles?category=Distributed+Systems", nil)
+	assert.JSONEq(t, want.Body.String(), recorder.Body.String(), "Category pages match the store's")
+}
+
+func TestArticleAPI(t *testing.T) {
+	router := setupAPIRouter(t)
+
+	decode := func(t *testing.T, recorder *httptest.ResponseRecorder) models.Article {
+		t.Helper()
+		require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+		var article models.Article
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &article))
+		return article
+	}
+
+	t.Run("HTML", func(t *testing.T) {
+		article := decode(t, getAPI(router, "/api/v1/articles/3", nil))
+		assert.Equal(t, "Markdown", article.PostTitle)
+		assert.Contains(t, string(article.HTMLHold), "<em>Markdown</em>")
+		assert.Empty(t, article.Source)
+		assert.Empty(t, article.SourceFormat)
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		article := decode(t, getAPI(router, "/api/v1/articles/3?body=source", nil))
+		assert.Equal(t, "# Hello\n\nWritten in *Markdown*.", article.Source)
+		assert.Equal(t, models.SourceFormatMarkdown, article.SourceFormat)
+		assert.Empty(t, article.HTMLHold)
+
+		article = decode(t, getAPI(router, "/api/v1/articles/2?body=source", nil))
+		assert.Equal(t, "<p>EMR body</p>", article.Source)
+		assert.Equal(t, models.SourceFormatHTML, article.SourceFormat)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		testCases := []struct {
+			path string
+			code int
+		}{
+			{"/api/v1/articles/emr", http.StatusBadRequest},
+			{"/api/v1/articles/2?body=pdf", http.StatusBadRequest},
+			{"/api/v1/articles/99", http.StatusNotFound},
+			{"/api/v1/articles/10", http.StatusNotFound},
+		}
+		for _, tc := range testCases {
+			recorder := getAPI(router, tc.path, nil)
+			assert.Equal(t, tc.code, recorder.Code, tc.path)
+			assert.Contains(t, recorder.Body.String(), `"error"`, tc.path)
+			assert.Equal(t, "no-cache", recorder.Header().Get("Cache-Control"), tc.path)
+		}
+	})
+
+	t.Run("ETag", func(t *testing.T) {
+		first := getAPI(router, "/api/v1/articles/2", nil)
+		require.Equal(t, http.StatusOK, first.Code)
+		etag := first.Header().Get("ETag")
+		require.NotEmpty(t, etag)
+		assert.Equal(t, "no-cache", first.Header().Get("Cache-Control"))
+
+		again := getAPI(router, "/api/v1/articles/2", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusNotModified, again.Code)
+		assert.Empty(t, again.Body.String())
+		assert.Equal(t, etag, again.Header().Get("ETag"))
+
+		source := getAPI(router, "/api/v1/articles/2?body=source", http.Header{"If-None-Match": {etag}})
+		assert.Equal(t, http.StatusOK, source.Code, "Each body mode has its own ETag")
+		assert.NotEqual(t, etag, source.Header().Get("ETag"))
+	})
+}
+
+func TestETagMatches(t *testing.T) {
+	etag := `W/"abc"`
+	assert.True(t, etagMatches(`W/"abc"`, etag))
+	assert.True(t, etagMatches(`"abc"`, etag))
+	assert.True(t, etagMatches(`"xyz", W/"abc"`, etag))
+	assert.True(t, etagMatches(`*`, etag))
+	assert.False(t, etagMatches(`W/"xyz"`, etag))
+	assert.False(t, etagMatches(``, etag))
+}
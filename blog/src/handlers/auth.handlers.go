@@ -2,9 +2,11 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"html/template"
 	"net/http"
 	"regexp"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
@@ -12,6 +14,7 @@ import (
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/etzelm/blog-in-golang/src/models"
 	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
 	"golang.org/x/crypto/bcrypt"
 )
 
@@ -65,104 +68,181 @@ func SecurePage(c *gin.Context) {
 			"title":   "Simple Secure Page",
 			"payload": cookie2,
 			"ip":      ip,
+			"admin":   models.IsAdminEmail(cookie2),
 		},
 	)
 
 }
 
 // AuthResponse : Saves the user's data in DynamoDB and displays static response.html
-func AuthResponse(c *gin.Context) {
-	c.Header("Cache-Control", "no-cache")
-	var form models.AuthForm
-	c.Bind(&form)
+func AuthResponse(clients *models.AWSClients) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		var form models.AuthForm
+		c.Bind(&form)
 
-	email := template.HTMLEscapeString(form.Email)
-	if m, _ := regexp.MatchString("^[ a-zA-Z0-9]+(@[a-zA-Z0-9.]+)*$", email); !m {
-		c.HTML(
-			// Set the HTTP status to 401 (Unauthorized)
-			http.StatusUnauthorized,
-			// Use the error.html template
-			"error.html",
-			// Pass the data that the page uses
-			gin.H{
-				"title": "401 (Unauthorized)",
-				"error": "Email should match a standard format like etzelm@live.com",
-			},
-		)
-		return
-	}
+		email := template.HTMLEscapeString(form.Email)
+		if m, _ := regexp.MatchString("^[ a-zA-Z0-9]+(@[a-zA-Z0-9.]+)*$", email); !m {
+			c.HTML(
+				// Set the HTTP status to 401 (Unauthorized)
+				http.StatusUnauthorized,
+				// Use the error.html template
+				"error.html",
+				// Pass the data that the page uses
+				gin.H{
+					"title": "401 (Unauthorized)",
+					"error": "Email should match a standard format like etzelm@live.com",
+				},
+			)
+			return
+		}
 
-	ctx := context.TODO()
-	dbSvc, err := createDynamoDBClient(ctx)
-	if err != nil {
-		c.HTML(
-			http.StatusInternalServerError,
-			"error.html",
-			gin.H{
-				"title": "500 Internal Server Error",
-				"error": err.Error(),
-			},
-		)
-		return
-	}
+		ctx := context.TODO()
+		dbSvc := clients.DynamoDB
 
-	result, err := dbSvc.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String("Auth"),
-		Key: map[string]types.AttributeValue{
-			"email": &types.AttributeValueMemberS{
-				Value: form.Email,
+		result, err := dbSvc.GetItem(ctx, &dynamodb.GetItemInput{
+			TableName: aws.String("Auth"),
+			Key: map[string]types.AttributeValue{
+				"email": &types.AttributeValueMemberS{
+					Value: form.Email,
+				},
 			},
-		},
-	})
-	if err != nil {
-		c.HTML(
-			http.StatusInternalServerError,
-			"error.html",
-			gin.H{
-				"title": "500 Internal Server Error",
-				"error": err.Error(),
-			},
-		)
-		return
-	}
+		})
+		if err != nil {
+			c.HTML(
+				http.StatusInternalServerError,
+				"error.html",
+				gin.H{
+					"title": "500 Internal Server Error",
+					"error": err.Error(),
+				},
+			)
+			return
+		}
+
+		authForm := models.AuthForm{}
+		err = attributevalue.UnmarshalMap(result.Item, &authForm)
+		if err != nil {
+			c.HTML(
+				http.StatusInternalServerError,
+				"error.html",
+				gin.H{
+					"title": "500 Internal Server Error",
+					"error": err.Error(),
+				},
+			)
+			return
+		}
+
+		if CheckPasswordHash(form.Password, authForm.Password) {
+			cipher, _ := HashPassword(form.Email)
+			// Secure: HTTPS-only (prod and Traefik-fronted dev are both HTTPS).
+			// HttpOnly: blocks JS access; /secure reads these server-side via
+			// c.Cookie(), unaffected. Fixes CodeQL alerts #27-30.
+			c.SetCookie("user", form.Email, 60*60*24, "/", "mitchelletzel.com", true, true)
+			c.SetCookie("userToken", cipher, 60*60*24, "/", "mitchelletzel.com", true, true)
+			// Admins also get a signed session for the /manage editor. It's
+			// host-only and SameSite=Strict so other sites can't ride it.
+			if secret := models.AdminSecret(); secret != "" && models.IsAdminEmail(form.Email) {
+				token := models.AdminSessionToken(secret, form.Email, time.Now().Add(models.AdminSessionTTL))
+				c.SetSameSite(http.SameSiteStrictMode)
+				c.SetCookie(models.AdminSessionCookie, token, int(models.AdminSessionTTL.Seconds()), "/manage", "", true, true)
+			}
+			// Everyone gets a user session, which the realtor write APIs take.
+			setUserSession(c, form.Email)
+		} else {
+			c.HTML(
+				// Set the HTTP status to 400 (Bad Request)
+				http.StatusBadRequest,
+				// Use the error.html template
+				"error.html",
+				// Pass the data that the page uses
+				gin.H{
+					"title": "400 Client Error",
+				},
+			)
+			return
+		}
+
+		c.Redirect(http.StatusFound, "/secure")
 
-	authForm := models.AuthForm{}
-	err = attributevalue.UnmarshalMap(result.Item, &authForm)
-	if err != nil {
-		c.HTML(
-			http.StatusInternalServerError,
-			"error.html",
-			gin.H{
-				"title": "500 Internal Server Error",
-				"error": err.Error(),
-			},
-		)
-		return
 	}
+	return gin.HandlerFunc(fn)
+}
 
-	if CheckPasswordHash(form.Password, authForm.Password) {
-		cipher, _ := HashPassword(form.Email)
-		// Secure: HTTPS-only (prod and Traefik-fronted dev are both HTTPS).
-		// HttpOnly: blocks JS access; /secure reads these server-side via
-		// c.Cookie(), unaffected. Fixes CodeQL alerts #27-30.
-		c.SetCookie("user", form.Email, 60*60*24, "/", "mitchelletzel.com", true, true)
-		c.SetCookie("userToken", cipher, 60*60*24, "/", "mitchelletzel.com", true, true)
-	} else {
-		c.HTML(
-			// Set the HTTP status to 400 (Bad Request)
-			http.StatusBadRequest,
-			// Use the error.html template
-			"error.html",
-			// Pass the data that the page uses
-			gin.H{
-				"title": "400 Client Error",
-			},
-		)
-		return
+// UserEmailKey is the gin context key userAuth stores the signed-in user's
+// email under.
+const UserEmailKey = "userEmail"
+
+// RealtorSession is the body of a successful POST /realtor/session.
+type RealtorSession struct {
+	User    string `json:"user"`
+	Token   string `json:"token"`
+	Expires string `json:"expires"`
+}
+
+// RealtorSessionAPI : Exchanges A Google Sign-In Credential For A Session
+//
+// The realtor app POSTs {"credential": "<ID token>"} after Google sign-in.
+// A credential verifier accepts starts a user session: the userSession
+// cookie is set and the token also comes back in the body, for clients that
+// would rather send it as a bearer token. 401 for a credential that doesn't
+// verify, 503 when sign-in isn't configured (no SESSION_SECRET, or no
+// verifier because GAPI is unset).
+func RealtorSessionAPI(verifier models.IDTokenVerifier) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		if verifier == nil || models.UserSecret() == "" {
+			apiError(c, http.StatusServiceUnavailable, "sign-in is not configured")
+			return
+		}
+		var body struct {
+			Credential string `json:"credential" binding:"required"`
+		}
+		if err := c.ShouldBindJSON(&body); err != nil {
+			apiError(c, http.StatusBadRequest, "send {\"credential\": \"<Google ID token>\"}")
+			return
+		}
+		email, err := verifier.Verify(c.Request.Context(), body.Credential)
+		if errors.Is(err, models.ErrInvalidIDToken) {
+			apiError(c, http.StatusUnauthorized, err.Error())
+			return
+		}
+		if err != nil {
+			log.WithError(err).Error("Verifying sign-in credential")
+			apiError(c, http.StatusBadGateway, "couldn't verify the credential; try again")
+			return
+		}
+		c.JSON(http.StatusOK, setUserSession(c, email))
 	}
+	return gin.HandlerFunc(fn)
+}
 
-	c.Redirect(http.StatusFound, "/secure")
+// EndRealtorSessionAPI : Signs The User Out By Clearing Their Session Cookie
+func EndRealtorSessionAPI(c *gin.Context) {
+	c.Header("Cache-Control", "no-cache")
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(models.UserSessionCookie, "", -1, "/", "", true, true)
+	c.Status(http.StatusNoContent)
+}
 
+// setUserSession sets the userSession cookie for email, if user sessions
+// are configured, and returns the session. It's host-only and
+// SameSite=Strict so other sites can't ride it.
+func setUserSession(c *gin.Context, email string) RealtorSession {
+	secret := models.UserSecret()
+	if secret == "" {
+		return RealtorSession{}
+	}
+	expires := time.Now().Add(models.UserSessionTTL)
+	session := RealtorSession{
+		User:    email,
+		Token:   models.UserSessionToken(secret, email, expires),
+		Expires: models.FormatDate(expires),
+	}
+	c.SetSameSite(http.SameSiteStrictMode)
+	c.SetCookie(models.UserSessionCookie, session.Token, int(models.UserSessionTTL.Seconds()), "/", "", true, true)
+	return session
 }
 
 // https://www.thepolyglotdeveloper.com/2018/02/encrypt-decrypt-data-golang-application-crypto-packages/
@@ -1,14 +1,22 @@
 package handlers
 
 import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"os"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/etzelm/blog-in-golang/src/models"
 	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestCheckPasswordHash(t *testing.T) {
@@ -168,7 +176,7 @@ func TestAuthResponse(t *testing.T) {
 		}
 	}()
 
-	router.POST("/auth", AuthResponse)
+	router.POST("/auth", AuthResponse(testAWSClients(t)))
 
 	testCases := []struct {
 		name            string
@@ -326,7 +334,7 @@ func TestAuthResponse_BadPassword(t *testing.T) {
 	}
 	router, _, _ := setupTestRouterWithHTMLTemplates(t, dummyTemplates)
 
-	router.POST("/auth", AuthResponse)
+	router.POST("/auth", AuthResponse(testAWSClients(t)))
 
 	formData := url.Values{
 		"email":    {"test@example.com"},
@@ -581,3 +589,76 @@ func TestCheckPasswordHash_EdgeCases(t *testing.T) {
 		})
 	}
 }
+
+// fakeVerifier accepts "good:<email>" credentials and fails everything else
+// as invalid, or with err when set.
+type fakeVerifier struct{ err error }
+
+func (v fakeVerifier) Verify(_ context.Context, credential string) (string, error) {
+	if v.err != nil {
+		return "", v.err
+	}
+	if email, ok := strings.CutPrefix(credential, "good:"); ok {
+		return email, nil
+	}
+	return "", fmt.Errorf("%w: bad signature", models.ErrInvalidIDToken)
+}
+
+func TestRealtorSessionAPI(t *testing.T) {
+	silenceLogrus(t)
+	gin.SetMode(gin.TestMode)
+	t.Setenv("SESSION_SECRET", "user-secret")
+
+	post := func(verifier models.IDTokenVerifier, body string) *httptest.ResponseRecorder {
+		router := gin.New()
+		router.POST("/realtor/session", RealtorSessionAPI(verifier))
+		req, _ := http.NewRequest(http.MethodPost, "/realtor/session", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, req)
+		return recorder
+	}
+
+	recorder := post(fakeVerifier{}, `{"credential": "good:seller@example.com"}`)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var session RealtorSession
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &session))
+	assert.Equal(t, "seller@example.com", session.User)
+	email, ok := models.ValidUserSession("user-secret", session.Token, time.Now())
+	assert.True(t, ok)
+	assert.Equal(t, "seller@example.com", email)
+
+	cookie := recorder.Result().Cookies()
+	require.Len(t, cookie, 1)
+	assert.Equal(t, models.UserSessionCookie, cookie[0].Name)
+	value, _ := url.QueryUnescape(cookie[0].Value)
+	assert.Equal(t, session.Token, value)
+	assert.True(t, cookie[0].HttpOnly)
+	assert.True(t, cookie[0].Secure)
+	assert.Equal(t, http.SameSiteStrictMode, cookie[0].SameSite)
+
+	assert.Equal(t, http.StatusUnauthorized, post(fakeVerifier{}, `{"credential": "forged"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, post(fakeVerifier{}, `{}`).Code)
+	assert.Equal(t, http.StatusBadGateway, post(fakeVerifier{err: errors.New("offline")}, `{"credential": "good:a@b.c"}`).Code)
+	assert.Equal(t, http.StatusServiceUnavailable, post(nil, `{"credential": "good:a@b.c"}`).Code, "No GAPI")
+
+	t.Setenv("SESSION_SECRET", "")
+	assert.Equal(t, http.StatusServiceUnavailable, post(fakeVerifier{}, `{"credential": "good:a@b.c"}`).Code, "No SESSION_SECRET")
+}
+
+func TestEndRealtorSessionAPI(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.DELETE("/realtor/session", EndRealtorSessionAPI)
+
+	req, _ := http.NewRequest(http.MethodDelete, "/realtor/session", nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	cookies := recorder.Result().Cookies()
+	require.Len(t, cookies, 1)
+	assert.Equal(t, models.UserSessionCookie, cookies[0].Name)
+	assert.Empty(t, cookies[0].Value)
+	assert.Negative(t, cookies[0].MaxAge)
+}
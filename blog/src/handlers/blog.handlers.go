@@ -4,13 +4,13 @@ import (
 	"context"
 	"html/template"
 	"net/http"
-	"os"
+	"net/url"
 	"regexp"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/etzelm/blog-in-golang/src/models"
@@ -18,67 +18,113 @@ import (
 	log "github.com/sirupsen/logrus"
 )
 
-// createAWSConfig creates AWS configuration with proper credentials
-func createAWSConfig(ctx context.Context) (aws.Config, error) {
-	aid := os.Getenv("AWS_ACCESS_KEY_ID")
-	key := os.Getenv("AWS_SECRET_ACCESS_KEY")
+// PostPage : Gets a Page of Article Panels (?page_token=&limit=) and Dynamically Displays index.html Template
+func PostPage(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		req, err := models.ParsePageRequest(c.Query("page_token"), c.Query("limit"), models.DefaultPostsPageLimit)
+		if err != nil {
+			renderErrorPage(c, 400, "400 (Bad Request)", "Please provide a valid page.")
+			return
+		}
+		panels, next, err := models.PagePanels(articles.GetArticlePanels(), req)
+		if err != nil {
+			renderErrorPage(c, 400, "400 (Bad Request)", "Please provide a valid page.")
+			return
+		}
 
-	var cfg aws.Config
-	var err error
+		data := gin.H{
+			"title":   "Blog Posts",
+			"payload": panels,
+		}
+		if next != "" {
+			data["nextPage"] = pageURL("/posts", next, c.Query("limit"))
+		}
+		if req.Token != "" {
+			data["firstPage"] = pageURL("/posts", "", c.Query("limit"))
+		}
 
-	if aid != "" && key != "" {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion("us-west-1"),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(aid, key, "")),
-		)
-	} else {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion("us-west-1"),
+		// Call the HTML method of the Context to render a template
+		c.HTML(
+			// Set the HTTP status to 200 (OK)
+			http.StatusOK,
+			// Use the index.html template
+			"index.html",
+			// Pass the data that the page uses
+			data,
 		)
 	}
-
-	return cfg, err
+	return gin.HandlerFunc(fn)
 }
 
-// createDynamoDBClient creates a DynamoDB client with proper configuration
-func createDynamoDBClient(ctx context.Context) (*dynamodb.Client, error) {
-	cfg, err := createAWSConfig(ctx)
-	if err != nil {
-		return nil, err
+// pageURL links to the page of path starting at token, carrying over an
+// explicit ?limit=.
+func pageURL(path, token, limit string) string {
+	query := url.Values{}
+	if token != "" {
+		query.Set("page_token", token)
+	}
+	if limit != "" {
+		query.Set("limit", limit)
 	}
-	return dynamodb.NewFromConfig(cfg), nil
+	if len(query) == 0 {
+		return path
+	}
+	return path + "?" + query.Encode()
 }
 
-// PostPage : Gets All Article Panels and Dynamically Displays index.html Template
-func PostPage(c *gin.Context) {
-	c.Header("Cache-Control", "public, max-age=31536000")
-	panels := models.GetArticlePanels()
-
-	// Call the HTML method of the Context to render a template
-	c.HTML(
-		// Set the HTTP status to 200 (OK)
-		http.StatusOK,
-		// Use the index.html template
-		"index.html",
-		// Pass the data that the page uses
-		gin.H{
-			"title":   "Blog Posts",
-			"payload": panels,
-		},
-	)
-
+// nextPageURL links to the page of u starting at token, carrying over the
+// rest of u's query (the search, the sort and ?limit=).
+func nextPageURL(u *url.URL, token string) string {
+	query := u.Query()
+	query.Set("page_token", token)
+	return u.Path + "?" + query.Encode()
 }
 
 // CategoryPage : Gets Category Article Panels and Dynamically Displays index.html Template
-func CategoryPage(c *gin.Context) {
-	c.Header("Cache-Control", "public, max-age=31536000")
-	if category := c.Param("category"); category != "" {
-		panels := models.GetCategoryPageArticlePanels(category)
+func CategoryPage(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		if category := c.Param("category"); category != "" {
+			panels := articles.GetCategoryPageArticlePanels(category)
+
+			if len(panels) <= 0 {
+				// If an invalid category is specified in the URL, abort with an error
+				renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid category")
+				return
+			}
 
-		if len(panels) <= 0 {
+			// Call the HTML method of the Context to render a template
+			c.HTML(
+				// Set the HTTP status to 200 (OK)
+				http.StatusOK,
+				// Use the index.html template
+				"index.html",
+				// Pass the data that the page uses
+				gin.H{
+					"payload":    panels,
+					"category":   category,
+					"title":      category,
+					"IsCategory": true,
+				},
+			)
+
+		} else {
 			// If an invalid category is specified in the URL, abort with an error
 			renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid category")
-			return
+		}
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// SearchPage : Searches the articles for ?q= and Displays the Matches with the index.html Template
+func SearchPage(index *models.SearchIndex) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+		query := strings.TrimSpace(c.Query("q"))
+		results := []models.Article{}
+		if query != "" {
+			results = index.Search(query)
 		}
 
 		// Call the HTML method of the Context to render a template
@@ -89,27 +135,22 @@ func CategoryPage(c *gin.Context) {
 			"index.html",
 			// Pass the data that the page uses
 			gin.H{
-				"payload":    panels,
-				"category":   category,
-				"title":      category,
-				"IsCategory": true,
+				"title":    "Search",
+				"payload":  results,
+				"query":    query,
+				"IsSearch": true,
 			},
 		)
-
-	} else {
-		// If an invalid category is specified in the URL, abort with an error
-		renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid category")
 	}
-
+	return gin.HandlerFunc(fn)
 }
 
-// ArticlePage : Queries DynamoDB for a Specific Article and Dynamically Displays article.html
-func ArticlePage(c *gin.Context) {
-	c.Header("Cache-Control", "public, max-age=31536000")
-	// Check if the article ID is valid
-	if articleID, err := strconv.Atoi(c.Param("article_id")); err == nil {
-		// Check if the article exists
-		if article, err := models.GetArticleByID(articleID); err == nil {
+// ArticlePage : Queries the ArticleStore for a Specific Article by Slug and Dynamically Displays article.html
+func ArticlePage(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		// Check if the article exists and is public
+		if article, err := articles.GetArticleBySlug(c.Param("slug")); err == nil && article.Public(time.Now()) {
 			// Check the post type for appropriateness
 			if article.PostType != "quote" && article.PostType != "" {
 				// Call the HTML method of the Context to render a template
@@ -120,22 +161,90 @@ func ArticlePage(c *gin.Context) {
 					"article.html",
 					// Pass the data that the page uses
 					gin.H{
-						"title":   article.ShortTitle,
-						"payload": article,
+						"title":     article.ShortTitle,
+						"payload":   article,
+						"canonical": models.SiteURL() + "/posts/" + article.Slug,
 					},
 				)
 			} else {
 				// If the article is not appropriate, abort with an error
-				renderErrorPage(c, 401, "401 (Unauthorized)", "Please provide a valid Article ID.")
+				renderErrorPage(c, 401, "401 (Unauthorized)", "Please provide a valid Article.")
+			}
+		} else {
+			// If the article is not found, abort with an error. Don't let
+			// browsers or the CDN hold on to it: it may be a scheduled post.
+			c.Header("Cache-Control", "no-cache")
+			renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid Article.")
+		}
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ArticleRedirect : Permanently Redirects the Old Numeric /article/:article_id URLs to /posts/:slug
+func ArticleRedirect(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		// Check if the article ID is valid
+		if articleID, err := strconv.Atoi(c.Param("article_id")); err == nil {
+			// Check if the article exists and is public
+			if article, err := articles.GetArticleByID(articleID); err == nil && article.Public(time.Now()) {
+				// Check the post type for appropriateness
+				if article.PostType != "quote" && article.PostType != "" {
+					c.Redirect(http.StatusMovedPermanently, "/posts/"+article.Slug)
+				} else {
+					// If the article is not appropriate, abort with an error
+					renderErrorPage(c, 401, "401 (Unauthorized)", "Please provide a valid Article ID.")
+				}
+			} else {
+				// If the article is not found, abort with an error
+				c.Header("Cache-Control", "no-cache")
+				renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid Article ID.")
 			}
 		} else {
-			// If the article is not found, abort with an error
+			// If an invalid article ID is specified in the URL, abort with an error
 			renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid Article ID.")
 		}
-	} else {
-		// If an invalid article ID is specified in the URL, abort with an error
-		renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid Article ID.")
 	}
+	return gin.HandlerFunc(fn)
+}
+
+// PreviewPage : Displays Any Article, Drafts Included, Given a Signed Link from models.PreviewURL
+func PreviewPage(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		articleID, err := strconv.Atoi(c.Param("article_id"))
+		// Check the link's signature and expiry before touching the store
+		if err != nil || !models.ValidPreview(models.PreviewSecret(), articleID, c.Query("expires"), c.Query("signature"), time.Now()) {
+			renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid preview link.")
+			return
+		}
+		// Check if the article exists
+		article, err := articles.GetArticleByID(articleID)
+		if err != nil {
+			renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid preview link.")
+			return
+		}
+		// Check the post type for appropriateness
+		if article.PostType == "quote" || article.PostType == "" {
+			renderErrorPage(c, 401, "401 (Unauthorized)", "Please provide a valid Article ID.")
+			return
+		}
+
+		// Call the HTML method of the Context to render a template
+		c.HTML(
+			// Set the HTTP status to 200 (OK)
+			http.StatusOK,
+			// Use the article.html template
+			"article.html",
+			// Pass the data that the page uses
+			gin.H{
+				"title":   "Preview: " + article.ShortTitle,
+				"payload": article,
+				"noindex": true,
+			},
+		)
+	}
+	return gin.HandlerFunc(fn)
 }
 
 // AboutPage : Renders the about.html résumé page from the resume.json single
@@ -186,7 +295,7 @@ func ContactPage(numOne *int, numTwo *int) gin.HandlerFunc {
 }
 
 // ContactResponse : Saves the user's data in DynamoDB and displays static response.html
-func ContactResponse(numOne *int, numTwo *int) gin.HandlerFunc {
+func ContactResponse(numOne *int, numTwo *int, clients *models.AWSClients) gin.HandlerFunc {
 	fn := func(c *gin.Context) {
 		c.Header("Cache-Control", "no-cache")
 		var form models.ContactForm
@@ -208,12 +317,7 @@ func ContactResponse(numOne *int, numTwo *int) gin.HandlerFunc {
 		}
 
 		ctx := context.TODO()
-		dbSvc, err := createDynamoDBClient(ctx)
-		if err != nil {
-			log.Error("Unable to create DynamoDB client:", err)
-			renderErrorPage(c, 500, "500 Internal Server Error", err.Error())
-			return
-		}
+		dbSvc := clients.DynamoDB
 
 		av, err := attributevalue.MarshalMap(form)
 		if err != nil {
@@ -0,0 +1,186 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-contrib/cache"
+	"github.com/gin-contrib/cache/persistence"
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	log "github.com/sirupsen/logrus"
+)
+
+// PageCacheMetrics : the Prometheus series a PageCache reports to. Any of
+// them may be nil.
+type PageCacheMetrics struct {
+	Hits    prometheus.Counter
+	Misses  prometheus.Counter
+	Entries prometheus.Gauge
+}
+
+// PageCache : the in-memory store behind the cache.CachePage routes. It
+// remembers which request URI each cache key belongs to (CachePage hashes
+// the URI into the key), so entries can be purged by URL or URL prefix as
+// well as all at once, and it counts hits and misses. Safe for concurrent use.
+type PageCache struct {
+	*persistence.InMemoryStore
+	ttl     time.Duration
+	metrics PageCacheMetrics
+
+	mu   sync.Mutex
+	uris map[string]string // cache key -> request URI
+}
+
+// NewPageCache returns an empty PageCache whose pages expire after ttl.
+func NewPageCache(ttl time.Duration, metrics PageCacheMetrics) *PageCache {
+	return &PageCache{
+		InMemoryStore: persistence.NewInMemoryStore(ttl),
+		ttl:           ttl,
+		metrics:       metrics,
+		uris:          map[string]string{},
+	}
+}
+
+// Page wraps handle in cache.CachePage backed by this store.
+func (p *PageCache) Page(handle gin.HandlerFunc) gin.HandlerFunc {
+	cached := cache.CachePage(p, p.ttl, handle)
+	return func(c *gin.Context) {
+		uri := c.Request.URL.RequestURI()
+		key := cache.CreateKey(uri)
+		// Counted here rather than in Get: CachePage's writer also calls Get
+		// on every Write, which would inflate the numbers.
+		if _, hit := p.InMemoryStore.Cache.Get(key); hit {
+			inc(p.metrics.Hits)
+		} else {
+			inc(p.metrics.Misses)
+		}
+		cached(c)
+		p.track(key, uri)
+	}
+}
+
+// track records uri against key if the response ended up in the store.
+func (p *PageCache) track(key, uri string) {
+	_, stored := p.InMemoryStore.Cache.Get(key)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if stored {
+		p.uris[key] = uri
+	} else {
+		delete(p.uris, key)
+	}
+	p.report()
+}
+
+// Len returns how many pages are cached.
+func (p *PageCache) Len() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.uris)
+}
+
+// PurgeURL drops the cached page for exactly uri (path plus any query, as
+// requested, e.g. "/category/Distributed%20Systems") and reports how many
+// entries went, zero or one.
+func (p *PageCache) PurgeURL(uri string) int {
+	return p.purge(func(cached string) bool { return cached == uri })
+}
+
+// PurgePrefix drops every cached page whose URI starts with prefix.
+func (p *PageCache) PurgePrefix(prefix string) int {
+	return p.purge(func(cached string) bool { return strings.HasPrefix(cached, prefix) })
+}
+
+// PurgeAll empties the store.
+func (p *PageCache) PurgeAll() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := len(p.uris)
+	_ = p.InMemoryStore.Flush()
+	p.uris = map[string]string{}
+	p.report()
+	return n
+}
+
+// Flush empties the store; it is PurgeAll without the count.
+func (p *PageCache) Flush() error {
+	p.PurgeAll()
+	return nil
+}
+
+func (p *PageCache) purge(match func(uri string) bool) int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := 0
+	for key, uri := range p.uris {
+		if match(uri) {
+			_ = p.InMemoryStore.Delete(key)
+			delete(p.uris, key)
+			n++
+		}
+	}
+	p.report()
+	return n
+}
+
+// report publishes the entry count; callers must hold p.mu.
+func (p *PageCache) report() {
+	if p.metrics.Entries != nil {
+		p.metrics.Entries.Set(float64(len(p.uris)))
+	}
+}
+
+func inc(counter prometheus.Counter) {
+	if counter != nil {
+		counter.Inc()
+	}
+}
+
+// CachePurgeRequest : body of POST /manage/cache/purge. Exactly one field
+// must be set.
+type CachePurgeRequest struct {
+	URL    string `json:"url"`
+	Prefix string `json:"prefix"`
+	All    bool   `json:"all"`
+}
+
+// CachePurge : Drops One URL, a URL Prefix or Every Page from the Page Cache
+func CachePurge(pages *PageCache) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+		var req CachePurgeRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid purge request: " + err.Error()})
+			return
+		}
+
+		set := 0
+		for _, given := range []bool{req.URL != "", req.Prefix != "", req.All} {
+			if given {
+				set++
+			}
+		}
+		if set != 1 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Set exactly one of url, prefix or all"})
+			return
+		}
+
+		var purged int
+		switch {
+		case req.All:
+			purged = pages.PurgeAll()
+		case req.Prefix != "":
+			purged = pages.PurgePrefix(req.Prefix)
+		default:
+			purged = pages.PurgeURL(req.URL)
+		}
+
+		log.WithFields(log.Fields{"url": req.URL, "prefix": req.Prefix, "all": req.All, "purged": purged}).Info("Purged page cache")
+		c.JSON(http.StatusOK, gin.H{"purged": purged, "entries": pages.Len()})
+	}
+	return gin.HandlerFunc(fn)
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// setupPageCacheRouter serves a counter page at a handful of paths through
+// a fresh PageCache, so tests can tell cached responses from fresh ones.
+func setupPageCacheRouter(t *testing.T) (*gin.Engine, *PageCache, PageCacheMetrics) {
+	t.Helper()
+	silenceLogrus(t)
+	gin.SetMode(gin.TestMode)
+
+	metrics := PageCacheMetrics{
+		Hits:    prometheus.NewCounter(prometheus.CounterOpts{Name: "hits"}),
+		Misses:  prometheus.NewCounter(prometheus.CounterOpts{Name: "misses"}),
+		Entries: prometheus.NewGauge(prometheus.GaugeOpts{Name: "entries"}),
+	}
+	pages := NewPageCache(time.Hour, metrics)
+
+	calls := 0
+	counter := func(c *gin.Context) {
+		calls++
+		// A fresh slice each time: CachePage keeps the slice it's given, so
+		// c.String's pooled fmt buffer would be overwritten under it.
+		c.Data(http.StatusOK, "text/plain", []byte(fmt.Sprintf("%s #%d", c.Request.URL.Path, calls)))
+	}
+
+	router := gin.New()
+	router.GET("/posts", pages.Page(counter))
+	router.GET("/category/:category", pages.Page(counter))
+	router.POST("/manage/cache/purge", CachePurge(pages))
+	return router, pages, metrics
+}
+
+// metricValue reads the current value of a counter or gauge.
+func metricValue(m prometheus.Metric) float64 {
+	var out dto.Metric
+	if err := m.Write(&out); err != nil {
+		return -1
+	}
+	if out.Counter != nil {
+		return out.Counter.GetValue()
+	}
+	return out.Gauge.GetValue()
+}
+
+func get(router *gin.Engine, path string) string {
+	req, _ := http.NewRequest(http.MethodGet, path, nil)
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder.Body.String()
+}
+
+func purge(router *gin.Engine, body string) *httptest.ResponseRecorder {
+	req, _ := http.NewRequest(http.MethodPost, "/manage/cache/purge", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestPageCache_HitsMissesAndEntries(t *testing.T) {
+	router, pages, metrics := setupPageCacheRouter(t)
+
+	assert.Equal(t, "/posts #1", get(router, "/posts"))
+	assert.Equal(t, "/posts #1", get(router, "/posts"), "Second request should be served from the cache")
+	assert.Equal(t, "/category/Cloud #2", get(router, "/category/Cloud"))
+
+	assert.Equal(t, 2, pages.Len())
+	assert.Equal(t, 1.0, metricValue(metrics.Hits))
+	assert.Equal(t, 2.0, metricValue(metrics.Misses))
+	assert.Equal(t, 2.0, metricValue(metrics.Entries))
+}
+
+func TestCachePurge(t *testing.T) {
+	router, pages, metrics := setupPageCacheRouter(t)
+	get(router, "/posts")
+	get(router, "/category/Cloud")
+	get(router, "/category/Distributed%20Systems")
+
+	recorder := purge(router, `{"url": "/category/Distributed%20Systems"}`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, `{"purged": 1, "entries": 2}`, recorder.Body.String())
+	assert.Equal(t, "/category/Distributed Systems #4", get(router, "/category/Distributed%20Systems"), "Purged URL should be rebuilt")
+	assert.Equal(t, "/posts #1", get(router, "/posts"), "Other URLs should stay cached")
+
+	recorder = purge(router, `{"prefix": "/category/"}`)
+	assert.JSONEq(t, `{"purged": 2, "entries": 1}`, recorder.Body.String())
+	assert.Equal(t, "/category/Cloud #5", get(router, "/category/Cloud"))
+
+	recorder = purge(router, `{"url": "/nope"}`)
+	assert.JSONEq(t, `{"purged": 0, "entries": 2}`, recorder.Body.String())
+
+	recorder = purge(router, `{"all": true}`)
+	assert.JSONEq(t, `{"purged": 2, "entries": 0}`, recorder.Body.String())
+	assert.Equal(t, 0, pages.Len())
+	assert.Equal(t, 0.0, metricValue(metrics.Entries))
+	assert.Equal(t, "/posts #6", get(router, "/posts"))
+}
+
+func TestCachePurge_BadRequests(t *testing.T) {
+	router, _, _ := setupPageCacheRouter(t)
+
+	for _, body := range []string{
+		``,
+		`not json`,
+		`{}`,
+		`{"all": false}`,
+		`{"url": "/posts", "all": true}`,
+		`{"url": "/posts", "prefix": "/"}`,
+	} {
+		recorder := purge(router, body)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, body)
+		assert.Contains(t, recorder.Body.String(), `"error"`, body)
+	}
+}
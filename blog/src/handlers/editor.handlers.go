@@ -0,0 +1,416 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+// ArticleSummary : one row of the editor's article list, the Item fields it
+// needs to pick an article without fetching every body.
+type ArticleSummary struct {
+	PostID       int    `json:"post-id"`
+	PostTitle    string `json:"post-title"`
+	PostType     string `json:"post-type"`
+	Status       string `json:"status,omitempty"`
+	PublishAt    string `json:"publish-at,omitempty"`
+	ModifiedDate string `json:"modified-date"`
+	Version      int    `json:"version"`
+}
+
+// EditorPage : Renders the Admin Article Editor
+func EditorPage(c *gin.Context) {
+	c.Header("Cache-Control", "private, no-store")
+	c.HTML(
+		http.StatusOK,
+		"editor.html",
+		gin.H{
+			"title": "Article Editor",
+		},
+	)
+}
+
+// EditorArticlesAPI : Lists Every Article, Drafts and Quotes Included, Newest First
+func EditorArticlesAPI(editor models.ArticleEditor) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		items, err := editor.ListItems()
+		if err != nil {
+			editorError(c, err)
+			return
+		}
+		summaries := make([]ArticleSummary, 0, len(items))
+		for _, item := range items {
+			summaries = append(summaries, ArticleSummary{
+				PostID:       item.PostID,
+				PostTitle:    item.PostTitle,
+				PostType:     item.PostType,
+				Status:       item.Status,
+				PublishAt:    item.PublishAt,
+				ModifiedDate: item.ModifiedDate,
+				Version:      item.Version,
+			})
+		}
+		c.JSON(http.StatusOK, gin.H{"articles": summaries})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// EditorArticleAPI : Returns One Article as Stored, Source and Version Included
+func EditorArticleAPI(editor models.ArticleEditor) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "article id must be a number")
+			return
+		}
+		item, err := editor.GetItem(id)
+		if err != nil {
+			editorError(c, err)
+			return
+		}
+		c.JSON(http.StatusOK, item)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// CreateArticleAPI : Creates an Article From a JSON Item; 409 if its Post ID is Taken
+func CreateArticleAPI(editor models.ArticleEditor, saved func()) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		item, ok := bindArticle(c, editor)
+		if !ok {
+			return
+		}
+		if item.CreatedDate == "" {
+			item.CreatedDate = item.ModifiedDate
+		}
+
+		stored, err := editor.CreateItem(item, editorChange(c, models.RevisionSourceEditor))
+		if err != nil {
+			editorError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"post_id": stored.PostID, "admin": c.GetString(AdminEmailKey)}).Info("Created article")
+		saved()
+		c.JSON(http.StatusCreated, stored)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// UpdateArticleAPI : Saves an Article Over the Version it was Loaded at; 409 if it Changed Since
+func UpdateArticleAPI(editor models.ArticleEditor, saved func()) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "article id must be a number")
+			return
+		}
+		item, ok := bindArticle(c, editor)
+		if !ok {
+			return
+		}
+		if item.PostID != id {
+			apiError(c, http.StatusBadRequest, "post-id in the body doesn't match the URL")
+			return
+		}
+
+		stored, err := editor.UpdateItem(item, editorChange(c, models.RevisionSourceEditor))
+		if err != nil {
+			editorError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"post_id": stored.PostID, "version": stored.Version, "admin": c.GetString(AdminEmailKey)}).Info("Updated article")
+		saved()
+		c.JSON(http.StatusOK, stored)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// DeleteArticleAPI : Deletes an Article at the Given ?version=; 409 if it Changed Since
+func DeleteArticleAPI(editor models.ArticleEditor, saved func()) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		id, err := strconv.Atoi(c.Param("id"))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "article id must be a number")
+			return
+		}
+		version, err := strconv.Atoi(c.Query("version"))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "version must be the number the article was loaded at")
+			return
+		}
+
+		if err := editor.DeleteItem(id, version, editorChange(c, models.RevisionSourceEditor)); err != nil {
+			editorError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"post_id": id, "admin": c.GetString(AdminEmailKey)}).Info("Deleted article")
+		saved()
+		c.Status(http.StatusNoContent)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ArticleHistoryAPI : Lists an Article's Revisions, Oldest First, Without Their Bodies
+func ArticleHistoryAPI(editor models.ArticleEditor) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		history, ok := loadHistory(c, editor)
+		if !ok {
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{"revisions": history.Summaries()})
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ArticleRevisionAPI : Returns One Revision of an Article, Body Included
+func ArticleRevisionAPI(editor models.ArticleEditor) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		version, err := strconv.Atoi(c.Param("version"))
+		if err != nil {
+			apiError(c, http.StatusBadRequest, "version must be a number")
+			return
+		}
+		history, ok := loadHistory(c, editor)
+		if !ok {
+			return
+		}
+		rev, ok := history.Version(version)
+		if !ok {
+			apiError(c, http.StatusNotFound, fmt.Sprintf("no revision %d", version))
+			return
+		}
+		c.JSON(http.StatusOK, rev)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ArticleDiffAPI : Diffs Two Revisions of an Article, ?from= and ?to= (Default the Latest), as Plain Text
+func ArticleDiffAPI(editor models.ArticleEditor) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		history, ok := loadHistory(c, editor)
+		if !ok {
+			return
+		}
+		latest, _ := history.Latest()
+		sides := [2]models.Revision{}
+		for i, param := range []string{"from", "to"} {
+			raw := c.Query(param)
+			if raw == "" && param == "to" {
+				sides[i] = latest
+				continue
+			}
+			version, err := strconv.Atoi(raw)
+			if err != nil {
+				apiError(c, http.StatusBadRequest, param+" must be a revision number")
+				return
+			}
+			rev, found := history.Version(version)
+			if !found {
+				apiError(c, http.StatusNotFound, fmt.Sprintf("no revision %d", version))
+				return
+			}
+			sides[i] = rev
+		}
+		from, to := sides[0], sides[1]
+		c.String(http.StatusOK, "%s", models.DiffItems(from.Item, to.Item,
+			"v"+strconv.Itoa(from.Version), "v"+strconv.Itoa(to.Version)))
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// RollbackRequest : body of a rollback, the revision to restore and the
+// version the article is at now (0 if it has been deleted).
+type RollbackRequest struct {
+	To      int `json:"to"`
+	Version int `json:"version"`
+}
+
+// RollbackArticleAPI : Restores a Prior Revision's Content as a New Version; 409 if the Article Changed Since
+func RollbackArticleAPI(editor models.ArticleEditor, saved func()) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
+		var req RollbackRequest
+		if err := c.ShouldBindJSON(&req); err != nil {
+			apiError(c, http.StatusBadRequest, "body must be a JSON rollback request: "+err.Error())
+			return
+		}
+		history, ok := loadHistory(c, editor)
+		if !ok {
+			return
+		}
+		rev, found := history.Version(req.To)
+		if !found {
+			apiError(c, http.StatusNotFound, fmt.Sprintf("no revision %d", req.To))
+			return
+		}
+		if rev.Deleted {
+			apiError(c, http.StatusUnprocessableEntity, fmt.Sprintf("revision %d is a delete; roll back to the one before it", req.To))
+			return
+		}
+
+		item := *rev.Item
+		item.Version = req.Version
+		item.ModifiedDate = models.FormatDate(time.Now())
+		item, ok = normalizeArticle(c, item)
+		if !ok || !checkArticle(c, editor, item) {
+			return
+		}
+		change := editorChange(c, models.RevisionSourceRollback)
+		var stored models.Item
+		_, err := editor.GetItem(item.PostID)
+		switch {
+		case errors.Is(err, models.ErrArticleNotFound):
+			stored, err = editor.CreateItem(item, change)
+		case err == nil:
+			stored, err = editor.UpdateItem(item, change)
+		}
+		if err != nil {
+			editorError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"post_id": stored.PostID, "version": stored.Version, "restored": rev.Version, "admin": c.GetString(AdminEmailKey)}).Info("Rolled back article")
+		saved()
+		c.JSON(http.StatusOK, stored)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// PreviewArticleAPI : Renders an Unsaved JSON Item Through article.html
+func PreviewArticleAPI(c *gin.Context) {
+	c.Header("Cache-Control", "private, no-store")
+	var item models.Item
+	if err := c.ShouldBindJSON(&item); err != nil {
+		apiError(c, http.StatusBadRequest, "body must be a JSON article: "+err.Error())
+		return
+	}
+	c.HTML(
+		http.StatusOK,
+		"article.html",
+		gin.H{
+			"title":   "Preview: " + item.ShortTitle,
+			"payload": item.Article(),
+			"noindex": true,
+		},
+	)
+}
+
+// AdminEmailKey is the gin context key adminAuth stores the logged-in
+// admin's email under ("" for bearer-token requests).
+const AdminEmailKey = "adminEmail"
+
+// editorChange attributes an editor write to the logged-in admin, or to the
+// admin token for scripted requests.
+func editorChange(c *gin.Context, source string) models.Change {
+	author := c.GetString(AdminEmailKey)
+	if author == "" {
+		author = "admin-token"
+	}
+	return models.Change{Author: author, Source: source}
+}
+
+// bindArticle reads a JSON Item from the request body, stamps its
+// ModifiedDate, normalizes its dates and checks it: 400 if it isn't JSON,
+// 422 if a date doesn't parse, it isn't a valid article or its slug is
+// taken.
+func bindArticle(c *gin.Context, editor models.ArticleEditor) (models.Item, bool) {
+	var item models.Item
+	if err := c.ShouldBindJSON(&item); err != nil {
+		apiError(c, http.StatusBadRequest, "body must be a JSON article: "+err.Error())
+		return item, false
+	}
+	item.ModifiedDate = models.FormatDate(time.Now())
+	item, ok := normalizeArticle(c, item)
+	return item, ok && checkArticle(c, editor, item)
+}
+
+// normalizeArticle stores item's dates as RFC 3339, answering 422 if one
+// doesn't parse.
+func normalizeArticle(c *gin.Context, item models.Item) (models.Item, bool) {
+	normalized, err := item.WithNormalizedDates()
+	if err != nil {
+		apiError(c, http.StatusUnprocessableEntity, err.Error())
+		return item, false
+	}
+	return normalized, true
+}
+
+// checkArticle answers 422 if item isn't a valid article or its slug is
+// taken.
+func checkArticle(c *gin.Context, editor models.ArticleEditor, item models.Item) bool {
+	if err := item.Validate(); err != nil {
+		apiError(c, http.StatusUnprocessableEntity, err.Error())
+		return false
+	}
+	items, err := editor.ListItems()
+	if err != nil {
+		editorError(c, err)
+		return false
+	}
+	if owner, taken := slugOwner(items, item); taken {
+		apiError(c, http.StatusUnprocessableEntity, fmt.Sprintf("slug %q is already used by post-id %d", item.ResolvedSlug(), owner))
+		return false
+	}
+	return true
+}
+
+// loadHistory reads the history of the :id article: 400 for a bad id, 404
+// if it has no revisions.
+func loadHistory(c *gin.Context, editor models.ArticleEditor) (models.History, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		apiError(c, http.StatusBadRequest, "article id must be a number")
+		return nil, false
+	}
+	history, err := editor.History(id)
+	if err != nil {
+		editorError(c, err)
+		return nil, false
+	}
+	if len(history) == 0 {
+		apiError(c, http.StatusNotFound, "article has no revisions")
+		return nil, false
+	}
+	return history, true
+}
+
+// slugOwner finds another article among items that resolves to item's slug.
+// Slugs map /posts/:slug to a single article, so a clash would hide one of
+// them.
+func slugOwner(items []models.Item, item models.Item) (int, bool) {
+	if item.PostType == "quote" {
+		return 0, false
+	}
+	for _, other := range items {
+		if other.PostID != item.PostID && other.PostType != "quote" && other.ResolvedSlug() == item.ResolvedSlug() {
+			return other.PostID, true
+		}
+	}
+	return 0, false
+}
+
+// editorError maps an ArticleEditor error to its status code.
+func editorError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrArticleNotFound):
+		apiError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrArticleExists), errors.Is(err, models.ErrArticleConflict):
+		apiError(c, http.StatusConflict, err.Error())
+	default:
+		log.WithError(err).Error("Article editor store failed")
+		apiError(c, http.StatusInternalServerError, "internal error")
+	}
+}
@@ -0,0 +1,271 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setupEditorRouter(t *testing.T) (*gin.Engine, *models.MemoryArticleStore, *int) {
+	router, _, _ := setupTestRouterWithHTMLTemplates(t, map[string]string{
+		"article.html": `<html><head><title>{{.title}}</title>{{if .noindex}}<meta name="robots" content="noindex">{{end}}</head><body>{{.payload.HTMLHold}}</body></html>`,
+		"editor.html":  "<html><body>{{.title}}</body></html>",
+	})
+	articles := testArticleStore()
+	saves := 0
+	saved := func() { saves++ }
+
+	router.GET("/manage/editor", EditorPage)
+	router.GET("/manage/articles", EditorArticlesAPI(articles))
+	router.POST("/manage/articles", CreateArticleAPI(articles, saved))
+	router.POST("/manage/articles/preview", PreviewArticleAPI)
+	router.GET("/manage/articles/:id", EditorArticleAPI(articles))
+	router.PUT("/manage/articles/:id", UpdateArticleAPI(articles, saved))
+	router.DELETE("/manage/articles/:id", DeleteArticleAPI(articles, saved))
+	router.GET("/manage/articles/:id/revisions", ArticleHistoryAPI(articles))
+	router.GET("/manage/articles/:id/revisions/:version", ArticleRevisionAPI(articles))
+	router.GET("/manage/articles/:id/diff", ArticleDiffAPI(articles))
+	router.POST("/manage/articles/:id/rollback", RollbackArticleAPI(articles, saved))
+	return router, articles, &saves
+}
+
+func sendJSON(router *gin.Engine, method, path string, body any) *httptest.ResponseRecorder {
+	raw, _ := json.Marshal(body)
+	req, _ := http.NewRequest(method, path, strings.NewReader(string(raw)))
+	req.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	return recorder
+}
+
+func TestEditorPage(t *testing.T) {
+	router, _, _ := setupEditorRouter(t)
+	recorder := getAPI(router, "/manage/editor", nil)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Article Editor")
+	assert.Equal(t, "private, no-store", recorder.Header().Get("Cache-Control"))
+}
+
+func TestEditorArticlesAPI(t *testing.T) {
+	router, articles, _ := setupEditorRouter(t)
+	articles.Put(models.Item{PostID: 10, PostTitle: "Draft", PostType: "standard", HTMLHold: "<p>d</p>", Status: models.StatusDraft, Version: 4})
+
+	recorder := getAPI(router, "/manage/articles", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var list struct {
+		Articles []ArticleSummary `json:"articles"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list))
+	require.Len(t, list.Articles, 4, "Drafts and quotes are listed too")
+	assert.Equal(t, ArticleSummary{PostID: 10, PostTitle: "Draft", PostType: "standard", Status: "draft", Version: 4}, list.Articles[0])
+
+	recorder = getAPI(router, "/manage/articles/10", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var item models.Item
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &item))
+	assert.Equal(t, "<p>d</p>", item.HTMLHold)
+	assert.Equal(t, 4, item.Version)
+
+	assert.Equal(t, http.StatusNotFound, getAPI(router, "/manage/articles/99", nil).Code)
+	assert.Equal(t, http.StatusBadRequest, getAPI(router, "/manage/articles/draft", nil).Code)
+}
+
+func TestCreateArticleAPI(t *testing.T) {
+	router, articles, saves := setupEditorRouter(t)
+
+	recorder := sendJSON(router, http.MethodPost, "/manage/articles", models.Item{
+		PostID: 3, PostTitle: "Hello Editor", PostType: "standard", Markdown: "*hi*", Status: models.StatusDraft,
+	})
+	require.Equal(t, http.StatusCreated, recorder.Code, recorder.Body.String())
+	var created models.Item
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &created))
+	assert.Equal(t, 1, created.Version)
+	assert.NotEmpty(t, created.ModifiedDate, "ModifiedDate is stamped on save")
+	assert.Equal(t, created.ModifiedDate, created.CreatedDate, "and CreatedDate defaults to it")
+	assert.Equal(t, 1, *saves, "Saving purges the page cache")
+
+	stored, err := articles.GetItem(3)
+	require.NoError(t, err)
+	assert.Equal(t, "*hi*", stored.Markdown)
+
+	testCases := []struct {
+		name string
+		item models.Item
+		code int
+	}{
+		{"Exists", models.Item{PostID: 2, PostTitle: "Taken", PostType: "standard", HTMLHold: "<p>x</p>"}, http.StatusConflict},
+		{"Invalid", models.Item{PostID: 4, PostType: "standard", HTMLHold: "<p>x</p>"}, http.StatusUnprocessableEntity},
+		{"SlugTaken", models.Item{PostID: 4, PostTitle: "EMR", ShortTitle: "Amazon EMR", PostType: "standard", HTMLHold: "<p>x</p>"}, http.StatusUnprocessableEntity},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := sendJSON(router, http.MethodPost, "/manage/articles", tc.item)
+			assert.Equal(t, tc.code, recorder.Code, recorder.Body.String())
+			assert.Contains(t, recorder.Body.String(), `"error"`)
+		})
+	}
+	assert.Equal(t, 1, *saves, "Failed saves don't purge")
+
+	req, _ := http.NewRequest(http.MethodPost, "/manage/articles", strings.NewReader("{"))
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, req)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+}
+
+func TestUpdateArticleAPI(t *testing.T) {
+	router, articles, saves := setupEditorRouter(t)
+
+	loaded, err := articles.GetItem(2)
+	require.NoError(t, err)
+
+	// Two editors load the same version; the second save is refused.
+	first, second := *loaded, *loaded
+	first.HTMLHold, second.HTMLHold = "<p>first</p>", "<p>second</p>"
+	recorder := sendJSON(router, http.MethodPut, "/manage/articles/2", first)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var saved models.Item
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &saved))
+	assert.Equal(t, loaded.Version+1, saved.Version)
+	assert.NotEqual(t, loaded.ModifiedDate, saved.ModifiedDate)
+	assert.Equal(t, loaded.CreatedDate, saved.CreatedDate)
+
+	recorder = sendJSON(router, http.MethodPut, "/manage/articles/2", second)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	stored, err := articles.GetItem(2)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>first</p>", stored.HTMLHold)
+	assert.Equal(t, 1, *saves)
+
+	assert.Equal(t, http.StatusBadRequest, sendJSON(router, http.MethodPut, "/manage/articles/0", first).Code, "Body and URL disagree")
+	missing := first
+	missing.PostID, missing.ShortTitle = 99, "Gone"
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, http.MethodPut, "/manage/articles/99", missing).Code)
+}
+
+func TestDeleteArticleAPI(t *testing.T) {
+	router, articles, saves := setupEditorRouter(t)
+
+	recorder := sendJSON(router, http.MethodDelete, "/manage/articles/2", nil)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code, "The version is required")
+	recorder = sendJSON(router, http.MethodDelete, "/manage/articles/2?version=3", nil)
+	assert.Equal(t, http.StatusConflict, recorder.Code)
+	recorder = sendJSON(router, http.MethodDelete, "/manage/articles/2?version=0", nil)
+	assert.Equal(t, http.StatusNoContent, recorder.Code)
+	assert.Equal(t, 1, *saves)
+
+	_, err := articles.GetItem(2)
+	assert.ErrorIs(t, err, models.ErrArticleNotFound)
+	recorder = sendJSON(router, http.MethodDelete, "/manage/articles/2?version=0", nil)
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+}
+
+func TestArticleHistoryAPI(t *testing.T) {
+	router, _, _ := setupEditorRouter(t)
+	item := models.Item{PostID: 3, PostTitle: "History", PostType: "standard", Markdown: "one\n"}
+	require.Equal(t, http.StatusCreated, sendJSON(router, http.MethodPost, "/manage/articles", item).Code)
+	item.Version, item.Markdown = 1, "two\n"
+	require.Equal(t, http.StatusOK, sendJSON(router, http.MethodPut, "/manage/articles/3", item).Code)
+
+	recorder := getAPI(router, "/manage/articles/3/revisions", nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var list struct {
+		Revisions models.History `json:"revisions"`
+	}
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &list))
+	require.Len(t, list.Revisions, 2)
+	assert.Equal(t, 1, list.Revisions[0].Version)
+	assert.Equal(t, "admin-token", list.Revisions[0].Author, "Requests without a session are the admin token's")
+	assert.Equal(t, models.RevisionSourceEditor, list.Revisions[0].Source)
+	assert.Nil(t, list.Revisions[0].Item, "The list leaves the bodies out")
+
+	recorder = getAPI(router, "/manage/articles/3/revisions/1", nil)
+	require.Equal(t, http.StatusOK, recorder.Code)
+	var rev models.Revision
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &rev))
+	assert.Equal(t, "one\n", rev.Item.Markdown)
+
+	recorder = getAPI(router, "/manage/articles/3/diff?from=1", nil)
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Equal(t, "text/plain; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Contains(t, recorder.Body.String(), "--- v1/markdown\n+++ v2/markdown\n")
+	assert.Contains(t, recorder.Body.String(), "-one\n+two\n")
+	assert.Empty(t, getAPI(router, "/manage/articles/3/diff?from=2&to=2", nil).Body.String())
+
+	testCases := []struct {
+		path string
+		code int
+	}{
+		{"/manage/articles/2/revisions", http.StatusNotFound},
+		{"/manage/articles/x/revisions", http.StatusBadRequest},
+		{"/manage/articles/3/revisions/9", http.StatusNotFound},
+		{"/manage/articles/3/revisions/x", http.StatusBadRequest},
+		{"/manage/articles/3/diff", http.StatusBadRequest},
+		{"/manage/articles/3/diff?from=1&to=9", http.StatusNotFound},
+	}
+	for _, tc := range testCases {
+		recorder := getAPI(router, tc.path, nil)
+		assert.Equal(t, tc.code, recorder.Code, tc.path)
+		assert.Contains(t, recorder.Body.String(), `"error"`, tc.path)
+	}
+}
+
+func TestRollbackArticleAPI(t *testing.T) {
+	router, articles, saves := setupEditorRouter(t)
+	item := models.Item{PostID: 3, PostTitle: "Rollback", PostType: "standard", Markdown: "good"}
+	require.Equal(t, http.StatusCreated, sendJSON(router, http.MethodPost, "/manage/articles", item).Code)
+	item.Version, item.Markdown = 1, "bad"
+	require.Equal(t, http.StatusOK, sendJSON(router, http.MethodPut, "/manage/articles/3", item).Code)
+
+	recorder := sendJSON(router, http.MethodPost, "/manage/articles/3/rollback", RollbackRequest{To: 1, Version: 1})
+	assert.Equal(t, http.StatusConflict, recorder.Code, "The caller must have seen the current version")
+
+	recorder = sendJSON(router, http.MethodPost, "/manage/articles/3/rollback", RollbackRequest{To: 1, Version: 2})
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	var restored models.Item
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &restored))
+	assert.Equal(t, 3, restored.Version, "A rollback is a new version, not a rewrite of history")
+	assert.Equal(t, "good", restored.Markdown)
+	assert.Equal(t, 3, *saves)
+
+	history, err := articles.History(3)
+	require.NoError(t, err)
+	require.Len(t, history, 3)
+	assert.Equal(t, models.RevisionSourceRollback, history[2].Source)
+	assert.Equal(t, history[0].Hash, history[2].Hash, "Same content as the revision it restores")
+
+	// A deleted article comes back from its history.
+	require.Equal(t, http.StatusNoContent, sendJSON(router, http.MethodDelete, "/manage/articles/3?version=3", nil).Code)
+	recorder = sendJSON(router, http.MethodPost, "/manage/articles/3/rollback", RollbackRequest{To: 4})
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code, "The tombstone has nothing to restore")
+	recorder = sendJSON(router, http.MethodPost, "/manage/articles/3/rollback", RollbackRequest{To: 2})
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	stored, err := articles.GetItem(3)
+	require.NoError(t, err)
+	assert.Equal(t, "bad", stored.Markdown)
+	assert.Equal(t, 5, stored.Version)
+
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, http.MethodPost, "/manage/articles/3/rollback", RollbackRequest{To: 9}).Code)
+	assert.Equal(t, http.StatusNotFound, sendJSON(router, http.MethodPost, "/manage/articles/2/rollback", RollbackRequest{To: 1}).Code)
+}
+
+func TestPreviewArticleAPI(t *testing.T) {
+	router, articles, _ := setupEditorRouter(t)
+
+	recorder := sendJSON(router, http.MethodPost, "/manage/articles/preview", models.Item{
+		PostID: 3, ShortTitle: "Unsaved", PostType: "standard", Markdown: "Some *Markdown*",
+	})
+	require.Equal(t, http.StatusOK, recorder.Code, recorder.Body.String())
+	assert.Contains(t, recorder.Body.String(), "<title>Preview: Unsaved</title>")
+	assert.Contains(t, recorder.Body.String(), "<em>Markdown</em>", "The body is rendered like a saved article")
+	assert.Contains(t, recorder.Body.String(), `<meta name="robots" content="noindex">`)
+
+	_, err := articles.GetItem(3)
+	assert.ErrorIs(t, err, models.ErrArticleNotFound, "Previews aren't saved")
+}
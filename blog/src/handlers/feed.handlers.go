@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+)
+
+const feedDescription = "Sour Beer & Distributed Systems"
+
+// RSSFeed : Serves every article as an RSS 2.0 feed at /feed.xml
+func RSSFeed(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		site := models.SiteURL()
+		feed := models.Feed{
+			Title:       "Mitchell Etzel",
+			Description: feedDescription,
+			Link:        site + "/posts",
+			Self:        site + "/feed.xml",
+			Articles:    models.FeedArticles(articles, ""),
+		}
+		renderXML(c, "application/rss+xml; charset=utf-8", feed.RSS())
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// AtomFeed : Serves every article as an Atom feed at /atom.xml
+func AtomFeed(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		site := models.SiteURL()
+		feed := models.Feed{
+			Title:       "Mitchell Etzel",
+			Description: feedDescription,
+			Link:        site + "/posts",
+			Self:        site + "/atom.xml",
+			Articles:    models.FeedArticles(articles, ""),
+		}
+		renderXML(c, "application/atom+xml; charset=utf-8", feed.Atom())
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// CategoryRSSFeed : Serves one category's articles as an RSS 2.0 feed at
+// /category/:category/feed.xml
+func CategoryRSSFeed(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		category := c.Param("category")
+		posts := models.FeedArticles(articles, category)
+		if category == "" || len(posts) == 0 {
+			// If an invalid category is specified in the URL, abort with an error
+			renderErrorPage(c, 404, "404 (Not Found)", "Please provide a valid category")
+			return
+		}
+
+		site := models.SiteURL()
+		escaped := url.PathEscape(category)
+		feed := models.Feed{
+			Title:       "Mitchell Etzel: " + category,
+			Description: feedDescription,
+			Link:        site + "/category/" + escaped,
+			Self:        site + "/category/" + escaped + "/feed.xml",
+			Articles:    posts,
+		}
+		renderXML(c, "application/rss+xml; charset=utf-8", feed.RSS())
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// Sitemap : Builds sitemap.xml from the live article panels
+func Sitemap(articles models.ArticleStore) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "public, max-age=31536000")
+		renderXML(c, "application/xml; charset=utf-8", models.BuildSitemap(articles.GetArticlePanels()))
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// renderXML writes doc as an XML document with the given content type.
+func renderXML(c *gin.Context, contentType string, doc interface{}) {
+	body, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		log.Error("Failed to marshal XML document:", err)
+		renderErrorPage(c, 500, "500 Internal Server Error", "Unable to build document.")
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
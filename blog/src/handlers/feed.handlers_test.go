@@ -0,0 +1,101 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupFeedRouter(t *testing.T) *gin.Engine {
+	t.Setenv("SITE_URL", "https://example.com")
+	router, _, _ := setupTestRouterWithHTMLTemplates(t, map[string]string{
+		"error.html": "<html><head><title>{{.title}}</title></head><body>Error Details: {{.error}}</body></html>",
+	})
+	articles := testArticleStore()
+	router.GET("/feed.xml", RSSFeed(articles))
+	router.GET("/atom.xml", AtomFeed(articles))
+	router.GET("/category/:category/feed.xml", CategoryRSSFeed(articles))
+	router.GET("/sitemap.xml", Sitemap(articles))
+	return router
+}
+
+func TestRSSFeed(t *testing.T) {
+	router := setupFeedRouter(t)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/feed.xml", nil)
+
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/rss+xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "public, max-age=31536000", recorder.Header().Get("Cache-Control"))
+
+	body := recorder.Body.String()
+	assert.Contains(t, body, `<?xml version="1.0" encoding="UTF-8"?>`)
+	assert.Contains(t, body, "<link>https://example.com/posts/amazon-emr</link>")
+	assert.Contains(t, body, "<link>https://example.com/posts/fault-tolerant-graph-store-api</link>")
+	assert.NotContains(t, body, "<guid isPermaLink=\"true\">https://example.com/article/1</guid>", "Quotes have no article page and should be left out")
+	assert.Contains(t, body, "<guid isPermaLink=\"true\">https://example.com/article/0</guid>", "GUIDs should stay on the numeric URL")
+	assert.Contains(t, body, "&lt;p&gt;Graph store body&lt;/p&gt;")
+	assert.Less(t, strings.Index(body, "/posts/amazon-emr"), strings.Index(body, "/posts/fault-tolerant-graph-store-api"), "Newest post should come first")
+}
+
+func TestAtomFeed(t *testing.T) {
+	router := setupFeedRouter(t)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/atom.xml", nil)
+
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/atom+xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.Contains(t, body, `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Contains(t, body, "<id>https://example.com/article/0</id>")
+	assert.Contains(t, body, "<published>2018-04-10T00:00:00Z</published>")
+}
+
+func TestCategoryRSSFeed(t *testing.T) {
+	router := setupFeedRouter(t)
+
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/category/My%20Projects/feed.xml", nil)
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	body := recorder.Body.String()
+	assert.Contains(t, body, "<title>Mitchell Etzel: My Projects</title>")
+	assert.Contains(t, body, "https://example.com/category/My%20Projects/feed.xml")
+	assert.Contains(t, body, "/posts/fault-tolerant-graph-store-api</link>")
+	assert.NotContains(t, body, "/posts/amazon-emr</link>", "Posts outside the category should be left out")
+
+	recorder = httptest.NewRecorder()
+	req, _ = http.NewRequest(http.MethodGet, "/category/Nope/feed.xml", nil)
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusNotFound, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), "Please provide a valid category")
+}
+
+func TestSitemap(t *testing.T) {
+	router := setupFeedRouter(t)
+	recorder := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodGet, "/sitemap.xml", nil)
+
+	router.ServeHTTP(recorder, req)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/xml; charset=utf-8", recorder.Header().Get("Content-Type"))
+	body := recorder.Body.String()
+	assert.Contains(t, body, "<loc>https://example.com/</loc>")
+	assert.Contains(t, body, "<loc>https://example.com/posts</loc>")
+	assert.Contains(t, body, "<loc>https://example.com/posts/amazon-emr</loc>")
+	assert.Contains(t, body, "<lastmod>2019-08-10</lastmod>")
+	assert.Contains(t, body, "<loc>https://example.com/category/Distributed%20Systems</loc>")
+	assert.NotContains(t, body, "/article/", "Sitemaps should only list the slug URLs")
+	assert.NotContains(t, body, "Quotes", "Categories only quotes use should be excluded")
+}
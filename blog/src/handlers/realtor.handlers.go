@@ -3,83 +3,206 @@ package handlers
 import (
 	"bytes"
 	"context"
+	"errors"
 	"net/http"
+	"path"
+	"strconv"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/etzelm/blog-in-golang/src/models"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 )
 
-// createS3Client creates an S3 client with proper configuration
-func createS3Client(ctx context.Context) (*s3.Client, error) {
-	cfg, err := createAWSConfig(ctx)
-	if err != nil {
-		return nil, err
-	}
-	return s3.NewFromConfig(cfg), nil
-}
+// ListingsGETAPI : Gets All Realtor Listings, or a Search or Page of Them
+//
+// ?user= narrows the listings to one user's, ?city= to one city's and a
+// radius (?lat=, ?lng= and ?miles=) or ?bbox= to the geohash cells covering
+// that area (each answered from a GSI rather than a Scan). The rest of the
+// search parameters (see models.ParseListingQuery) filter what that
+// returns, and ?sort= (modified, listed, price, size or distance) and
+// ?order= (asc or desc) order it, most recently modified first by default,
+// or nearest first when ?lat= and ?lng= are given. The number of matches
+// comes back in X-Total-Count. Without ?limit= or ?page_token= every match
+// is returned, as the realtor pages expect. With either, the body is one
+// page of them and the next page's token, if any, comes back in the
+// X-Next-Page-Token header and a rel="next" Link header.
+//
+// Deleted listings are left out. ?include_deleted=true brings them back,
+// but only when ?user= is the signed-in user: it is refused with 401 for a
+// visitor who isn't signed in and with 403 for anyone else's listings or
+// without ?user=.
+//
+// Searches by location need geocoder, the one ListingPOSTAPI places listings
+// with: without it new listings have no location, so a search near a point
+// or in a box would silently miss them and is refused with 503 instead.
+func ListingsGETAPI(clients *models.AWSClients, geocoder models.Geocoder) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+
+		query, err := models.ParseListingQuery(c.Request.URL.Query())
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		order, err := models.ParseListingOrder(c.Query("sort"), c.Query("order"), query.Near)
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		if geocoder == nil && (query.Near != nil || query.Box != nil) {
+			apiError(c, http.StatusServiceUnavailable, "location search is off: the server has no ZIP table (ZIP_CENTROIDS)")
+			return
+		}
+		if c.Query("include_deleted") == "true" {
+			switch signedIn := c.GetString(UserEmailKey); {
+			case signedIn == "":
+				apiError(c, http.StatusUnauthorized, "sign in to see your deleted listings")
+				return
+			case c.Query("user") != signedIn:
+				apiError(c, http.StatusForbidden, "include_deleted is only for your own listings; pass ?user= as yourself")
+				return
+			}
+			query.IncludeDeleted = true
+		}
 
-// ListingsGETAPI : Gets All Realtor Listings
-func ListingsGETAPI(c *gin.Context) {
-	c.Header("Cache-Control", "no-cache")
-	listings := models.GetRealtorListings()
+		var listings []models.Listing
+		switch user, cells := c.Query("user"), query.GeoCells(); {
+		case user != "":
+			listings = models.GetRealtorListingsByUser(clients.DynamoDB, user)
+		case query.City != "":
+			listings = models.GetRealtorListingsByCity(clients.DynamoDB, query.City)
+		case cells != nil:
+			listings = models.GetRealtorListingsInCells(clients.DynamoDB, cells)
+		default:
+			listings = models.GetRealtorListings(clients.DynamoDB)
+		}
+		listings = query.Filter(listings)
+		order.Sort(listings)
+		c.Header("X-Total-Count", strconv.Itoa(len(listings)))
+
+		token, limit := c.Query("page_token"), c.Query("limit")
+		if token != "" || limit != "" {
+			req, err := models.ParsePageRequest(token, limit, models.DefaultListingsPageLimit)
+			if err == nil {
+				var next string
+				listings, next, err = models.PageListings(listings, order, req)
+				if next != "" {
+					c.Header("X-Next-Page-Token", next)
+					c.Header("Link", "<"+nextPageURL(c.Request.URL, next)+`>; rel="next"`)
+				}
+			}
+			if err != nil {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
 
-	// Call the JSON method of the Context to return the results
-	c.JSON(200, listings)
+		// Call the JSON method of the Context to return the results
+		c.JSON(200, listings)
 
+	}
+	return gin.HandlerFunc(fn)
 }
 
 // ListingGETAPI : Gets A Realtor Listing
-func ListingGETAPI(c *gin.Context) {
-	c.Header("Cache-Control", "no-cache")
-
-	if listing := c.Param("listing"); listing != "" {
+//
+// A deleted listing is answered like a missing one, unless its owner asks
+// with ?include_deleted=true.
+func ListingGETAPI(clients *models.AWSClients) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+
+		if listing := c.Param("listing"); listing != "" {
+
+			card := models.GetRealtorListing(clients.DynamoDB, listing)
+			if len(card) > 0 && card[0].IsDeleted() &&
+				(c.Query("include_deleted") != "true" || card[0].User != c.GetString(UserEmailKey)) {
+				card = []models.Listing{}
+			}
 
-		card := models.GetRealtorListing(listing)
+			// Call the JSON method of the Context to return the results
+			c.JSON(200, card)
 
-		// Call the JSON method of the Context to return the results
-		c.JSON(200, card)
+		} else {
 
-	} else {
+			empty := []byte(``)
 
-		empty := []byte(``)
+			// Call the JSON method of the Context to 404
+			c.JSON(404, empty)
 
-		// Call the JSON method of the Context to 404
-		c.JSON(404, empty)
+		}
 
 	}
-
+	return gin.HandlerFunc(fn)
 }
 
-// ListingPOSTAPI : Saves the user's data in DynamoDB and displays static response.html
-func ListingPOSTAPI(c *gin.Context) {
-	c.Header("Cache-Control", "no-cache")
-
-	if key := c.Param("key"); key == "HowMuchDoesSecurityCost" {
-
-		var listing models.Listing
-		c.BindJSON(&listing)
+// ListingPOSTAPI : Saves A Listing For The Signed-In User
+//
+// Behind userAuth. The listing's User is the signed-in user's email, not
+// whatever the body says, and the write is conditional on the MLS number
+// being new or already theirs: a listing that belongs to someone else is
+// refused with 403, and a deleted one with 409 until it's restored; whether a
+// listing is deleted is only changed by ListingDELETEAPI and
+// ListingRestorePOSTAPI. A listing that fails models.Listing.Validate is refused
+// with 422 and every field's problem. Its Latitude and Longitude are set
+// from its address by geocoder (nil leaves them as sent); a listing the
+// geocoder can't place is saved without them.
+func ListingPOSTAPI(clients *models.AWSClients, geocoder models.Geocoder) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+
+		owner := c.GetString(UserEmailKey)
+		if owner == "" {
+			apiError(c, http.StatusUnauthorized, "sign in to save listings")
+			return
+		}
 
-		ctx := context.TODO()
-		dbSvc, err := createDynamoDBClient(ctx)
+		body, err := c.GetRawData()
 		if err != nil {
-			log.Error("Unable to create DynamoDB client:", err)
-			c.HTML(
-				http.StatusInternalServerError,
-				"error.html",
-				gin.H{
-					"title": "500 Internal Server Error",
-					"error": err.Error(),
-				},
-			)
+			apiError(c, http.StatusBadRequest, err.Error())
 			return
 		}
+		listing, err := models.DecodeListing(body)
+		if err == nil {
+			err = listing.Validate()
+		}
+		var invalid models.ValidationErrors
+		if errors.As(err, &invalid) {
+			listingInvalid(c, invalid)
+			return
+		}
+		if err != nil {
+			apiError(c, http.StatusBadRequest, err.Error())
+			return
+		}
+		listing.User = owner
+		listing.Deleted, listing.DeletedAt, listing.DeletedBy = "false", "", ""
+		// Clients still send epoch milliseconds; store RFC 3339. A date
+		// that doesn't parse is stored as sent.
+		if normalized, err := listing.WithNormalizedDates(); err == nil {
+			listing = normalized
+		} else {
+			log.Warn("Storing listing ", listing.MLS, " with unparsed date: ", err)
+		}
+
+		ctx := context.TODO()
+		dbSvc := clients.DynamoDB
+
+		listing, err = listing.WithLocation(ctx, geocoder)
+		switch {
+		case geocoder == nil:
+			log.WithFields(log.Fields{"mls": listing.MLS, "zip": listing.ZipCode}).Warn("Storing listing without geocoding it: no ZIP table")
+		case err != nil:
+			log.WithFields(log.Fields{"mls": listing.MLS, "zip": listing.ZipCode}).Warn("Storing listing without a location: ", err)
+		}
 
-		av, err := attributevalue.MarshalMap(listing)
+		av, err := attributevalue.MarshalMap(listing.WithIndexKeys())
 		if err != nil {
 			log.Error("Error marshalling listing:", err)
 			c.HTML(
@@ -94,12 +217,31 @@ func ListingPOSTAPI(c *gin.Context) {
 		}
 
 		input := &dynamodb.PutItemInput{
-			Item:      av,
-			TableName: aws.String("Listings"),
+			Item:                av,
+			TableName:           aws.String(models.ListingsTable),
+			ConditionExpression: aws.String("attribute_not_exists(MLS) OR (#user = :user AND (attribute_not_exists(deleted) OR deleted <> :true))"),
+			ExpressionAttributeNames: map[string]string{
+				"#user": "User",
+			},
+			ExpressionAttributeValues: map[string]types.AttributeValue{
+				":user": &types.AttributeValueMemberS{Value: owner},
+				":true": &types.AttributeValueMemberS{Value: "true"},
+			},
+			ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
 		}
 
 		_, err = dbSvc.PutItem(ctx, input)
 
+		var refused *types.ConditionalCheckFailedException
+		if errors.As(err, &refused) {
+			if old, ok := refused.Item["User"].(*types.AttributeValueMemberS); ok && old.Value == owner {
+				apiError(c, http.StatusConflict, "listing "+listing.MLS+": "+models.ErrListingDeleted.Error())
+				return
+			}
+			log.WithFields(log.Fields{"mls": listing.MLS, "user": owner}).Warn("Refused a write to another user's listing")
+			apiError(c, http.StatusForbidden, "listing "+listing.MLS+" belongs to another user")
+			return
+		}
 		if err != nil {
 			log.Error("Got error calling PutItem:")
 			log.Error(err.Error())
@@ -122,55 +264,144 @@ func ListingPOSTAPI(c *gin.Context) {
 		// Call the JSON method of the Context to return the results
 		c.JSON(200, success)
 
-	} else {
+	}
+	return gin.HandlerFunc(fn)
+}
 
-		empty := []byte(``)
+// ListingDELETEAPI : Soft-Deletes One Of The Signed-In User's Listings
+//
+// Behind userAuth. The listing is marked deleted, with when and by whom, and
+// left out of /listings and /listing/:listing until it's restored. Answers
+// with the listing; 404 if there's no such listing and 403 if it's someone
+// else's. Deleting a deleted listing changes nothing.
+func ListingDELETEAPI(clients *models.AWSClients) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+
+		owner := c.GetString(UserEmailKey)
+		if owner == "" {
+			apiError(c, http.StatusUnauthorized, "sign in to delete listings")
+			return
+		}
+		listing, err := models.DeleteRealtorListing(clients.DynamoDB, c.Param("mls"), owner, time.Now())
+		if err != nil {
+			listingError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"mls": listing.MLS, "user": owner}).Info("Deleted listing")
+		c.JSON(http.StatusOK, listing)
+	}
+	return gin.HandlerFunc(fn)
+}
 
-		// Call the JSON method of the Context to 404
-		c.JSON(404, empty)
+// ListingRestorePOSTAPI : Restores One Of The Signed-In User's Deleted Listings
+//
+// Behind userAuth. Answers with the listing, errors as ListingDELETEAPI.
+// Restoring a listing that isn't deleted changes nothing.
+func ListingRestorePOSTAPI(clients *models.AWSClients) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+
+		owner := c.GetString(UserEmailKey)
+		if owner == "" {
+			apiError(c, http.StatusUnauthorized, "sign in to restore listings")
+			return
+		}
+		listing, err := models.RestoreRealtorListing(clients.DynamoDB, c.Param("mls"), owner)
+		if err != nil {
+			listingError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"mls": listing.MLS, "user": owner}).Info("Restored listing")
+		c.JSON(http.StatusOK, listing)
+	}
+	return gin.HandlerFunc(fn)
+}
+
+// ListingPurgeAPI : Removes A Deleted Listing And Its Images For Good
+//
+// Behind adminAuth. Only a listing that has been soft-deleted can be purged
+// (409 otherwise). Answers with the image keys removed, e.g.
+// {"mls": "123", "images": ["/media/agent@example.com/photo1.jpg"]}.
+func ListingPurgeAPI(clients *models.AWSClients) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "private, no-store")
 
+		listing, images, err := models.PurgeRealtorListing(clients, c.Param("mls"))
+		if err != nil {
+			listingError(c, err)
+			return
+		}
+		log.WithFields(log.Fields{"mls": listing.MLS, "user": listing.User, "images": len(images),
+			"admin": c.GetString(AdminEmailKey)}).Info("Purged listing")
+		c.JSON(http.StatusOK, gin.H{"mls": listing.MLS, "images": images})
 	}
+	return gin.HandlerFunc(fn)
+}
 
+// listingError answers a failed listing write.
+func listingError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrListingNotFound):
+		apiError(c, http.StatusNotFound, err.Error())
+	case errors.Is(err, models.ErrNotListingOwner):
+		apiError(c, http.StatusForbidden, err.Error())
+	case errors.Is(err, models.ErrListingDeleted), errors.Is(err, models.ErrListingNotDeleted):
+		apiError(c, http.StatusConflict, err.Error())
+	default:
+		log.WithError(err).Error("Listing write failed")
+		apiError(c, http.StatusInternalServerError, "internal error")
+	}
 }
 
-// UploadImagePOSTAPI : Upload an image to S3
-func UploadImagePOSTAPI(c *gin.Context) {
+// listingInvalid answers 422 with each field's problem, e.g.
+// {"error": "Zip Code must be ...", "fields": [{"field": "Zip Code", "message": "must be ..."}]}.
+func listingInvalid(c *gin.Context, invalid models.ValidationErrors) {
 	c.Header("Cache-Control", "no-cache")
+	c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": invalid.Error(), "fields": invalid})
+}
 
-	if user := c.Param("user"); user != "" {
+// UploadImagePOSTAPI : Uploads The Signed-In User's Images To S3
+//
+// Behind userAuth. Each file in the multipart "file" field is stored under
+// /media/<email>/ for the signed-in user, so the URLs the realtor app
+// builds for them follow from who is signed in.
+func UploadImagePOSTAPI(clients *models.AWSClients) gin.HandlerFunc {
+	fn := func(c *gin.Context) {
+		c.Header("Cache-Control", "no-cache")
+
+		user := c.GetString(UserEmailKey)
+		if user == "" {
+			apiError(c, http.StatusUnauthorized, "sign in to upload images")
+			return
+		}
 
-		ctx := context.TODO()
-		svc, err := createS3Client(ctx)
+		form, err := c.MultipartForm()
 		if err != nil {
-			log.Error("Unable to create S3 client:", err)
-			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			apiError(c, http.StatusBadRequest, "send the images as multipart/form-data in a \"file\" field")
 			return
 		}
 
-		form, _ := c.MultipartForm()
-
-		files := form.File["file"]
+		ctx := context.TODO()
+		svc := clients.S3
 
-		for _, file := range files {
+		for _, file := range form.File["file"] {
 
 			f, err := file.Open()
-
 			if err != nil {
 				log.Error(err)
+				continue
 			}
 
-			defer f.Close()
-
-			size := file.Size
-			buffer := make([]byte, size)
-
+			buffer := make([]byte, file.Size)
 			f.Read(buffer)
+			f.Close()
 			fileBytes := bytes.NewReader(buffer)
 			fileType := http.DetectContentType(buffer)
-			path := "/media/" + user + "/" + file.Filename
+			key := "/media/" + user + "/" + path.Base(file.Filename)
 			params := &s3.PutObjectInput{
-				Bucket:      aws.String("blog-in-golang"),
-				Key:         aws.String(path),
+				Bucket:      aws.String(models.ImageBucket),
+				Key:         aws.String(key),
 				Body:        fileBytes,
 				ContentType: aws.String(fileType),
 			}
@@ -185,13 +416,6 @@ func UploadImagePOSTAPI(c *gin.Context) {
 		empty := []byte(``)
 		c.JSON(http.StatusOK, empty)
 
-	} else {
-
-		empty := []byte(``)
-
-		// Call the JSON method of the Context to 404
-		c.JSON(404, empty)
-
 	}
-
+	return gin.HandlerFunc(fn)
 }
@@ -2,8 +2,8 @@ package handlers
 
 import (
 	"bytes"
-	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"mime/multipart"
 	"net/http"
@@ -17,6 +17,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func setupTestRouter() *gin.Engine {
@@ -28,83 +29,190 @@ func setupTestRouter() *gin.Engine {
 	if err != nil {
 		logrus.WithError(err).Warn("Failed to create dummy error template, proceeding without HTML templates")
 	} else {
+		router.SetFuncMap(models.TemplateFuncs())
 		router.LoadHTMLGlob(filepath.Join(tempDir, "*.html"))
 	}
 	return router
 }
 
+// asUser stands in for userAuth, signing every request in as email.
+func asUser(email string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(UserEmailKey, email)
+	}
+}
+
+// stubDynamoDB points the DynamoDB client at a server that answers every
+// call with status and body, and returns the clients and the JSON of the
+// last request it saw.
+func stubDynamoDB(t *testing.T, status int, body string) (*models.AWSClients, *map[string]any) {
+	t.Helper()
+	last := map[string]any{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = map[string]any{}
+		json.NewDecoder(r.Body).Decode(&last)
+		w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_ACCESS_KEY_ID", "FAKE_KEY_ID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "FAKE_SECRET_KEY")
+	t.Setenv("DYNAMODB_ENDPOINT", server.URL)
+	return testAWSClients(t), &last
+}
+
 func TestListingPOSTAPI(t *testing.T) {
 	silenceLogrus(t)
-	router := setupTestRouter()
-	router.POST("/listings/add/:key", ListingPOSTAPI)
 
 	mockListing := models.Listing{
 		MLS:          "TestMLS123",
 		Street1:      "123 Test St",
 		City:         "Testville",
-		State:        "TS",
+		State:        "OR",
 		ZipCode:      "12345",
-		SalesPrice:   "100000",
-		Bedrooms:     "3",
-		Bathrooms:    "2",
-		SquareFeet:   "1500",
-		LotSize:      "3000",
-		GarageSize:   "2 car",
+		SalesPrice:   100000,
+		Bedrooms:     3,
+		Bathrooms:    2,
+		SquareFeet:   1500,
+		LotSize:      3000,
+		GarageSize:   2,
 		Description:  "A test listing",
 		Neighborhood: "Test Hood",
 		DateListed:   "1678886400000",
 		LastModified: "1678886400000",
-		User:         "test@example.com",
+		User:         "someone-else@example.com",
 		Deleted:      "false",
 		ListPhoto:    "http://example.com/photo.jpg",
 		PhotoArray:   []string{"http://example.com/photo1.jpg"},
 	}
 	listingJSON, _ := json.Marshal(mockListing)
+	zips := models.ZIPCentroids{"12345": {Lat: 44.0582, Lng: -121.3153}}
 
-	t.Run("SuccessPathLeadsToAWSError", func(t *testing.T) {
+	post := func(clients *models.AWSClients, body []byte, signedIn bool) *httptest.ResponseRecorder {
+		router := setupTestRouter()
+		if signedIn {
+			router.Use(asUser("seller@example.com"))
+		}
+		router.POST("/listings", ListingPOSTAPI(clients, zips))
 		w := httptest.NewRecorder()
-		req, _ := http.NewRequest(http.MethodPost, "/listings/add/HowMuchDoesSecurityCost", bytes.NewBuffer(listingJSON))
+		req, _ := http.NewRequest(http.MethodPost, "/listings", bytes.NewBuffer(body))
 		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
 
-		os.Setenv("AWS_ACCESS_KEY_ID", "FAKE_KEY_ID")
-		os.Setenv("AWS_SECRET_ACCESS_KEY", "FAKE_SECRET_KEY")
-		defer os.Unsetenv("AWS_ACCESS_KEY_ID")
-		defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+	t.Run("Saved", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		w := post(clients, listingJSON, true)
 
-		router.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+		item := (*last)["Item"].(map[string]any)
+		assert.Equal(t, map[string]any{"S": "seller@example.com"}, item["User"], "The owner is whoever is signed in, not the body's User")
+		assert.Equal(t, map[string]any{"S": "seller@example.com"}, item["user-key"])
+		assert.Equal(t, map[string]any{"S": "2023-03-15T13:20:00Z"}, item["Date Listed"])
+		assert.Equal(t, map[string]any{"N": "100000"}, item["Sales Price"], "Numbers are stored as numbers")
+		assert.Equal(t, map[string]any{"N": "44.0582"}, item["Latitude"], "The listing is placed at its ZIP code")
+		assert.Equal(t, map[string]any{"S": "9rcd"}, item["geo-cell"])
+		assert.Equal(t, map[string]any{"S": "false"}, item["deleted"], "Saving never deletes or restores a listing")
+		assert.Equal(t, "attribute_not_exists(MLS) OR (#user = :user AND (attribute_not_exists(deleted) OR deleted <> :true))", (*last)["ConditionExpression"])
+		assert.Equal(t, map[string]any{
+			":user": map[string]any{"S": "seller@example.com"},
+			":true": map[string]any{"S": "true"},
+		}, (*last)["ExpressionAttributeValues"])
+	})
+
+	t.Run("FormStrings", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		body := bytes.Replace(listingJSON, []byte(`"Sales Price":100000`), []byte(`"Sales Price":"$100,000"`), 1)
+		body = bytes.Replace(body, []byte(`"State":"OR"`), []byte(`"State":"or"`), 1)
+		w := post(clients, body, true)
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		item := (*last)["Item"].(map[string]any)
+		assert.Equal(t, map[string]any{"N": "100000"}, item["Sales Price"])
+		assert.Equal(t, map[string]any{"S": "OR"}, item["State"])
+	})
+
+	t.Run("Unplaced", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		body := bytes.Replace(listingJSON, []byte(`"Zip Code":"12345"`), []byte(`"Zip Code":"54321","Latitude":1,"Longitude":2`), 1)
+		w := post(clients, body, true)
+
+		assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		item := (*last)["Item"].(map[string]any)
+		assert.NotContains(t, item, "Latitude", "A listing the geocoder can't place is saved without coordinates")
+		assert.NotContains(t, item, "geo-cell")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		w := post(clients, []byte(`{"MLS": "TestMLS123", "Street1": "123 Test St", "City": "Testville",
+			"State": "ZZ", "Zip Code": "1234", "Sales Price": "a lot", "Bathrooms": 2, "Square Feet": 1500,
+			"List Photo": "file:///etc/passwd", "Photo Array": "http://example.com/photo1.jpg"}`), true)
+
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		assert.Empty(t, *last, "Nothing is written")
+		var resp struct {
+			Fields []models.FieldError `json:"fields"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		fields := []string{}
+		for _, f := range resp.Fields {
+			fields = append(fields, f.Field)
+		}
+		assert.Equal(t, []string{"Sales Price", "Bedrooms", "Photo Array"}, fields, "Fields that don't decode are reported first, and on their own")
+
+		w = post(clients, []byte(`{"MLS": "TestMLS123", "Street1": "123 Test St", "City": "Testville",
+			"State": "ZZ", "Zip Code": "1234", "Sales Price": 1, "Bedrooms": 1.5, "Bathrooms": 2, "Square Feet": 1500,
+			"List Photo": "file:///etc/passwd"}`), true)
+		assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+		resp.Fields = nil
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		fields = []string{}
+		for _, f := range resp.Fields {
+			fields = append(fields, f.Field)
+		}
+		assert.Equal(t, []string{"State", "Zip Code", "Bedrooms", "List Photo"}, fields)
+		assert.Empty(t, *last, "Nothing is written")
+	})
+
+	t.Run("AnotherUsersListing", func(t *testing.T) {
+		clients, _ := stubDynamoDB(t, http.StatusBadRequest,
+			`{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`)
+		w := post(clients, listingJSON, true)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "belongs to another user")
+	})
+
+	t.Run("AWSError", func(t *testing.T) {
+		clients, _ := stubDynamoDB(t, http.StatusBadRequest,
+			`{"__type":"com.amazon.coral.service#UnrecognizedClientException","message":"The security token included in the request is invalid."}`)
+		w := post(clients, listingJSON, true)
 
 		assert.Equal(t, http.StatusInternalServerError, w.Code)
 		assert.Contains(t, w.Body.String(), "UnrecognizedClientException")
 		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
 	})
 
-	t.Run("InvalidKey", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		req, _ := http.NewRequest(http.MethodPost, "/listings/add/WrongKey", bytes.NewBuffer(listingJSON))
-		req.Header.Set("Content-Type", "application/json")
-
-		router.ServeHTTP(w, req)
+	t.Run("NotSignedIn", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		w := post(clients, listingJSON, false)
 
-		assert.Equal(t, http.StatusNotFound, w.Code)
-		assert.Equal(t, `""`, w.Body.String())
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, *last, "Nothing is written")
 		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
 	})
 
 	t.Run("BindError", func(t *testing.T) {
-		w := httptest.NewRecorder()
-		invalidJSON := []byte(`{"MLS": "TestMLS123", "Street1": 123`)
-		req, _ := http.NewRequest(http.MethodPost, "/listings/add/HowMuchDoesSecurityCost", bytes.NewBuffer(invalidJSON))
-		req.Header.Set("Content-Type", "application/json")
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		w := post(clients, []byte(`{"MLS": "TestMLS123", "Street1": 123`), true)
 
-		os.Setenv("AWS_ACCESS_KEY_ID", "FAKE_KEY_ID_FOR_BIND_ERROR")
-		os.Setenv("AWS_SECRET_ACCESS_KEY", "FAKE_SECRET_KEY_FOR_BIND_ERROR")
-		defer os.Unsetenv("AWS_ACCESS_KEY_ID")
-		defer os.Unsetenv("AWS_SECRET_ACCESS_KEY")
-
-		router.ServeHTTP(w, req)
-
-		assert.Equal(t, http.StatusBadRequest, w.Code, "Expected 500 status for invalid JSON leading to AWS error")
-		assert.Contains(t, w.Body.String(), "Error:")
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), `"error"`)
+		assert.Empty(t, *last, "Nothing is written")
 		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
 	})
 }
@@ -112,7 +220,8 @@ func TestListingPOSTAPI(t *testing.T) {
 func TestUploadImagePOSTAPI(t *testing.T) {
 	silenceLogrus(t)
 	router := setupTestRouter()
-	router.POST("/upload/image/:user", UploadImagePOSTAPI)
+	router.POST("/upload/image", asUser("testuser@example.com"), UploadImagePOSTAPI(testAWSClients(t)))
+	router.POST("/anonymous/upload/image", UploadImagePOSTAPI(testAWSClients(t)))
 
 	t.Run("Success", func(t *testing.T) {
 		silenceLogrus(t)
@@ -127,7 +236,7 @@ func TestUploadImagePOSTAPI(t *testing.T) {
 		err = writer.Close()
 		assert.NoError(t, err)
 
-		req, _ := http.NewRequest(http.MethodPost, "/upload/image/testuser", body)
+		req, _ := http.NewRequest(http.MethodPost, "/upload/image", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 
 		os.Setenv("AWS_ACCESS_KEY_ID", "FAKE_KEY_ID")
@@ -148,7 +257,7 @@ func TestUploadImagePOSTAPI(t *testing.T) {
 		writer := multipart.NewWriter(body)
 		writer.Close()
 
-		req, _ := http.NewRequest(http.MethodPost, "/upload/image/testuser", body)
+		req, _ := http.NewRequest(http.MethodPost, "/upload/image", body)
 		req.Header.Set("Content-Type", writer.FormDataContentType())
 
 		router.ServeHTTP(w, req)
@@ -158,19 +267,36 @@ func TestUploadImagePOSTAPI(t *testing.T) {
 		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
 	})
 
-	t.Run("MissingUserInRoute", func(t *testing.T) {
+	t.Run("NotMultipart", func(t *testing.T) {
 		w := httptest.NewRecorder()
-		req, _ := http.NewRequest(http.MethodPost, "/upload/image/", nil)
+		req, _ := http.NewRequest(http.MethodPost, "/upload/image", strings.NewReader("raw image bytes"))
+		req.Header.Set("Content-Type", "image/jpeg")
 
 		router.ServeHTTP(w, req)
 
-		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "multipart/form-data")
+	})
+
+	t.Run("NotSignedIn", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		body := new(bytes.Buffer)
+		writer := multipart.NewWriter(body)
+		writer.Close()
+
+		req, _ := http.NewRequest(http.MethodPost, "/anonymous/upload/image", body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+
+		router.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
 	})
 }
 
 func TestListingsGETAPI(t *testing.T) {
 	router := setupTestRouter()
-	router.GET("/listings", ListingsGETAPI)
+	router.GET("/listings", ListingsGETAPI(testAWSClients(t), nil))
 
 	t.Run("Success", func(t *testing.T) {
 		w := httptest.NewRecorder()
@@ -187,11 +313,159 @@ func TestListingsGETAPI(t *testing.T) {
 		assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
 		assert.Equal(t, `[]`, w.Body.String())
 	})
+
+	t.Run("BadPageRequest", func(t *testing.T) {
+		for _, query := range []string{"?limit=0", "?limit=ten", "?page_token=!!!"} {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/listings"+query, nil)
+
+			os.Setenv("AWS_ACCESS_KEY_ID", "FAKE_KEY_ID")
+			os.Setenv("AWS_SECRET_ACCESS_KEY", "FAKE_SECRET_KEY")
+			router.ServeHTTP(w, req)
+			os.Unsetenv("AWS_ACCESS_KEY_ID")
+			os.Unsetenv("AWS_SECRET_ACCESS_KEY")
+
+			assert.Equal(t, http.StatusBadRequest, w.Code, query)
+			assert.Contains(t, w.Body.String(), "error", query)
+		}
+	})
+}
+
+func TestListingsGETAPI_Search(t *testing.T) {
+	silenceLogrus(t)
+	item := func(mls, city string, price, beds int, description string) string {
+		return fmt.Sprintf(`{"MLS": {"S": %q}, "City": {"S": %q}, "State": {"S": "OR"}, "Zip Code": {"S": "97701"},
+			"Sales Price": {"N": "%d"}, "Bedrooms": {"N": "%d"}, "Square Feet": {"S": "1200 sqft"},
+			"Last Modified": {"S": "2024-01-0%sT00:00:00Z"}, "Description": {"S": %q}}`,
+			mls, city, price, beds, mls[len(mls)-1:], description)
+	}
+	scan := `{"Count": 4, "ScannedCount": 4, "Items": [` + strings.Join([]string{
+		item("mls-1", "Bend", 503000, 3, "Mountain views"),
+		item("mls-2", "Bend", 425000, 2, "Close to the river"),
+		item("mls-3", "Redmond", 389000, 4, "Big yard, mountain views"),
+		item("mls-4", "Bend", 610000, 4, "Mountain views and a pool"),
+	}, ",") + `]}`
+	clients, _ := stubDynamoDB(t, http.StatusOK, scan)
+	router := setupTestRouter()
+	router.GET("/listings", ListingsGETAPI(clients, nil))
+
+	get := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/listings"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	mls := func(w *httptest.ResponseRecorder) []string {
+		var listings []models.Listing
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listings), w.Body.String())
+		out := []string{}
+		for _, listing := range listings {
+			out = append(out, listing.MLS)
+		}
+		return out
+	}
+
+	w := get("")
+	assert.Equal(t, []string{"mls-4", "mls-3", "mls-2", "mls-1"}, mls(w), "Most recently modified first")
+	assert.Equal(t, "4", w.Header().Get("X-Total-Count"))
+
+	w = get("?state=or&min_beds=3&q=MOUNTAIN+views&sort=price")
+	assert.Equal(t, []string{"mls-3", "mls-1", "mls-4"}, mls(w), "Cheapest first")
+	assert.Equal(t, "3", w.Header().Get("X-Total-Count"))
+
+	w = get("?min_price=400,000&max_price=600000&min_sqft=1000&sort=price&order=desc")
+	assert.Equal(t, []string{"mls-1", "mls-2"}, mls(w))
+
+	w = get("?min_beds=3&sort=price&limit=2")
+	assert.Equal(t, []string{"mls-3", "mls-1"}, mls(w))
+	assert.Equal(t, "3", w.Header().Get("X-Total-Count"), "The total counts every match, not just the page")
+	next := w.Header().Get("X-Next-Page-Token")
+	require.NotEmpty(t, next)
+	assert.Contains(t, w.Header().Get("Link"), "min_beds=3", "The next page keeps the search")
+	assert.Contains(t, w.Header().Get("Link"), "sort=price")
+
+	w = get("?min_beds=3&sort=price&limit=2&page_token=" + next)
+	assert.Equal(t, []string{"mls-4"}, mls(w))
+	assert.Empty(t, w.Header().Get("X-Next-Page-Token"))
+
+	for _, query := range []string{"?min_price=lots", "?sort=color", "?order=sideways", "?sort=size&page_token=" + next} {
+		w := get(query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"error"`, query)
+	}
+}
+
+func TestListingsGETAPI_Near(t *testing.T) {
+	silenceLogrus(t)
+	item := func(mls, location string) string {
+		return fmt.Sprintf(`{"MLS": {"S": %q}, "Last Modified": {"S": "2024-01-01T00:00:00Z"}%s}`, mls, location)
+	}
+	at := func(lat, lng string) string {
+		return fmt.Sprintf(`, "Latitude": {"N": %q}, "Longitude": {"N": %q}`, lat, lng)
+	}
+	items := `{"Count": 4, "ScannedCount": 4, "Items": [` + strings.Join([]string{
+		item("far", at("44.1", "-121.2")),
+		item("near", at("44.051", "-121.201")),
+		item("unplaced", ""),
+		item("nearby", at("44.06", "-121.21")),
+	}, ",") + `]}`
+	clients, last := stubDynamoDB(t, http.StatusOK, items)
+	router := setupTestRouter()
+	router.GET("/listings", ListingsGETAPI(clients, models.ZIPCentroids{}))
+	router.GET("/ungeocoded/listings", ListingsGETAPI(clients, nil))
+
+	get := func(query string) ([]string, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/listings"+query, nil)
+		router.ServeHTTP(w, req)
+		var listings []models.Listing
+		_ = json.Unmarshal(w.Body.Bytes(), &listings)
+		out := []string{}
+		for _, listing := range listings {
+			out = append(out, listing.MLS)
+		}
+		return out, w
+	}
+
+	mls, w := get("?lat=44.05&lng=-121.2&miles=2")
+	assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.Equal(t, []string{"near", "nearby"}, mls, "Nearest first, within the radius")
+	assert.Equal(t, models.ListingsGeoIndex, (*last)["IndexName"], "A radius is a Query of the cells around it")
+
+	mls, _ = get("?lat=44.05&lng=-121.2")
+	assert.Equal(t, []string{"near", "nearby", "far", "unplaced"}, mls, "A point alone orders by distance")
+	assert.Equal(t, "Listings", (*last)["TableName"])
+	assert.NotContains(t, *last, "IndexName", "and Scans")
+
+	mls, _ = get("?bbox=-121.205,44.04,-121.195,44.07&sort=modified")
+	assert.Equal(t, []string{"near"}, mls)
+
+	for _, query := range []string{"?lat=44.05", "?lat=91&lng=0", "?miles=5", "?lat=44&lng=-121&miles=-1",
+		"?bbox=1,2,3", "?bbox=-121,45,-122,44", "?sort=distance"} {
+		_, w := get(query)
+		assert.Equal(t, http.StatusBadRequest, w.Code, query)
+		assert.Contains(t, w.Body.String(), `"error"`, query)
+	}
+
+	// Without a ZIP table new listings aren't placed, so location searches
+	// are refused rather than silently missing them.
+	ungeocoded := func(query string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/ungeocoded/listings"+query, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	for _, query := range []string{"?lat=44.05&lng=-121.2&miles=2", "?lat=44.05&lng=-121.2", "?bbox=-121.205,44.04,-121.195,44.07"} {
+		w := ungeocoded(query)
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code, query)
+		assert.Contains(t, w.Body.String(), "ZIP_CENTROIDS", query)
+	}
+	assert.Equal(t, http.StatusOK, ungeocoded("?city=Bend").Code, "Other searches still work")
 }
 
 func TestListingGETAPI(t *testing.T) {
 	router := setupTestRouter()
-	router.GET("/listing/:listing", ListingGETAPI)
+	router.GET("/listing/:listing", ListingGETAPI(testAWSClients(t)))
 
 	t.Run("SuccessWithValidListingParam", func(t *testing.T) {
 		w := httptest.NewRecorder()
@@ -215,7 +489,7 @@ func TestListingGETAPI(t *testing.T) {
 		// Use a route pattern that allows empty listing parameter to reach our handler
 		router.GET("/listing-test", func(c *gin.Context) {
 			// Simulate empty listing parameter by not setting it
-			ListingGETAPI(c)
+			ListingGETAPI(testAWSClients(t))(c)
 		})
 
 		w := httptest.NewRecorder()
@@ -238,105 +512,11 @@ func TestListingGETAPI(t *testing.T) {
 	})
 }
 
-func TestUploadImagePOSTAPI_EmptyUserParam(t *testing.T) {
-	silenceLogrus(t)
-	router := setupTestRouter()
-	// Add a route that captures empty user parameter to test the else branch
-	router.POST("/upload/image/", UploadImagePOSTAPI)
-
-	w := httptest.NewRecorder()
-	body := new(bytes.Buffer)
-	writer := multipart.NewWriter(body)
-	part, err := writer.CreateFormFile("file", "test.jpg")
-	assert.NoError(t, err)
-	_, err = io.WriteString(part, "fake image data")
-	assert.NoError(t, err)
-	err = writer.Close()
-	assert.NoError(t, err)
-
-	// Create a request with empty user parameter to test the else branch
-	req, _ := http.NewRequest(http.MethodPost, "/upload/image/", body)
-	req.Header.Set("Content-Type", writer.FormDataContentType())
-
-	router.ServeHTTP(w, req)
-
-	// Should return 404 when user parameter is empty
-	assert.Equal(t, http.StatusNotFound, w.Code)
-	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
-}
-
-func TestCreateS3Client_Success(t *testing.T) {
-	silenceLogrus(t)
-
-	// Set up AWS credentials for testing
-	originalAccessKey, accessKeySet := os.LookupEnv("AWS_ACCESS_KEY_ID")
-	originalSecretKey, secretKeySet := os.LookupEnv("AWS_SECRET_ACCESS_KEY")
-	originalRegion, regionSet := os.LookupEnv("AWS_REGION")
-
-	os.Setenv("AWS_ACCESS_KEY_ID", "test_access_key")
-	os.Setenv("AWS_SECRET_ACCESS_KEY", "test_secret_key")
-	os.Setenv("AWS_REGION", "us-east-1")
-
-	defer func() {
-		if accessKeySet {
-			os.Setenv("AWS_ACCESS_KEY_ID", originalAccessKey)
-		} else {
-			os.Unsetenv("AWS_ACCESS_KEY_ID")
-		}
-		if secretKeySet {
-			os.Setenv("AWS_SECRET_ACCESS_KEY", originalSecretKey)
-		} else {
-			os.Unsetenv("AWS_SECRET_ACCESS_KEY")
-		}
-		if regionSet {
-			os.Setenv("AWS_REGION", originalRegion)
-		} else {
-			os.Unsetenv("AWS_REGION")
-		}
-	}()
-
-	client, err := createS3Client(context.Background())
-
-	if err != nil {
-		t.Errorf("createS3Client should not error with valid credentials: %v", err)
-	}
-
-	if client == nil {
-		t.Error("Expected non-nil S3 client")
-	}
-}
-
-func TestCreateS3Client_NoCredentials(t *testing.T) {
-	silenceLogrus(t)
-
-	// Remove AWS credentials to test fallback
-	originalAccessKey, accessKeySet := os.LookupEnv("AWS_ACCESS_KEY_ID")
-	originalSecretKey, secretKeySet := os.LookupEnv("AWS_SECRET_ACCESS_KEY")
-
-	os.Unsetenv("AWS_ACCESS_KEY_ID")
-	os.Unsetenv("AWS_SECRET_ACCESS_KEY")
-
-	defer func() {
-		if accessKeySet {
-			os.Setenv("AWS_ACCESS_KEY_ID", originalAccessKey)
-		}
-		if secretKeySet {
-			os.Setenv("AWS_SECRET_ACCESS_KEY", originalSecretKey)
-		}
-	}()
-
-	client, _ := createS3Client(context.Background())
-
-	// The function should still return a client, using default credential chain
-	if client == nil {
-		t.Error("Expected non-nil S3 client even without explicit credentials")
-	}
-}
-
 func TestListingPOSTAPI_ValidationErrors(t *testing.T) {
 	silenceLogrus(t)
 	router := setupTestRouter()
-	router.POST("/listings/add/:key", ListingPOSTAPI)
+	router.POST("/listings", asUser("seller@example.com"), ListingPOSTAPI(testAWSClients(t), nil))
+	router.POST("/anonymous/listings", ListingPOSTAPI(testAWSClients(t), nil))
 
 	// Set AWS credentials to pass DynamoDB client creation
 	os.Setenv("AWS_ACCESS_KEY_ID", "test_key")
@@ -349,36 +529,36 @@ func TestListingPOSTAPI_ValidationErrors(t *testing.T) {
 	testCases := []struct {
 		name         string
 		jsonPayload  string
-		key          string
+		path         string
 		expectedCode int
 		expectedBody string
 	}{
 		{
 			name:         "EmptyPayload",
 			jsonPayload:  "",
-			key:          "HowMuchDoesSecurityCost",
+			path:         "/listings",
 			expectedCode: http.StatusBadRequest,
-			expectedBody: "Error:",
+			expectedBody: `"error"`,
 		},
 		{
 			name:         "MalformedJSON",
 			jsonPayload:  `{"MLS": "test", invalid}`,
-			key:          "HowMuchDoesSecurityCost",
+			path:         "/listings",
 			expectedCode: http.StatusBadRequest,
-			expectedBody: "Error:",
+			expectedBody: `"error"`,
 		},
 		{
-			name:         "ValidJSONWrongKey",
+			name:         "ValidJSONNotSignedIn",
 			jsonPayload:  `{"MLS": "TestMLS", "Street1": "123 Test St"}`,
-			key:          "WrongSecurityKey",
-			expectedCode: http.StatusNotFound,
-			expectedBody: `""`,
+			path:         "/anonymous/listings",
+			expectedCode: http.StatusUnauthorized,
+			expectedBody: `"error"`,
 		},
 	}
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			req, _ := http.NewRequest(http.MethodPost, "/listings/add/"+tc.key, bytes.NewBufferString(tc.jsonPayload))
+			req, _ := http.NewRequest(http.MethodPost, tc.path, bytes.NewBufferString(tc.jsonPayload))
 			req.Header.Set("Content-Type", "application/json")
 
 			recorder := httptest.NewRecorder()
@@ -405,7 +585,7 @@ func TestListingPOSTAPI_ValidationErrors(t *testing.T) {
 func TestUploadImagePOSTAPI_FileHandling(t *testing.T) {
 	silenceLogrus(t)
 	router := setupTestRouter()
-	router.POST("/upload/image/:user", UploadImagePOSTAPI)
+	router.POST("/upload/image", asUser("testuser@example.com"), UploadImagePOSTAPI(testAWSClients(t)))
 
 	// Set AWS credentials
 	os.Setenv("AWS_ACCESS_KEY_ID", "test_key")
@@ -417,28 +597,24 @@ func TestUploadImagePOSTAPI_FileHandling(t *testing.T) {
 
 	testCases := []struct {
 		name         string
-		user         string
 		fileName     string
 		fileContent  string
 		expectedCode int
 	}{
 		{
 			name:         "ValidImageUpload",
-			user:         "testuser@example.com",
 			fileName:     "test-image.jpg",
 			fileContent:  "fake jpeg data",
 			expectedCode: http.StatusOK,
 		},
 		{
 			name:         "ValidUserDifferentFileType",
-			user:         "user2@example.com",
 			fileName:     "document.pdf",
 			fileContent:  "fake pdf data",
 			expectedCode: http.StatusOK,
 		},
 		{
 			name:         "EmptyFile",
-			user:         "user3@example.com",
 			fileName:     "empty.txt",
 			fileContent:  "",
 			expectedCode: http.StatusOK,
@@ -460,7 +636,7 @@ func TestUploadImagePOSTAPI_FileHandling(t *testing.T) {
 
 			writer.Close()
 
-			req, _ := http.NewRequest(http.MethodPost, "/upload/image/"+tc.user, body)
+			req, _ := http.NewRequest(http.MethodPost, "/upload/image", body)
 			req.Header.Set("Content-Type", writer.FormDataContentType())
 
 			recorder := httptest.NewRecorder()
@@ -486,7 +662,7 @@ func TestUploadImagePOSTAPI_FileHandling(t *testing.T) {
 func TestListingsGETAPI_ErrorHandling(t *testing.T) {
 	silenceLogrus(t)
 	router := setupTestRouter()
-	router.GET("/listings", ListingsGETAPI)
+	router.GET("/listings", ListingsGETAPI(testAWSClients(t), nil))
 
 	// Test without AWS credentials to force error path
 	originalAccessKey, accessKeySet := os.LookupEnv("AWS_ACCESS_KEY_ID")
@@ -527,7 +703,7 @@ func TestListingsGETAPI_ErrorHandling(t *testing.T) {
 func TestListingGETAPI_ParameterHandling(t *testing.T) {
 	silenceLogrus(t)
 	router := setupTestRouter()
-	router.GET("/listing/:listing", ListingGETAPI)
+	router.GET("/listing/:listing", ListingGETAPI(testAWSClients(t)))
 
 	// Set AWS credentials
 	os.Setenv("AWS_ACCESS_KEY_ID", "test_key")
@@ -596,7 +772,7 @@ func TestListingGETAPI_EmptyParameter(t *testing.T) {
 	// Create a route that will call ListingGETAPI with empty parameter
 	router.GET("/test-empty-listing", func(c *gin.Context) {
 		// Don't set any path parameter to simulate empty listing
-		ListingGETAPI(c)
+		ListingGETAPI(testAWSClients(t))(c)
 	})
 
 	req, _ := http.NewRequest(http.MethodGet, "/test-empty-listing", nil)
@@ -617,3 +793,204 @@ func TestListingGETAPI_EmptyParameter(t *testing.T) {
 		t.Errorf("Expected Cache-Control header %q, got %q", expectedCacheControl, actualCacheControl)
 	}
 }
+
+func TestListingDELETEAPI(t *testing.T) {
+	silenceLogrus(t)
+	const conditionFailed = `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"`
+	del := func(clients *models.AWSClients, user string) *httptest.ResponseRecorder {
+		router := setupTestRouter()
+		if user != "" {
+			router.Use(asUser(user))
+		}
+		router.DELETE("/listings/:mls", ListingDELETEAPI(clients))
+		router.POST("/listings/:mls/restore", ListingRestorePOSTAPI(clients))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/listings/mls-1", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Deleted", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{"Attributes": {"MLS": {"S": "mls-1"}, "User": {"S": "seller@example.com"},
+			"deleted": {"S": "true"}, "Deleted At": {"S": "2024-05-01T12:00:00Z"}, "Deleted By": {"S": "seller@example.com"}}}`)
+		w := del(clients, "seller@example.com")
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		var listing models.Listing
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listing))
+		assert.True(t, listing.IsDeleted())
+		assert.Equal(t, "seller@example.com", listing.DeletedBy)
+		assert.Equal(t, map[string]any{"S": "mls-1"}, (*last)["Key"].(map[string]any)["MLS"])
+		assert.Contains(t, (*last)["UpdateExpression"], "SET")
+		assert.Equal(t, "ALL_OLD", (*last)["ReturnValuesOnConditionCheckFailure"])
+	})
+
+	t.Run("AlreadyDeleted", func(t *testing.T) {
+		clients, _ := stubDynamoDB(t, http.StatusBadRequest, conditionFailed+`, "Item": {"MLS": {"S": "mls-1"},
+			"User": {"S": "seller@example.com"}, "deleted": {"S": "true"}, "Deleted At": {"S": "2024-04-01T00:00:00Z"}}}`)
+		w := del(clients, "seller@example.com")
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), "2024-04-01T00:00:00Z", "Deleting twice keeps the first deletion")
+	})
+
+	t.Run("AnotherUsersListing", func(t *testing.T) {
+		clients, _ := stubDynamoDB(t, http.StatusBadRequest, conditionFailed+`, "Item": {"MLS": {"S": "mls-1"},
+			"User": {"S": "someone-else@example.com"}, "deleted": {"S": "false"}}}`)
+		w := del(clients, "seller@example.com")
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "belongs to another user")
+	})
+
+	t.Run("Missing", func(t *testing.T) {
+		clients, _ := stubDynamoDB(t, http.StatusBadRequest, conditionFailed+`}`)
+		w := del(clients, "seller@example.com")
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "listing not found")
+	})
+
+	t.Run("NotSignedIn", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{}`)
+		w := del(clients, "")
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Empty(t, *last, "Nothing is written")
+	})
+
+	t.Run("Restored", func(t *testing.T) {
+		clients, last := stubDynamoDB(t, http.StatusOK, `{"Attributes": {"MLS": {"S": "mls-1"}, "User": {"S": "seller@example.com"},
+			"deleted": {"S": "false"}}}`)
+		router := setupTestRouter()
+		router.POST("/listings/:mls/restore", asUser("seller@example.com"), ListingRestorePOSTAPI(clients))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodPost, "/listings/mls-1/restore", nil)
+		router.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+		assert.Contains(t, w.Body.String(), `"deleted":"false"`)
+		assert.NotContains(t, w.Body.String(), "Deleted At")
+		assert.Contains(t, (*last)["UpdateExpression"], "REMOVE")
+	})
+}
+
+func TestListingPOSTAPI_DeletedListing(t *testing.T) {
+	silenceLogrus(t)
+	clients, _ := stubDynamoDB(t, http.StatusBadRequest,
+		`{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed",
+		"Item": {"MLS": {"S": "1234567890"}, "User": {"S": "seller@example.com"}, "deleted": {"S": "true"}}}`)
+	router := setupTestRouter()
+	router.POST("/listings", asUser("seller@example.com"), ListingPOSTAPI(clients, nil))
+	body := `{"MLS": "1234567890", "Street1": "123 Real Avenue", "City": "Bend", "State": "OR", "Zip Code": "97701",
+		"Sales Price": 503000, "Bedrooms": 3, "Bathrooms": 2, "Square Feet": 1200, "deleted": "false"}`
+	w := httptest.NewRecorder()
+	req, _ := http.NewRequest(http.MethodPost, "/listings", strings.NewReader(body))
+	router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code, "Saving doesn't bring a deleted listing back")
+	assert.Contains(t, w.Body.String(), "restore it before editing it")
+}
+
+func TestListingsGETAPI_Deleted(t *testing.T) {
+	silenceLogrus(t)
+	clients, _ := stubDynamoDB(t, http.StatusOK, `{"Count": 2, "ScannedCount": 2, "Items": [
+		{"MLS": {"S": "live"}, "User": {"S": "seller@example.com"}, "deleted": {"S": "false"}},
+		{"MLS": {"S": "gone"}, "User": {"S": "seller@example.com"}, "deleted": {"S": "true"}}]}`)
+	get := func(path, user string) *httptest.ResponseRecorder {
+		router := setupTestRouter()
+		if user != "" {
+			router.Use(asUser(user))
+		}
+		router.GET("/listings", ListingsGETAPI(clients, nil))
+		router.GET("/listing/:listing", ListingGETAPI(clients))
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, path, nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+	mls := func(w *httptest.ResponseRecorder) []string {
+		var listings []models.Listing
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &listings), w.Body.String())
+		out := []string{}
+		for _, listing := range listings {
+			out = append(out, listing.MLS)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"live"}, mls(get("/listings?user=seller@example.com", "")), "Deleted listings are left out")
+	assert.Equal(t, []string{"gone", "live"}, mls(get("/listings?user=seller@example.com&include_deleted=true", "seller@example.com")),
+		"unless their owner asks for them")
+	assert.Equal(t, http.StatusUnauthorized, get("/listings?user=seller@example.com&include_deleted=true", "").Code)
+	assert.Equal(t, http.StatusForbidden, get("/listings?user=seller@example.com&include_deleted=true", "buyer@example.com").Code)
+	assert.Equal(t, http.StatusForbidden, get("/listings?include_deleted=true", "seller@example.com").Code)
+
+	one, _ := stubDynamoDB(t, http.StatusOK, `{"Item": {"MLS": {"S": "gone"}, "User": {"S": "seller@example.com"}, "deleted": {"S": "true"}}}`)
+	clients = one
+	assert.Equal(t, `[]`, get("/listing/gone", "").Body.String(), "A deleted listing reads as missing")
+	assert.Equal(t, `[]`, get("/listing/gone?include_deleted=true", "buyer@example.com").Body.String())
+	assert.Equal(t, []string{"gone"}, mls(get("/listing/gone?include_deleted=true", "seller@example.com")))
+}
+
+func TestListingPurgeAPI(t *testing.T) {
+	silenceLogrus(t)
+	var deleted []string
+	var deleteBody string
+	var listingItem string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		switch target := r.Header.Get("X-Amz-Target"); {
+		case strings.HasSuffix(target, ".DeleteItem"):
+			deleteBody = string(body)
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			if listingItem == "" {
+				w.WriteHeader(http.StatusBadRequest)
+				io.WriteString(w, `{"__type":"com.amazonaws.dynamodb.v20120810#ConditionalCheckFailedException","message":"The conditional request failed"}`)
+				return
+			}
+			io.WriteString(w, `{"Attributes": `+listingItem+`}`)
+		case strings.HasSuffix(target, ".Query"):
+			w.Header().Set("Content-Type", "application/x-amz-json-1.0")
+			io.WriteString(w, `{"Count": 2, "Items": [`+listingItem+`, {"MLS": {"S": "other"}, "User": {"S": "seller@example.com"},
+				"List Photo": {"S": "https://images.example.com/media/seller@example.com/photo1.jpg"}}]}`)
+		case r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+			for _, key := range strings.Split(string(body), "<Key>")[1:] {
+				deleted = append(deleted, key[:strings.Index(key, "</Key>")])
+			}
+			w.Header().Set("Content-Type", "application/xml")
+			io.WriteString(w, `<DeleteResult></DeleteResult>`)
+		default:
+			http.Error(w, "unexpected "+r.Method+" "+r.URL.String(), http.StatusTeapot)
+		}
+	}))
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_ACCESS_KEY_ID", "FAKE_KEY_ID")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "FAKE_SECRET_KEY")
+	t.Setenv("DYNAMODB_ENDPOINT", server.URL)
+	t.Setenv("S3_ENDPOINT", server.URL)
+
+	router := setupTestRouter()
+	router.DELETE("/manage/listings/:mls", ListingPurgeAPI(testAWSClients(t)))
+	purge := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodDelete, "/manage/listings/mls-1", nil)
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	listingItem = `{"MLS": {"S": "mls-1"}, "User": {"S": "seller@example.com"}, "deleted": {"S": "true"},
+		"List Photo": {"S": "https://images.example.com/media/seller@example.com/photo1.jpg"},
+		"Photo Array": {"L": [{"S": "https://images.example.com/media/seller@example.com/kitchen.jpg"},
+			{"S": "https://images.example.com/media/someone-else@example.com/pool.jpg"},
+			{"S": "https://elsewhere.example.com/stock.jpg"}]}}`
+	w := purge()
+	require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	assert.JSONEq(t, `{"mls": "mls-1", "images": ["/media/seller@example.com/kitchen.jpg"]}`, w.Body.String(),
+		"Only the owner's images no other listing shows are removed")
+	assert.Equal(t, []string{"/media/seller@example.com/kitchen.jpg"}, deleted)
+	assert.Contains(t, deleteBody, "attribute_exists", "Only a deleted listing is purged")
+
+	listingItem = ""
+	assert.Equal(t, http.StatusNotFound, purge().Code)
+}
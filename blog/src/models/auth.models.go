@@ -1,8 +1,115 @@
 package models
 
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
 //AuthForm : structure used to grab user data from /contact POST requests
 type AuthForm struct {
-	Email     string `json:"email" form:"email" binding:"required"`
-	Password  string `json:"password" form:"password" binding:"required"`
-	UserToken string `json:"userToken"`
+	Email     string `json:"email" form:"email" binding:"required" dynamodbav:"email"`
+	Password  string `json:"password" form:"password" binding:"required" dynamodbav:"password"`
+	UserToken string `json:"userToken" dynamodbav:"userToken,omitempty"`
+}
+
+// AdminSessionCookie is the cookie the /auth login sets for admins, scoped
+// to /manage. Its value is an AdminSessionToken.
+const AdminSessionCookie = "adminSession"
+
+// AdminSessionTTL is how long an admin login lasts.
+const AdminSessionTTL = 24 * time.Hour
+
+// AdminSecret is the key admin sessions are signed with: ADMIN_TOKEN, the
+// same bearer token the daemon sends to /manage. Rotating it logs everyone
+// out. Admin logins are disabled when it's unset.
+func AdminSecret() string {
+	return os.Getenv("ADMIN_TOKEN")
+}
+
+// IsAdminEmail reports whether email is listed in ADMIN_EMAILS (comma-
+// separated, compared case-insensitively), the accounts that may use the
+// admin editor after logging in at /auth.
+func IsAdminEmail(email string) bool {
+	for _, admin := range strings.Split(os.Getenv("ADMIN_EMAILS"), ",") {
+		if admin = strings.TrimSpace(admin); admin != "" && strings.EqualFold(admin, email) {
+			return true
+		}
+	}
+	return false
+}
+
+// AdminSessionToken is "email|expires|signature", the signature being the
+// hex HMAC-SHA256 of the email and Unix expiry time under secret.
+func AdminSessionToken(secret, email string, expires time.Time) string {
+	return sessionToken("admin", secret, email, expires)
+}
+
+// ValidAdminSession returns the email an AdminSessionToken under secret was
+// issued to, if it hasn't expired at now and the email is still an admin.
+// An empty secret never validates.
+func ValidAdminSession(secret, token string, now time.Time) (string, bool) {
+	email, ok := validSession("admin", secret, token, now)
+	return email, ok && IsAdminEmail(email)
+}
+
+// UserSessionCookie is the cookie a realtor sign-in (or an /auth login)
+// sets for any account. Its value is a UserSessionToken, which the realtor
+// write APIs also take as a bearer token.
+const UserSessionCookie = "userSession"
+
+// UserSessionTTL is how long a user sign-in lasts.
+const UserSessionTTL = 24 * time.Hour
+
+// UserSecret is the key user sessions are signed with: SESSION_SECRET.
+// Rotating it signs everyone out. User sign-in is disabled when it's unset.
+func UserSecret() string {
+	return os.Getenv("SESSION_SECRET")
+}
+
+// UserSessionToken is an AdminSessionToken for any account, signed so that
+// neither kind of token passes for the other.
+func UserSessionToken(secret, email string, expires time.Time) string {
+	return sessionToken("user", secret, email, expires)
+}
+
+// ValidUserSession returns the email a UserSessionToken under secret was
+// issued to, if it hasn't expired at now. An empty secret never validates.
+func ValidUserSession(secret, token string, now time.Time) (string, bool) {
+	return validSession("user", secret, token, now)
+}
+
+func sessionToken(kind, secret, email string, expires time.Time) string {
+	unix := strconv.FormatInt(expires.Unix(), 10)
+	return email + "|" + unix + "|" + sessionSignature(kind, secret, email, unix)
+}
+
+func validSession(kind, secret, token string, now time.Time) (string, bool) {
+	if secret == "" {
+		return "", false
+	}
+	parts := strings.Split(token, "|")
+	if len(parts) != 3 {
+		return "", false
+	}
+	email, unix, signature := parts[0], parts[1], parts[2]
+	expires, err := strconv.ParseInt(unix, 10, 64)
+	if err != nil || now.After(time.Unix(expires, 0)) {
+		return "", false
+	}
+	if !hmac.Equal([]byte(signature), []byte(sessionSignature(kind, secret, email, unix))) {
+		return "", false
+	}
+	return email, email != ""
+}
+
+func sessionSignature(kind, secret, email, unix string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%s:%s:%s", kind, email, unix)
+	return hex.EncodeToString(mac.Sum(nil))
 }
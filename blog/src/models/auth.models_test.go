@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIsAdminEmail(t *testing.T) {
+	t.Setenv("ADMIN_EMAILS", "etzelm@live.com, Editor@Example.com")
+	assert.True(t, IsAdminEmail("etzelm@live.com"))
+	assert.True(t, IsAdminEmail("editor@example.com"), "Emails compare case-insensitively")
+	assert.False(t, IsAdminEmail("reader@example.com"))
+	assert.False(t, IsAdminEmail(""))
+
+	t.Setenv("ADMIN_EMAILS", "")
+	assert.False(t, IsAdminEmail("etzelm@live.com"), "No one is an admin when ADMIN_EMAILS is unset")
+}
+
+func TestAdminSession(t *testing.T) {
+	t.Setenv("ADMIN_EMAILS", "etzelm@live.com")
+	now := time.Now()
+	token := AdminSessionToken("s3cret", "etzelm@live.com", now.Add(time.Hour))
+
+	email, ok := ValidAdminSession("s3cret", token, now)
+	assert.True(t, ok)
+	assert.Equal(t, "etzelm@live.com", email)
+
+	_, ok = ValidAdminSession("s3cret", token, now.Add(2*time.Hour))
+	assert.False(t, ok, "Expired")
+	_, ok = ValidAdminSession("other", token, now)
+	assert.False(t, ok, "Signed with another secret")
+	_, ok = ValidAdminSession("", AdminSessionToken("", "etzelm@live.com", now.Add(time.Hour)), now)
+	assert.False(t, ok, "An empty secret never validates")
+	_, ok = ValidAdminSession("s3cret", AdminSessionToken("s3cret", "reader@example.com", now.Add(time.Hour)), now)
+	assert.False(t, ok, "Only admins")
+	_, ok = ValidAdminSession("s3cret", "etzelm@live.com|9999999999|forged", now)
+	assert.False(t, ok, "Forged signature")
+
+	t.Setenv("ADMIN_EMAILS", "")
+	_, ok = ValidAdminSession("s3cret", token, now)
+	assert.False(t, ok, "Removing someone from ADMIN_EMAILS ends their session")
+}
+
+func TestUserSession(t *testing.T) {
+	now := time.Now()
+	token := UserSessionToken("s3cret", "seller@example.com", now.Add(time.Hour))
+
+	email, ok := ValidUserSession("s3cret", token, now)
+	assert.True(t, ok)
+	assert.Equal(t, "seller@example.com", email)
+
+	_, ok = ValidUserSession("s3cret", token, now.Add(2*time.Hour))
+	assert.False(t, ok, "Expired")
+	_, ok = ValidUserSession("other", token, now)
+	assert.False(t, ok, "Signed with another secret")
+	_, ok = ValidUserSession("", UserSessionToken("", "seller@example.com", now.Add(time.Hour)), now)
+	assert.False(t, ok, "An empty secret never validates")
+	_, ok = ValidUserSession("s3cret", "other@example.com"+token[len("seller@example.com"):], now)
+	assert.False(t, ok, "The signature covers the email")
+
+	t.Setenv("ADMIN_EMAILS", "seller@example.com")
+	_, ok = ValidUserSession("s3cret", AdminSessionToken("s3cret", "seller@example.com", now.Add(time.Hour)), now)
+	assert.False(t, ok, "An admin session isn't a user session")
+	_, ok = ValidAdminSession("s3cret", token, now)
+	assert.False(t, ok, "Nor the other way round")
+}
@@ -0,0 +1,195 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/aws/smithy-go/middleware"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Defaults for AWSOptions.
+const (
+	DefaultAWSRegion      = "us-west-1"
+	DefaultAWSMaxAttempts = retry.DefaultMaxAttempts
+	DefaultAWSTimeout     = 10 * time.Second
+)
+
+// DEPLOYMENT=local swaps AWS for stand-ins on the developer's machine:
+// DynamoDB Local and an S3-compatible store such as MinIO. The credentials
+// are only used when AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY aren't set;
+// DynamoDB Local accepts any, and these are MinIO's out-of-the-box root user.
+const (
+	LocalDeployment       = "local"
+	LocalDynamoDBEndpoint = "http://localhost:8000"
+	LocalS3Endpoint       = "http://localhost:9000"
+	LocalAccessKeyID      = "minioadmin"
+	LocalSecretAccessKey  = "minioadmin"
+)
+
+// ImageBucket is the S3 bucket listing photos are uploaded to.
+const ImageBucket = "blog-in-golang"
+
+// AWSOptions : how the process talks to AWS, read once at startup by
+// AWSOptionsFromEnv.
+type AWSOptions struct {
+	// Local is set by DEPLOYMENT=local: the endpoints default to the local
+	// stand-ins and the local credentials fill in for missing ones.
+	Local bool
+	// Region is AWS_REGION.
+	Region string
+	// DynamoDBEndpoint (DYNAMODB_ENDPOINT) points DynamoDB at another
+	// endpoint, e.g. http://localhost:8000 for DynamoDB Local.
+	DynamoDBEndpoint string
+	// S3Endpoint (S3_ENDPOINT) points S3 at another endpoint, e.g.
+	// http://localhost:9000 for MinIO. It switches to path-style addressing.
+	S3Endpoint string
+	// MaxAttempts (AWS_MAX_ATTEMPTS) is how many times a call is tried,
+	// first attempt included, before its error is returned.
+	MaxAttempts int
+	// Timeout (AWS_HTTP_TIMEOUT, a Go duration) bounds each HTTP attempt.
+	Timeout time.Duration
+}
+
+// AWSOptionsFromEnv reads AWSOptions from the environment, applying the
+// defaults for anything unset.
+func AWSOptionsFromEnv() (AWSOptions, error) {
+	opts := AWSOptions{
+		Local:            os.Getenv("DEPLOYMENT") == LocalDeployment,
+		Region:           os.Getenv("AWS_REGION"),
+		DynamoDBEndpoint: os.Getenv("DYNAMODB_ENDPOINT"),
+		S3Endpoint:       os.Getenv("S3_ENDPOINT"),
+		MaxAttempts:      DefaultAWSMaxAttempts,
+		Timeout:          DefaultAWSTimeout,
+	}
+	if opts.Region == "" {
+		opts.Region = DefaultAWSRegion
+	}
+	if opts.Local {
+		if opts.DynamoDBEndpoint == "" {
+			opts.DynamoDBEndpoint = LocalDynamoDBEndpoint
+		}
+		if opts.S3Endpoint == "" {
+			opts.S3Endpoint = LocalS3Endpoint
+		}
+	}
+	if v := os.Getenv("AWS_MAX_ATTEMPTS"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			return opts, fmt.Errorf("AWS_MAX_ATTEMPTS must be a positive integer, got %q", v)
+		}
+		opts.MaxAttempts = n
+	}
+	if v := os.Getenv("AWS_HTTP_TIMEOUT"); v != "" {
+		d, err := time.ParseDuration(v)
+		if err != nil || d <= 0 {
+			return opts, fmt.Errorf("AWS_HTTP_TIMEOUT must be a positive duration like 10s, got %q", v)
+		}
+		opts.Timeout = d
+	}
+	return opts, nil
+}
+
+// AWSMetrics : the Prometheus collectors every SDK call reports to, both
+// labelled by service and operation (Errors also by error code). Either may
+// be nil.
+type AWSMetrics struct {
+	Latency *prometheus.HistogramVec
+	Errors  *prometheus.CounterVec
+}
+
+// AWSClients : the process-wide AWS clients, built once in main by
+// NewAWSClients and handed to the stores and handlers that need them. The
+// SDK clients are safe for concurrent use and pool their connections.
+type AWSClients struct {
+	DynamoDB *dynamodb.Client
+	S3       *s3.Client
+}
+
+// NewAWSClients loads the shared AWS configuration and builds the clients.
+// Credentials come from AWS_ACCESS_KEY_ID/AWS_SECRET_ACCESS_KEY when both
+// are set, otherwise from the local credentials in local mode or the SDK's
+// default chain. Nothing is sent to AWS until the first call.
+func NewAWSClients(ctx context.Context, opts AWSOptions, metrics AWSMetrics) (*AWSClients, error) {
+	loadOpts := []func(*config.LoadOptions) error{
+		config.WithRegion(opts.Region),
+		config.WithRetryer(func() aws.Retryer {
+			return retry.NewStandard(func(o *retry.StandardOptions) {
+				o.MaxAttempts = opts.MaxAttempts
+			})
+		}),
+		config.WithHTTPClient(awshttp.NewBuildableClient().WithTimeout(opts.Timeout)),
+	}
+	aid, key := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if (aid == "" || key == "") && opts.Local {
+		aid, key = LocalAccessKeyID, LocalSecretAccessKey
+	}
+	if aid != "" && key != "" {
+		loadOpts = append(loadOpts, config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(aid, key, "")))
+	}
+
+	cfg, err := config.LoadDefaultConfig(ctx, loadOpts...)
+	if err != nil {
+		return nil, err
+	}
+	cfg.APIOptions = append(cfg.APIOptions, metrics.addMiddleware)
+
+	return &AWSClients{
+		DynamoDB: dynamodb.NewFromConfig(cfg, func(o *dynamodb.Options) {
+			if opts.DynamoDBEndpoint != "" {
+				o.BaseEndpoint = aws.String(opts.DynamoDBEndpoint)
+			}
+		}),
+		S3: s3.NewFromConfig(cfg, func(o *s3.Options) {
+			if opts.S3Endpoint != "" {
+				o.BaseEndpoint = aws.String(opts.S3Endpoint)
+				o.UsePathStyle = true
+			}
+		}),
+	}, nil
+}
+
+// addMiddleware times every call, retries included. It sits at the end of
+// the initialize step, after the SDK has recorded the service and operation
+// names in the context.
+func (m AWSMetrics) addMiddleware(stack *middleware.Stack) error {
+	if m.Latency == nil && m.Errors == nil {
+		return nil
+	}
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("BlogAWSMetrics",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			start := time.Now()
+			out, md, err := next.HandleInitialize(ctx, in)
+			service, operation := awsmiddleware.GetServiceID(ctx), awsmiddleware.GetOperationName(ctx)
+			if m.Latency != nil {
+				m.Latency.WithLabelValues(service, operation).Observe(time.Since(start).Seconds())
+			}
+			if err != nil && m.Errors != nil {
+				m.Errors.WithLabelValues(service, operation, awsErrorCode(err)).Inc()
+			}
+			return out, md, err
+		}), middleware.After)
+}
+
+// awsErrorCode is the API error code of err (ThrottlingException, …), or
+// "transport" for failures that never got an answer from AWS.
+func awsErrorCode(err error) string {
+	var apiErr smithy.APIError
+	if errors.As(err, &apiErr) {
+		return apiErr.ErrorCode()
+	}
+	return "transport"
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testAWSClients builds clients from the current environment that try each
+// call once, for tests that expect AWS to be unreachable.
+func testAWSClients(t *testing.T) *AWSClients {
+	t.Helper()
+	opts, err := AWSOptionsFromEnv()
+	require.NoError(t, err)
+	opts.MaxAttempts = 1
+	clients, err := NewAWSClients(context.Background(), opts, AWSMetrics{})
+	require.NoError(t, err)
+	return clients
+}
+
+func TestAWSOptionsFromEnv(t *testing.T) {
+	t.Setenv("DEPLOYMENT", "")
+	t.Setenv("AWS_REGION", "")
+	t.Setenv("DYNAMODB_ENDPOINT", "")
+	t.Setenv("S3_ENDPOINT", "")
+	t.Setenv("AWS_MAX_ATTEMPTS", "")
+	t.Setenv("AWS_HTTP_TIMEOUT", "")
+
+	opts, err := AWSOptionsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, AWSOptions{
+		Region:      DefaultAWSRegion,
+		MaxAttempts: DefaultAWSMaxAttempts,
+		Timeout:     DefaultAWSTimeout,
+	}, opts)
+
+	t.Setenv("AWS_REGION", "us-east-2")
+	t.Setenv("DYNAMODB_ENDPOINT", "http://localhost:8000")
+	t.Setenv("S3_ENDPOINT", "http://localhost:9000")
+	t.Setenv("AWS_MAX_ATTEMPTS", "5")
+	t.Setenv("AWS_HTTP_TIMEOUT", "2s")
+
+	opts, err = AWSOptionsFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, AWSOptions{
+		Region:           "us-east-2",
+		DynamoDBEndpoint: "http://localhost:8000",
+		S3Endpoint:       "http://localhost:9000",
+		MaxAttempts:      5,
+		Timeout:          2 * time.Second,
+	}, opts)
+
+	for env, value := range map[string]string{"AWS_MAX_ATTEMPTS": "0", "AWS_HTTP_TIMEOUT": "soon"} {
+		t.Run(env, func(t *testing.T) {
+			t.Setenv(env, value)
+			_, err := AWSOptionsFromEnv()
+			assert.ErrorContains(t, err, env)
+		})
+	}
+}
+
+func TestAWSOptionsFromEnv_Local(t *testing.T) {
+	t.Setenv("DEPLOYMENT", LocalDeployment)
+	t.Setenv("DYNAMODB_ENDPOINT", "")
+	t.Setenv("S3_ENDPOINT", "http://minio:9000")
+
+	opts, err := AWSOptionsFromEnv()
+	require.NoError(t, err)
+	assert.True(t, opts.Local)
+	assert.Equal(t, LocalDynamoDBEndpoint, opts.DynamoDBEndpoint)
+	assert.Equal(t, "http://minio:9000", opts.S3Endpoint, "An explicit endpoint wins over the local default")
+}
+
+func TestNewAWSClients_LocalCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	clients, err := NewAWSClients(context.Background(), AWSOptions{
+		Local:            true,
+		Region:           DefaultAWSRegion,
+		DynamoDBEndpoint: LocalDynamoDBEndpoint,
+		S3Endpoint:       LocalS3Endpoint,
+	}, AWSMetrics{})
+	require.NoError(t, err)
+
+	creds, err := clients.S3.Options().Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, LocalAccessKeyID, creds.AccessKeyID)
+	assert.Equal(t, LocalSecretAccessKey, creds.SecretAccessKey)
+}
+
+func TestNewAWSClients(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test_access_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test_secret_key")
+
+	clients, err := NewAWSClients(context.Background(), AWSOptions{
+		Region:           "us-east-2",
+		DynamoDBEndpoint: "http://localhost:8000",
+		S3Endpoint:       "http://localhost:9000",
+		MaxAttempts:      2,
+		Timeout:          time.Second,
+	}, AWSMetrics{})
+	require.NoError(t, err)
+
+	db := clients.DynamoDB.Options()
+	assert.Equal(t, "us-east-2", db.Region)
+	assert.Equal(t, "http://localhost:8000", *db.BaseEndpoint)
+	assert.Equal(t, 2, db.Retryer.MaxAttempts())
+
+	s3 := clients.S3.Options()
+	assert.Equal(t, "http://localhost:9000", *s3.BaseEndpoint)
+	assert.True(t, s3.UsePathStyle, "MinIO needs path-style addressing")
+
+	creds, err := db.Credentials.Retrieve(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "test_access_key", creds.AccessKeyID)
+}
+
+func TestNewAWSClients_NoCredentials(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "")
+
+	// Without static credentials the SDK's default chain is used, which
+	// isn't consulted until the first call.
+	clients, err := NewAWSClients(context.Background(), AWSOptions{Region: DefaultAWSRegion}, AWSMetrics{})
+	require.NoError(t, err)
+	assert.NotNil(t, clients.DynamoDB)
+	assert.NotNil(t, clients.S3)
+	assert.Nil(t, clients.DynamoDB.Options().BaseEndpoint)
+}
+
+func TestAWSMetrics(t *testing.T) {
+	t.Setenv("AWS_ACCESS_KEY_ID", "test_access_key")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "test_secret_key")
+
+	metrics := AWSMetrics{
+		Latency: prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: "test_aws_latency"}, []string{"service", "operation"}),
+		Errors:  prometheus.NewCounterVec(prometheus.CounterOpts{Name: "test_aws_errors"}, []string{"service", "operation", "code"}),
+	}
+	// Nothing listens on port 1, so the call fails without reaching AWS.
+	clients, err := NewAWSClients(context.Background(), AWSOptions{
+		Region:           DefaultAWSRegion,
+		DynamoDBEndpoint: "http://127.0.0.1:1",
+		MaxAttempts:      1,
+		Timeout:          time.Second,
+	}, metrics)
+	require.NoError(t, err)
+
+	_, err = clients.DynamoDB.ListTables(context.Background(), &dynamodb.ListTablesInput{})
+	require.Error(t, err)
+
+	m := &dto.Metric{}
+	require.NoError(t, metrics.Latency.WithLabelValues("DynamoDB", "ListTables").(prometheus.Metric).Write(m))
+	assert.Equal(t, uint64(1), m.GetHistogram().GetSampleCount())
+
+	m = &dto.Metric{}
+	require.NoError(t, metrics.Errors.WithLabelValues("DynamoDB", "ListTables", "transport").Write(m))
+	assert.Equal(t, 1.0, m.GetCounter().GetValue())
+}
@@ -2,48 +2,69 @@ package models
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"html"
 	"html/template"
 	"os"
-	"sort"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/config"
-	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/smithy-go"
 	log "github.com/sirupsen/logrus"
 )
 
 // ContactForm : structure used to grab user data from /contact POST requests
+//
+// The dynamodbav names are the ones ContactResponse has always stored, Email
+// being the Contact table's key; without them the daemon's SDK would fall
+// back to the json names.
 type ContactForm struct {
-	Name       string `json:"name" form:"name" binding:"required"`
-	Email      string `json:"email" form:"email" binding:"required"`
-	Website    string `json:"website" form:"website"`
-	Message    string `json:"message" form:"message" binding:"required"`
-	RobotCheck int    `json:"robot" form:"robot"`
-	RobotNum   int    `json:"number" form:"number"`
+	Name       string `json:"name" form:"name" binding:"required" dynamodbav:"Name"`
+	Email      string `json:"email" form:"email" binding:"required" dynamodbav:"Email"`
+	Website    string `json:"website" form:"website" dynamodbav:"Website"`
+	Message    string `json:"message" form:"message" binding:"required" dynamodbav:"Message"`
+	RobotCheck int    `json:"robot" form:"robot" dynamodbav:"RobotCheck"`
+	RobotNum   int    `json:"number" form:"number" dynamodbav:"RobotNum"`
 }
 
 // Item : structure used to get data from DynamoDB requests
 type Item struct {
-	ArticlePicture string `dynamodbav:"article-picture"`
-	Author         string `dynamodbav:"author"`
-	Categories     string `dynamodbav:"categories"`
-	CreatedDate    string `dynamodbav:"created-date"`
-	Excerpt        string `dynamodbav:"excerpt"`
-	HTMLHold       string `dynamodbav:"html-hold"`
-	ModifiedDate   string `dynamodbav:"modified-date"`
-	PanelPicture   string `dynamodbav:"panel-picture"`
-	PostID         int    `dynamodbav:"post-id"`
-	PostTitle      string `dynamodbav:"post-title"`
-	ShortTitle     string `dynamodbav:"short-title"`
-	PostType       string `dynamodbav:"post-type"`
+	ArticlePicture string `json:"article-picture" dynamodbav:"article-picture"`
+	Author         string `json:"author" dynamodbav:"author"`
+	Categories     string `json:"categories" dynamodbav:"categories"`
+	CreatedDate    string `json:"created-date" dynamodbav:"created-date"`
+	Excerpt        string `json:"excerpt" dynamodbav:"excerpt"`
+	HTMLHold       string `json:"html-hold" dynamodbav:"html-hold"`
+	Markdown       string `json:"markdown,omitempty" dynamodbav:"markdown,omitempty"`
+	ModifiedDate   string `json:"modified-date" dynamodbav:"modified-date"`
+	PanelPicture   string `json:"panel-picture" dynamodbav:"panel-picture"`
+	PostID         int    `json:"post-id" dynamodbav:"post-id"`
+	PostTitle      string `json:"post-title" dynamodbav:"post-title"`
+	ShortTitle     string `json:"short-title" dynamodbav:"short-title"`
+	PostType       string `json:"post-type" dynamodbav:"post-type"`
+	Slug           string `json:"slug,omitempty" dynamodbav:"slug,omitempty"`
+	Status         string `json:"status,omitempty" dynamodbav:"status,omitempty"`
+	PublishAt      string `json:"publish-at,omitempty" dynamodbav:"publish-at,omitempty"`
+	// Version is bumped by every write through the admin editor or the
+	// daemon. The editor's writes are conditional on it (see ArticleEditor),
+	// so two people saving the same article can't silently overwrite each
+	// other.
+	Version int `json:"version,omitempty" dynamodbav:"version,omitempty"`
+	// CategorySet mirrors Categories as a DynamoDB string set. It is derived
+	// (see WithIndexAttributes), never read from a manifest.
+	CategorySet []string `json:"-" dynamodbav:"category-set,stringset,omitempty"`
+	// SlugKey is ResolvedSlug, stored so ArticlesSlugIndex can find an
+	// article by a slug derived from its title. It is derived too.
+	SlugKey string `json:"-" dynamodbav:"slug-key,omitempty"`
 }
 
 // Article : structure used to make DynamoDB data functional
@@ -60,159 +81,205 @@ type Article struct {
 	PostTitle      string        `json:"post-title"`
 	ShortTitle     string        `json:"short-title"`
 	PostType       string        `json:"post-type"`
+	Slug           string        `json:"slug"`
+	Status         string        `json:"status"`
+	PublishAt      string        `json:"publish-at"`
+	// Source is the body as it was written, Markdown or HTML as SourceFormat
+	// says; HTMLHold is what it renders to. Only full articles carry it.
+	Source       string `json:"source,omitempty"`
+	SourceFormat string `json:"source-format,omitempty"`
+	// History is the article's revisions, oldest first. The stores leave it
+	// empty; LoadHistory fills it for the admin tools that need it.
+	History History `json:"history,omitempty"`
 }
 
+// Source formats of Article.Source.
+const (
+	SourceFormatHTML     = "html"
+	SourceFormatMarkdown = "markdown"
+)
+
 // Category : structure used to access data in HTML Templates
 type Category struct {
 	Category string `json:"category"`
 }
 
-// createDynamoDBClient creates a DynamoDB client with proper configuration
-func createDynamoDBClient(ctx context.Context) (*dynamodb.Client, error) {
-	aid := os.Getenv("AWS_ACCESS_KEY_ID")
-	key := os.Getenv("AWS_SECRET_ACCESS_KEY")
+// ArticleStore is the persistence boundary for blog articles. Handlers receive
+// one through dependency injection (see LoadServerRoutes in app.go) so the same
+// pages can be served from DynamoDB in prod, from a directory of files in
+// local development, or from memory in tests.
+type ArticleStore interface {
+	// GetArticlePanels returns every Listed article panel for the front page,
+	// newest (latest CreatedDate, then highest PostID) first.
+	GetArticlePanels() []Article
+	// GetAllArticlePanels returns every article's panel whatever its status,
+	// drafts and scheduled posts included, in the same order. GetArticlePanels
+	// is these filtered down to the ones Listed now.
+	GetAllArticlePanels() []Article
+	// GetCategoryPageArticlePanels returns the Listed panels whose categories
+	// contain category, in the reverse order: oldest first.
+	GetCategoryPageArticlePanels(category string) []Article
+	// GetArticleByID returns the full article with the given PostID, whatever
+	// its status, or an error if it doesn't exist.
+	GetArticleByID(id int) (*Article, error)
+	// GetArticleBySlug returns the full article whose ResolvedSlug is slug,
+	// whatever its status, or an error if there is none.
+	GetArticleBySlug(slug string) (*Article, error)
+}
 
-	var cfg aws.Config
-	var err error
+// DynamoArticleStore : ArticleStore backed by the DynamoDB table named by the
+// ARTICLES environment variable (Test-Articles when unset).
+type DynamoArticleStore struct {
+	db *dynamodb.Client
+}
 
-	if aid != "" && key != "" {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion("us-west-1"),
-			config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(aid, key, "")),
-		)
-	} else {
-		cfg, err = config.LoadDefaultConfig(ctx,
-			config.WithRegion("us-west-1"),
-		)
-	}
+// NewDynamoArticleStore returns the production ArticleStore, reading through
+// the shared client db (see AWSClients).
+func NewDynamoArticleStore(db *dynamodb.Client) *DynamoArticleStore {
+	return &DynamoArticleStore{db: db}
+}
 
-	if err != nil {
-		return nil, err
+// tableName resolves the articles table at call time, not construction time,
+// so a process (or test) can repoint ARTICLES without rebuilding the store.
+func (s *DynamoArticleStore) tableName() string {
+	tableName := os.Getenv("ARTICLES")
+	if tableName == "" {
+		tableName = "Test-Articles"
 	}
+	return tableName
+}
 
-	return dynamodb.NewFromConfig(cfg), nil
+// ArticlesSlugIndex is the articles table's GSI on slug-key. It projects
+// only the key, so a lookup is a Query for the post-id and then a GetItem.
+const ArticlesSlugIndex = "slug-key-index"
+
+// CategoryIndexTable names the category index that goes with an articles
+// table: ARTICLE_CATEGORIES when set, otherwise the articles table name with
+// a -Categories suffix. The index holds one item per (category, post-id)
+// pair, so a category page is a Query rather than a Scan of every article.
+func CategoryIndexTable(articlesTable string) string {
+	if table := os.Getenv("ARTICLE_CATEGORIES"); table != "" {
+		return table
+	}
+	return articlesTable + "-Categories"
 }
 
 // GetArticlePanels Return a list of all the article panels for the Front Page
-func GetArticlePanels() []Article {
-	ctx := context.TODO()
-
-	dbSvc, err := createDynamoDBClient(ctx)
-	if err != nil {
-		log.Error("Unable to create DynamoDB client:", err)
-		return []Article{}
-	}
+func (s *DynamoArticleStore) GetArticlePanels() []Article {
+	return listedPanels(s.GetAllArticlePanels(), time.Now())
+}
 
+// GetAllArticlePanels scans every article's panel, newest first.
+func (s *DynamoArticleStore) GetAllArticlePanels() []Article {
 	filt := expression.Name("post-id").GreaterThanEqual(expression.Value(0))
 
-	proj := expression.NamesList(expression.Name("post-title"), expression.Name("post-id"), expression.Name("post-type"),
-		expression.Name("author"), expression.Name("categories"), expression.Name("excerpt"),
-		expression.Name("modified-date"), expression.Name("panel-picture"))
+	articles := s.scanPanels(filt)
+	slices.SortFunc(articles, compareNewestFirst)
 
-	expr, _ := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
+	return articles
+}
 
-	tableName := os.Getenv("ARTICLES")
-	if tableName == "" {
-		tableName = "Test-Articles"
-	}
+// GetCategoryPageArticlePanels Return a list of all the article panels for the Category Pages
+func (s *DynamoArticleStore) GetCategoryPageArticlePanels(category string) []Article {
+	unescapedCategory := html.UnescapeString(category)
 
-	params := &dynamodb.ScanInput{
+	articles := listedPanels(s.categoryPanels(unescapedCategory), time.Now())
+	slices.SortFunc(articles, func(a, b Article) int { return compareNewestFirst(b, a) })
+
+	return articles
+}
+
+// categoryPanels looks the category up in the category index and fetches
+// just those articles' panels. Until the index table has been created (see
+// the daemon's migrate command) it falls back to scanning the articles table.
+func (s *DynamoArticleStore) categoryPanels(category string) []Article {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	dbSvc := s.db
+
+	keyCond := expression.Key("category").Equal(expression.Value(category))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	entries, err := queryAll(ctx, dbSvc, &dynamodb.QueryInput{
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
-		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String(tableName),
+		KeyConditionExpression:    expr.KeyCondition(),
+		TableName:                 aws.String(CategoryIndexTable(s.tableName())),
+	})
+	var missing *types.ResourceNotFoundException
+	if errors.As(err, &missing) {
+		log.Warn("Category index table is missing, scanning articles instead:", err)
+		return s.scanPanels(expression.Name("categories").Contains(category))
 	}
-
-	// Make the DynamoDB Query API call
-	result, err := dbSvc.Scan(ctx, params)
 	if err != nil {
-		log.Error("Failed to scan DynamoDB:", err)
+		log.Error("Failed to query category index:", err)
 		return []Article{}
 	}
 
-	articles := []Article{}
-
-	for _, i := range result.Items {
-		item := Item{}
-		article := Article{}
-
-		err := attributevalue.UnmarshalMap(i, &item)
-
-		if err != nil {
-			log.Error("Got error unmarshalling:")
-			log.Error(err.Error())
-			return []Article{}
-		}
-
-		categories := []Category{}
-		for _, category := range strings.Split(item.Categories, ",") {
-			categories = append(categories, Category{category})
-		}
-
-		article.Author = template.HTML(item.Author)
-		article.Categories = categories
-		article.Excerpt = template.HTML(item.Excerpt)
-		article.ModifiedDate = item.ModifiedDate
-		article.PanelPicture = template.HTML(item.PanelPicture)
-		article.PostID = item.PostID
-		article.PostTitle = item.PostTitle
-		article.PostType = item.PostType
-		articles = append(articles, article)
+	keys := []map[string]types.AttributeValue{}
+	for _, entry := range entries {
+		keys = append(keys, map[string]types.AttributeValue{"post-id": entry["post-id"]})
 	}
 
-	sort.Slice(articles[:], func(i, j int) bool {
-		return articles[i].PostID > articles[j].PostID
+	proj, _ := expression.NewBuilder().WithProjection(panelProjection()).Build()
+	items, err := batchGetAll(ctx, dbSvc, s.tableName(), types.KeysAndAttributes{
+		Keys:                     keys,
+		ExpressionAttributeNames: proj.Names(),
+		ProjectionExpression:     proj.Projection(),
 	})
-
-	return articles
-}
-
-// GetCategoryPageArticlePanels Return a list of all the article panels for the Category Pages
-func GetCategoryPageArticlePanels(category string) []Article {
-	ctx := context.TODO()
-
-	dbSvc, err := createDynamoDBClient(ctx)
 	if err != nil {
-		log.Error("Unable to create DynamoDB client:", err)
+		log.Error("Failed to get category panels:", err)
 		return []Article{}
 	}
+	return unmarshalPanels(items)
+}
 
-	unescapedCategory := html.UnescapeString(category)
-
-	filt := expression.Name("categories").Contains(unescapedCategory)
-
-	proj := expression.NamesList(expression.Name("post-title"), expression.Name("post-id"), expression.Name("post-type"),
+// panelProjection is the attributes Panel needs.
+func panelProjection() expression.ProjectionBuilder {
+	return expression.NamesList(expression.Name("post-title"), expression.Name("post-id"), expression.Name("post-type"),
 		expression.Name("author"), expression.Name("categories"), expression.Name("excerpt"),
-		expression.Name("modified-date"), expression.Name("panel-picture"))
+		expression.Name("created-date"), expression.Name("modified-date"), expression.Name("panel-picture"),
+		expression.Name("short-title"), expression.Name("slug"),
+		expression.Name("status"), expression.Name("publish-at"))
+}
 
-	expr, _ := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
+// scanPanels runs a filtered Scan, every page of it, projected down to the
+// panel attributes and converts the results. Errors are logged and yield an
+// empty (never nil) slice so listing pages degrade to "no posts" rather than
+// a 500.
+func (s *DynamoArticleStore) scanPanels(filt expression.ConditionBuilder) []Article {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
 
-	tableName := os.Getenv("ARTICLES")
-	if tableName == "" {
-		tableName = "Test-Articles"
-	}
+	dbSvc := s.db
+
+	expr, _ := expression.NewBuilder().WithFilter(filt).WithProjection(panelProjection()).Build()
 
 	params := &dynamodb.ScanInput{
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		FilterExpression:          expr.Filter(),
 		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String(tableName),
+		TableName:                 aws.String(s.tableName()),
 	}
 
-	// Make the DynamoDB Query API call
-	result, err := dbSvc.Scan(ctx, params)
+	// Make the DynamoDB Scan API calls
+	items, err := scanAll(ctx, dbSvc, params)
 	if err != nil {
 		log.Error("Failed to scan DynamoDB:", err)
 		return []Article{}
 	}
 
+	return unmarshalPanels(items)
+}
+
+// unmarshalPanels converts projected article items into panels.
+func unmarshalPanels(items []map[string]types.AttributeValue) []Article {
 	articles := []Article{}
 
-	for _, i := range result.Items {
+	for _, i := range items {
 		item := Item{}
-		article := Article{}
 
 		err := attributevalue.UnmarshalMap(i, &item)
 
@@ -222,45 +289,21 @@ func GetCategoryPageArticlePanels(category string) []Article {
 			return []Article{}
 		}
 
-		categories := []Category{}
-		for _, category := range strings.Split(item.Categories, ",") {
-			categories = append(categories, Category{category})
-		}
-
-		article.Author = template.HTML(item.Author)
-		article.Categories = categories
-		article.Excerpt = template.HTML(item.Excerpt)
-		article.ModifiedDate = item.ModifiedDate
-		article.PanelPicture = template.HTML(item.PanelPicture)
-		article.PostID = item.PostID
-		article.PostTitle = item.PostTitle
-		article.PostType = item.PostType
-		articles = append(articles, article)
+		articles = append(articles, item.Panel())
 	}
 
-	sort.Slice(articles[:], func(i, j int) bool {
-		return articles[i].PostID < articles[j].PostID
-	})
-
 	return articles
 }
 
 // GetArticleByID gets an article from DDB by id number
-func GetArticleByID(id int) (*Article, error) {
-	ctx := context.TODO()
+func (s *DynamoArticleStore) GetArticleByID(id int) (*Article, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
 
-	dbSvc, err := createDynamoDBClient(ctx)
-	if err != nil {
-		log.Error("Unable to create DynamoDB client:", err)
-		return nil, err
-	}
+	dbSvc := s.db
 
-	table := os.Getenv("ARTICLES")
-	if table == "" {
-		table = "Test-Articles"
-	}
 	result, err := dbSvc.GetItem(ctx, &dynamodb.GetItemInput{
-		TableName: aws.String(table),
+		TableName: aws.String(s.tableName()),
 		Key: map[string]types.AttributeValue{
 			"post-id": &types.AttributeValueMemberN{
 				Value: strconv.Itoa(id),
@@ -278,7 +321,6 @@ func GetArticleByID(id int) (*Article, error) {
 	}
 
 	item := Item{}
-	article := Article{}
 
 	err = attributevalue.UnmarshalMap(result.Item, &item)
 
@@ -287,21 +329,243 @@ func GetArticleByID(id int) (*Article, error) {
 		return nil, fmt.Errorf("failed to unmarshal record: %v", err)
 	}
 
+	article := item.Article()
+	return &article, nil
+}
+
+// GetArticleBySlug gets an article from DDB by slug: it queries
+// ArticlesSlugIndex for the post-id and then fetches it by id. Until the
+// daemon's migrate has added the index it scans the panels, drafts
+// included, for the matching one instead; any other error is returned.
+func (s *DynamoArticleStore) GetArticleBySlug(slug string) (*Article, error) {
+	if slug == "" {
+		return nil, fmt.Errorf("article with slug %q not found", slug)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	keyCond := expression.Key("slug-key").Equal(expression.Value(slug))
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+
+	items, err := queryAll(ctx, s.db, &dynamodb.QueryInput{
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		KeyConditionExpression:    expr.KeyCondition(),
+		IndexName:                 aws.String(ArticlesSlugIndex),
+		TableName:                 aws.String(s.tableName()),
+	})
+	if isMissingIndex(err) {
+		log.Warn("Slug index is missing, scanning articles instead:", err)
+		for _, panel := range s.scanPanels(expression.Name("post-id").GreaterThanEqual(expression.Value(0))) {
+			if panel.Slug == slug {
+				return s.GetArticleByID(panel.PostID)
+			}
+		}
+		return nil, fmt.Errorf("article with slug %q not found", slug)
+	}
+	if err != nil {
+		log.Error("Failed to query slug index:", err)
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, fmt.Errorf("article with slug %q not found", slug)
+	}
+
+	key := struct {
+		PostID int `dynamodbav:"post-id"`
+	}{}
+	if err := attributevalue.UnmarshalMap(items[0], &key); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal slug index entry: %v", err)
+	}
+	return s.GetArticleByID(key.PostID)
+}
+
+// isMissingIndex reports whether err is DynamoDB refusing a Query because
+// the table has no index by the name it was given. That's a
+// ValidationException like any malformed request, so it's told apart by its
+// message.
+func isMissingIndex(err error) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == "ValidationException" &&
+		strings.Contains(apiErr.ErrorMessage(), "does not have the specified index")
+}
+
+// Panel converts an Item into the subset of Article fields the listing pages
+// render — the same attributes the DynamoDB scan projects.
+func (item Item) Panel() Article {
+	return Article{
+		Author:       template.HTML(item.Author),
+		Categories:   item.categoryList(),
+		CreatedDate:  item.CreatedDate,
+		Excerpt:      template.HTML(item.Excerpt),
+		ModifiedDate: item.ModifiedDate,
+		PanelPicture: template.HTML(item.PanelPicture),
+		PostID:       item.PostID,
+		PostTitle:    item.PostTitle,
+		PostType:     item.PostType,
+		Slug:         item.ResolvedSlug(),
+		Status:       item.Status,
+		PublishAt:    item.PublishAt,
+	}
+}
+
+// Article converts an Item into the full Article the article page renders.
+// Markdown articles are rendered to sanitized HTML here, author included;
+// hand-written HTML articles pass through as-is.
+func (item Item) Article() Article {
+	body, format, author := item.HTMLHold, SourceFormatHTML, item.Author
+	if item.Markdown != "" {
+		format = SourceFormatMarkdown
+		author = markdownPolicy.Sanitize(author)
+		rendered, err := RenderMarkdown(item.Markdown)
+		if err != nil {
+			log.WithError(err).WithField("post_id", item.PostID).Error("Failed to render Markdown article")
+			rendered = "<pre>" + html.EscapeString(item.Markdown) + "</pre>"
+		}
+		body = rendered
+	}
+
+	article := Article{
+		ArticlePicture: template.HTML(item.ArticlePicture),
+		Author:         template.HTML(author),
+		Categories:     item.categoryList(),
+		CreatedDate:    item.CreatedDate,
+		Excerpt:        template.HTML(item.Excerpt),
+		HTMLHold:       template.HTML(body),
+		ModifiedDate:   item.ModifiedDate,
+		PostID:         item.PostID,
+		PostTitle:      item.PostTitle,
+		ShortTitle:     item.ShortTitle,
+		PostType:       item.PostType,
+		Slug:           item.ResolvedSlug(),
+		Status:         item.Status,
+		PublishAt:      item.PublishAt,
+		Source:         item.HTMLHold,
+		SourceFormat:   format,
+	}
+	if format == SourceFormatMarkdown {
+		article.Source = item.Markdown
+	}
+	return article
+}
+
+// categoryList splits the comma-separated Categories attribute.
+func (item Item) categoryList() []Category {
 	categories := []Category{}
 	for _, category := range strings.Split(item.Categories, ",") {
 		categories = append(categories, Category{category})
 	}
+	return categories
+}
+
+// CategoryNames is the distinct, non-empty categories of the item in the
+// order they're listed: the members of its category set.
+func (item Item) CategoryNames() []string {
+	names := []string{}
+	seen := map[string]bool{}
+	for _, category := range strings.Split(item.Categories, ",") {
+		if category != "" && !seen[category] {
+			seen[category] = true
+			names = append(names, category)
+		}
+	}
+	return names
+}
 
-	article.ArticlePicture = template.HTML(item.ArticlePicture)
-	article.Author = template.HTML(item.Author)
-	article.Categories = categories
-	article.CreatedDate = item.CreatedDate
-	article.HTMLHold = template.HTML(item.HTMLHold)
-	article.ModifiedDate = item.ModifiedDate
-	article.PostID = item.PostID
-	article.PostTitle = item.PostTitle
-	article.ShortTitle = item.ShortTitle
-	article.PostType = item.PostType
+// WithIndexAttributes returns the item with its derived attributes
+// (CategorySet and SlugKey) filled in from the authored ones. Call it before
+// every write.
+func (item Item) WithIndexAttributes() Item {
+	item.CategorySet = item.CategoryNames()
+	if len(item.CategorySet) == 0 {
+		item.CategorySet = nil
+	}
+	item.SlugKey = item.ResolvedSlug()
+	return item
+}
 
-	return &article, nil
+// Validate checks the fields the blog needs to render a post.
+func (item Item) Validate() error {
+	if item.PostID < 0 {
+		return fmt.Errorf("post-id must not be negative, got %d", item.PostID)
+	}
+	if item.PostTitle == "" {
+		return errors.New("post-title is required")
+	}
+	switch item.PostType {
+	case "standard":
+		if item.HTMLHold == "" && item.Markdown == "" {
+			return errors.New("standard posts need a body, HTML or Markdown")
+		}
+		if slug := item.ResolvedSlug(); !ValidSlug(slug) {
+			return fmt.Errorf("slug %q must be lower-case letters and digits separated by single hyphens", slug)
+		}
+	case "quote":
+		if item.Excerpt == "" {
+			return errors.New("quote posts need an excerpt")
+		}
+	default:
+		return fmt.Errorf("post-type must be standard or quote, got %q", item.PostType)
+	}
+	if !ValidStatus(item.Status) {
+		return fmt.Errorf("status must be draft, scheduled, published or archived, got %q", item.Status)
+	}
+	if item.Status == StatusScheduled && item.PublishAt == "" {
+		return errors.New("scheduled posts need a publish-at")
+	}
+	for name, date := range map[string]string{"created-date": item.CreatedDate, "modified-date": item.ModifiedDate} {
+		if _, err := ParseDate(date); date != "" && err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	if item.PublishAt != "" {
+		if _, err := ParsePublishAt(item.PublishAt); err != nil {
+			return fmt.Errorf("publish-at must be an RFC 3339 timestamp like 2026-11-01T09:00:00-07:00, got %q", item.PublishAt)
+		}
+	}
+	return nil
+}
+
+// ResolvedSlug is the article's URL slug (/posts/:slug): the explicit Slug
+// when set, otherwise one derived from ShortTitle, falling back to
+// PostTitle. Set Slug explicitly to keep a URL stable across retitles.
+func (item Item) ResolvedSlug() string {
+	if item.Slug != "" {
+		return item.Slug
+	}
+	if item.ShortTitle != "" {
+		return Slugify(item.ShortTitle)
+	}
+	return Slugify(item.PostTitle)
+}
+
+// ValidSlug reports whether slug is lower-case letters and digits in
+// hyphen-separated runs, the form Slugify produces.
+func ValidSlug(slug string) bool {
+	return validSlug.MatchString(slug)
+}
+
+var validSlug = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// Slugify turns a title into a URL slug: "Go & React" becomes "go-react".
+// Apostrophes are dropped rather than split on, so "AWS's" becomes "awss".
+func Slugify(title string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(title) {
+		switch {
+		case r == '\'' || r == '’':
+			continue
+		case (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9'):
+			if hyphen && b.Len() > 0 {
+				b.WriteByte('-')
+			}
+			hyphen = false
+			b.WriteRune(r)
+		default:
+			hyphen = true
+		}
+	}
+	return b.String()
 }
@@ -0,0 +1,231 @@
+package models
+
+import (
+	"fmt"
+	"html/template"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Dates on articles (CreatedDate, ModifiedDate) and listings (DateListed,
+// LastModified) are stored as RFC 3339 timestamps in UTC, written by
+// FormatDate, so they sort as strings and parse as times. Older items hold
+// hand-written dates ("November 26th, 2020") or epoch milliseconds
+// ("1589161257428"); ParseDate reads those too, and the daemon's migrate
+// command rewrites them.
+
+var ordinalSuffix = regexp.MustCompile(`(\d+)(st|nd|rd|th)\b`)
+
+// dateLayouts are the layouts ParseDate tries after RFC 3339, most specific
+// first. Values without a zone are taken as UTC.
+var dateLayouts = []string{
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+	"January 2, 2006",
+	"January 2 2006",
+	"Jan 2, 2006",
+	"Jan 2 2006",
+	"2 January 2006",
+	"1/2/2006",
+}
+
+// ParseDate reads a stored date: an RFC 3339 timestamp, a hand-written date
+// like "April 10th, 2018" or "2018-04-10", or epoch seconds or milliseconds.
+func ParseDate(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty date")
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t, nil
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); err == nil {
+		// Milliseconds since 1973 have 12 or more digits; seconds won't
+		// until the year 5138.
+		if len(strings.TrimLeft(s, "-")) >= 12 {
+			return time.UnixMilli(n).UTC(), nil
+		}
+		return time.Unix(n, 0).UTC(), nil
+	}
+	written := ordinalSuffix.ReplaceAllString(s, "$1")
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, written); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unrecognised date %q; use RFC 3339, e.g. 2026-11-01T09:00:00Z", s)
+}
+
+// FormatDate writes t the way dates are stored: RFC 3339 in UTC.
+func FormatDate(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// NormalizeDate rewrites a stored date in the stored format. Empty stays
+// empty.
+func NormalizeDate(s string) (string, error) {
+	if strings.TrimSpace(s) == "" {
+		return "", nil
+	}
+	t, err := ParseDate(s)
+	if err != nil {
+		return s, err
+	}
+	return FormatDate(t), nil
+}
+
+// parsedOrZero is ParseDate for sorting: a date that doesn't parse is the
+// zero time, which sorts as the oldest.
+func parsedOrZero(s string) time.Time {
+	t, err := ParseDate(s)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
+
+// Created is when the article was first posted, or the zero time if its
+// CreatedDate doesn't parse.
+func (article Article) Created() time.Time {
+	return parsedOrZero(article.CreatedDate)
+}
+
+// Modified is when the article last changed, or the zero time if its
+// ModifiedDate doesn't parse.
+func (article Article) Modified() time.Time {
+	return parsedOrZero(article.ModifiedDate)
+}
+
+// compareNewestFirst orders articles by CreatedDate, newest first, breaking
+// ties (and undated legacy posts) by PostID, highest first, so the order
+// (and so every page token) is stable. Use it with slices.SortFunc.
+func compareNewestFirst(a, b Article) int {
+	if c := b.Created().Compare(a.Created()); c != 0 {
+		return c
+	}
+	return b.PostID - a.PostID
+}
+
+// WithNormalizedDates returns item with CreatedDate and ModifiedDate in the
+// stored format (see NormalizeDate), or an error naming the first one that
+// doesn't parse.
+func (item Item) WithNormalizedDates() (Item, error) {
+	var err error
+	if item.CreatedDate, err = NormalizeDate(item.CreatedDate); err != nil {
+		return item, fmt.Errorf("created-date: %v", err)
+	}
+	if item.ModifiedDate, err = NormalizeDate(item.ModifiedDate); err != nil {
+		return item, fmt.Errorf("modified-date: %v", err)
+	}
+	return item, nil
+}
+
+// Listed is when the listing was first listed, or the zero time if its
+// DateListed doesn't parse.
+func (listing Listing) Listed() time.Time {
+	return parsedOrZero(listing.DateListed)
+}
+
+// Modified is when the listing last changed, or the zero time if its
+// LastModified doesn't parse.
+func (listing Listing) Modified() time.Time {
+	return parsedOrZero(listing.LastModified)
+}
+
+// WithNormalizedDates returns listing with DateListed and LastModified in
+// the stored format, or an error naming the first one that doesn't parse.
+func (listing Listing) WithNormalizedDates() (Listing, error) {
+	var err error
+	if listing.DateListed, err = NormalizeDate(listing.DateListed); err != nil {
+		return listing, fmt.Errorf("Date Listed: %v", err)
+	}
+	if listing.LastModified, err = NormalizeDate(listing.LastModified); err != nil {
+		return listing, fmt.Errorf("Last Modified: %v", err)
+	}
+	return listing, nil
+}
+
+// HumanDate writes t the way dates were written by hand: "August 10th,
+// 2019".
+func HumanDate(t time.Time) string {
+	day := t.Day()
+	suffix := "th"
+	switch {
+	case day%100 >= 11 && day%100 <= 13:
+	case day%10 == 1:
+		suffix = "st"
+	case day%10 == 2:
+		suffix = "nd"
+	case day%10 == 3:
+		suffix = "rd"
+	}
+	return fmt.Sprintf("%s %d%s, %d", t.Month(), day, suffix, t.Year())
+}
+
+// RelativeTime describes t relative to now: "just now", "5 minutes ago",
+// "3 days ago", "in 2 hours".
+func RelativeTime(t, now time.Time) string {
+	elapsed := now.Sub(t)
+	format := "%d %s ago"
+	if elapsed < 0 {
+		elapsed, format = -elapsed, "in %d %s"
+	}
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"year", 365 * 24 * time.Hour},
+		{"month", 30 * 24 * time.Hour},
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+	}
+	for _, unit := range units {
+		if n := int(elapsed / unit.size); n >= 1 {
+			name := unit.name
+			if n > 1 {
+				name += "s"
+			}
+			return fmt.Sprintf(format, n, name)
+		}
+	}
+	return "just now"
+}
+
+// TemplateFuncs are the helpers every HTML template gets, each taking a
+// stored date string:
+//
+//	{{humanDate .ModifiedDate}}     August 10th, 2019
+//	{{relativeTime .ModifiedDate}}  3 days ago (as of rendering; keep it off cached pages)
+//	{{isoDate .ModifiedDate}}       2019-08-10T00:00:00Z, for <time datetime> and structured data
+//
+// A date that doesn't parse is shown as stored by humanDate and
+// relativeTime, and left out ("") by isoDate.
+func TemplateFuncs() template.FuncMap {
+	return template.FuncMap{
+		"humanDate": func(s string) string {
+			t, err := ParseDate(s)
+			if err != nil {
+				return s
+			}
+			return HumanDate(t)
+		},
+		"relativeTime": func(s string) string {
+			t, err := ParseDate(s)
+			if err != nil {
+				return s
+			}
+			return RelativeTime(t, time.Now())
+		},
+		"isoDate": func(s string) string {
+			t, err := ParseDate(s)
+			if err != nil {
+				return ""
+			}
+			return FormatDate(t)
+		},
+	}
+}
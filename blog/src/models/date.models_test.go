@@ -0,0 +1,159 @@
+package models
+
+import (
+	"bytes"
+	"html/template"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseDate(t *testing.T) {
+	testCases := map[string]time.Time{
+		"April 10th, 2018":          time.Date(2018, time.April, 10, 0, 0, 0, 0, time.UTC),
+		"May 1st, 2020":             time.Date(2020, time.May, 1, 0, 0, 0, 0, time.UTC),
+		"May 2nd, 2021":             time.Date(2021, time.May, 2, 0, 0, 0, 0, time.UTC),
+		"May 3rd, 2022":             time.Date(2022, time.May, 3, 0, 0, 0, 0, time.UTC),
+		" March 20th, 2022 ":        time.Date(2022, time.March, 20, 0, 0, 0, 0, time.UTC),
+		"Nov 26, 2020":              time.Date(2020, time.November, 26, 0, 0, 0, 0, time.UTC),
+		"2020-11-26":                time.Date(2020, time.November, 26, 0, 0, 0, 0, time.UTC),
+		"2020-11-26T09:30:00Z":      time.Date(2020, time.November, 26, 9, 30, 0, 0, time.UTC),
+		"2020-11-26T01:30:00-08:00": time.Date(2020, time.November, 26, 9, 30, 0, 0, time.UTC),
+		"1589161257428":             time.UnixMilli(1589161257428),
+		"1589161257":                time.Unix(1589161257, 0),
+	}
+	for in, want := range testCases {
+		got, err := ParseDate(in)
+		require.NoError(t, err, in)
+		assert.True(t, want.Equal(got), "%q: want %v, got %v", in, want, got)
+	}
+
+	for _, in := range []string{"", "someday", "2020-13-01"} {
+		_, err := ParseDate(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestNormalizeDate(t *testing.T) {
+	got, err := NormalizeDate("November 26th, 2020")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-11-26T00:00:00Z", got)
+
+	got, err = NormalizeDate("1589161257428")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-05-11T01:40:57Z", got)
+
+	got, err = NormalizeDate("2020-11-26T01:30:00-08:00")
+	require.NoError(t, err)
+	assert.Equal(t, "2020-11-26T09:30:00Z", got, "Stored dates are UTC")
+
+	got, err = NormalizeDate("")
+	require.NoError(t, err)
+	assert.Empty(t, got)
+
+	got, err = NormalizeDate("someday")
+	assert.Error(t, err)
+	assert.Equal(t, "someday", got)
+}
+
+func TestWithNormalizedDates(t *testing.T) {
+	item, err := Item{CreatedDate: "April 10th, 2018", ModifiedDate: "2019-08-10"}.WithNormalizedDates()
+	require.NoError(t, err)
+	assert.Equal(t, "2018-04-10T00:00:00Z", item.CreatedDate)
+	assert.Equal(t, "2019-08-10T00:00:00Z", item.ModifiedDate)
+
+	_, err = Item{CreatedDate: "April 10th, 2018", ModifiedDate: "later"}.WithNormalizedDates()
+	assert.ErrorContains(t, err, "modified-date")
+
+	listing, err := Listing{DateListed: "1589161257428"}.WithNormalizedDates()
+	require.NoError(t, err)
+	assert.Equal(t, "2020-05-11T01:40:57Z", listing.DateListed)
+	assert.Empty(t, listing.LastModified)
+
+	_, err = Listing{LastModified: "soon"}.WithNormalizedDates()
+	assert.ErrorContains(t, err, "Last Modified")
+}
+
+func TestCompareNewestFirst(t *testing.T) {
+	articles := []Article{
+		{PostID: 9, CreatedDate: "2018-01-01T00:00:00Z"},
+		{PostID: 1, CreatedDate: "2021-01-01T00:00:00Z"},
+		{PostID: 3},
+		{PostID: 4, CreatedDate: "March 2nd, 2020"},
+		{PostID: 5, CreatedDate: "2020-03-02T00:00:00Z"},
+	}
+	slices.SortFunc(articles, compareNewestFirst)
+
+	ids := []int{}
+	for _, article := range articles {
+		ids = append(ids, article.PostID)
+	}
+	assert.Equal(t, []int{1, 5, 4, 9, 3}, ids, "By date, not PostID; ties and undated posts by PostID")
+}
+
+func TestHumanDate(t *testing.T) {
+	testCases := map[string]string{
+		"2019-08-10": "August 10th, 2019",
+		"2020-11-01": "November 1st, 2020",
+		"2020-05-02": "May 2nd, 2020",
+		"2021-03-23": "March 23rd, 2021",
+		"2021-03-11": "March 11th, 2021",
+		"2021-03-12": "March 12th, 2021",
+		"2021-03-13": "March 13th, 2021",
+		"2021-03-31": "March 31st, 2021",
+	}
+	for day, want := range testCases {
+		at, err := time.Parse("2006-01-02", day)
+		require.NoError(t, err)
+		assert.Equal(t, want, HumanDate(at), day)
+	}
+}
+
+func TestRelativeTime(t *testing.T) {
+	now := time.Date(2026, time.November, 1, 12, 0, 0, 0, time.UTC)
+	testCases := map[time.Duration]string{
+		10 * time.Second:     "just now",
+		time.Minute:          "1 minute ago",
+		5 * time.Minute:      "5 minutes ago",
+		3 * time.Hour:        "3 hours ago",
+		3 * 24 * time.Hour:   "3 days ago",
+		45 * 24 * time.Hour:  "1 month ago",
+		800 * 24 * time.Hour: "2 years ago",
+		-2 * time.Hour:       "in 2 hours",
+	}
+	for ago, want := range testCases {
+		assert.Equal(t, want, RelativeTime(now.Add(-ago), now), ago.String())
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	tmpl := template.Must(template.New("dates").Funcs(TemplateFuncs()).Parse(
+		`<time datetime="{{isoDate .}}">{{humanDate .}}</time>`))
+
+	render := func(date string) string {
+		var out bytes.Buffer
+		require.NoError(t, tmpl.Execute(&out, date))
+		return out.String()
+	}
+	assert.Equal(t, `<time datetime="2019-08-10T00:00:00Z">August 10th, 2019</time>`, render("2019-08-10T00:00:00Z"))
+	assert.Equal(t, `<time datetime="2019-08-10T00:00:00Z">August 10th, 2019</time>`, render("August 10th, 2019"), "Legacy dates render the same")
+	assert.Equal(t, `<time datetime="">someday</time>`, render("someday"), "Unparseable dates are shown as stored")
+}
+
+func TestListingsSortByParsedDate(t *testing.T) {
+	listings := []Listing{
+		{MLS: "a", LastModified: "999999999999"},
+		{MLS: "b", LastModified: "1589161257428"},
+		{MLS: "c", LastModified: "2021-01-01T00:00:00Z"},
+	}
+	sortListings(listings)
+
+	mls := []string{}
+	for _, listing := range listings {
+		mls = append(mls, listing.MLS)
+	}
+	assert.Equal(t, []string{"c", "b", "a"}, mls, "Newest first by time, not by string")
+}
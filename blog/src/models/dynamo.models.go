@@ -0,0 +1,86 @@
+package models
+
+import (
+	"context"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// dynamoReadTimeout bounds a whole paginated read, every page included, so
+// a slow or throttled table can't hold a request open indefinitely.
+const dynamoReadTimeout = 10 * time.Second
+
+// scanAll runs a Scan to completion, following LastEvaluatedKey from page to
+// page (each page stops at 1 MB) until the table is exhausted. It returns
+// every matching item, or an error if any page fails or ctx expires — never
+// a silently truncated result.
+func scanAll(ctx context.Context, client dynamodb.ScanAPIClient, params *dynamodb.ScanInput) ([]map[string]types.AttributeValue, error) {
+	items := []map[string]types.AttributeValue{}
+	paginator := dynamodb.NewScanPaginator(client, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+// queryAll is scanAll for a Query.
+func queryAll(ctx context.Context, client dynamodb.QueryAPIClient, params *dynamodb.QueryInput) ([]map[string]types.AttributeValue, error) {
+	items := []map[string]types.AttributeValue{}
+	paginator := dynamodb.NewQueryPaginator(client, params)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, page.Items...)
+	}
+	return items, nil
+}
+
+// batchGetMaxKeys is the most keys one BatchGetItem call accepts.
+const batchGetMaxKeys = 100
+
+// batchGetAPIClient is the slice of the DynamoDB client batchGetAll uses.
+type batchGetAPIClient interface {
+	BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error)
+}
+
+// batchGetAll fetches every key in request.Keys from table, batchGetMaxKeys
+// at a time, re-requesting whatever DynamoDB leaves in UnprocessedKeys until
+// nothing is left or ctx expires. The other fields of request (projection,
+// consistency) apply to every batch. Items come back in no particular order.
+func batchGetAll(ctx context.Context, client batchGetAPIClient, table string, request types.KeysAndAttributes) ([]map[string]types.AttributeValue, error) {
+	items := []map[string]types.AttributeValue{}
+	keys := request.Keys
+	for len(keys) > 0 {
+		n := min(batchGetMaxKeys, len(keys))
+		batch := request
+		batch.Keys = keys[:n]
+		keys = keys[n:]
+
+		pending := map[string]types.KeysAndAttributes{table: batch}
+		for backoff := 50 * time.Millisecond; len(pending) > 0; backoff *= 2 {
+			out, err := client.BatchGetItem(ctx, &dynamodb.BatchGetItemInput{RequestItems: pending})
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, out.Responses[table]...)
+			pending = out.UnprocessedKeys
+			if len(pending) == 0 {
+				break
+			}
+			select {
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			case <-time.After(backoff):
+			}
+		}
+	}
+	return items, nil
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagedTable serves Scan and Query calls a page of pageSize items at a
+// time, the way DynamoDB cuts results off at 1 MB, recording the
+// ExclusiveStartKey of every call.
+type pagedTable struct {
+	items    []map[string]types.AttributeValue
+	pageSize int
+	failAt   int // fail the call with this index (1-based); 0 never fails
+	starts   []string
+}
+
+func newPagedTable(n, pageSize int) *pagedTable {
+	table := &pagedTable{pageSize: pageSize}
+	for i := 0; i < n; i++ {
+		table.items = append(table.items, map[string]types.AttributeValue{
+			"post-id": &types.AttributeValueMemberN{Value: strconv.Itoa(i)},
+		})
+	}
+	return table
+}
+
+func (p *pagedTable) page(ctx context.Context, start map[string]types.AttributeValue) ([]map[string]types.AttributeValue, map[string]types.AttributeValue, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, nil, err
+	}
+	from := 0
+	if start != nil {
+		from, _ = strconv.Atoi(start["post-id"].(*types.AttributeValueMemberN).Value)
+		from++
+		p.starts = append(p.starts, strconv.Itoa(from-1))
+	} else {
+		p.starts = append(p.starts, "")
+	}
+	if p.failAt == len(p.starts) {
+		return nil, nil, errors.New("ProvisionedThroughputExceededException")
+	}
+
+	to := min(from+p.pageSize, len(p.items))
+	var last map[string]types.AttributeValue
+	if to < len(p.items) {
+		last = p.items[to-1]
+	}
+	return p.items[from:to], last, nil
+}
+
+func (p *pagedTable) Scan(ctx context.Context, params *dynamodb.ScanInput, optFns ...func(*dynamodb.Options)) (*dynamodb.ScanOutput, error) {
+	items, last, err := p.page(ctx, params.ExclusiveStartKey)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.ScanOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+func (p *pagedTable) Query(ctx context.Context, params *dynamodb.QueryInput, optFns ...func(*dynamodb.Options)) (*dynamodb.QueryOutput, error) {
+	items, last, err := p.page(ctx, params.ExclusiveStartKey)
+	if err != nil {
+		return nil, err
+	}
+	return &dynamodb.QueryOutput{Items: items, LastEvaluatedKey: last}, nil
+}
+
+func TestScanAll_FollowsLastEvaluatedKey(t *testing.T) {
+	table := newPagedTable(7, 3)
+
+	items, err := scanAll(context.Background(), table, &dynamodb.ScanInput{})
+	require.NoError(t, err)
+	assert.Len(t, items, 7, "Every page should be read, not just the first")
+	assert.Equal(t, []string{"", "2", "5"}, table.starts)
+}
+
+func TestQueryAll_FollowsLastEvaluatedKey(t *testing.T) {
+	table := newPagedTable(4, 2)
+
+	items, err := queryAll(context.Background(), table, &dynamodb.QueryInput{})
+	require.NoError(t, err)
+	assert.Len(t, items, 4)
+	assert.Equal(t, []string{"", "1"}, table.starts)
+}
+
+func TestScanAll_FailingPage(t *testing.T) {
+	table := newPagedTable(7, 3)
+	table.failAt = 2
+
+	items, err := scanAll(context.Background(), table, &dynamodb.ScanInput{})
+	assert.Error(t, err, "A failed page must be an error, not a short result")
+	assert.Nil(t, items)
+}
+
+func TestScanAll_Deadline(t *testing.T) {
+	table := newPagedTable(7, 3)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+
+	_, err := scanAll(ctx, table, &dynamodb.ScanInput{})
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+// batchTable serves BatchGetItem, leaving the last key of the first call
+// unprocessed the way DynamoDB does under throttling.
+type batchTable struct {
+	calls     int
+	batchSize []int
+}
+
+func (b *batchTable) BatchGetItem(ctx context.Context, params *dynamodb.BatchGetItemInput, optFns ...func(*dynamodb.Options)) (*dynamodb.BatchGetItemOutput, error) {
+	b.calls++
+	request := params.RequestItems["Articles"]
+	b.batchSize = append(b.batchSize, len(request.Keys))
+
+	out := &dynamodb.BatchGetItemOutput{Responses: map[string][]map[string]types.AttributeValue{}}
+	keys := request.Keys
+	if b.calls == 1 {
+		unprocessed := request
+		unprocessed.Keys = keys[len(keys)-1:]
+		out.UnprocessedKeys = map[string]types.KeysAndAttributes{"Articles": unprocessed}
+		keys = keys[:len(keys)-1]
+	}
+	out.Responses["Articles"] = keys
+	return out, nil
+}
+
+func TestBatchGetAll(t *testing.T) {
+	keys := []map[string]types.AttributeValue{}
+	for i := 0; i < 150; i++ {
+		keys = append(keys, map[string]types.AttributeValue{
+			"post-id": &types.AttributeValueMemberN{Value: strconv.Itoa(i)},
+		})
+	}
+	table := &batchTable{}
+
+	items, err := batchGetAll(context.Background(), table, "Articles", types.KeysAndAttributes{Keys: keys})
+	require.NoError(t, err)
+	assert.Len(t, items, 150, "Unprocessed keys should be retried")
+	assert.Equal(t, []int{100, 1, 50}, table.batchSize)
+
+	items, err = batchGetAll(context.Background(), table, "Articles", types.KeysAndAttributes{})
+	require.NoError(t, err)
+	assert.Empty(t, items)
+}
@@ -0,0 +1,461 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	log "github.com/sirupsen/logrus"
+)
+
+// ArticleEditor is the write side of an ArticleStore, used by the admin
+// editor. Every write is conditional on the post-id, so a create never
+// replaces an existing article, and on the Version the caller loaded, so an
+// update or delete fails with ErrArticleConflict if someone else saved the
+// article in between rather than silently discarding their change.
+//
+// Every write also records an immutable Revision, in the same transaction,
+// attributed to the Change passed in; History reads them back.
+//
+// The DynamoDB and memory stores implement it; the file store is read-only.
+type ArticleEditor interface {
+	// ListItems returns every item, drafts and quotes included, newest
+	// (highest PostID) first.
+	ListItems() ([]Item, error)
+	// GetItem returns the item with the given PostID as it is stored, or
+	// ErrArticleNotFound.
+	GetItem(id int) (*Item, error)
+	// CreateItem stores item one version past the last revision of its
+	// PostID (Version 1 for a new one) and returns what was stored. It
+	// fails with ErrArticleExists if the PostID is taken.
+	CreateItem(item Item, change Change) (Item, error)
+	// UpdateItem replaces the stored item with item if the stored Version
+	// is still item.Version, and returns what was stored, one version on.
+	UpdateItem(item Item, change Change) (Item, error)
+	// DeleteItem removes the item with the given PostID if its stored
+	// Version is still version, leaving a tombstone revision one version on.
+	DeleteItem(id, version int, change Change) error
+	// History returns the revisions of the given PostID, oldest first, or
+	// an empty History if it has none.
+	History(id int) (History, error)
+}
+
+// Errors returned by ArticleEditor writes.
+var (
+	ErrArticleNotFound = errors.New("article not found")
+	ErrArticleExists   = errors.New("an article with that post-id already exists")
+	ErrArticleConflict = errors.New("article was changed since it was loaded; reload it and try again")
+)
+
+// ListItems scans the whole articles table.
+func (s *DynamoArticleStore) ListItems() ([]Item, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	results, err := scanAll(ctx, s.db, &dynamodb.ScanInput{TableName: aws.String(s.tableName())})
+	if err != nil {
+		return nil, err
+	}
+	items := []Item{}
+	if err := attributevalue.UnmarshalListOfMaps(results, &items); err != nil {
+		return nil, err
+	}
+	sortItems(items)
+	return items, nil
+}
+
+// GetItem gets the stored item from DDB by id number.
+func (s *DynamoArticleStore) GetItem(id int) (*Item, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	result, err := s.db.GetItem(ctx, &dynamodb.GetItemInput{
+		TableName:      aws.String(s.tableName()),
+		Key:            postIDKey(id),
+		ConsistentRead: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	if result.Item == nil {
+		return nil, ErrArticleNotFound
+	}
+	item := Item{}
+	if err := attributevalue.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal record: %v", err)
+	}
+	return &item, nil
+}
+
+// CreateItem puts item on the condition that no item has its post-id.
+func (s *DynamoArticleStore) CreateItem(item Item, change Change) (Item, error) {
+	latest, err := s.latestVersion(item.PostID)
+	if err != nil {
+		return Item{}, err
+	}
+	item.Version = latest + 1
+	op, err := s.putOp(item, expression.AttributeNotExists(expression.Name("post-id")))
+	if err != nil {
+		return Item{}, err
+	}
+	err = s.writeWithRevision(op, NewRevision(item, change, time.Now()))
+	switch {
+	case transactionConditionFailed(err, 0):
+		return Item{}, ErrArticleExists
+	case transactionConditionFailed(err, 1):
+		// Someone else created and recorded this version first.
+		return Item{}, ErrArticleConflict
+	case err != nil:
+		return Item{}, err
+	}
+	s.syncCategoryIndex(nil, item)
+	return item, nil
+}
+
+// UpdateItem puts item on the condition that the stored version is the one
+// item was loaded at.
+func (s *DynamoArticleStore) UpdateItem(item Item, change Change) (Item, error) {
+	live, err := s.GetItem(item.PostID)
+	if err != nil {
+		return Item{}, err
+	}
+	if live.Version != item.Version {
+		return Item{}, ErrArticleConflict
+	}
+	expected := item.Version
+	item.Version++
+	op, err := s.putOp(item, versionCondition(expected))
+	if err != nil {
+		return Item{}, err
+	}
+	err = s.writeWithRevision(op, NewRevision(item, change, time.Now()))
+	if transactionConditionFailed(err, 0) || transactionConditionFailed(err, 1) {
+		return Item{}, s.conditionError(item.PostID)
+	}
+	if err != nil {
+		return Item{}, err
+	}
+	s.syncCategoryIndex(live, item)
+	return item, nil
+}
+
+// DeleteItem deletes the item on the condition that the stored version is
+// version, and drops its category index entries.
+func (s *DynamoArticleStore) DeleteItem(id, version int, change Change) error {
+	live, err := s.GetItem(id)
+	if err != nil {
+		return err
+	}
+	if live.Version != version {
+		return ErrArticleConflict
+	}
+	expr, err := expression.NewBuilder().WithCondition(versionCondition(version)).Build()
+	if err != nil {
+		return err
+	}
+	op := types.TransactWriteItem{Delete: &types.Delete{
+		TableName:                 aws.String(s.tableName()),
+		Key:                       postIDKey(id),
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}
+	err = s.writeWithRevision(op, NewTombstone(id, version+1, change, time.Now()))
+	if transactionConditionFailed(err, 0) || transactionConditionFailed(err, 1) {
+		return s.conditionError(id)
+	}
+	if err != nil {
+		return err
+	}
+	s.syncCategoryIndex(live, Item{PostID: id})
+	return nil
+}
+
+// History queries the revision table for id's revisions.
+func (s *DynamoArticleStore) History(id int) (History, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	keyCond := expression.Key("post-id").Equal(expression.Value(id))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return nil, err
+	}
+	results, err := queryAll(ctx, s.db, &dynamodb.QueryInput{
+		TableName:                 aws.String(RevisionTable(s.tableName())),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ConsistentRead:            aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+	history := History{}
+	if err := attributevalue.UnmarshalListOfMaps(results, &history); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal revisions: %v", err)
+	}
+	return history, nil
+}
+
+// latestVersion returns the version of id's newest revision, or 0 if it has
+// none.
+func (s *DynamoArticleStore) latestVersion(id int) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	keyCond := expression.Key("post-id").Equal(expression.Value(id))
+	expr, err := expression.NewBuilder().WithKeyCondition(keyCond).Build()
+	if err != nil {
+		return 0, err
+	}
+	result, err := s.db.Query(ctx, &dynamodb.QueryInput{
+		TableName:                 aws.String(RevisionTable(s.tableName())),
+		KeyConditionExpression:    expr.KeyCondition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+		ScanIndexForward:          aws.Bool(false),
+		Limit:                     aws.Int32(1),
+		ConsistentRead:            aws.Bool(true),
+	})
+	if err != nil {
+		return 0, err
+	}
+	if len(result.Items) == 0 {
+		return 0, nil
+	}
+	latest := Revision{}
+	if err := attributevalue.UnmarshalMap(result.Items[0], &latest); err != nil {
+		return 0, fmt.Errorf("failed to unmarshal revision: %v", err)
+	}
+	return latest.Version, nil
+}
+
+// putOp is the transaction write putting item under cond.
+func (s *DynamoArticleStore) putOp(item Item, cond expression.ConditionBuilder) (types.TransactWriteItem, error) {
+	av, err := attributevalue.MarshalMap(item.WithIndexAttributes())
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return types.TransactWriteItem{}, err
+	}
+	return types.TransactWriteItem{Put: &types.Put{
+		TableName:                 aws.String(s.tableName()),
+		Item:                      av,
+		ConditionExpression:       expr.Condition(),
+		ExpressionAttributeNames:  expr.Names(),
+		ExpressionAttributeValues: expr.Values(),
+	}}, nil
+}
+
+// writeWithRevision runs op, a write to the articles table, and records rev
+// in one transaction, so the article never changes without a revision and a
+// revision is never overwritten. In the transaction's cancellation reasons
+// op is 0 and rev is 1.
+func (s *DynamoArticleStore) writeWithRevision(op types.TransactWriteItem, rev Revision) error {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	av, err := attributevalue.MarshalMap(rev)
+	if err != nil {
+		return err
+	}
+	expr, err := expression.NewBuilder().
+		WithCondition(expression.AttributeNotExists(expression.Name("post-id"))).Build()
+	if err != nil {
+		return err
+	}
+	_, err = s.db.TransactWriteItems(ctx, &dynamodb.TransactWriteItemsInput{
+		TransactItems: []types.TransactWriteItem{op, {Put: &types.Put{
+			TableName:                aws.String(RevisionTable(s.tableName())),
+			Item:                     av,
+			ConditionExpression:      expr.Condition(),
+			ExpressionAttributeNames: expr.Names(),
+		}}},
+	})
+	return err
+}
+
+// conditionError explains a failed version condition on id: the article is
+// either gone or at another version.
+func (s *DynamoArticleStore) conditionError(id int) error {
+	if _, err := s.GetItem(id); errors.Is(err, ErrArticleNotFound) {
+		return ErrArticleNotFound
+	}
+	return ErrArticleConflict
+}
+
+// syncCategoryIndex adds the (category, post-id) entries item needs to the
+// category index and removes the ones only old had. Failures are logged,
+// not returned: the article itself has been written by then, and the
+// category pages fall back to a scan while the index is missing.
+func (s *DynamoArticleStore) syncCategoryIndex(old *Item, item Item) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	index := CategoryIndexTable(s.tableName())
+	logger := log.WithFields(log.Fields{"table": index, "post_id": item.PostID})
+	names := item.CategoryNames()
+	for _, name := range names {
+		_, err := s.db.PutItem(ctx, &dynamodb.PutItemInput{
+			TableName: aws.String(index),
+			Item:      categoryEntryKey(name, item.PostID),
+		})
+		if err != nil {
+			logger.WithError(err).Warn("Failed to add category index entry")
+			return
+		}
+	}
+	if old == nil {
+		return
+	}
+	for _, name := range old.CategoryNames() {
+		if slices.Contains(names, name) {
+			continue
+		}
+		_, err := s.db.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+			TableName: aws.String(index),
+			Key:       categoryEntryKey(name, item.PostID),
+		})
+		if err != nil {
+			logger.WithError(err).Warn("Failed to remove category index entry")
+			return
+		}
+	}
+}
+
+func postIDKey(id int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"post-id": &types.AttributeValueMemberN{Value: strconv.Itoa(id)},
+	}
+}
+
+func categoryEntryKey(category string, id int) map[string]types.AttributeValue {
+	return map[string]types.AttributeValue{
+		"category": &types.AttributeValueMemberS{Value: category},
+		"post-id":  &types.AttributeValueMemberN{Value: strconv.Itoa(id)},
+	}
+}
+
+// versionCondition matches a stored item at version. Items written before
+// versions existed have no version attribute and count as version 0.
+func versionCondition(version int) expression.ConditionBuilder {
+	if version == 0 {
+		return expression.AttributeExists(expression.Name("post-id")).
+			And(expression.AttributeNotExists(expression.Name("version")))
+	}
+	return expression.Name("version").Equal(expression.Value(version))
+}
+
+// transactionConditionFailed reports whether err cancelled a transaction
+// because its i'th write failed its condition.
+func transactionConditionFailed(err error, i int) bool {
+	var canceled *types.TransactionCanceledException
+	if !errors.As(err, &canceled) || i >= len(canceled.CancellationReasons) {
+		return false
+	}
+	return aws.ToString(canceled.CancellationReasons[i].Code) == "ConditionalCheckFailed"
+}
+
+// ListItems returns every item in memory.
+func (s *MemoryArticleStore) ListItems() ([]Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	items := s.snapshot()
+	sortItems(items)
+	return items, nil
+}
+
+// GetItem gets the stored item from memory by id number.
+func (s *MemoryArticleStore) GetItem(id int) (*Item, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil, ErrArticleNotFound
+	}
+	return &item, nil
+}
+
+// CreateItem stores item unless its post-id is taken.
+func (s *MemoryArticleStore) CreateItem(item Item, change Change) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.items[item.PostID]; ok {
+		return Item{}, ErrArticleExists
+	}
+	item.Version = 1
+	if latest, ok := s.revisions[item.PostID].Latest(); ok {
+		item.Version = latest.Version + 1
+	}
+	s.items[item.PostID] = item.WithIndexAttributes()
+	s.record(NewRevision(item, change, time.Now()))
+	return item, nil
+}
+
+// UpdateItem replaces the stored item if it is still at item.Version.
+func (s *MemoryArticleStore) UpdateItem(item Item, change Change) (Item, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkVersion(item.PostID, item.Version); err != nil {
+		return Item{}, err
+	}
+	item.Version++
+	s.items[item.PostID] = item.WithIndexAttributes()
+	s.record(NewRevision(item, change, time.Now()))
+	return item, nil
+}
+
+// DeleteItem removes the stored item if it is still at version.
+func (s *MemoryArticleStore) DeleteItem(id, version int, change Change) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.checkVersion(id, version); err != nil {
+		return err
+	}
+	delete(s.items, id)
+	s.record(NewTombstone(id, version+1, change, time.Now()))
+	return nil
+}
+
+// History returns a copy of id's revisions.
+func (s *MemoryArticleStore) History(id int) (History, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append(History{}, s.revisions[id]...), nil
+}
+
+// record appends rev to its article's history; callers must hold s.mu.
+func (s *MemoryArticleStore) record(rev Revision) {
+	s.revisions[rev.PostID] = append(s.revisions[rev.PostID], rev)
+}
+
+// checkVersion is versionCondition for the memory store; callers must hold
+// s.mu.
+func (s *MemoryArticleStore) checkVersion(id, version int) error {
+	stored, ok := s.items[id]
+	if !ok {
+		return ErrArticleNotFound
+	}
+	if stored.Version != version {
+		return ErrArticleConflict
+	}
+	return nil
+}
+
+func sortItems(items []Item) {
+	sort.Slice(items, func(i, j int) bool {
+		return items[i].PostID > items[j].PostID
+	})
+}
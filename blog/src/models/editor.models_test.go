@@ -0,0 +1,100 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestArticleEditor_Implementations(t *testing.T) {
+	var _ ArticleEditor = NewDynamoArticleStore(nil)
+	var _ ArticleEditor = NewMemoryArticleStore()
+}
+
+var editorChange = Change{Author: "admin@example.com", Source: RevisionSourceEditor}
+
+func TestMemoryArticleStore_EditorWrites(t *testing.T) {
+	store := NewMemoryArticleStore(Item{PostID: 0, PostTitle: "Legacy", PostType: "standard", HTMLHold: "<p>old</p>"})
+
+	created, err := store.CreateItem(Item{PostID: 1, PostTitle: "New", PostType: "standard", Markdown: "new", Categories: "Go,AWS"}, editorChange)
+	require.NoError(t, err)
+	assert.Equal(t, 1, created.Version)
+	_, err = store.CreateItem(Item{PostID: 1, PostTitle: "Again"}, editorChange)
+	assert.ErrorIs(t, err, ErrArticleExists, "A create must not replace an existing article")
+
+	items, err := store.ListItems()
+	require.NoError(t, err)
+	require.Len(t, items, 2)
+	assert.Equal(t, 1, items[0].PostID, "Newest first")
+
+	// Two editors load version 1; the first save wins, the second conflicts.
+	first, second := created, created
+	first.PostTitle, second.PostTitle = "First", "Second"
+	saved, err := store.UpdateItem(first, editorChange)
+	require.NoError(t, err)
+	assert.Equal(t, 2, saved.Version)
+	_, err = store.UpdateItem(second, editorChange)
+	assert.ErrorIs(t, err, ErrArticleConflict)
+
+	stored, err := store.GetItem(1)
+	require.NoError(t, err)
+	assert.Equal(t, "First", stored.PostTitle)
+
+	// Items written before versions existed are at version 0.
+	legacy, err := store.GetItem(0)
+	require.NoError(t, err)
+	legacy.HTMLHold = "<p>new</p>"
+	_, err = store.UpdateItem(*legacy, editorChange)
+	require.NoError(t, err)
+
+	_, err = store.UpdateItem(Item{PostID: 9}, editorChange)
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+	assert.ErrorIs(t, store.DeleteItem(1, 1, editorChange), ErrArticleConflict)
+	require.NoError(t, store.DeleteItem(1, 2, editorChange))
+	_, err = store.GetItem(1)
+	assert.ErrorIs(t, err, ErrArticleNotFound)
+}
+
+func TestVersionCondition(t *testing.T) {
+	expr, err := expression.NewBuilder().WithCondition(versionCondition(0)).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "(attribute_exists (#0)) AND (attribute_not_exists (#1))", *expr.Condition())
+	assert.Equal(t, "version", expr.Names()["#1"])
+
+	expr, err = expression.NewBuilder().WithCondition(versionCondition(3)).Build()
+	require.NoError(t, err)
+	assert.Equal(t, "#0 = :0", *expr.Condition())
+}
+
+func TestItemValidate(t *testing.T) {
+	valid := Item{PostID: 3, PostTitle: "Title", PostType: "standard", Markdown: "body"}
+	require.NoError(t, valid.Validate())
+
+	testCases := []struct {
+		name   string
+		modify func(*Item)
+	}{
+		{"NegativeID", func(i *Item) { i.PostID = -1 }},
+		{"NoTitle", func(i *Item) { i.PostTitle = "" }},
+		{"NoBody", func(i *Item) { i.Markdown = "" }},
+		{"BadSlug", func(i *Item) { i.Slug = "Not A Slug" }},
+		{"BadType", func(i *Item) { i.PostType = "video" }},
+		{"BadStatus", func(i *Item) { i.Status = "hidden" }},
+		{"ScheduledWithoutTime", func(i *Item) { i.Status = StatusScheduled }},
+		{"BadPublishAt", func(i *Item) { i.PublishAt = "tomorrow" }},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			item := valid
+			tc.modify(&item)
+			assert.Error(t, item.Validate())
+		})
+	}
+
+	quote := Item{PostID: 4, PostTitle: "Quote", PostType: "quote"}
+	assert.Error(t, quote.Validate(), "Quotes need an excerpt")
+	quote.Excerpt = "Words."
+	assert.NoError(t, quote.Validate())
+}
@@ -0,0 +1,286 @@
+package models
+
+import (
+	"encoding/xml"
+	"fmt"
+	"html"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+)
+
+// defaultSiteURL is the public origin links in feeds point at when SITE_URL
+// is unset. DOMAIN can't be reused here: it names the host certmagic serves
+// (e.g. gcp.mitchelletzel.com), not the canonical one.
+const defaultSiteURL = "https://mitchelletzel.com"
+
+// SiteURL returns the public origin of the blog, without a trailing slash.
+func SiteURL() string {
+	site := os.Getenv("SITE_URL")
+	if site == "" {
+		site = defaultSiteURL
+	}
+	return strings.TrimRight(site, "/")
+}
+
+// FeedArticles returns the full articles a feed is built from: the same set
+// GetArticlePanels (or, with a category, GetCategoryPageArticlePanels)
+// returns, newest first, minus quotes — they have no page to link to.
+func FeedArticles(articles ArticleStore, category string) []Article {
+	var panels []Article
+	if category == "" {
+		panels = articles.GetArticlePanels()
+	} else {
+		panels = articles.GetCategoryPageArticlePanels(category)
+	}
+
+	feed := []Article{}
+	for _, panel := range panels {
+		if panel.PostType == "quote" {
+			continue
+		}
+		article, err := articles.GetArticleByID(panel.PostID)
+		if err != nil {
+			continue
+		}
+		feed = append(feed, *article)
+	}
+
+	// Category panels come back oldest first; feeds always lead with the
+	// newest post.
+	slices.SortFunc(feed, compareNewestFirst)
+	return feed
+}
+
+// RSS : an RSS 2.0 document. Full article bodies go in content:encoded and
+// author names in dc:creator, since RSS's own <author> wants an email address.
+type RSS struct {
+	XMLName   xml.Name   `xml:"rss"`
+	Version   string     `xml:"version,attr"`
+	ContentNS string     `xml:"xmlns:content,attr"`
+	AtomNS    string     `xml:"xmlns:atom,attr"`
+	DCNS      string     `xml:"xmlns:dc,attr"`
+	Channel   RSSChannel `xml:"channel"`
+}
+
+// RSSChannel : the single channel of an RSS document.
+type RSSChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          AtomLink  `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	Language      string    `xml:"language"`
+	LastBuildDate string    `xml:"lastBuildDate,omitempty"`
+	Items         []RSSItem `xml:"item"`
+}
+
+// RSSItem : one article in an RSS channel.
+type RSSItem struct {
+	Title       string   `xml:"title"`
+	Link        string   `xml:"link"`
+	GUID        RSSGUID  `xml:"guid"`
+	Creator     string   `xml:"dc:creator,omitempty"`
+	Categories  []string `xml:"category"`
+	PubDate     string   `xml:"pubDate,omitempty"`
+	Description string   `xml:"description"`
+	Content     string   `xml:"content:encoded"`
+}
+
+// RSSGUID : an item's permanent identifier.
+type RSSGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+// AtomLink : a <link> element in an Atom feed (or an RSS atom:link).
+type AtomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+// AtomFeed : an Atom (RFC 4287) document.
+type AtomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Links   []AtomLink  `xml:"link"`
+	Updated string      `xml:"updated"`
+	Author  AtomPerson  `xml:"author"`
+	Entries []AtomEntry `xml:"entry"`
+}
+
+// AtomEntry : one article in an Atom feed.
+type AtomEntry struct {
+	Title      string         `xml:"title"`
+	ID         string         `xml:"id"`
+	Link       AtomLink       `xml:"link"`
+	Published  string         `xml:"published,omitempty"`
+	Updated    string         `xml:"updated"`
+	Author     AtomPerson     `xml:"author"`
+	Categories []AtomCategory `xml:"category"`
+	Summary    AtomText       `xml:"summary"`
+	Content    AtomText       `xml:"content"`
+}
+
+// AtomPerson : an Atom author.
+type AtomPerson struct {
+	Name string `xml:"name"`
+}
+
+// AtomCategory : an Atom category.
+type AtomCategory struct {
+	Term string `xml:"term,attr"`
+}
+
+// AtomText : an Atom text construct holding escaped HTML.
+type AtomText struct {
+	Type  string `xml:"type,attr"`
+	Value string `xml:",chardata"`
+}
+
+// Feed : what an RSS or Atom document describes — a titled list of articles
+// published at Link, with the feed itself served from Self.
+type Feed struct {
+	Title       string
+	Description string
+	Link        string
+	Self        string
+	Articles    []Article
+}
+
+// RSS builds the RSS 2.0 rendering of f.
+func (f Feed) RSS() RSS {
+	items := []RSSItem{}
+	var latest time.Time
+	for _, article := range f.Articles {
+		link := articleURL(article)
+		item := RSSItem{
+			Title:       article.PostTitle,
+			Link:        link,
+			GUID:        RSSGUID{IsPermaLink: true, Value: articleGUID(article)},
+			Creator:     plainText(string(article.Author)),
+			Categories:  categoryNames(article),
+			Description: string(article.Excerpt),
+			Content:     string(article.HTMLHold),
+		}
+		if created, err := ParseDate(article.CreatedDate); err == nil {
+			item.PubDate = created.Format(time.RFC1123Z)
+		}
+		if updated := articleUpdated(article); updated.After(latest) {
+			latest = updated
+		}
+		items = append(items, item)
+	}
+
+	channel := RSSChannel{
+		Title:       f.Title,
+		Link:        f.Link,
+		Self:        AtomLink{Href: f.Self, Rel: "self", Type: "application/rss+xml"},
+		Description: f.Description,
+		Language:    "en-us",
+		Items:       items,
+	}
+	if !latest.IsZero() {
+		channel.LastBuildDate = latest.Format(time.RFC1123Z)
+	}
+
+	return RSS{
+		Version:   "2.0",
+		ContentNS: "http://purl.org/rss/1.0/modules/content/",
+		AtomNS:    "http://www.w3.org/2005/Atom",
+		DCNS:      "http://purl.org/dc/elements/1.1/",
+		Channel:   channel,
+	}
+}
+
+// Atom builds the Atom rendering of f.
+func (f Feed) Atom() AtomFeed {
+	entries := []AtomEntry{}
+	var latest time.Time
+	for _, article := range f.Articles {
+		link := articleURL(article)
+		categories := []AtomCategory{}
+		for _, name := range categoryNames(article) {
+			categories = append(categories, AtomCategory{Term: name})
+		}
+		updated := articleUpdated(article)
+		if updated.After(latest) {
+			latest = updated
+		}
+		entry := AtomEntry{
+			Title:      article.PostTitle,
+			ID:         articleGUID(article),
+			Link:       AtomLink{Href: link, Rel: "alternate", Type: "text/html"},
+			Updated:    updated.Format(time.RFC3339),
+			Author:     AtomPerson{Name: plainText(string(article.Author))},
+			Categories: categories,
+			Summary:    AtomText{Type: "html", Value: string(article.Excerpt)},
+			Content:    AtomText{Type: "html", Value: string(article.HTMLHold)},
+		}
+		if created, err := ParseDate(article.CreatedDate); err == nil {
+			entry.Published = created.Format(time.RFC3339)
+		}
+		entries = append(entries, entry)
+	}
+	// Atom requires <updated>; a feed with nothing dated in it was last
+	// updated now, as far as a reader can tell.
+	if latest.IsZero() {
+		latest = time.Now().UTC()
+	}
+
+	return AtomFeed{
+		Title: f.Title,
+		ID:    f.Self,
+		Links: []AtomLink{
+			{Href: f.Self, Rel: "self", Type: "application/atom+xml"},
+			{Href: f.Link, Rel: "alternate", Type: "text/html"},
+		},
+		Updated: latest.Format(time.RFC3339),
+		Author:  AtomPerson{Name: "Mitchell Etzel"},
+		Entries: entries,
+	}
+}
+
+// articleURL is the absolute link to an article's page.
+func articleURL(article Article) string {
+	return SiteURL() + "/posts/" + article.Slug
+}
+
+// articleGUID is an article's permanent feed identifier. It uses the numeric
+// URL, which redirects to the slug, so retitling a post doesn't make feed
+// readers see it as new.
+func articleGUID(article Article) string {
+	return fmt.Sprintf("%s/article/%d", SiteURL(), article.PostID)
+}
+
+// articleUpdated is when an article last changed: ModifiedDate, or
+// CreatedDate if that doesn't parse, or the zero time.
+func articleUpdated(article Article) time.Time {
+	for _, date := range []string{article.ModifiedDate, article.CreatedDate} {
+		if t, err := ParseDate(date); err == nil {
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+func categoryNames(article Article) []string {
+	names := []string{}
+	for _, category := range article.Categories {
+		if category.Category != "" {
+			names = append(names, category.Category)
+		}
+	}
+	return names
+}
+
+var htmlTag = regexp.MustCompile(`<[^>]*>`)
+
+// plainText strips the markup out of fields like Author, which hold a styled
+// link rather than a bare name.
+func plainText(s string) string {
+	return strings.TrimSpace(html.UnescapeString(htmlTag.ReplaceAllString(s, "")))
+}
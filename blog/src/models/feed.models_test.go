@@ -0,0 +1,109 @@
+package models
+
+import (
+	"encoding/xml"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSiteURL(t *testing.T) {
+	t.Setenv("SITE_URL", "")
+	assert.Equal(t, "https://mitchelletzel.com", SiteURL())
+
+	t.Setenv("SITE_URL", "http://localhost:8080/")
+	assert.Equal(t, "http://localhost:8080", SiteURL(), "Trailing slash should be trimmed")
+}
+
+func TestFeedArticles(t *testing.T) {
+	store := NewMemoryArticleStore(testArticleItems()...)
+
+	all := FeedArticles(store, "")
+	ids := []int{}
+	for _, article := range all {
+		ids = append(ids, article.PostID)
+	}
+	assert.Equal(t, []int{5, 4, 2, 1, 0}, ids, "Feeds should be newest first and skip quotes")
+	assert.Equal(t, "<p>graph store</p>", string(all[len(all)-1].HTMLHold), "Feed entries should carry the full body")
+	assert.Equal(t, "REST-accessible graph storage service", string(all[len(all)-1].Excerpt))
+
+	category := FeedArticles(store, "Distributed Systems")
+	ids = []int{}
+	for _, article := range category {
+		ids = append(ids, article.PostID)
+	}
+	assert.Equal(t, []int{5, 4, 2, 0}, ids, "Category feeds should be newest first too")
+
+	assert.Empty(t, FeedArticles(store, "Nope"))
+}
+
+func testFeed(t *testing.T) Feed {
+	t.Setenv("SITE_URL", "https://example.com")
+	store := NewMemoryArticleStore(testArticleItems()[0])
+	return Feed{
+		Title:       "Test Feed",
+		Description: "Testing",
+		Link:        "https://example.com/posts",
+		Self:        "https://example.com/feed.xml",
+		Articles:    FeedArticles(store, ""),
+	}
+}
+
+func TestFeedRSS(t *testing.T) {
+	rss := testFeed(t).RSS()
+
+	raw, err := xml.Marshal(rss)
+	require.NoError(t, err)
+
+	assert.Equal(t, "2.0", rss.Version)
+	assert.Equal(t, "Test Feed", rss.Channel.Title)
+	assert.Equal(t, "https://example.com/feed.xml", rss.Channel.Self.Href)
+	assert.Equal(t, "Sat, 10 Aug 2019 00:00:00 +0000", rss.Channel.LastBuildDate)
+	require.Len(t, rss.Channel.Items, 1)
+
+	item := rss.Channel.Items[0]
+	assert.Equal(t, "Scalable, Fault Tolerant, & Strongly Consistent Graph Store API", item.Title)
+	assert.Equal(t, "https://example.com/posts/fault-tolerant-graph-store-api", item.Link)
+	assert.Equal(t, "https://example.com/article/0", item.GUID.Value)
+	assert.Equal(t, "Mitchell Etzel", item.Creator, "Author markup should be stripped")
+	assert.Equal(t, []string{"Distributed Systems", "My Projects"}, item.Categories)
+	assert.Equal(t, "Tue, 10 Apr 2018 00:00:00 +0000", item.PubDate)
+	assert.Equal(t, "REST-accessible graph storage service", item.Description)
+	assert.Equal(t, "<p>graph store</p>", item.Content)
+
+	assert.Contains(t, string(raw), "<content:encoded>&lt;p&gt;graph store&lt;/p&gt;</content:encoded>", "Body HTML should be escaped inside the element")
+}
+
+func TestFeedAtom(t *testing.T) {
+	atom := testFeed(t).Atom()
+
+	raw, err := xml.Marshal(atom)
+	require.NoError(t, err)
+
+	assert.Contains(t, string(raw), `<feed xmlns="http://www.w3.org/2005/Atom">`)
+	assert.Equal(t, "https://example.com/feed.xml", atom.ID)
+	assert.Equal(t, "2019-08-10T00:00:00Z", atom.Updated)
+	require.Len(t, atom.Entries, 1)
+
+	entry := atom.Entries[0]
+	assert.Equal(t, "https://example.com/article/0", entry.ID)
+	assert.Equal(t, "https://example.com/posts/fault-tolerant-graph-store-api", entry.Link.Href)
+	assert.Equal(t, "2018-04-10T00:00:00Z", entry.Published)
+	assert.Equal(t, "2019-08-10T00:00:00Z", entry.Updated)
+	assert.Equal(t, "Mitchell Etzel", entry.Author.Name)
+	assert.Equal(t, []AtomCategory{{Term: "Distributed Systems"}, {Term: "My Projects"}}, entry.Categories)
+	assert.Equal(t, AtomText{Type: "html", Value: "<p>graph store</p>"}, entry.Content)
+	assert.Equal(t, AtomText{Type: "html", Value: "REST-accessible graph storage service"}, entry.Summary)
+}
+
+func TestFeedAtomEmpty(t *testing.T) {
+	before := time.Now().UTC().Truncate(time.Second)
+	atom := Feed{Title: "Empty", Self: "https://example.com/atom.xml"}.Atom()
+
+	updated, err := time.Parse(time.RFC3339, atom.Updated)
+	require.NoError(t, err, "An empty feed still has a valid <updated>")
+	assert.False(t, updated.Before(before), "An empty feed was updated now, not at %s", atom.Updated)
+	assert.Empty(t, atom.Entries)
+}
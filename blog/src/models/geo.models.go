@@ -0,0 +1,261 @@
+package models
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// GeoPoint is a latitude and longitude in degrees.
+type GeoPoint struct {
+	Lat float64
+	Lng float64
+}
+
+func (p GeoPoint) String() string {
+	return strconv.FormatFloat(p.Lat, 'f', -1, 64) + "," + strconv.FormatFloat(p.Lng, 'f', -1, 64)
+}
+
+// earthRadiusMiles is the mean radius of the Earth.
+const earthRadiusMiles = 3958.8
+
+// DistanceMiles is the great-circle distance between a and b.
+func DistanceMiles(a, b GeoPoint) float64 {
+	lat1, lat2 := a.Lat*math.Pi/180, b.Lat*math.Pi/180
+	dLat, dLng := lat2-lat1, (b.Lng-a.Lng)*math.Pi/180
+	h := math.Sin(dLat/2)*math.Sin(dLat/2) + math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLng/2)*math.Sin(dLng/2)
+	return 2 * earthRadiusMiles * math.Asin(math.Min(1, math.Sqrt(h)))
+}
+
+// Around is the smallest box holding every point within miles of p. Near a
+// pole, or where the box would cross the antimeridian, it spans every
+// longitude.
+func (p GeoPoint) Around(miles float64) GeoBox {
+	dLat := miles / earthRadiusMiles * 180 / math.Pi
+	box := GeoBox{South: math.Max(p.Lat-dLat, -90), West: -180, North: math.Min(p.Lat+dLat, 90), East: 180}
+	if box.South > -90 && box.North < 90 {
+		dLng := dLat / math.Cos(p.Lat*math.Pi/180)
+		if p.Lng-dLng >= -180 && p.Lng+dLng <= 180 {
+			box.West, box.East = p.Lng-dLng, p.Lng+dLng
+		}
+	}
+	return box
+}
+
+// GeoBox is the area between two parallels and two meridians, edges
+// included. It never crosses the antimeridian: West is at most East.
+type GeoBox struct {
+	South, West, North, East float64
+}
+
+// Contains reports whether p is in the box.
+func (b GeoBox) Contains(p GeoPoint) bool {
+	return p.Lat >= b.South && p.Lat <= b.North && p.Lng >= b.West && p.Lng <= b.East
+}
+
+// intersect is the area in both boxes, which is empty (South above North or
+// West past East) if they don't overlap.
+func (b GeoBox) intersect(o GeoBox) GeoBox {
+	return GeoBox{
+		South: math.Max(b.South, o.South), West: math.Max(b.West, o.West),
+		North: math.Min(b.North, o.North), East: math.Min(b.East, o.East),
+	}
+}
+
+func (b GeoBox) empty() bool {
+	return b.South > b.North || b.West > b.East
+}
+
+// Geohash cells. Listings are indexed by the GeoCellPrecision-character
+// prefix of their geohash, a cell about 24 by 12 miles at mid latitudes, so
+// a search for a few miles around a point is a Query of a few cells.
+const (
+	GeoCellPrecision = 4
+	geohashPrecision = 9
+	// MaxGeoCells is the most cells an area search queries one by one;
+	// a larger area is cheaper to Scan.
+	MaxGeoCells = 32
+)
+
+const geohashAlphabet = "0123456789bcdefghjkmnpqrstuvwxyz"
+
+// Geohash encodes p as a geohash of precision characters. Points sharing a
+// prefix are in the same cell.
+func Geohash(p GeoPoint, precision int) string {
+	lat, lng := [2]float64{-90, 90}, [2]float64{-180, 180}
+	hash := make([]byte, 0, precision)
+	bits, ch := 0, 0
+	for even := true; len(hash) < precision; even = !even {
+		r, v := &lat, p.Lat
+		if even {
+			r, v = &lng, p.Lng
+		}
+		ch <<= 1
+		if mid := (r[0] + r[1]) / 2; v >= mid {
+			ch |= 1
+			r[0] = mid
+		} else {
+			r[1] = mid
+		}
+		if bits++; bits == 5 {
+			hash = append(hash, geohashAlphabet[ch])
+			bits, ch = 0, 0
+		}
+	}
+	return string(hash)
+}
+
+// Cells are the geohash cells of precision characters that cover the box,
+// or nil if there are more than MaxGeoCells of them. An empty box has none.
+func (b GeoBox) Cells(precision int) []string {
+	if b.empty() {
+		return []string{}
+	}
+	// Longitude takes the odd bits of a geohash and latitude the even ones.
+	lngBits, latBits := (5*precision+1)/2, 5*precision/2
+	dLat, dLng := 180/math.Exp2(float64(latBits)), 360/math.Exp2(float64(lngBits))
+	row := func(v, lo, d float64, bits int) int {
+		return min(int((v-lo)/d), 1<<bits-1)
+	}
+	south, north := row(b.South, -90, dLat, latBits), row(b.North, -90, dLat, latBits)
+	west, east := row(b.West, -180, dLng, lngBits), row(b.East, -180, dLng, lngBits)
+	if (north-south+1)*(east-west+1) > MaxGeoCells {
+		return nil
+	}
+
+	cells := make([]string, 0, (north-south+1)*(east-west+1))
+	for i := south; i <= north; i++ {
+		for j := west; j <= east; j++ {
+			center := GeoPoint{Lat: -90 + (float64(i)+0.5)*dLat, Lng: -180 + (float64(j)+0.5)*dLng}
+			cells = append(cells, Geohash(center, precision))
+		}
+	}
+	return cells
+}
+
+// Location is where the listing is, if it has been geocoded. A listing at
+// 0,0 is taken to have no location; there are no homes there.
+func (listing Listing) Location() (GeoPoint, bool) {
+	p := GeoPoint{Lat: listing.Latitude, Lng: listing.Longitude}
+	return p, p != GeoPoint{}
+}
+
+// Geocoder finds where a listing is from its address.
+type Geocoder interface {
+	Geocode(ctx context.Context, listing Listing) (GeoPoint, error)
+}
+
+// ErrNotGeocoded is returned by a Geocoder that can't place an address.
+var ErrNotGeocoded = errors.New("address not found")
+
+// WithLocation returns the listing with Latitude and Longitude set by
+// geocoder, or cleared if it can't place the listing, so coordinates always
+// follow the address. With a nil geocoder the listing is returned as is.
+func (listing Listing) WithLocation(ctx context.Context, geocoder Geocoder) (Listing, error) {
+	if geocoder == nil {
+		return listing, nil
+	}
+	p, err := geocoder.Geocode(ctx, listing)
+	if err != nil {
+		listing.Latitude, listing.Longitude = 0, 0
+		return listing, err
+	}
+	listing.Latitude, listing.Longitude = p.Lat, p.Lng
+	return listing, nil
+}
+
+// ZIPCentroids is a Geocoder that places a listing at the center of its ZIP
+// code, looked up in a table loaded by LoadZIPCentroids. It needs no network
+// and is good to a few miles, which is what a radius search asks of it.
+type ZIPCentroids map[string]GeoPoint
+
+// SampleZIPCentroidsPath is the Central Oregon sample ZIP table the blog
+// ships for its tests and the daemon's seed. It covers a handful of ZIP
+// codes, so it is never a default.
+const SampleZIPCentroidsPath = "data/zip-centroids-sample.tsv"
+
+// ZIPCentroidsPath is the ZIP table the blog geocodes listings with, from
+// ZIP_CENTROIDS, or "" when there is none.
+func ZIPCentroidsPath() string {
+	return os.Getenv("ZIP_CENTROIDS")
+}
+
+// OpenZIPCentroids loads the ZIP table at path.
+func OpenZIPCentroids(path string) (ZIPCentroids, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	zips, err := LoadZIPCentroids(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return zips, nil
+}
+
+// LoadZIPCentroids reads a ZIP table in the tab-separated format of the
+// Census Bureau's ZCTA gazetteer file: a header row naming GEOID, INTPTLAT
+// and INTPTLONG columns among others, then a row per ZIP code. Lines
+// starting with # are comments.
+func LoadZIPCentroids(r io.Reader) (ZIPCentroids, error) {
+	scanner := bufio.NewScanner(r)
+	zips := ZIPCentroids{}
+	columns := map[string]int{}
+	for line := 0; scanner.Scan(); {
+		text := scanner.Text()
+		if strings.TrimSpace(text) == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		line++
+		fields := strings.Split(text, "\t")
+		for i := range fields {
+			fields[i] = strings.TrimSpace(fields[i])
+		}
+		if line == 1 {
+			for i, name := range fields {
+				columns[name] = i
+			}
+			for _, name := range []string{"GEOID", "INTPTLAT", "INTPTLONG"} {
+				if _, ok := columns[name]; !ok {
+					return nil, fmt.Errorf("header has no %s column", name)
+				}
+			}
+			continue
+		}
+		if len(fields) < len(columns) {
+			return nil, fmt.Errorf("row %d has %d columns, want %d", line, len(fields), len(columns))
+		}
+		lat, latErr := strconv.ParseFloat(fields[columns["INTPTLAT"]], 64)
+		lng, lngErr := strconv.ParseFloat(fields[columns["INTPTLONG"]], 64)
+		if latErr != nil || lngErr != nil {
+			return nil, fmt.Errorf("row %d: coordinates %q, %q are not numbers", line, fields[columns["INTPTLAT"]], fields[columns["INTPTLONG"]])
+		}
+		zips[fields[columns["GEOID"]]] = GeoPoint{Lat: lat, Lng: lng}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(columns) == 0 {
+		return nil, errors.New("no header row")
+	}
+	return zips, nil
+}
+
+// Geocode places the listing at the center of its ZIP code. A ZIP+4 is
+// looked up by its first five digits.
+func (zips ZIPCentroids) Geocode(_ context.Context, listing Listing) (GeoPoint, error) {
+	zip := digits(listing.ZipCode)
+	if len(zip) >= 5 {
+		if p, ok := zips[zip[:5]]; ok {
+			return p, nil
+		}
+	}
+	return GeoPoint{}, fmt.Errorf("ZIP code %q: %w", listing.ZipCode, ErrNotGeocoded)
+}
@@ -0,0 +1,124 @@
+package models
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGeohash(t *testing.T) {
+	assert.Equal(t, "u4pruydqqvj", Geohash(GeoPoint{Lat: 57.64911, Lng: 10.40744}, 11))
+	assert.Equal(t, "9rcd", Geohash(GeoPoint{Lat: 44.0582, Lng: -121.3153}, GeoCellPrecision))
+	assert.Equal(t, "s0000", Geohash(GeoPoint{}, 5))
+}
+
+func TestDistanceMiles(t *testing.T) {
+	bend, redmond := GeoPoint{Lat: 44.0582, Lng: -121.3153}, GeoPoint{Lat: 44.2726, Lng: -121.1739}
+	assert.InDelta(t, 16.4, DistanceMiles(bend, redmond), 0.2)
+	assert.Zero(t, DistanceMiles(bend, bend))
+	assert.InDelta(t, 69.1, DistanceMiles(GeoPoint{Lat: 0, Lng: 0}, GeoPoint{Lat: 1, Lng: 0}), 0.1, "A degree of latitude")
+}
+
+func TestGeoPointAround(t *testing.T) {
+	p := GeoPoint{Lat: 44.05, Lng: -121.3}
+	box := p.Around(5)
+	for _, corner := range []GeoPoint{{box.South, p.Lng}, {box.North, p.Lng}, {p.Lat, box.West}, {p.Lat, box.East}} {
+		assert.InDelta(t, 5, DistanceMiles(p, corner), 0.01)
+	}
+	assert.Equal(t, GeoBox{South: 89.8, West: -180, North: 90, East: 180}, GeoPoint{Lat: 89.99}.Around(10).round(), "Near a pole every longitude is close")
+	assert.Equal(t, -180.0, GeoPoint{Lat: 0, Lng: 179.99}.Around(10).West, "as it is across the antimeridian")
+}
+
+// round is the box to a tenth of a degree, to compare in tests.
+func (b GeoBox) round() GeoBox {
+	r := func(f float64) float64 { return float64(int(f*10)) / 10 }
+	return GeoBox{South: r(b.South), West: r(b.West), North: r(b.North), East: r(b.East)}
+}
+
+func TestGeoBoxCells(t *testing.T) {
+	bend := GeoPoint{Lat: 44.0582, Lng: -121.3153}
+	cells := bend.Around(5).Cells(GeoCellPrecision)
+	assert.Contains(t, cells, Geohash(bend, GeoCellPrecision))
+	assert.LessOrEqual(t, len(cells), 4, "A few miles is a few cells")
+	for _, p := range []GeoPoint{{44.0582 + 0.07, -121.3153}, {44.0582, -121.3153 - 0.1}, {44.0582 - 0.07, -121.3153 + 0.1}} {
+		assert.Contains(t, cells, Geohash(p, GeoCellPrecision), "Every point in the box is in a cell: %v", p)
+	}
+
+	assert.Equal(t, []string{"9rcd"}, GeoBox{South: 44.05, West: -121.32, North: 44.06, East: -121.31}.Cells(GeoCellPrecision))
+	assert.Nil(t, bend.Around(100).Cells(GeoCellPrecision), "Too many cells to query one by one")
+	assert.Equal(t, []string{}, GeoBox{South: 1, North: 0}.Cells(GeoCellPrecision), "An empty box has no cells")
+
+	q := ListingQuery{Near: &bend, Miles: 5, Box: &GeoBox{South: 50, West: 0, North: 51, East: 1}}
+	assert.Equal(t, []string{}, q.GeoCells(), "A radius outside the box finds nothing")
+}
+
+const zipTable = `# A sample in the Census ZCTA gazetteer format.
+GEOID	ALAND	AWATER	ALAND_SQMI	AWATER_SQMI	INTPTLAT	INTPTLONG                                                                                                               
+97701	1063447025	4436487	410.600	1.713	44.101	-121.205                    
+97702	474081154	1212853	183.044	0.468	43.981	-121.247
+`
+
+func TestZIPCentroids(t *testing.T) {
+	zips, err := LoadZIPCentroids(strings.NewReader(zipTable))
+	require.NoError(t, err)
+	assert.Len(t, zips, 2)
+
+	p, err := zips.Geocode(context.Background(), Listing{ZipCode: "97701-1234"})
+	require.NoError(t, err)
+	assert.Equal(t, GeoPoint{Lat: 44.101, Lng: -121.205}, p, "ZIP+4 is placed by its ZIP code")
+
+	_, err = zips.Geocode(context.Background(), Listing{ZipCode: "10001"})
+	assert.ErrorIs(t, err, ErrNotGeocoded)
+	_, err = zips.Geocode(context.Background(), Listing{})
+	assert.ErrorIs(t, err, ErrNotGeocoded)
+
+	for name, table := range map[string]string{
+		"no header":   "",
+		"no INTPTLAT": "GEOID\tINTPTLONG\n97701\t-121.2\n",
+		"short row":   "GEOID\tINTPTLAT\tINTPTLONG\n97701\t44.1\n",
+		"not numbers": "GEOID\tINTPTLAT\tINTPTLONG\n97701\tnorth\twest\n",
+	} {
+		_, err := LoadZIPCentroids(strings.NewReader(table))
+		assert.Error(t, err, name)
+	}
+}
+
+func TestListingWithLocation(t *testing.T) {
+	zips := ZIPCentroids{"97701": {Lat: 44.101, Lng: -121.205}}
+	listing, err := Listing{ZipCode: "97701", Latitude: 1, Longitude: 2}.WithLocation(context.Background(), zips)
+	require.NoError(t, err)
+	p, ok := listing.Location()
+	assert.True(t, ok)
+	assert.Equal(t, GeoPoint{Lat: 44.101, Lng: -121.205}, p, "Coordinates follow the address")
+
+	listing.ZipCode = "10001"
+	listing, err = listing.WithLocation(context.Background(), zips)
+	assert.ErrorIs(t, err, ErrNotGeocoded)
+	_, ok = listing.Location()
+	assert.False(t, ok, "A listing that can't be placed loses its old coordinates")
+
+	listing = Listing{Latitude: 1, Longitude: 2}
+	kept, err := listing.WithLocation(context.Background(), nil)
+	require.NoError(t, err)
+	assert.Equal(t, listing, kept, "Without a geocoder the listing is left as is")
+}
+
+func TestSampleZIPCentroids(t *testing.T) {
+	zips, err := OpenZIPCentroids("../../" + SampleZIPCentroidsPath)
+	require.NoError(t, err)
+	for _, zip := range []string{"97701", "97702"} {
+		_, err := zips.Geocode(context.Background(), Listing{ZipCode: zip})
+		assert.NoError(t, err, "The realtor test listings' ZIP %s is in the table", zip)
+	}
+
+	_, err = OpenZIPCentroids("no-such-table.tsv")
+	assert.Error(t, err)
+
+	t.Setenv("ZIP_CENTROIDS", "")
+	assert.Empty(t, ZIPCentroidsPath(), "The sample is never the default table")
+	t.Setenv("ZIP_CENTROIDS", "/data/2023_Gaz_zcta_national.txt")
+	assert.Equal(t, "/data/2023_Gaz_zcta_national.txt", ZIPCentroidsPath())
+}
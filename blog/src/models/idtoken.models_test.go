@@ -0,0 +1,120 @@
+package models
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// signIDToken builds an RS256 JWT over claims, signed by key as kid.
+func signIDToken(t *testing.T, key *rsa.PrivateKey, kid string, claims map[string]any) string {
+	t.Helper()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": kid, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signed := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signed))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	require.NoError(t, err)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+func TestGoogleIDTokenVerifier(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	now := time.Unix(1_800_000_000, 0)
+	verifier := &GoogleIDTokenVerifier{
+		ClientID: "realtor.apps.googleusercontent.com",
+		Keys: func(context.Context) (map[string]*rsa.PublicKey, error) {
+			return map[string]*rsa.PublicKey{"k1": &key.PublicKey}, nil
+		},
+		Now: func() time.Time { return now },
+	}
+	claims := func(change func(map[string]any)) map[string]any {
+		c := map[string]any{
+			"iss":            "https://accounts.google.com",
+			"aud":            "realtor.apps.googleusercontent.com",
+			"exp":            now.Add(time.Hour).Unix(),
+			"email":          "Seller@Example.com",
+			"email_verified": true,
+		}
+		if change != nil {
+			change(c)
+		}
+		return c
+	}
+
+	email, err := verifier.Verify(context.Background(), signIDToken(t, key, "k1", claims(nil)))
+	require.NoError(t, err)
+	assert.Equal(t, "seller@example.com", email)
+
+	invalid := map[string]string{
+		"not a JWT":       "abc.def",
+		"unknown key":     signIDToken(t, key, "k2", claims(nil)),
+		"forged":          signIDToken(t, other, "k1", claims(nil)),
+		"other issuer":    signIDToken(t, key, "k1", claims(func(c map[string]any) { c["iss"] = "https://evil.example" })),
+		"other client":    signIDToken(t, key, "k1", claims(func(c map[string]any) { c["aud"] = "someone-else" })),
+		"expired":         signIDToken(t, key, "k1", claims(func(c map[string]any) { c["exp"] = now.Add(-time.Minute).Unix() })),
+		"unverified mail": signIDToken(t, key, "k1", claims(func(c map[string]any) { c["email_verified"] = false })),
+		"no email":        signIDToken(t, key, "k1", claims(func(c map[string]any) { delete(c, "email") })),
+	}
+	for name, token := range invalid {
+		_, err := verifier.Verify(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidIDToken, name)
+	}
+
+	parts := strings.Split(signIDToken(t, key, "k1", claims(nil)), ".")
+	tampered := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"email":"admin@example.com"}`)) + "." + parts[2]
+	_, err = verifier.Verify(context.Background(), tampered)
+	assert.ErrorIs(t, err, ErrInvalidIDToken, "Changing the claims breaks the signature")
+
+	verifier.Keys = func(context.Context) (map[string]*rsa.PublicKey, error) {
+		return nil, fmt.Errorf("offline")
+	}
+	_, err = verifier.Verify(context.Background(), signIDToken(t, key, "k1", claims(nil)))
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidIDToken, "Not reaching Google isn't the credential's fault")
+}
+
+func TestJWKSCache(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{
+			{"kid": "k1", "kty": "RSA", "alg": "RS256",
+				"n": base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+				"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes())},
+			{"kid": "ec", "kty": "EC"},
+		}})
+	}))
+	defer server.Close()
+
+	cache := &jwksCache{url: server.URL, client: server.Client(), ttl: time.Hour}
+	keys, err := cache.get(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, keys, "k1")
+	assert.NotContains(t, keys, "ec", "Only RSA keys are kept")
+	assert.True(t, key.PublicKey.Equal(keys["k1"]))
+
+	_, err = cache.get(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, fetches, "Keys are cached")
+}
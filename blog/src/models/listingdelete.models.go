@@ -0,0 +1,223 @@
+package models
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// Errors returned by listing writes.
+var (
+	ErrListingNotFound   = errors.New("listing not found")
+	ErrNotListingOwner   = errors.New("listing belongs to another user")
+	ErrListingDeleted    = errors.New("listing is deleted; restore it before editing it")
+	ErrListingNotDeleted = errors.New("listing is not deleted; delete it before purging it")
+)
+
+// IsDeleted reports whether the listing has been soft-deleted. Listings
+// deleted before DeleteRealtorListing existed say so in deleted alone, with
+// no Deleted At.
+func (listing Listing) IsDeleted() bool {
+	return listing.Deleted == "true"
+}
+
+// notDeleted is the condition that a listing item isn't soft-deleted.
+func notDeleted() expression.ConditionBuilder {
+	return expression.AttributeNotExists(expression.Name("deleted")).
+		Or(expression.Name("deleted").NotEqual(expression.Value("true")))
+}
+
+// DeleteRealtorListing soft-deletes owner's listing: it's marked deleted,
+// with when and by whom, and left out of reads until it's restored. It
+// returns the listing as stored; deleting a deleted listing changes nothing.
+func DeleteRealtorListing(db *dynamodb.Client, mls, owner string, now time.Time) (Listing, error) {
+	update := expression.Set(expression.Name("deleted"), expression.Value("true")).
+		Set(expression.Name("Deleted At"), expression.Value(FormatDate(now))).
+		Set(expression.Name("Deleted By"), expression.Value(owner))
+	return updateOwnListing(db, mls, owner, update, notDeleted())
+}
+
+// RestoreRealtorListing undoes DeleteRealtorListing, returning the listing
+// as stored; restoring a listing that isn't deleted changes nothing.
+func RestoreRealtorListing(db *dynamodb.Client, mls, owner string) (Listing, error) {
+	update := expression.Set(expression.Name("deleted"), expression.Value("false")).
+		Remove(expression.Name("Deleted At")).
+		Remove(expression.Name("Deleted By"))
+	return updateOwnListing(db, mls, owner, update, expression.Name("deleted").Equal(expression.Value("true")))
+}
+
+// updateOwnListing applies update to owner's listing if it passes when, and
+// returns the listing as stored. A listing that fails only when is already
+// as update would leave it, so it's returned as it is.
+func updateOwnListing(db *dynamodb.Client, mls, owner string, update expression.UpdateBuilder, when expression.ConditionBuilder) (Listing, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	cond := expression.AttributeExists(expression.Name("MLS")).
+		And(expression.Name("User").Equal(expression.Value(owner))).
+		And(when)
+	expr, err := expression.NewBuilder().WithCondition(cond).WithUpdate(update).Build()
+	if err != nil {
+		return Listing{}, err
+	}
+
+	out, err := db.UpdateItem(ctx, &dynamodb.UpdateItemInput{
+		TableName:                           aws.String(ListingsTable),
+		Key:                                 map[string]types.AttributeValue{"MLS": &types.AttributeValueMemberS{Value: mls}},
+		ConditionExpression:                 expr.Condition(),
+		UpdateExpression:                    expr.Update(),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ReturnValues:                        types.ReturnValueAllNew,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		if failed.Item == nil {
+			return Listing{}, ErrListingNotFound
+		}
+		old, err := unmarshalListing(failed.Item)
+		if err != nil {
+			return Listing{}, err
+		}
+		if old.User != owner {
+			return Listing{}, ErrNotListingOwner
+		}
+		return old, nil
+	}
+	if err != nil {
+		return Listing{}, err
+	}
+	return unmarshalListing(out.Attributes)
+}
+
+// PurgeRealtorListing removes a soft-deleted listing for good, along with
+// the images it shows from its owner's /media/ folder that none of the
+// owner's other listings show. It returns the listing and the image keys
+// removed. A listing that isn't deleted is refused with ErrListingNotDeleted.
+func PurgeRealtorListing(clients *AWSClients, mls string) (Listing, []string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	cond := expression.AttributeExists(expression.Name("MLS")).
+		And(expression.Name("deleted").Equal(expression.Value("true")))
+	expr, err := expression.NewBuilder().WithCondition(cond).Build()
+	if err != nil {
+		return Listing{}, nil, err
+	}
+	out, err := clients.DynamoDB.DeleteItem(ctx, &dynamodb.DeleteItemInput{
+		TableName:                           aws.String(ListingsTable),
+		Key:                                 map[string]types.AttributeValue{"MLS": &types.AttributeValueMemberS{Value: mls}},
+		ConditionExpression:                 expr.Condition(),
+		ExpressionAttributeNames:            expr.Names(),
+		ExpressionAttributeValues:           expr.Values(),
+		ReturnValues:                        types.ReturnValueAllOld,
+		ReturnValuesOnConditionCheckFailure: types.ReturnValuesOnConditionCheckFailureAllOld,
+	})
+	var failed *types.ConditionalCheckFailedException
+	if errors.As(err, &failed) {
+		if failed.Item == nil {
+			return Listing{}, nil, ErrListingNotFound
+		}
+		return Listing{}, nil, ErrListingNotDeleted
+	}
+	if err != nil {
+		return Listing{}, nil, err
+	}
+	listing, err := unmarshalListing(out.Attributes)
+	if err != nil {
+		return Listing{}, nil, err
+	}
+
+	images := listing.ownImages()
+	if len(images) == 0 {
+		return listing, []string{}, nil
+	}
+	// The GSI may still hold the purged listing, so it's skipped by MLS.
+	others, err := queryAll(ctx, clients.DynamoDB, &dynamodb.QueryInput{
+		TableName:                 aws.String(ListingsTable),
+		IndexName:                 aws.String(ListingsUserIndex),
+		KeyConditionExpression:    aws.String("#key = :user"),
+		ExpressionAttributeNames:  map[string]string{"#key": "user-key"},
+		ExpressionAttributeValues: map[string]types.AttributeValue{":user": &types.AttributeValueMemberS{Value: listing.User}},
+	})
+	if err != nil {
+		return listing, nil, fmt.Errorf("listing %s was purged, but its images weren't: %w", mls, err)
+	}
+	for _, item := range others {
+		other, err := unmarshalListing(item)
+		if err != nil {
+			return listing, nil, fmt.Errorf("listing %s was purged, but its images weren't: %w", mls, err)
+		}
+		if other.MLS == listing.MLS {
+			continue
+		}
+		for key := range other.ownImages() {
+			delete(images, key)
+		}
+	}
+	if len(images) == 0 {
+		return listing, []string{}, nil
+	}
+
+	keys := make([]string, 0, len(images))
+	objects := make([]s3types.ObjectIdentifier, 0, len(images))
+	for key := range images {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		objects = append(objects, s3types.ObjectIdentifier{Key: aws.String(key)})
+	}
+	removed, err := clients.S3.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+		Bucket: aws.String(ImageBucket),
+		Delete: &s3types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+	})
+	if err == nil && len(removed.Errors) > 0 {
+		err = fmt.Errorf("%s: %s", aws.ToString(removed.Errors[0].Key), aws.ToString(removed.Errors[0].Message))
+	}
+	if err != nil {
+		return listing, nil, fmt.Errorf("listing %s was purged, but its images weren't: %w", mls, err)
+	}
+	return listing, keys, nil
+}
+
+// ownImages are the image bucket keys of the photos the listing shows from
+// its owner's /media/ folder, where UploadImagePOSTAPI puts them. Photos
+// from anywhere else aren't the listing's to remove.
+func (listing Listing) ownImages() map[string]bool {
+	images := map[string]bool{}
+	if listing.User == "" {
+		return images
+	}
+	folder := "/media/" + listing.User + "/"
+	for _, photo := range append([]string{listing.ListPhoto}, listing.PhotoArray...) {
+		u, err := url.Parse(photo)
+		if err != nil || !strings.HasPrefix(u.Path, folder) || strings.Contains(u.Path, "/../") {
+			continue
+		}
+		images[u.Path] = true
+	}
+	return images
+}
+
+// unmarshalListing converts one listing item.
+func unmarshalListing(item map[string]types.AttributeValue) (Listing, error) {
+	listing := Listing{}
+	if err := attributevalue.UnmarshalMap(item, &listing); err != nil {
+		return Listing{}, err
+	}
+	return listing, nil
+}
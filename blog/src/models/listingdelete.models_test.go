@@ -0,0 +1,36 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListingIsDeleted(t *testing.T) {
+	assert.True(t, Listing{Deleted: "true"}.IsDeleted())
+	assert.False(t, Listing{Deleted: "false"}.IsDeleted())
+	assert.False(t, Listing{}.IsDeleted(), "Listings saved without the flag are live")
+
+	deleted := Listing{MLS: "1", Deleted: "true"}
+	assert.False(t, ListingQuery{}.Matches(deleted), "Searches leave deleted listings out")
+	assert.True(t, ListingQuery{IncludeDeleted: true}.Matches(deleted))
+}
+
+func TestListingOwnImages(t *testing.T) {
+	listing := Listing{
+		User:      "agent@example.com",
+		ListPhoto: "https://realtor-site-images.s3-us-west-1.amazonaws.com/media/agent@example.com/front.jpg",
+		PhotoArray: []string{
+			"https://realtor-site-images.s3-us-west-1.amazonaws.com/media/agent%40example.com/back%20yard.jpg",
+			"https://realtor-site-images.s3-us-west-1.amazonaws.com/media/other@example.com/pool.jpg",
+			"https://realtor-site-images.s3-us-west-1.amazonaws.com/media/agent@example.com/../other@example.com/pool.jpg",
+			"https://files.mitchelletzel.com/full-house.jpg",
+			"",
+		},
+	}
+	assert.Equal(t, map[string]bool{
+		"/media/agent@example.com/front.jpg":     true,
+		"/media/agent@example.com/back yard.jpg": true,
+	}, listing.ownImages(), "Only the owner's uploads are theirs to remove")
+	assert.Empty(t, Listing{ListPhoto: "https://example.com/media//x.jpg"}.ownImages(), "A listing without an owner has none")
+}
@@ -0,0 +1,386 @@
+package models
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ListingOrder : the order listings are returned in. Key is one of
+// ListingSortKeys; ties are broken by MLS number. Origin is the point a
+// "distance" order measures from.
+type ListingOrder struct {
+	Key        string
+	Descending bool
+	Origin     GeoPoint
+}
+
+// ListingSortKeys are the ?sort= values /listings accepts.
+var ListingSortKeys = []string{"modified", "listed", "price", "size", "distance"}
+
+// DefaultListingOrder is most recently modified first, the order
+// GetRealtorListings returns.
+var DefaultListingOrder = ListingOrder{Key: "modified", Descending: true}
+
+// ParseListingOrder reads ?sort= and ?order=. Dates sort newest first and
+// price, size and distance smallest first unless order says otherwise.
+// Distance is from near, the point a search is around; a search around a
+// point is ordered by distance unless sort says otherwise, and a search that
+// isn't can't be.
+func ParseListingOrder(key, order string, near *GeoPoint) (ListingOrder, error) {
+	switch {
+	case key == "" && near != nil:
+		key = "distance"
+	case key == "":
+		key = DefaultListingOrder.Key
+	case key == "distance" && near == nil:
+		return ListingOrder{}, fmt.Errorf("sort=distance needs lat and lng")
+	}
+	if !slices.Contains(ListingSortKeys, key) {
+		return ListingOrder{}, fmt.Errorf("sort must be one of %s, got %q", strings.Join(ListingSortKeys, ", "), key)
+	}
+	o := ListingOrder{Key: key, Descending: key == "modified" || key == "listed"}
+	if key == "distance" {
+		o.Origin = *near
+	}
+	switch order {
+	case "":
+	case "asc":
+		o.Descending = false
+	case "desc":
+		o.Descending = true
+	default:
+		return ListingOrder{}, fmt.Errorf("order must be asc or desc, got %q", order)
+	}
+	return o, nil
+}
+
+func (o ListingOrder) String() string {
+	s := o.Key + " asc"
+	if o.Descending {
+		s = o.Key + " desc"
+	}
+	if o.Key == "distance" {
+		s += " " + o.Origin.String()
+	}
+	return s
+}
+
+// Sort puts listings in this order.
+func (o ListingOrder) Sort(listings []Listing) {
+	sort.SliceStable(listings, func(i, j int) bool { return o.before(listings[i], listings[j]) })
+}
+
+// before reports whether a comes before b. Listings whose date doesn't
+// parse, or that have no location, go last whichever way they're sorted.
+func (o ListingOrder) before(a, b Listing) bool {
+	var c int
+	switch o.Key {
+	case "price":
+		c = compareAmounts(a.SalesPrice, b.SalesPrice)
+	case "size":
+		c = compareAmounts(a.SquareFeet, b.SquareFeet)
+	case "distance":
+		ap, aok := a.Location()
+		bp, bok := b.Location()
+		switch {
+		case !aok && !bok:
+		case !aok:
+			return false
+		case !bok:
+			return true
+		default:
+			c = compareAmounts(Amount(DistanceMiles(o.Origin, ap)), Amount(DistanceMiles(o.Origin, bp)))
+		}
+	default:
+		at, bt := a.Modified(), b.Modified()
+		if o.Key == "listed" {
+			at, bt = a.Listed(), b.Listed()
+		}
+		switch {
+		case at.Equal(bt):
+		case at.IsZero():
+			return false
+		case bt.IsZero():
+			return true
+		default:
+			c = at.Compare(bt)
+		}
+	}
+	if c != 0 {
+		return (c < 0) != o.Descending
+	}
+	return a.MLS < b.MLS
+}
+
+func compareAmounts(a, b Amount) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// cursorValue is the sort key of listing as a page token holds it.
+func (o ListingOrder) cursorValue(listing Listing) string {
+	switch o.Key {
+	case "price":
+		return strconv.FormatFloat(float64(listing.SalesPrice), 'f', -1, 64)
+	case "size":
+		return strconv.FormatFloat(float64(listing.SquareFeet), 'f', -1, 64)
+	case "listed":
+		return listing.DateListed
+	case "distance":
+		if p, ok := listing.Location(); ok {
+			return p.String()
+		}
+		return ""
+	}
+	return listing.LastModified
+}
+
+// cursor is a listing with the sort key and MLS number a page token holds.
+func (o ListingOrder) cursor(value, mls string) (Listing, error) {
+	listing := Listing{MLS: mls}
+	switch o.Key {
+	case "price", "size":
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return Listing{}, ErrBadPageRequest
+		}
+		listing.SalesPrice, listing.SquareFeet = Amount(f), Amount(f)
+	case "distance":
+		if value == "" {
+			break
+		}
+		p, err := parseGeoPoint(value)
+		if err != nil {
+			return Listing{}, ErrBadPageRequest
+		}
+		listing.Latitude, listing.Longitude = p.Lat, p.Lng
+	case "listed":
+		listing.DateListed = value
+	default:
+		listing.LastModified = value
+	}
+	return listing, nil
+}
+
+// ListingQuery : the filters of a /listings search. Empty strings and nil
+// bounds don't filter; bounds are inclusive.
+type ListingQuery struct {
+	City         string
+	State        string
+	Zip          string
+	Neighborhood string
+	MLS          string
+	// Text must appear in the description, every word of it, in any case.
+	Text          string
+	MinPrice      *Amount
+	MaxPrice      *Amount
+	MinBedrooms   *Amount
+	MinBathrooms  *Amount
+	MinSquareFeet *Amount
+	MaxSquareFeet *Amount
+	// Near is the point a search is around, and Miles, if set, how far from
+	// it a listing may be. Box is the area a listing must be in. A listing
+	// without a location matches none of them.
+	Near  *GeoPoint
+	Miles float64
+	Box   *GeoBox
+	// IncludeDeleted lets soft-deleted listings match. It's for owners
+	// looking at their own listings, so it isn't a search parameter.
+	IncludeDeleted bool
+}
+
+// ParseListingQuery reads the search parameters of /listings: city, state,
+// zip, neighborhood, mls, q (description text), min_price, max_price,
+// min_beds, min_baths, min_sqft and max_sqft, read by ParseAmount so
+// "500,000" will do; lat and lng, the point to search around, and miles,
+// the radius; and bbox, an area given as west,south,east,north in degrees.
+func ParseListingQuery(values url.Values) (ListingQuery, error) {
+	q := ListingQuery{
+		City:         strings.TrimSpace(values.Get("city")),
+		State:        strings.TrimSpace(values.Get("state")),
+		Zip:          strings.TrimSpace(values.Get("zip")),
+		Neighborhood: strings.TrimSpace(values.Get("neighborhood")),
+		MLS:          strings.TrimSpace(values.Get("mls")),
+		Text:         strings.TrimSpace(values.Get("q")),
+	}
+	for _, bound := range []struct {
+		name string
+		dest **Amount
+	}{
+		{"min_price", &q.MinPrice},
+		{"max_price", &q.MaxPrice},
+		{"min_beds", &q.MinBedrooms},
+		{"min_baths", &q.MinBathrooms},
+		{"min_sqft", &q.MinSquareFeet},
+		{"max_sqft", &q.MaxSquareFeet},
+	} {
+		value := strings.TrimSpace(values.Get(bound.name))
+		if value == "" {
+			continue
+		}
+		n, err := ParseAmount(value)
+		if err != nil {
+			return ListingQuery{}, fmt.Errorf("%s must be a number, got %q", bound.name, value)
+		}
+		*bound.dest = &n
+	}
+
+	lat, lng := strings.TrimSpace(values.Get("lat")), strings.TrimSpace(values.Get("lng"))
+	if lat != "" || lng != "" {
+		p, err := parseGeoPoint(lat + "," + lng)
+		if err != nil {
+			return ListingQuery{}, fmt.Errorf("lat and lng must be given together: %w", err)
+		}
+		q.Near = &p
+	}
+	if miles := strings.TrimSpace(values.Get("miles")); miles != "" {
+		f, err := strconv.ParseFloat(miles, 64)
+		switch {
+		case err != nil || math.IsNaN(f) || math.IsInf(f, 0) || f <= 0:
+			return ListingQuery{}, fmt.Errorf("miles must be a number greater than 0, got %q", miles)
+		case q.Near == nil:
+			return ListingQuery{}, fmt.Errorf("miles needs lat and lng")
+		}
+		q.Miles = f
+	}
+	if bbox := strings.TrimSpace(values.Get("bbox")); bbox != "" {
+		box, err := parseGeoBox(bbox)
+		if err != nil {
+			return ListingQuery{}, err
+		}
+		q.Box = &box
+	}
+	return q, nil
+}
+
+// parseGeoPoint reads "lat,lng" in degrees.
+func parseGeoPoint(s string) (GeoPoint, error) {
+	lat, lng, ok := strings.Cut(s, ",")
+	if !ok {
+		return GeoPoint{}, fmt.Errorf("%q is not lat,lng", s)
+	}
+	p := GeoPoint{}
+	var latErr, lngErr error
+	p.Lat, latErr = strconv.ParseFloat(strings.TrimSpace(lat), 64)
+	p.Lng, lngErr = strconv.ParseFloat(strings.TrimSpace(lng), 64)
+	if latErr != nil || lngErr != nil || !(p.Lat >= -90 && p.Lat <= 90) || !(p.Lng >= -180 && p.Lng <= 180) {
+		return GeoPoint{}, fmt.Errorf("%q is not a latitude from -90 to 90 and a longitude from -180 to 180", s)
+	}
+	return p, nil
+}
+
+// parseGeoBox reads a ?bbox= of west,south,east,north.
+func parseGeoBox(s string) (GeoBox, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 4 {
+		return GeoBox{}, fmt.Errorf("bbox must be west,south,east,north in degrees, got %q", s)
+	}
+	sw, swErr := parseGeoPoint(parts[1] + "," + parts[0])
+	ne, neErr := parseGeoPoint(parts[3] + "," + parts[2])
+	switch {
+	case swErr != nil || neErr != nil:
+		return GeoBox{}, fmt.Errorf("bbox must be west,south,east,north in degrees, got %q", s)
+	case sw.Lat > ne.Lat:
+		return GeoBox{}, fmt.Errorf("bbox south must not be above north, got %q", s)
+	case sw.Lng > ne.Lng:
+		return GeoBox{}, fmt.Errorf("bbox must not cross the antimeridian, got %q", s)
+	}
+	return GeoBox{South: sw.Lat, West: sw.Lng, North: ne.Lat, East: ne.Lng}, nil
+}
+
+// GeoCells are the geohash cells covering the area q searches, to Query
+// ListingsGeoIndex for, or nil if q isn't limited to an area or the area
+// covers more than MaxGeoCells.
+func (q ListingQuery) GeoCells() []string {
+	var area *GeoBox
+	if q.Near != nil && q.Miles > 0 {
+		around := q.Near.Around(q.Miles)
+		area = &around
+	}
+	if q.Box != nil {
+		if area == nil {
+			area = q.Box
+		} else {
+			both := area.intersect(*q.Box)
+			area = &both
+		}
+	}
+	if area == nil {
+		return nil
+	}
+	return area.Cells(GeoCellPrecision)
+}
+
+// Matches reports whether listing passes every filter of q.
+func (q ListingQuery) Matches(listing Listing) bool {
+	switch {
+	case listing.IsDeleted() && !q.IncludeDeleted:
+		return false
+	case q.City != "" && CityKey(listing.City) != CityKey(q.City):
+		return false
+	case q.State != "" && !strings.EqualFold(strings.TrimSpace(listing.State), q.State):
+		return false
+	case q.Zip != "" && !strings.HasPrefix(digits(listing.ZipCode), digits(q.Zip)):
+		return false
+	case q.Neighborhood != "" && !strings.EqualFold(strings.TrimSpace(listing.Neighborhood), q.Neighborhood):
+		return false
+	case q.MLS != "" && listing.MLS != q.MLS:
+		return false
+	}
+	for _, bound := range []struct {
+		value    Amount
+		min, max *Amount
+	}{
+		{listing.SalesPrice, q.MinPrice, q.MaxPrice},
+		{listing.Bedrooms, q.MinBedrooms, nil},
+		{listing.Bathrooms, q.MinBathrooms, nil},
+		{listing.SquareFeet, q.MinSquareFeet, q.MaxSquareFeet},
+	} {
+		if (bound.min != nil && bound.value < *bound.min) || (bound.max != nil && bound.value > *bound.max) {
+			return false
+		}
+	}
+	if q.Box != nil || (q.Near != nil && q.Miles > 0) {
+		p, ok := listing.Location()
+		switch {
+		case !ok:
+			return false
+		case q.Box != nil && !q.Box.Contains(p):
+			return false
+		case q.Near != nil && q.Miles > 0 && DistanceMiles(*q.Near, p) > q.Miles:
+			return false
+		}
+	}
+	description := strings.ToLower(listing.Description)
+	for _, word := range strings.Fields(strings.ToLower(q.Text)) {
+		if !strings.Contains(description, word) {
+			return false
+		}
+	}
+	return true
+}
+
+// Filter returns the listings that match q, in the order given, reusing
+// the storage of listings.
+func (q ListingQuery) Filter(listings []Listing) []Listing {
+	return slices.DeleteFunc(listings, func(l Listing) bool { return !q.Matches(l) })
+}
+
+func digits(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r < '0' || r > '9' {
+			return -1
+		}
+		return r
+	}, s)
+}
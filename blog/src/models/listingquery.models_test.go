@@ -0,0 +1,210 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseListingOrder(t *testing.T) {
+	testCases := map[[2]string]ListingOrder{
+		{"", ""}:             DefaultListingOrder,
+		{"listed", ""}:       {Key: "listed", Descending: true},
+		{"price", ""}:        {Key: "price"},
+		{"size", "desc"}:     {Key: "size", Descending: true},
+		{"modified", "asc"}:  {Key: "modified"},
+		{"price", "desc"}:    {Key: "price", Descending: true},
+		{"listed", "asc"}:    {Key: "listed"},
+		{"size", ""}:         {Key: "size"},
+		{"modified", "desc"}: DefaultListingOrder,
+	}
+	for in, want := range testCases {
+		got, err := ParseListingOrder(in[0], in[1], nil)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	near := &GeoPoint{Lat: 44.05, Lng: -121.3}
+	got, err := ParseListingOrder("", "", near)
+	require.NoError(t, err)
+	assert.Equal(t, ListingOrder{Key: "distance", Origin: *near}, got, "A search around a point is nearest first")
+	assert.Equal(t, "distance asc 44.05,-121.3", got.String())
+	got, err = ParseListingOrder("price", "", near)
+	require.NoError(t, err)
+	assert.Equal(t, ListingOrder{Key: "price"}, got)
+
+	_, err = ParseListingOrder("bedrooms", "", nil)
+	assert.ErrorContains(t, err, "sort must be one of modified, listed, price, size, distance")
+	_, err = ParseListingOrder("price", "up", nil)
+	assert.ErrorContains(t, err, "order must be asc or desc")
+	_, err = ParseListingOrder("distance", "", nil)
+	assert.ErrorContains(t, err, "sort=distance needs lat and lng")
+}
+
+func TestListingOrderSort(t *testing.T) {
+	listings := []Listing{
+		{MLS: "a", SalesPrice: 300, SquareFeet: 900, DateListed: "2020-01-01T00:00:00Z", LastModified: "someday"},
+		{MLS: "b", SalesPrice: 100, SquareFeet: 1500, DateListed: "1589161257428", LastModified: "2021-01-01T00:00:00Z"},
+		{MLS: "c", SalesPrice: 200, SquareFeet: 1500, DateListed: "", LastModified: "2022-01-01T00:00:00Z"},
+		{MLS: "d", SalesPrice: 100, SquareFeet: 1200, DateListed: "2019-01-01T00:00:00Z", LastModified: "2020-01-01T00:00:00Z"},
+	}
+	mls := func(order ListingOrder) []string {
+		sorted := append([]Listing(nil), listings...)
+		order.Sort(sorted)
+		out := []string{}
+		for _, listing := range sorted {
+			out = append(out, listing.MLS)
+		}
+		return out
+	}
+
+	assert.Equal(t, []string{"c", "b", "d", "a"}, mls(DefaultListingOrder), "Unparseable dates go last")
+	assert.Equal(t, []string{"d", "b", "c", "a"}, mls(ListingOrder{Key: "modified"}), "whichever way dates are sorted")
+	assert.Equal(t, []string{"b", "a", "d", "c"}, mls(ListingOrder{Key: "listed", Descending: true}))
+	assert.Equal(t, []string{"b", "d", "c", "a"}, mls(ListingOrder{Key: "price"}), "Ties are broken by MLS")
+	assert.Equal(t, []string{"b", "c", "d", "a"}, mls(ListingOrder{Key: "size", Descending: true}))
+}
+
+func TestParseListingQuery(t *testing.T) {
+	q, err := ParseListingQuery(url.Values{
+		"city": {" Bend "}, "state": {"or"}, "zip": {"97701"}, "q": {"mountain views"},
+		"min_price": {"$400,000"}, "max_price": {"600000"}, "min_beds": {"3"}, "min_baths": {"1.5"},
+		"min_sqft": {""}, "max_sqft": {"2000"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "Bend", q.City)
+	assert.Equal(t, "mountain views", q.Text)
+	require.NotNil(t, q.MinPrice)
+	assert.Equal(t, Amount(400000), *q.MinPrice)
+	assert.Equal(t, Amount(1.5), *q.MinBathrooms)
+	assert.Nil(t, q.MinSquareFeet, "Blank bounds don't filter")
+
+	assert.Nil(t, q.Near)
+	assert.Nil(t, q.GeoCells(), "A search that isn't of an area isn't a Query of cells")
+
+	q, err = ParseListingQuery(url.Values{"lat": {"44.05"}, "lng": {" -121.3"}, "miles": {"5"}, "bbox": {"-121.4,44,-121.2,44.1"}})
+	require.NoError(t, err)
+	assert.Equal(t, &GeoPoint{Lat: 44.05, Lng: -121.3}, q.Near)
+	assert.Equal(t, 5.0, q.Miles)
+	assert.Equal(t, &GeoBox{South: 44, West: -121.4, North: 44.1, East: -121.2}, q.Box)
+
+	for query, want := range map[string]string{
+		"max_sqft=big":              `max_sqft must be a number, got "big"`,
+		"lat=44":                    "lat and lng must be given together",
+		"lat=44&lng=east":           "lat and lng must be given together",
+		"lat=-91&lng=0":             "latitude from -90 to 90",
+		"miles=5":                   "miles needs lat and lng",
+		"lat=44&lng=-121&miles=0":   "miles must be a number greater than 0",
+		"bbox=-121,44,-120":         "bbox must be west,south,east,north",
+		"bbox=-121,45,-120,44":      "bbox south must not be above north",
+		"bbox=179,-15,-179,-14":     "bbox must not cross the antimeridian",
+		"lat=44&lng=-121&miles=NaN": "miles must be a number greater than 0",
+	} {
+		values, _ := url.ParseQuery(query)
+		_, err := ParseListingQuery(values)
+		assert.ErrorContains(t, err, want, query)
+	}
+}
+
+func TestListingQueryMatches(t *testing.T) {
+	listing := Listing{
+		MLS: "1234567890", City: "Bend", State: "OR", ZipCode: "97701-1234", Neighborhood: "Wells Acres",
+		SalesPrice: 503000, Bedrooms: 3, Bathrooms: 1.5, SquareFeet: 1200,
+		Description: "Mountain views from a quiet cul-de-sac",
+	}
+	amount := func(n Amount) *Amount { return &n }
+
+	matching := map[string]ListingQuery{
+		"no filters":       {},
+		"city, any case":   {City: "bend"},
+		"state, any case":  {State: "or"},
+		"zip, not zip+4":   {Zip: "97701"},
+		"zip prefix":       {Zip: "977"},
+		"neighborhood":     {Neighborhood: "wells acres"},
+		"mls":              {MLS: "1234567890"},
+		"words, any order": {Text: "QUIET mountain"},
+		"inclusive bounds": {MinPrice: amount(503000), MaxPrice: amount(503000), MinBedrooms: amount(3), MinBathrooms: amount(1.5), MinSquareFeet: amount(1200), MaxSquareFeet: amount(1200)},
+	}
+	for name, q := range matching {
+		assert.True(t, q.Matches(listing), name)
+	}
+
+	missing := map[string]ListingQuery{
+		"city":          {City: "Redmond"},
+		"state":         {State: "WA"},
+		"zip":           {Zip: "97702"},
+		"neighborhood":  {Neighborhood: "Awbrey Butte"},
+		"mls":           {MLS: "123456789"},
+		"text":          {Text: "mountain pool"},
+		"min price":     {MinPrice: amount(503001)},
+		"max price":     {MaxPrice: amount(502999)},
+		"min bedrooms":  {MinBedrooms: amount(4)},
+		"min bathrooms": {MinBathrooms: amount(2)},
+		"min sqft":      {MinSquareFeet: amount(1201)},
+		"max sqft":      {MaxSquareFeet: amount(1199)},
+	}
+	for name, q := range missing {
+		assert.False(t, q.Matches(listing), name)
+	}
+
+	placed := listing
+	placed.Latitude, placed.Longitude = 44.0582, -121.3153
+	near := &GeoPoint{Lat: 44.1, Lng: -121.3}
+	assert.True(t, ListingQuery{Near: near, Miles: 3}.Matches(placed), "About 3 miles away")
+	assert.False(t, ListingQuery{Near: near, Miles: 2.5}.Matches(placed))
+	assert.True(t, ListingQuery{Near: near}.Matches(listing), "A point without a radius doesn't filter")
+	assert.False(t, ListingQuery{Near: near, Miles: 3000}.Matches(listing), "An unplaced listing is in no area")
+	assert.True(t, ListingQuery{Box: &GeoBox{South: 44, West: -121.4, North: 44.1, East: -121.3}}.Matches(placed))
+	assert.False(t, ListingQuery{Box: &GeoBox{South: 44, West: -121.3, North: 44.1, East: -121.2}}.Matches(placed))
+}
+
+func TestListingOrderSortByDistance(t *testing.T) {
+	order := ListingOrder{Key: "distance", Origin: GeoPoint{Lat: 44.05, Lng: -121.3}}
+	listings := []Listing{
+		{MLS: "a", Latitude: 44.2, Longitude: -121.3},
+		{MLS: "b"},
+		{MLS: "c", Latitude: 44.06, Longitude: -121.3},
+		{MLS: "d", Latitude: 44.05, Longitude: -121.1},
+	}
+	order.Sort(listings)
+	assert.Equal(t, []string{"c", "d", "a", "b"}, []string{listings[0].MLS, listings[1].MLS, listings[2].MLS, listings[3].MLS}, "Unplaced listings go last")
+
+	order.Descending = true
+	order.Sort(listings)
+	assert.Equal(t, []string{"a", "d", "c", "b"}, []string{listings[0].MLS, listings[1].MLS, listings[2].MLS, listings[3].MLS}, "either way")
+
+	order.Descending = false
+	order.Sort(listings)
+	page, next, err := PageListings(listings, order, PageRequest{Limit: 3})
+	require.NoError(t, err)
+	assert.Len(t, page, 3)
+	page, _, err = PageListings(listings, order, PageRequest{Token: next, Limit: 3})
+	require.NoError(t, err)
+	assert.Equal(t, []Listing{{MLS: "b"}}, page)
+
+	elsewhere := order
+	elsewhere.Origin = GeoPoint{Lat: 45, Lng: -122}
+	_, _, err = PageListings(listings, elsewhere, PageRequest{Token: next, Limit: 3})
+	assert.ErrorIs(t, err, ErrBadPageRequest, "A token is for the point it was searched around")
+}
+
+func TestPageListingsByPrice(t *testing.T) {
+	order := ListingOrder{Key: "price", Descending: true}
+	listings := []Listing{{MLS: "a", SalesPrice: 100}, {MLS: "b", SalesPrice: 250.5}, {MLS: "c", SalesPrice: 250.5}, {MLS: "d", SalesPrice: 50}}
+	order.Sort(listings)
+
+	page, next, err := PageListings(listings, order, PageRequest{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []Listing{listings[0], listings[1]}, page)
+	assert.Equal(t, "b", page[0].MLS)
+
+	page, next, err = PageListings(listings, order, PageRequest{Token: next, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "d"}, []string{page[0].MLS, page[1].MLS})
+	assert.Empty(t, next)
+
+	_, _, err = PageListings(listings, order, PageRequest{Token: encodePageToken(order.String(), "cheap", "a"), Limit: 2})
+	assert.ErrorIs(t, err, ErrBadPageRequest)
+}
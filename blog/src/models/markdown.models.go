@@ -0,0 +1,202 @@
+package models
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+
+	"github.com/microcosm-cc/bluemonday"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/parser"
+	goldmarkhtml "github.com/yuin/goldmark/renderer/html"
+	"github.com/yuin/goldmark/text"
+	"github.com/yuin/goldmark/util"
+	"go.yaml.in/yaml/v3"
+)
+
+// FrontMatter : the YAML block at the top of a Markdown article. Each key maps
+// onto the Item field of the same meaning; see ParseMarkdownArticle.
+type FrontMatter struct {
+	ID         int      `yaml:"id"`
+	Title      string   `yaml:"title"`
+	ShortTitle string   `yaml:"short_title"`
+	Slug       string   `yaml:"slug"`
+	Author     string   `yaml:"author"`
+	Categories []string `yaml:"categories"`
+	Created    string   `yaml:"created"`
+	Modified   string   `yaml:"modified"`
+	Excerpt    string   `yaml:"excerpt"`
+	PostType   string   `yaml:"type"`
+	Status     string   `yaml:"status"`
+	PublishAt  string   `yaml:"publish_at"`
+	Hero       Hero     `yaml:"hero"`
+}
+
+// Hero : the article's lead image. Panel falls back to Image when unset.
+type Hero struct {
+	Image string `yaml:"image"`
+	Panel string `yaml:"panel"`
+	Alt   string `yaml:"alt"`
+}
+
+const frontMatterFence = "---"
+
+// ParseMarkdownArticle splits a Markdown article into its front matter and
+// body and returns the equivalent Item. The body is kept as Markdown source
+// in Item.Markdown; Item.Article renders it when the page is served. The
+// author reaches the page as HTML, so it's held to the body's allow-list.
+func ParseMarkdownArticle(src []byte) (Item, error) {
+	item := Item{}
+
+	matter, body, err := splitFrontMatter(src)
+	if err != nil {
+		return item, err
+	}
+
+	fm := FrontMatter{}
+	if err := yaml.Unmarshal(matter, &fm); err != nil {
+		return item, fmt.Errorf("front matter: %v", err)
+	}
+	if fm.Title == "" {
+		return item, fmt.Errorf("front matter: title is required")
+	}
+
+	postType := fm.PostType
+	if postType == "" {
+		postType = "standard"
+	}
+	shortTitle := fm.ShortTitle
+	if shortTitle == "" {
+		shortTitle = fm.Title
+	}
+
+	item.Author = markdownPolicy.Sanitize(fm.Author)
+	item.Categories = strings.Join(fm.Categories, ",")
+	item.CreatedDate = fm.Created
+	item.Excerpt = html.EscapeString(fm.Excerpt)
+	item.Markdown = string(body)
+	item.ModifiedDate = fm.Modified
+	item.PostID = fm.ID
+	item.PostTitle = fm.Title
+	item.ShortTitle = shortTitle
+	item.PostType = postType
+	item.Slug = fm.Slug
+	item.Status = fm.Status
+	item.PublishAt = fm.PublishAt
+	if fm.Hero.Image != "" {
+		item.ArticlePicture = heroArticlePicture(fm.Hero)
+		item.PanelPicture = heroPanelPicture(fm.Hero, item.ResolvedSlug())
+	}
+
+	return item, nil
+}
+
+// splitFrontMatter expects src to open with a "---" line and returns what
+// sits between it and the next "---" line, plus everything after.
+func splitFrontMatter(src []byte) ([]byte, []byte, error) {
+	src = bytes.TrimPrefix(src, []byte("\ufeff"))
+	lines := strings.SplitAfter(string(src), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != frontMatterFence {
+		return nil, nil, fmt.Errorf("front matter: article must start with a %q line", frontMatterFence)
+	}
+	for i := 1; i < len(lines); i++ {
+		if strings.TrimSpace(lines[i]) == frontMatterFence {
+			matter := strings.Join(lines[1:i], "")
+			body := strings.Join(lines[i+1:], "")
+			return []byte(matter), []byte(body), nil
+		}
+	}
+	return nil, nil, fmt.Errorf("front matter: missing closing %q line", frontMatterFence)
+}
+
+// heroArticlePicture builds the same markup the hand-written
+// articlePicture.html files use.
+func heroArticlePicture(hero Hero) string {
+	return fmt.Sprintf(`<div class="media-wrap entry__media">
+    <div class="entry__post-thumb">
+        <img src="%s" alt="%s">
+    </div>
+</div>`, html.EscapeString(hero.Image), html.EscapeString(hero.Alt))
+}
+
+// heroPanelPicture builds the same markup the hand-written panelPicture.html
+// files use, linking back to the article.
+func heroPanelPicture(hero Hero, slug string) string {
+	src := hero.Panel
+	if src == "" {
+		src = hero.Image
+	}
+	return fmt.Sprintf(`<div class="entry__thumb">
+    <a href="/posts/%s" class="entry__thumb-link">
+        <img src="%s" alt="%s">
+    </a>
+</div>`, html.EscapeString(slug), html.EscapeString(src), html.EscapeString(hero.Alt))
+}
+
+// markdown is the shared converter: GitHub-flavoured Markdown (tables,
+// strikethrough, autolinks, task lists), fenced code blocks tagged with a
+// language-* class, and an id plus a self-link on every heading.
+var markdown = goldmark.New(
+	goldmark.WithExtensions(extension.GFM),
+	goldmark.WithParserOptions(
+		parser.WithAutoHeadingID(),
+		parser.WithASTTransformers(util.Prioritized(headingAnchors{}, 100)),
+	),
+	// Raw HTML is passed through here and cleaned up by markdownPolicy below,
+	// so authors can still drop in the odd <figure> or <kbd>.
+	goldmark.WithRendererOptions(goldmarkhtml.WithUnsafe()),
+)
+
+// markdownPolicy is the allow-list rendered Markdown is sanitized against.
+var markdownPolicy = func() *bluemonday.Policy {
+	p := bluemonday.UGCPolicy()
+	// nofollow only off-site links; in-page heading anchors stay plain.
+	p.RequireNoFollowOnLinks(false)
+	p.RequireNoFollowOnFullyQualifiedLinks(true)
+	p.AllowAttrs("id").Matching(regexp.MustCompile(`^[A-Za-z0-9_-]+$`)).OnElements("h1", "h2", "h3", "h4", "h5", "h6")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^language-[A-Za-z0-9_+-]+$`)).OnElements("code")
+	p.AllowAttrs("class").Matching(regexp.MustCompile(`^heading-anchor$`)).OnElements("a")
+	return p
+}()
+
+// RenderMarkdown converts Markdown source to sanitized HTML wrapped in the
+// entry__content container the theme styles article bodies with.
+func RenderMarkdown(src string) (string, error) {
+	var buf bytes.Buffer
+	if err := markdown.Convert([]byte(src), &buf); err != nil {
+		return "", err
+	}
+	return "<div class=\"entry__content\">\n" + markdownPolicy.Sanitize(buf.String()) + "</div>", nil
+}
+
+// headingAnchors appends a "#" self-link to every heading that received an
+// auto-generated id, so readers can copy a link to any section.
+type headingAnchors struct{}
+
+func (headingAnchors) Transform(doc *ast.Document, reader text.Reader, pc parser.Context) {
+	_ = ast.Walk(doc, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		heading, ok := n.(*ast.Heading)
+		if !entering || !ok {
+			return ast.WalkContinue, nil
+		}
+		id, ok := heading.AttributeString("id")
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		idBytes, ok := id.([]byte)
+		if !ok {
+			return ast.WalkContinue, nil
+		}
+		link := ast.NewLink()
+		link.Destination = append([]byte("#"), idBytes...)
+		link.SetAttributeString("class", []byte("heading-anchor"))
+		link.AppendChild(link, ast.NewString([]byte("#")))
+		heading.AppendChild(heading, ast.NewString([]byte(" ")))
+		heading.AppendChild(heading, link)
+		return ast.WalkSkipChildren, nil
+	})
+}
@@ -0,0 +1,171 @@
+package models
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testMarkdownArticle = `---
+id: 7
+title: Writing Posts in Markdown
+short_title: Markdown Posts
+author: Mitchell Etzel
+categories:
+  - Web Development
+  - My Projects
+created: October 1st, 2026
+modified: October 2nd, 2026
+excerpt: No more hand-written <div> soup.
+hero:
+  image: https://files.mitchelletzel.com/posts/MD/MD-article-1000.png
+  panel: https://files.mitchelletzel.com/posts/MD/MD-cover-600.png
+  alt: A keyboard
+---
+## Why Markdown
+
+Hand-written HTML was getting old.
+
+` + "```go\nfmt.Println(\"hello\")\n```" + `
+`
+
+func TestParseMarkdownArticle(t *testing.T) {
+	item, err := ParseMarkdownArticle([]byte(testMarkdownArticle))
+
+	require.NoError(t, err)
+	assert.Equal(t, 7, item.PostID)
+	assert.Equal(t, "Writing Posts in Markdown", item.PostTitle)
+	assert.Equal(t, "Markdown Posts", item.ShortTitle)
+	assert.Equal(t, "Mitchell Etzel", item.Author)
+	assert.Equal(t, "Web Development,My Projects", item.Categories)
+	assert.Equal(t, "October 1st, 2026", item.CreatedDate)
+	assert.Equal(t, "October 2nd, 2026", item.ModifiedDate)
+	assert.Equal(t, "No more hand-written &lt;div&gt; soup.", item.Excerpt, "Excerpt is plain text and should be escaped")
+	assert.Equal(t, "standard", item.PostType, "PostType should default to standard")
+	assert.True(t, strings.HasPrefix(item.Markdown, "## Why Markdown"), "Markdown should hold the body after the front matter, got %q", item.Markdown)
+	assert.Empty(t, item.HTMLHold, "Markdown articles are rendered at serve time, not parse time")
+	assert.Contains(t, item.ArticlePicture, `src="https://files.mitchelletzel.com/posts/MD/MD-article-1000.png"`)
+	assert.Contains(t, item.PanelPicture, `href="/posts/markdown-posts"`, "Panel should link to the slug derived from short_title")
+	assert.Contains(t, item.PanelPicture, `src="https://files.mitchelletzel.com/posts/MD/MD-cover-600.png"`)
+}
+
+func TestParseMarkdownArticle_Errors(t *testing.T) {
+	testCases := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{name: "NoFrontMatter", src: "# Just a heading\n", want: "must start with"},
+		{name: "Unterminated", src: "---\ntitle: Open\n\nBody\n", want: "missing closing"},
+		{name: "BadYAML", src: "---\ntitle: [unclosed\n---\nBody\n", want: "front matter"},
+		{name: "NoTitle", src: "---\nid: 3\n---\nBody\n", want: "title is required"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := ParseMarkdownArticle([]byte(tc.src))
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.want)
+		})
+	}
+}
+
+func TestRenderMarkdown(t *testing.T) {
+	out, err := RenderMarkdown("## Section One\n\n```go\nx := 1\n```\n")
+
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(out, `<div class="entry__content">`), "Body should be wrapped like the HTML articles, got %q", out)
+	assert.Contains(t, out, `<h2 id="section-one">`, "Headings should get an id")
+	assert.Contains(t, out, `<a href="#section-one" class="heading-anchor">#</a>`, "Headings should get a self-link")
+	assert.Contains(t, out, `<pre><code class="language-go">`, "Fenced code should keep its language class")
+}
+
+func TestRenderMarkdown_Sanitizes(t *testing.T) {
+	out, err := RenderMarkdown("Hi <script>alert(1)</script>\n\n" +
+		"<a href=\"javascript:alert(1)\" onclick=\"steal()\">click</a>\n\n" +
+		"<img src=\"x.png\" onerror=\"steal()\">\n\n" +
+		"[off site](https://example.com)\n")
+
+	require.NoError(t, err)
+	assert.NotContains(t, out, "<script")
+	assert.NotContains(t, out, "javascript:")
+	assert.NotContains(t, out, "onclick")
+	assert.NotContains(t, out, "onerror")
+	assert.Contains(t, out, `rel="nofollow"`, "Off-site links should be nofollow")
+}
+
+func TestParseMarkdownArticle_SanitizesAuthor(t *testing.T) {
+	src := strings.Replace(testMarkdownArticle, "author: Mitchell Etzel",
+		`author: '<a href="javascript:alert(1)" onclick="steal()">Mitchell</a> <script>alert(1)</script><em>Etzel</em>'`, 1)
+	item, err := ParseMarkdownArticle([]byte(src))
+	require.NoError(t, err)
+
+	for _, author := range []string{item.Author, string(item.Panel().Author), string(item.Article().Author)} {
+		assert.NotContains(t, author, "<script")
+		assert.NotContains(t, author, "javascript:")
+		assert.NotContains(t, author, "onclick")
+		assert.Contains(t, author, "<em>Etzel</em>", "Markup the body allows stays")
+	}
+
+	// A Markdown item written without going through the parser is cleaned
+	// when it's served, too.
+	stored := Item{Markdown: "Hi", Author: `Mitchell <img src="x.png" onerror="steal()">`}
+	assert.NotContains(t, string(stored.Article().Author), "onerror")
+}
+
+func TestItemArticle_RendersMarkdown(t *testing.T) {
+	item, err := ParseMarkdownArticle([]byte(testMarkdownArticle))
+	require.NoError(t, err)
+
+	article := item.Article()
+
+	assert.Contains(t, string(article.HTMLHold), `<h2 id="why-markdown">`)
+	assert.Contains(t, string(article.HTMLHold), "Hand-written HTML was getting old.")
+
+	// Hand-written HTML articles pass through untouched, inline styles and all.
+	legacy := Item{PostID: 0, HTMLHold: `<p style="color:#9C6708;">legacy</p>`}
+	assert.Equal(t, `<p style="color:#9C6708;">legacy</p>`, string(legacy.Article().HTMLHold))
+}
+
+func TestItemArticle_KeepsSource(t *testing.T) {
+	item, err := ParseMarkdownArticle([]byte(testMarkdownArticle))
+	require.NoError(t, err)
+
+	article := item.Article()
+	assert.Equal(t, SourceFormatMarkdown, article.SourceFormat)
+	assert.Equal(t, item.Markdown, article.Source)
+
+	legacy := Item{PostID: 0, HTMLHold: "<p>legacy</p>"}.Article()
+	assert.Equal(t, SourceFormatHTML, legacy.SourceFormat)
+	assert.Equal(t, "<p>legacy</p>", legacy.Source)
+
+	assert.Empty(t, item.Panel().Source, "Panels don't carry the body")
+}
+
+func TestLoadArticleDir_Markdown(t *testing.T) {
+	silenceLogrus(t)
+	root := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "markdownPosts"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "markdownPosts", ArticleMarkdownFile), []byte(testMarkdownArticle), 0644))
+	writeArticleDir(t, root, "legacy", Item{PostID: 0, PostTitle: "Legacy", PostType: "standard", Categories: "My Projects"},
+		map[string]string{ArticleBodyFile: "<p>legacy body</p>"})
+
+	store := NewFileArticleStore(root)
+
+	panels := store.GetArticlePanels()
+	require.Len(t, panels, 2, "HTML and Markdown articles should be served side by side")
+	assert.Equal(t, 7, panels[0].PostID)
+	assert.Len(t, store.GetCategoryPageArticlePanels("My Projects"), 2)
+
+	article, err := store.GetArticleByID(7)
+	require.NoError(t, err)
+	assert.Contains(t, string(article.HTMLHold), `<h2 id="why-markdown">`)
+
+	legacy, err := store.GetArticleByID(0)
+	require.NoError(t, err)
+	assert.Equal(t, "<p>legacy body</p>", string(legacy.HTMLHold))
+}
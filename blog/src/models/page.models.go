@@ -0,0 +1,125 @@
+package models
+
+import (
+	"encoding/base64"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Limits on ?limit= for the paginated endpoints.
+const (
+	DefaultPostsPageLimit    = 10
+	DefaultListingsPageLimit = 24
+	MaxPageLimit             = 100
+)
+
+// ErrBadPageRequest is returned for a malformed ?page_token= or ?limit=.
+var ErrBadPageRequest = errors.New("invalid page_token or limit")
+
+// PageRequest : a parsed ?page_token=&limit= pair. Tokens are opaque
+// cursors naming the last item of the previous page, so a page stays put
+// when items are added or removed ahead of it.
+type PageRequest struct {
+	Token string
+	Limit int
+}
+
+// ParsePageRequest validates the query parameters, using defaultLimit when
+// limit is empty and clamping it to MaxPageLimit.
+func ParsePageRequest(token, limit string, defaultLimit int) (PageRequest, error) {
+	req := PageRequest{Token: token, Limit: defaultLimit}
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return req, ErrBadPageRequest
+		}
+		req.Limit = n
+	}
+	if req.Limit > MaxPageLimit {
+		req.Limit = MaxPageLimit
+	}
+	return req, nil
+}
+
+// PagePanels returns the page of panels req asks for and the token for the
+// next one ("" on the last page). panels must be in GetArticlePanels order,
+// newest first.
+func PagePanels(panels []Article, req PageRequest) ([]Article, string, error) {
+	start := 0
+	if req.Token != "" {
+		parts, err := decodePageToken(req.Token, 2)
+		if err != nil {
+			return nil, "", err
+		}
+		id, err := strconv.Atoi(parts[1])
+		if err != nil {
+			return nil, "", ErrBadPageRequest
+		}
+		cursor := Article{CreatedDate: parts[0], PostID: id}
+		start = sort.Search(len(panels), func(i int) bool { return compareNewestFirst(cursor, panels[i]) < 0 })
+	}
+
+	end := min(start+req.Limit, len(panels))
+	page := panels[start:end]
+	next := ""
+	if end < len(panels) {
+		last := page[len(page)-1]
+		next = encodePageToken(last.CreatedDate, strconv.Itoa(last.PostID))
+	}
+	return page, next, nil
+}
+
+// PageListings is PagePanels for listings already sorted in order. Its
+// tokens name the order they were made for, and are refused for another.
+func PageListings(listings []Listing, order ListingOrder, req PageRequest) ([]Listing, string, error) {
+	start := 0
+	if req.Token != "" {
+		parts, err := decodePageToken(req.Token, 3)
+		if err != nil {
+			return nil, "", err
+		}
+		if parts[0] != order.String() {
+			return nil, "", ErrBadPageRequest
+		}
+		cursor, err := order.cursor(parts[1], parts[2])
+		if err != nil {
+			return nil, "", err
+		}
+		start = sort.Search(len(listings), func(i int) bool { return order.before(cursor, listings[i]) })
+	}
+
+	end := min(start+req.Limit, len(listings))
+	page := listings[start:end]
+	next := ""
+	if end < len(listings) {
+		last := page[len(page)-1]
+		next = encodePageToken(order.String(), order.cursorValue(last), last.MLS)
+	}
+	return page, next, nil
+}
+
+// sortListings orders listings in DefaultListingOrder: most recently
+// modified first, breaking ties (and listings whose LastModified doesn't
+// parse, which go last) by MLS number so the order (and so every page
+// token) is stable.
+func sortListings(listings []Listing) {
+	DefaultListingOrder.Sort(listings)
+}
+
+func encodePageToken(parts ...string) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strings.Join(parts, "\x00")))
+}
+
+func decodePageToken(token string, n int) ([]string, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrBadPageRequest
+	}
+	parts := strings.Split(string(raw), "\x00")
+	if len(parts) != n {
+		return nil, ErrBadPageRequest
+	}
+	return parts, nil
+}
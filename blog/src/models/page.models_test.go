@@ -0,0 +1,102 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePageRequest(t *testing.T) {
+	req, err := ParsePageRequest("", "", 10)
+	require.NoError(t, err)
+	assert.Equal(t, PageRequest{Limit: 10}, req)
+
+	req, err = ParsePageRequest("abc", "5", 10)
+	require.NoError(t, err)
+	assert.Equal(t, PageRequest{Token: "abc", Limit: 5}, req)
+
+	req, err = ParsePageRequest("", "5000", 10)
+	require.NoError(t, err)
+	assert.Equal(t, MaxPageLimit, req.Limit, "Limits should be clamped")
+
+	for _, limit := range []string{"0", "-1", "ten"} {
+		_, err := ParsePageRequest("", limit, 10)
+		assert.ErrorIs(t, err, ErrBadPageRequest, limit)
+	}
+}
+
+func TestPagePanels(t *testing.T) {
+	panels := NewMemoryArticleStore(testArticleItems()...).GetArticlePanels()
+
+	ids := func(page []Article) []int {
+		out := []int{}
+		for _, panel := range page {
+			out = append(out, panel.PostID)
+		}
+		return out
+	}
+
+	page, next, err := PagePanels(panels, PageRequest{Limit: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []int{5, 4, 3, 2}, ids(page))
+	require.NotEmpty(t, next)
+
+	page, last, err := PagePanels(panels, PageRequest{Token: next, Limit: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, ids(page))
+	assert.Empty(t, last, "The last page has no next token")
+
+	// Deleting the post the cursor names doesn't shift the next page.
+	without := append([]Article{}, panels[:3]...)
+	without = append(without, panels[4:]...)
+	page, _, err = PagePanels(without, PageRequest{Token: next, Limit: 4})
+	require.NoError(t, err)
+	assert.Equal(t, []int{1, 0}, ids(page))
+
+	page, next, err = PagePanels(panels, PageRequest{Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, page, 6)
+	assert.Empty(t, next)
+
+	for _, token := range []string{"!!!", encodePageToken("x"), encodePageToken("2020-01-01T00:00:00Z", "two")} {
+		_, _, err := PagePanels(panels, PageRequest{Token: token, Limit: 4})
+		assert.ErrorIs(t, err, ErrBadPageRequest, token)
+	}
+}
+
+func TestPageListings(t *testing.T) {
+	listings := []Listing{
+		{MLS: "b", LastModified: "1700000000002"},
+		{MLS: "d", LastModified: "1700000000001"},
+		{MLS: "a", LastModified: "1700000000003"},
+		{MLS: "c", LastModified: "1700000000002"},
+	}
+	sortListings(listings)
+
+	mls := func(page []Listing) []string {
+		out := []string{}
+		for _, listing := range page {
+			out = append(out, listing.MLS)
+		}
+		return out
+	}
+	assert.Equal(t, []string{"a", "b", "c", "d"}, mls(listings), "Newest first, ties broken by MLS")
+
+	page, next, err := PageListings(listings, DefaultListingOrder, PageRequest{Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, mls(page))
+
+	page, next, err = PageListings(listings, DefaultListingOrder, PageRequest{Token: next, Limit: 2})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"c", "d"}, mls(page), "The tie at the page boundary should be split by MLS")
+	assert.Empty(t, next)
+
+	_, _, err = PageListings(listings, DefaultListingOrder, PageRequest{Token: encodePageToken("x"), Limit: 2})
+	assert.ErrorIs(t, err, ErrBadPageRequest)
+
+	_, next, err = PageListings(listings, DefaultListingOrder, PageRequest{Limit: 2})
+	require.NoError(t, err)
+	_, _, err = PageListings(listings, ListingOrder{Key: "price"}, PageRequest{Token: next, Limit: 2})
+	assert.ErrorIs(t, err, ErrBadPageRequest, "A token is only good for the order it was made in")
+}
@@ -0,0 +1,93 @@
+package models
+
+import (
+	"html"
+	"slices"
+	"sync"
+	"time"
+)
+
+// PanelSnapshot : an ArticleStore that answers GetArticlePanels and
+// GetCategoryPageArticlePanels from one shared read of every panel, so the
+// page cache watcher, the search index and the JSON API don't each scan the
+// articles table. The read includes scheduled posts, so a post goes live at
+// its publish-at without another one. Whole articles come straight from the
+// wrapped store. Safe for concurrent use.
+type PanelSnapshot struct {
+	ArticleStore
+
+	mu     sync.RWMutex
+	loaded bool
+	// panels is every panel, whatever its status, newest first.
+	panels []Article
+}
+
+// NewPanelSnapshot returns a snapshot of articles' panels, read on first use.
+func NewPanelSnapshot(articles ArticleStore) *PanelSnapshot {
+	return &PanelSnapshot{ArticleStore: articles}
+}
+
+// Refresh re-reads every panel from the wrapped store.
+func (s *PanelSnapshot) Refresh() {
+	panels := s.ArticleStore.GetAllArticlePanels()
+
+	s.mu.Lock()
+	s.panels = panels
+	s.loaded = true
+	s.mu.Unlock()
+}
+
+// GetArticlePanels returns the panels Listed now, newest first, from the
+// snapshot.
+func (s *PanelSnapshot) GetArticlePanels() []Article {
+	return listedPanels(s.GetAllArticlePanels(), time.Now())
+}
+
+// GetCategoryPageArticlePanels returns the panels Listed now in category,
+// oldest first, from the snapshot.
+func (s *PanelSnapshot) GetCategoryPageArticlePanels(category string) []Article {
+	unescapedCategory := html.UnescapeString(category)
+
+	articles := []Article{}
+	for _, panel := range s.GetArticlePanels() {
+		if slices.Contains(categoryNames(panel), unescapedCategory) {
+			articles = append(articles, panel)
+		}
+	}
+	slices.Reverse(articles)
+
+	return articles
+}
+
+// GetAllArticlePanels returns every panel in the snapshot, newest first.
+func (s *PanelSnapshot) GetAllArticlePanels() []Article {
+	s.mu.RLock()
+	loaded := s.loaded
+	s.mu.RUnlock()
+	if !loaded {
+		s.Refresh()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return append([]Article{}, s.panels...)
+}
+
+// NextPublishAt returns the earliest publish-at after now among the
+// snapshot's scheduled posts, and false if none is pending.
+func (s *PanelSnapshot) NextPublishAt(now time.Time) (time.Time, bool) {
+	var next time.Time
+	for _, panel := range s.GetAllArticlePanels() {
+		if panel.Status != StatusScheduled {
+			continue
+		}
+		at, err := ParsePublishAt(panel.PublishAt)
+		if err != nil || !at.After(now) {
+			continue
+		}
+		if next.IsZero() || at.Before(next) {
+			next = at
+		}
+	}
+	return next, !next.IsZero()
+}
@@ -0,0 +1,65 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// countingStore counts the reads of every panel that reach the store.
+type countingStore struct {
+	*MemoryArticleStore
+	reads int
+}
+
+func (s *countingStore) GetArticlePanels() []Article {
+	s.reads++
+	return s.MemoryArticleStore.GetArticlePanels()
+}
+
+func (s *countingStore) GetAllArticlePanels() []Article {
+	s.reads++
+	return s.MemoryArticleStore.GetAllArticlePanels()
+}
+
+func TestPanelSnapshot(t *testing.T) {
+	soon := time.Now().Add(50 * time.Millisecond)
+	store := &countingStore{MemoryArticleStore: NewMemoryArticleStore(
+		Item{PostID: 1, PostTitle: "Live", PostType: "standard", CreatedDate: "2026-01-01T00:00:00Z"},
+		Item{PostID: 2, PostTitle: "Soon", PostType: "standard", CreatedDate: "2026-02-01T00:00:00Z",
+			Status: StatusScheduled, PublishAt: soon.Format(time.RFC3339Nano)},
+		Item{PostID: 3, PostTitle: "Draft", PostType: "standard", Status: StatusDraft},
+	)}
+	snapshot := NewPanelSnapshot(store)
+	ids := func(panels []Article) []int {
+		out := []int{}
+		for _, panel := range panels {
+			out = append(out, panel.PostID)
+		}
+		return out
+	}
+
+	assert.Equal(t, []int{1}, ids(snapshot.GetArticlePanels()))
+	assert.Equal(t, []int{2, 1, 3}, ids(snapshot.GetAllArticlePanels()))
+	next, ok := snapshot.NextPublishAt(time.Now())
+	assert.True(t, ok)
+	assert.True(t, next.Equal(soon), "next %v, want %v", next, soon)
+	assert.Equal(t, 1, store.reads, "The snapshot is read once and shared")
+
+	store.Put(Item{PostID: 4, PostTitle: "New", PostType: "standard"})
+	assert.Equal(t, []int{1}, ids(snapshot.GetArticlePanels()), "Writes show up on Refresh")
+	snapshot.Refresh()
+	assert.Equal(t, []int{1, 4}, ids(snapshot.GetArticlePanels()))
+	assert.Equal(t, 2, store.reads)
+
+	time.Sleep(time.Until(soon))
+	assert.Equal(t, []int{2, 1, 4}, ids(snapshot.GetArticlePanels()), "A scheduled post goes live without another read")
+	_, ok = snapshot.NextPublishAt(time.Now())
+	assert.False(t, ok)
+	assert.Equal(t, 2, store.reads)
+
+	article, err := snapshot.GetArticleByID(3)
+	assert.NoError(t, err, "Everything else goes to the store")
+	assert.Equal(t, "Draft", article.PostTitle)
+}
@@ -2,16 +2,20 @@ package models
 
 import (
 	"context"
-	"sort"
+	"strings"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
 	"github.com/aws/aws-sdk-go-v2/service/dynamodb"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	log "github.com/sirupsen/logrus"
 )
 
 // Listing : structure used to make DynamoDB data functional
+//
+// The numeric fields are Amounts, stored as numbers; listings saved before
+// they were typed hold strings, which still read (see ParseAmount).
 type Listing struct {
 	MLS          string   `json:"MLS" dynamodbav:"MLS"`
 	Street1      string   `json:"Street1" dynamodbav:"Street1"`
@@ -20,123 +24,195 @@ type Listing struct {
 	State        string   `json:"State" dynamodbav:"State"`
 	ZipCode      string   `json:"Zip Code" dynamodbav:"Zip Code"`
 	Neighborhood string   `json:"Neighborhood" dynamodbav:"Neighborhood"`
-	SalesPrice   string   `json:"Sales Price" dynamodbav:"Sales Price"`
+	SalesPrice   Amount   `json:"Sales Price" dynamodbav:"Sales Price"`
 	DateListed   string   `json:"Date Listed" dynamodbav:"Date Listed"`
 	LastModified string   `json:"Last Modified" dynamodbav:"Last Modified"`
-	Bedrooms     string   `json:"Bedrooms" dynamodbav:"Bedrooms"`
+	Bedrooms     Amount   `json:"Bedrooms" dynamodbav:"Bedrooms"`
 	ListPhoto    string   `json:"List Photo" dynamodbav:"List Photo"`
 	PhotoArray   []string `json:"Photo Array" dynamodbav:"Photo Array"`
-	Bathrooms    string   `json:"Bathrooms" dynamodbav:"Bathrooms"`
-	GarageSize   string   `json:"Garage Size" dynamodbav:"Garage Size"`
-	SquareFeet   string   `json:"Square Feet" dynamodbav:"Square Feet"`
-	LotSize      string   `json:"Lot Size" dynamodbav:"Lot Size"`
+	Bathrooms    Amount   `json:"Bathrooms" dynamodbav:"Bathrooms"`
+	GarageSize   Amount   `json:"Garage Size" dynamodbav:"Garage Size"`
+	SquareFeet   Amount   `json:"Square Feet" dynamodbav:"Square Feet"`
+	LotSize      Amount   `json:"Lot Size" dynamodbav:"Lot Size"`
 	Description  string   `json:"Description" dynamodbav:"Description"`
 	User         string   `json:"User" dynamodbav:"User"`
 	Deleted      string   `json:"deleted" dynamodbav:"deleted"`
+	// DeletedAt (RFC 3339) and DeletedBy record when and by whom a listing
+	// was soft-deleted (see DeleteRealtorListing).
+	DeletedAt string `json:"Deleted At,omitempty" dynamodbav:"Deleted At,omitempty"`
+	DeletedBy string `json:"Deleted By,omitempty" dynamodbav:"Deleted By,omitempty"`
+	// Latitude and Longitude are set from the address by a Geocoder when the
+	// listing is saved (see WithLocation), and are zero until it's placed.
+	Latitude  float64 `json:"Latitude,omitempty" dynamodbav:"Latitude,omitempty"`
+	Longitude float64 `json:"Longitude,omitempty" dynamodbav:"Longitude,omitempty"`
+	// UserKey, CityKey and GeoCell are the partition keys of the
+	// ListingsUserIndex, ListingsCityIndex and ListingsGeoIndex GSIs, derived
+	// from User, City and Location by WithIndexKeys; Geohash is the whole
+	// geohash GeoCell is a prefix of. They're omitted when empty because
+	// DynamoDB rejects an empty string as an index key.
+	UserKey string `json:"-" dynamodbav:"user-key,omitempty"`
+	CityKey string `json:"-" dynamodbav:"city-key,omitempty"`
+	GeoCell string `json:"-" dynamodbav:"geo-cell,omitempty"`
+	Geohash string `json:"-" dynamodbav:"geohash,omitempty"`
 }
 
-// GetRealtorListings Get a list of all the current realtor listings
-func GetRealtorListings() []Listing {
-	ctx := context.TODO()
+// The Listings table is keyed by MLS, with a global secondary index on each
+// of the derived user-key, city-key and geo-cell attributes.
+const (
+	ListingsTable     = "Listings"
+	ListingsUserIndex = "user-key-index"
+	ListingsCityIndex = "city-key-index"
+	ListingsGeoIndex  = "geo-cell-index"
+)
 
-	dbSvc, err := createDynamoDBClient(ctx)
-	if err != nil {
-		log.Error("Unable to create DynamoDB client:", err)
-		return []Listing{}
+// WithIndexKeys returns the listing with UserKey, CityKey, GeoCell and
+// Geohash filled in. City keys are case-folded so ?city=bend finds listings
+// in "Bend"; a listing without a location has no geohash. Call it before
+// every write.
+func (listing Listing) WithIndexKeys() Listing {
+	listing.UserKey = listing.User
+	listing.CityKey = CityKey(listing.City)
+	listing.GeoCell, listing.Geohash = "", ""
+	if p, ok := listing.Location(); ok {
+		listing.Geohash = Geohash(p, geohashPrecision)
+		listing.GeoCell = listing.Geohash[:GeoCellPrecision]
 	}
+	return listing
+}
 
-	filt := expression.Name("deleted").NotEqual(expression.Value("anything"))
+// CityKey normalises a city name the way WithIndexKeys stores it.
+func CityKey(city string) string {
+	return strings.ToLower(strings.TrimSpace(city))
+}
 
-	proj := expression.NamesList(expression.Name("MLS"), expression.Name("Street1"), expression.Name("Street2"),
+// listingProjection is every attribute of a Listing the API returns.
+func listingProjection() expression.ProjectionBuilder {
+	return expression.NamesList(expression.Name("MLS"), expression.Name("Street1"), expression.Name("Street2"),
 		expression.Name("City"), expression.Name("State"), expression.Name("Zip Code"), expression.Name("User"),
 		expression.Name("Neighborhood"), expression.Name("Sales Price"), expression.Name("Date Listed"),
 		expression.Name("Last Modified"), expression.Name("Bedrooms"), expression.Name("List Photo"),
 		expression.Name("Photo Array"), expression.Name("Bathrooms"), expression.Name("Garage Size"),
 		expression.Name("Square Feet"), expression.Name("Lot Size"), expression.Name("Description"),
-		expression.Name("deleted"))
+		expression.Name("deleted"), expression.Name("Deleted At"), expression.Name("Deleted By"),
+		expression.Name("Latitude"), expression.Name("Longitude"))
+}
+
+// GetRealtorListings Get a list of all the current realtor listings, most
+// recently modified first, leaving out deleted ones
+func GetRealtorListings(db *dynamodb.Client) []Listing {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
 
-	expr, _ := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
+	filt := notDeleted()
+
+	expr, _ := expression.NewBuilder().WithFilter(filt).WithProjection(listingProjection()).Build()
 
 	params := &dynamodb.ScanInput{
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
 		FilterExpression:          expr.Filter(),
 		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String("Listings"),
+		TableName:                 aws.String(ListingsTable),
 	}
 
-	// Make the DynamoDB Query API call
-	result, err := dbSvc.Scan(ctx, params)
+	// Make the DynamoDB Scan API calls
+	items, err := scanAll(ctx, db, params)
 	if err != nil {
 		log.Error("Failed to scan DynamoDB:", err)
 		return []Listing{}
 	}
 
-	listings := []Listing{}
-
-	for _, i := range result.Items {
-		listing := Listing{}
+	return unmarshalListings(items)
+}
 
-		err := attributevalue.UnmarshalMapWithOptions(i, &listing, func(o *attributevalue.DecoderOptions) {
-			o.TagKey = "dynamodbav"
-		})
+// GetRealtorListingsByUser Get the listings a user has posted, most recently
+// modified first, from the ListingsUserIndex GSI
+func GetRealtorListingsByUser(db *dynamodb.Client, user string) []Listing {
+	return queryListings(db, ListingsUserIndex, "user-key", user)
+}
 
-		if err != nil {
-			log.Error("Got error unmarshalling:")
-			log.Error(err.Error())
-			return []Listing{}
-		}
+// GetRealtorListingsByCity Get the listings in a city (matched without
+// regard to case), most recently modified first, from the ListingsCityIndex
+// GSI
+func GetRealtorListingsByCity(db *dynamodb.Client, city string) []Listing {
+	return queryListings(db, ListingsCityIndex, "city-key", CityKey(city))
+}
 
-		listings = append(listings, listing)
+// GetRealtorListingsInCells Get the listings in any of the geohash cells
+// (see GeoBox.Cells), most recently modified first, from the
+// ListingsGeoIndex GSI
+func GetRealtorListingsInCells(db *dynamodb.Client, cells []string) []Listing {
+	listings := []Listing{}
+	for _, cell := range cells {
+		listings = append(listings, queryListings(db, ListingsGeoIndex, "geo-cell", cell)...)
 	}
-
-	sort.Slice(listings[:], func(i, j int) bool {
-		return listings[i].LastModified > listings[j].LastModified
-	})
-
+	sortListings(listings)
 	return listings
 }
 
-// GetRealtorListing Get a current realtor listing
-func GetRealtorListing(listing string) []Listing {
-	ctx := context.TODO()
-
-	dbSvc, err := createDynamoDBClient(ctx)
-	if err != nil {
-		log.Error("Unable to create DynamoDB client:", err)
+// queryListings runs a Query for value on one of the Listings GSIs.
+func queryListings(db *dynamodb.Client, index, key, value string) []Listing {
+	if value == "" {
 		return []Listing{}
 	}
 
-	filt := expression.Name("MLS").Equal(expression.Value(listing))
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
 
-	proj := expression.NamesList(expression.Name("MLS"), expression.Name("Street1"), expression.Name("Street2"),
-		expression.Name("City"), expression.Name("State"), expression.Name("Zip Code"), expression.Name("User"),
-		expression.Name("Neighborhood"), expression.Name("Sales Price"), expression.Name("Date Listed"),
-		expression.Name("Last Modified"), expression.Name("Bedrooms"), expression.Name("List Photo"),
-		expression.Name("Photo Array"), expression.Name("Bathrooms"), expression.Name("Garage Size"),
-		expression.Name("Square Feet"), expression.Name("Lot Size"), expression.Name("Description"),
-		expression.Name("deleted"))
+	keyCond := expression.Key(key).Equal(expression.Value(value))
 
-	expr, _ := expression.NewBuilder().WithFilter(filt).WithProjection(proj).Build()
+	expr, _ := expression.NewBuilder().WithKeyCondition(keyCond).WithProjection(listingProjection()).Build()
 
-	params := &dynamodb.ScanInput{
+	params := &dynamodb.QueryInput{
 		ExpressionAttributeNames:  expr.Names(),
 		ExpressionAttributeValues: expr.Values(),
-		FilterExpression:          expr.Filter(),
+		KeyConditionExpression:    expr.KeyCondition(),
 		ProjectionExpression:      expr.Projection(),
-		TableName:                 aws.String("Listings"),
+		IndexName:                 aws.String(index),
+		TableName:                 aws.String(ListingsTable),
 	}
 
-	// Make the DynamoDB Query API call
-	result, err := dbSvc.Scan(ctx, params)
+	items, err := queryAll(ctx, db, params)
 	if err != nil {
-		log.Error("Failed to scan DynamoDB:", err)
+		log.Error("Failed to query DynamoDB:", err)
 		return []Listing{}
 	}
 
+	return unmarshalListings(items)
+}
+
+// GetRealtorListing Get a current realtor listing by its MLS number
+func GetRealtorListing(db *dynamodb.Client, listing string) []Listing {
+	ctx, cancel := context.WithTimeout(context.Background(), dynamoReadTimeout)
+	defer cancel()
+
+	expr, _ := expression.NewBuilder().WithProjection(listingProjection()).Build()
+
+	result, err := db.GetItem(ctx, &dynamodb.GetItemInput{
+		ExpressionAttributeNames: expr.Names(),
+		ProjectionExpression:     expr.Projection(),
+		TableName:                aws.String(ListingsTable),
+		Key: map[string]types.AttributeValue{
+			"MLS": &types.AttributeValueMemberS{Value: listing},
+		},
+	})
+	if err != nil {
+		log.Error("Failed to get listing from DynamoDB:", err)
+		return []Listing{}
+	}
+	if result.Item == nil {
+		return []Listing{}
+	}
+
+	return unmarshalListings([]map[string]types.AttributeValue{result.Item})
+}
+
+// unmarshalListings converts listing items, most recently modified first.
+// An unreadable item empties the whole result, as a failed read would.
+func unmarshalListings(items []map[string]types.AttributeValue) []Listing {
 	listings := []Listing{}
 
-	for _, i := range result.Items {
+	for _, i := range items {
 		listing := Listing{}
 
 		err := attributevalue.UnmarshalMapWithOptions(i, &listing, func(o *attributevalue.DecoderOptions) {
@@ -152,9 +228,7 @@ func GetRealtorListing(listing string) []Listing {
 		listings = append(listings, listing)
 	}
 
-	sort.Slice(listings[:], func(i, j int) bool {
-		return listings[i].LastModified > listings[j].LastModified
-	})
+	sortListings(listings)
 
 	return listings
 }
@@ -2,8 +2,10 @@ package models
 
 import (
 	"os"
+	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -28,13 +30,13 @@ func TestGetRealtorListings_Simple(t *testing.T) {
 		}
 	}()
 
-	listings := GetRealtorListings()
+	listings := GetRealtorListings(testAWSClients(t).DynamoDB)
 	assert.NotNil(t, listings, "GetRealtorListings should return an empty slice, not nil, on typical scan failure with dummy credentials")
 }
 
 func TestGetRealtorListings_Real(t *testing.T) {
 	silenceLogrus(t)
-	listings := GetRealtorListings()
+	listings := GetRealtorListings(testAWSClients(t).DynamoDB)
 	assert.NotNil(t, listings, "GetRealtorListings should return an empty slice, not nil, on typical scan failure with dummy credentials")
 }
 
@@ -60,14 +62,14 @@ func TestGetRealtorListing_Simple(t *testing.T) {
 	}()
 
 	dummyMLSID := "0000000000"
-	listings := GetRealtorListing(dummyMLSID)
+	listings := GetRealtorListing(testAWSClients(t).DynamoDB, dummyMLSID)
 	assert.NotNil(t, listings, "GetRealtorListing should return an empty slice, not nil, on typical scan failure")
 }
 
 func TestGetRealtorListing_Real(t *testing.T) {
 	silenceLogrus(t)
 	realMLSID := "e0377-aed6-f12-0f66-ee8ab4edcfdc"
-	listing := GetRealtorListing(realMLSID)
+	listing := GetRealtorListing(testAWSClients(t).DynamoDB, realMLSID)
 	assert.NotNil(t, listing, "GetRealtorListing should return an empty slice, not nil, on typical scan failure")
 }
 
@@ -90,7 +92,7 @@ func TestGetRealtorListings_ErrorHandling(t *testing.T) {
 		}
 	}()
 
-	listings := GetRealtorListings()
+	listings := GetRealtorListings(testAWSClients(t).DynamoDB)
 
 	// Function should handle errors gracefully and return empty slice
 	assert.NotNil(t, listings, "GetRealtorListings should never return nil")
@@ -128,7 +130,7 @@ func TestGetRealtorListings_WithCredentials(t *testing.T) {
 		}
 	}()
 
-	listings := GetRealtorListings()
+	listings := GetRealtorListings(testAWSClients(t).DynamoDB)
 
 	assert.NotNil(t, listings, "GetRealtorListings should never return nil")
 	assert.IsType(t, []Listing{}, listings, "GetRealtorListings should return []Listing type")
@@ -184,7 +186,7 @@ func TestGetRealtorListing_ParameterVariations(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
-			listing := GetRealtorListing(tc.mlsID)
+			listing := GetRealtorListing(testAWSClients(t).DynamoDB, tc.mlsID)
 
 			assert.NotNil(t, listing, "GetRealtorListing should never return nil for MLS ID: %s", tc.mlsID)
 			assert.IsType(t, []Listing{}, listing, "GetRealtorListing should return []Listing type")
@@ -215,7 +217,7 @@ func TestGetRealtorListing_ErrorHandling(t *testing.T) {
 	}()
 
 	testMLSID := "test-mls-123"
-	listing := GetRealtorListing(testMLSID)
+	listing := GetRealtorListing(testAWSClients(t).DynamoDB, testMLSID)
 
 	// Function should handle errors gracefully
 	assert.NotNil(t, listing, "GetRealtorListing should never return nil")
@@ -227,7 +229,7 @@ func TestGetRealtorListings_DataStructure(t *testing.T) {
 	silenceLogrus(t)
 
 	// Test that the function returns proper data structure even with minimal setup
-	listings := GetRealtorListings()
+	listings := GetRealtorListings(testAWSClients(t).DynamoDB)
 
 	assert.NotNil(t, listings, "GetRealtorListings should never return nil")
 	assert.IsType(t, []Listing{}, listings, "GetRealtorListings should return []Listing slice")
@@ -255,7 +257,7 @@ func TestGetRealtorListing_DataStructure(t *testing.T) {
 	silenceLogrus(t)
 
 	testMLSID := "test-structure-123"
-	listings := GetRealtorListing(testMLSID)
+	listings := GetRealtorListing(testAWSClients(t).DynamoDB, testMLSID)
 
 	assert.NotNil(t, listings, "GetRealtorListing should never return nil")
 	assert.IsType(t, []Listing{}, listings, "GetRealtorListing should return []Listing slice")
@@ -291,7 +293,7 @@ func TestGetRealtorListings_EmptyResultHandling(t *testing.T) {
 		os.Unsetenv("AWS_SECRET_ACCESS_KEY")
 	}()
 
-	listings := GetRealtorListings()
+	listings := GetRealtorListings(testAWSClients(t).DynamoDB)
 
 	// Should handle empty results gracefully
 	assert.NotNil(t, listings, "GetRealtorListings should return empty slice, not nil")
@@ -315,7 +317,7 @@ func TestGetRealtorListing_EmptyResultHandling(t *testing.T) {
 	}()
 
 	nonExistentMLS := "definitely-does-not-exist-12345"
-	listings := GetRealtorListing(nonExistentMLS)
+	listings := GetRealtorListing(testAWSClients(t).DynamoDB, nonExistentMLS)
 
 	// Should handle empty results gracefully
 	assert.NotNil(t, listings, "GetRealtorListing should return empty slice, not nil")
@@ -326,3 +328,33 @@ func TestGetRealtorListing_EmptyResultHandling(t *testing.T) {
 		assert.Len(t, listings, 0, "Empty result should be length 0")
 	}
 }
+
+func TestListingWithIndexKeys(t *testing.T) {
+	listing := Listing{MLS: "1", User: "agent@example.com", City: "  Bend "}.WithIndexKeys()
+	assert.Equal(t, "agent@example.com", listing.UserKey)
+	assert.Equal(t, "bend", listing.CityKey)
+	assert.Equal(t, CityKey("BEND"), listing.CityKey, "City lookups should ignore case")
+
+	blank := Listing{MLS: "2"}.WithIndexKeys()
+	assert.Empty(t, blank.UserKey)
+	assert.Empty(t, blank.CityKey)
+
+	av, err := attributevalue.MarshalMap(blank)
+	assert.NoError(t, err)
+	assert.NotContains(t, av, "user-key", "Empty index keys must be left off the item")
+	assert.NotContains(t, av, "city-key")
+	assert.NotContains(t, av, "geo-cell")
+	assert.NotContains(t, av, "Latitude", "An unplaced listing has no coordinates")
+
+	placed := Listing{MLS: "3", Latitude: 44.0582, Longitude: -121.3153}.WithIndexKeys()
+	assert.Equal(t, "9rcd", placed.GeoCell)
+	assert.True(t, strings.HasPrefix(placed.Geohash, placed.GeoCell))
+	placed.Latitude, placed.Longitude = 0, 0
+	assert.Empty(t, placed.WithIndexKeys().GeoCell, "A listing that loses its location loses its geohash")
+}
+
+func TestGetRealtorListingsByIndex_EmptyValue(t *testing.T) {
+	silenceLogrus(t)
+	assert.Equal(t, []Listing{}, GetRealtorListingsByUser(nil, ""))
+	assert.Equal(t, []Listing{}, GetRealtorListingsByCity(nil, "   "))
+}
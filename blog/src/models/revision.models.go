@@ -0,0 +1,214 @@
+package models
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+)
+
+// Revision is one immutable entry in an article's history. Every write to
+// the articles table, from the admin editor or the daemon, stores one in the
+// same transaction, keyed by the article's post-id and the Version the write
+// produced (see RevisionTable). A delete stores a Deleted tombstone, so the
+// history of a deleted article survives it and can be rolled back from.
+type Revision struct {
+	PostID  int `json:"post-id" dynamodbav:"post-id"`
+	Version int `json:"version" dynamodbav:"version"`
+	// Author is who made the write: the admin's email for the editor,
+	// "daemon:<user>" for the daemon.
+	Author string `json:"author" dynamodbav:"author"`
+	// SavedAt is when the write was made, in RFC 3339.
+	SavedAt string `json:"saved-at" dynamodbav:"saved-at"`
+	// Source is what made the write, one of the RevisionSource constants.
+	Source string `json:"source" dynamodbav:"source"`
+	// Hash is Item's ContentHash; empty for a tombstone.
+	Hash    string `json:"hash,omitempty" dynamodbav:"hash,omitempty"`
+	Deleted bool   `json:"deleted,omitempty" dynamodbav:"deleted,omitempty"`
+	// Item is the article exactly as this write left it; nil for a
+	// tombstone, and in summaries (see History.Summaries).
+	Item *Item `json:"item,omitempty" dynamodbav:"item,omitempty"`
+}
+
+// Sources of a Revision.
+const (
+	RevisionSourceEditor   = "editor"
+	RevisionSourceRollback = "rollback"
+	RevisionSourcePublish  = "publish"
+	RevisionSourceSeed     = "seed"
+	RevisionSourceImport   = "import"
+	RevisionSourceMigrate  = "migrate"
+)
+
+// Change says who is making an article write and through what; the store
+// records it on the write's Revision.
+type Change struct {
+	Author string
+	Source string
+}
+
+// History is an article's revisions, oldest first.
+type History []Revision
+
+// Latest returns the newest revision.
+func (h History) Latest() (Revision, bool) {
+	if len(h) == 0 {
+		return Revision{}, false
+	}
+	return h[len(h)-1], true
+}
+
+// Version returns the revision that produced version.
+func (h History) Version(version int) (Revision, bool) {
+	for _, rev := range h {
+		if rev.Version == version {
+			return rev, true
+		}
+	}
+	return Revision{}, false
+}
+
+// Summaries returns h without the article bodies, for listing.
+func (h History) Summaries() History {
+	summaries := make(History, len(h))
+	for i, rev := range h {
+		rev.Item = nil
+		summaries[i] = rev
+	}
+	return summaries
+}
+
+// LoadHistory fills article.History from editor, as summaries.
+func (article *Article) LoadHistory(editor ArticleEditor) error {
+	history, err := editor.History(article.PostID)
+	if err != nil {
+		return err
+	}
+	article.History = history.Summaries()
+	return nil
+}
+
+// NewRevision records item, as written at item.Version, by change at t.
+func NewRevision(item Item, change Change, t time.Time) Revision {
+	item.CategorySet, item.SlugKey = nil, ""
+	return Revision{
+		PostID:  item.PostID,
+		Version: item.Version,
+		Author:  change.Author,
+		SavedAt: t.UTC().Format(time.RFC3339),
+		Source:  change.Source,
+		Hash:    item.ContentHash(),
+		Item:    &item,
+	}
+}
+
+// NewTombstone records the delete of article id, which bumped it to version.
+func NewTombstone(id, version int, change Change, t time.Time) Revision {
+	return Revision{
+		PostID:  id,
+		Version: version,
+		Author:  change.Author,
+		SavedAt: t.UTC().Format(time.RFC3339),
+		Source:  change.Source,
+		Deleted: true,
+	}
+}
+
+// ContentHash is a SHA-256 over every stored field of item but its Version
+// and derived attributes, so two revisions with the same content hash the
+// same whatever version they were written at.
+func (item Item) ContentHash() string {
+	item.Version = 0
+	item.CategorySet, item.SlugKey = nil, ""
+	raw, _ := json.Marshal(item)
+	sum := sha256.Sum256(raw)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// RevisionTable names the table holding the revisions of articlesTable:
+// ARTICLE_REVISIONS if set, otherwise articlesTable + "-Revisions". It is
+// keyed by post-id (N, hash) and version (N, range).
+func RevisionTable(articlesTable string) string {
+	if table := os.Getenv("ARTICLE_REVISIONS"); table != "" {
+		return table
+	}
+	return articlesTable + "-Revisions"
+}
+
+// itemField is one Item attribute as shown in a diff.
+type itemField struct {
+	name  string
+	value func(Item) string
+	// body fields are multi-line HTML/Markdown and get a unified diff rather
+	// than a before/after pair.
+	body bool
+}
+
+var itemFields = []itemField{
+	{name: "post-title", value: func(i Item) string { return i.PostTitle }},
+	{name: "short-title", value: func(i Item) string { return i.ShortTitle }},
+	{name: "slug", value: func(i Item) string { return i.ResolvedSlug() }},
+	{name: "post-type", value: func(i Item) string { return i.PostType }},
+	{name: "status", value: func(i Item) string { return i.Status }},
+	{name: "publish-at", value: func(i Item) string { return i.PublishAt }},
+	{name: "author", value: func(i Item) string { return i.Author }},
+	{name: "categories", value: func(i Item) string { return i.Categories }},
+	{name: "created-date", value: func(i Item) string { return i.CreatedDate }},
+	{name: "modified-date", value: func(i Item) string { return i.ModifiedDate }},
+	{name: "excerpt", value: func(i Item) string { return i.Excerpt }},
+	{name: "html-hold", value: func(i Item) string { return i.HTMLHold }, body: true},
+	{name: "markdown", value: func(i Item) string { return i.Markdown }, body: true},
+	{name: "article-picture", value: func(i Item) string { return i.ArticlePicture }, body: true},
+	{name: "panel-picture", value: func(i Item) string { return i.PanelPicture }, body: true},
+}
+
+// DiffItems reports, field by field, what changed from before to after,
+// labelling the two sides from and to. A nil side is an article that doesn't
+// exist (yet, or any more). The result is empty when nothing changed.
+func DiffItems(before, after *Item, from, to string) string {
+	a, b := Item{}, Item{}
+	if before != nil {
+		a = *before
+	}
+	if after != nil {
+		b = *after
+	}
+
+	var out strings.Builder
+	for _, field := range itemFields {
+		old, cur := field.value(a), field.value(b)
+		if old == cur {
+			continue
+		}
+		if !field.body {
+			fmt.Fprintf(&out, "%s:\n  - %q\n  + %q\n", field.name, old, cur)
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        diffLines(old),
+			B:        diffLines(cur),
+			FromFile: from + "/" + field.name,
+			ToFile:   to + "/" + field.name,
+			Context:  3,
+		})
+		if err != nil {
+			diff = err.Error() + "\n"
+		}
+		fmt.Fprintf(&out, "%s:\n%s", field.name, diff)
+	}
+	return out.String()
+}
+
+// diffLines splits s into newline-terminated lines, with no phantom blank
+// line for a trailing newline or for an empty field.
+func diffLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return difflib.SplitLines(strings.TrimSuffix(s, "\n"))
+}
@@ -0,0 +1,130 @@
+package models
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryArticleStore_History(t *testing.T) {
+	store := NewMemoryArticleStore()
+
+	created, err := store.CreateItem(Item{PostID: 1, PostTitle: "First", PostType: "standard", Markdown: "one", Categories: "Go"}, editorChange)
+	require.NoError(t, err)
+	created.Markdown = "two"
+	updated, err := store.UpdateItem(created, Change{Author: "daemon:ci", Source: RevisionSourcePublish})
+	require.NoError(t, err)
+	require.NoError(t, store.DeleteItem(1, updated.Version, editorChange))
+
+	// A re-created article carries on from the tombstone's version.
+	again, err := store.CreateItem(Item{PostID: 1, PostTitle: "Again", PostType: "standard", Markdown: "three"}, editorChange)
+	require.NoError(t, err)
+	assert.Equal(t, 4, again.Version)
+
+	history, err := store.History(1)
+	require.NoError(t, err)
+	require.Len(t, history, 4)
+	for i, rev := range history {
+		assert.Equal(t, i+1, rev.Version, "Oldest first, one revision per write")
+		assert.Equal(t, 1, rev.PostID)
+		_, err := time.Parse(time.RFC3339, rev.SavedAt)
+		assert.NoError(t, err)
+	}
+	assert.Equal(t, "admin@example.com", history[0].Author)
+	assert.Equal(t, RevisionSourceEditor, history[0].Source)
+	assert.Equal(t, "one", history[0].Item.Markdown)
+	assert.Nil(t, history[0].Item.CategorySet, "Revisions hold the item as written, not its index attributes")
+	assert.Equal(t, "daemon:ci", history[1].Author)
+	assert.Equal(t, history[1].Item.ContentHash(), history[1].Hash)
+	assert.True(t, history[2].Deleted)
+	assert.Nil(t, history[2].Item)
+	assert.Empty(t, history[2].Hash)
+
+	latest, ok := history.Latest()
+	require.True(t, ok)
+	assert.Equal(t, "three", latest.Item.Markdown)
+	rev, ok := history.Version(2)
+	require.True(t, ok)
+	assert.Equal(t, "two", rev.Item.Markdown)
+	_, ok = history.Version(9)
+	assert.False(t, ok)
+
+	for _, summary := range history.Summaries() {
+		assert.Nil(t, summary.Item)
+	}
+	assert.NotNil(t, history[0].Item, "Summaries doesn't touch the History it was called on")
+
+	none, err := store.History(2)
+	require.NoError(t, err)
+	assert.Empty(t, none)
+	_, ok = none.Latest()
+	assert.False(t, ok)
+
+	// A failed write records nothing.
+	_, err = store.UpdateItem(created, editorChange)
+	require.ErrorIs(t, err, ErrArticleConflict)
+	history, err = store.History(1)
+	require.NoError(t, err)
+	assert.Len(t, history, 4)
+}
+
+func TestArticleLoadHistory(t *testing.T) {
+	store := NewMemoryArticleStore()
+	item, err := store.CreateItem(Item{PostID: 5, PostTitle: "Loaded", PostType: "standard", HTMLHold: "<p>x</p>"}, editorChange)
+	require.NoError(t, err)
+
+	article := item.Article()
+	require.NoError(t, article.LoadHistory(store))
+	require.Len(t, article.History, 1)
+	assert.Equal(t, 1, article.History[0].Version)
+	assert.Nil(t, article.History[0].Item)
+}
+
+func TestItemContentHash(t *testing.T) {
+	item := Item{PostID: 1, PostTitle: "Hash", Markdown: "body", Version: 3}
+	bumped := item.WithIndexAttributes()
+	bumped.Version = 7
+	assert.Equal(t, item.ContentHash(), bumped.ContentHash(), "Version and index attributes aren't content")
+	assert.Regexp(t, `^sha256:[0-9a-f]{64}$`, item.ContentHash())
+
+	item.Markdown = "changed"
+	assert.NotEqual(t, bumped.ContentHash(), item.ContentHash())
+}
+
+func TestRevisionTable(t *testing.T) {
+	t.Setenv("ARTICLE_REVISIONS", "")
+	assert.Equal(t, "Articles-Revisions", RevisionTable("Articles"))
+	t.Setenv("ARTICLE_REVISIONS", "History")
+	assert.Equal(t, "History", RevisionTable("Articles"))
+}
+
+func TestDiffItems(t *testing.T) {
+	before := Item{PostID: 1, PostTitle: "Old", Markdown: "one\ntwo\n"}
+	after := Item{PostID: 1, PostTitle: "New", Markdown: "one\nthree\n"}
+
+	diff := DiffItems(&before, &after, "v1", "v2")
+	assert.Contains(t, diff, "post-title:\n  - \"Old\"\n  + \"New\"\n")
+	assert.Contains(t, diff, "--- v1/markdown\n+++ v2/markdown\n")
+	assert.Contains(t, diff, "-two\n+three\n")
+	assert.NotContains(t, diff, "html-hold")
+
+	assert.Empty(t, DiffItems(&before, &before, "v1", "v1"))
+	assert.Contains(t, DiffItems(nil, &after, "none", "v2"), "+three\n", "A missing side diffs as an empty article")
+}
+
+func TestTransactionConditionFailed(t *testing.T) {
+	err := &types.TransactionCanceledException{CancellationReasons: []types.CancellationReason{
+		{Code: aws.String("None")},
+		{Code: aws.String("ConditionalCheckFailed")},
+	}}
+	assert.False(t, transactionConditionFailed(err, 0))
+	assert.True(t, transactionConditionFailed(err, 1))
+	assert.False(t, transactionConditionFailed(err, 2))
+	assert.False(t, transactionConditionFailed(errors.New("throttled"), 0))
+	assert.False(t, transactionConditionFailed(nil, 0))
+}
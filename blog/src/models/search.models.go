@@ -0,0 +1,334 @@
+package models
+
+import (
+	"html"
+	"html/template"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/microcosm-cc/bluemonday"
+	log "github.com/sirupsen/logrus"
+)
+
+// Field weights: a hit in the title counts five times one in the body.
+const (
+	searchWeightTitle    = 5.0
+	searchWeightCategory = 3.0
+	searchWeightExcerpt  = 2.0
+	searchWeightBody     = 1.0
+)
+
+// SearchResultLimit caps how many panels a search returns.
+const SearchResultLimit = 20
+
+// snippetRadius is roughly how many characters of context a snippet keeps
+// on each side of the first hit.
+const snippetRadius = 100
+
+// searchStopWords are too common to be worth indexing.
+var searchStopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "are": true, "as": true, "at": true,
+	"be": true, "by": true, "for": true, "from": true, "in": true, "is": true,
+	"it": true, "of": true, "on": true, "or": true, "that": true, "the": true,
+	"this": true, "to": true, "with": true,
+}
+
+// textPolicy strips every tag (and script/style contents) to get at the
+// readable text of an article body, leaving a space where each tag was so
+// "<p>one</p><p>two</p>" doesn't index as "onetwo".
+var textPolicy = func() *bluemonday.Policy {
+	p := bluemonday.StrictPolicy()
+	p.AddSpaceWhenStrippingTag(true)
+	return p
+}()
+
+// searchDoc is one indexed article: its panel, for rendering results, and
+// its plain-text body, for snippets.
+type searchDoc struct {
+	panel Article
+	text  string
+}
+
+// SearchIndex : an in-process inverted index over every non-quote article's
+// title, categories, excerpt and body text. It's built lazily on the first
+// search and rebuilt by Refresh whenever the article set changes; safe for
+// concurrent use.
+type SearchIndex struct {
+	articles ArticleStore
+
+	mu          sync.RWMutex
+	built       bool
+	fingerprint string
+	docs        map[int]searchDoc
+	// postings maps a term to the field-weighted count of its occurrences
+	// in each article.
+	postings map[string]map[int]float64
+}
+
+// NewSearchIndex returns an empty index over articles. Pass a PanelSnapshot
+// so checking for changes costs no read of its own.
+func NewSearchIndex(articles ArticleStore) *SearchIndex {
+	return &SearchIndex{articles: articles}
+}
+
+// Refresh rebuilds the index if the article panels have changed since the
+// last build (a post added, removed, retitled or given a new ModifiedDate)
+// and reports whether it did.
+func (s *SearchIndex) Refresh() bool {
+	panels := s.articles.GetArticlePanels()
+	fingerprint := PanelFingerprint(panels)
+
+	s.mu.RLock()
+	current := s.built && s.fingerprint == fingerprint
+	s.mu.RUnlock()
+	if current {
+		return false
+	}
+
+	s.build(panels, fingerprint)
+	return true
+}
+
+// Rebuild re-reads every article and rebuilds the index unconditionally.
+func (s *SearchIndex) Rebuild() {
+	panels := s.articles.GetArticlePanels()
+	s.build(panels, PanelFingerprint(panels))
+}
+
+func (s *SearchIndex) build(panels []Article, fingerprint string) {
+	docs := map[int]searchDoc{}
+	postings := map[string]map[int]float64{}
+	add := func(id int, text string, weight float64) {
+		for _, term := range searchTerms(text) {
+			if postings[term] == nil {
+				postings[term] = map[int]float64{}
+			}
+			postings[term][id] += weight
+		}
+	}
+
+	for _, panel := range panels {
+		if panel.PostType == "quote" || panel.PostType == "" {
+			continue
+		}
+		article, err := s.articles.GetArticleByID(panel.PostID)
+		if err != nil {
+			log.WithError(err).WithField("post_id", panel.PostID).Error("Search index: unable to load article")
+			continue
+		}
+
+		body := plainTextHTML(string(article.HTMLHold))
+		excerpt := plainTextHTML(string(panel.Excerpt))
+		docs[panel.PostID] = searchDoc{panel: panel, text: body}
+
+		add(panel.PostID, panel.PostTitle, searchWeightTitle)
+		add(panel.PostID, strings.Join(categoryNames(panel), " "), searchWeightCategory)
+		add(panel.PostID, excerpt, searchWeightExcerpt)
+		add(panel.PostID, body, searchWeightBody)
+	}
+
+	s.mu.Lock()
+	s.docs = docs
+	s.postings = postings
+	s.fingerprint = fingerprint
+	s.built = true
+	s.mu.Unlock()
+
+	log.WithFields(log.Fields{"articles": len(docs), "terms": len(postings)}).Info("Search index built")
+}
+
+// Search returns up to SearchResultLimit article panels matching query,
+// best match first. Each panel's Excerpt is replaced with a snippet of the
+// article around the first hit, with matching words wrapped in <mark>.
+func (s *SearchIndex) Search(query string) []Article {
+	terms := uniqueTerms(searchTerms(query))
+	if len(terms) == 0 {
+		return []Article{}
+	}
+
+	s.mu.RLock()
+	built := s.built
+	s.mu.RUnlock()
+	if !built {
+		s.Refresh()
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// TF-IDF over the weighted counts, scaled by the share of query terms a
+	// document contains so articles matching every word rank first.
+	scores := map[int]float64{}
+	matched := map[int]int{}
+	for _, term := range terms {
+		posting := s.postings[term]
+		if len(posting) == 0 {
+			continue
+		}
+		idf := math.Log(1 + float64(len(s.docs))/float64(len(posting)))
+		for id, tf := range posting {
+			scores[id] += (1 + math.Log(tf)) * idf
+			matched[id]++
+		}
+	}
+
+	ids := make([]int, 0, len(scores))
+	for id := range scores {
+		scores[id] *= float64(matched[id]) / float64(len(terms))
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if scores[ids[i]] != scores[ids[j]] {
+			return scores[ids[i]] > scores[ids[j]]
+		}
+		return ids[i] > ids[j]
+	})
+	if len(ids) > SearchResultLimit {
+		ids = ids[:SearchResultLimit]
+	}
+
+	results := []Article{}
+	for _, id := range ids {
+		doc := s.docs[id]
+		panel := doc.panel
+		text := doc.text
+		if text == "" {
+			text = plainTextHTML(string(panel.Excerpt))
+		}
+		panel.Excerpt = highlightSnippet(text, terms)
+		results = append(results, panel)
+	}
+	return results
+}
+
+// searchTerms lower-cases text and splits it into indexable words.
+func searchTerms(text string) []string {
+	terms := []string{}
+	for _, word := range strings.FieldsFunc(strings.ToLower(text), isNotWordRune) {
+		if !searchStopWords[word] {
+			terms = append(terms, word)
+		}
+	}
+	return terms
+}
+
+func uniqueTerms(terms []string) []string {
+	seen := map[string]bool{}
+	unique := []string{}
+	for _, term := range terms {
+		if !seen[term] {
+			seen[term] = true
+			unique = append(unique, term)
+		}
+	}
+	return unique
+}
+
+func isNotWordRune(r rune) bool {
+	return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+}
+
+// plainTextHTML returns the readable text of an HTML fragment with runs of
+// whitespace collapsed.
+func plainTextHTML(s string) string {
+	return strings.Join(strings.Fields(html.UnescapeString(textPolicy.Sanitize(s))), " ")
+}
+
+// highlightSnippet cuts a window of text around the first word matching one
+// of terms, HTML-escapes it and wraps every matching word in <mark>.
+func highlightSnippet(text string, terms []string) template.HTML {
+	want := map[string]bool{}
+	for _, term := range terms {
+		want[term] = true
+	}
+
+	type span struct{ start, end int }
+	words := []span{}
+	start := -1
+	for i, r := range text + " " {
+		if isNotWordRune(r) {
+			if start >= 0 {
+				words = append(words, span{start, i})
+				start = -1
+			}
+		} else if start < 0 {
+			start = i
+		}
+	}
+
+	first := 0
+	for _, w := range words {
+		if want[strings.ToLower(text[w.start:w.end])] {
+			first = w.start
+			break
+		}
+	}
+
+	from, to := first-snippetRadius, first+2*snippetRadius
+	if from < 0 {
+		to -= from
+		from = 0
+	}
+	if to > len(text) {
+		to = len(text)
+	}
+	// Snap both ends outwards to word boundaries so no word is cut in half,
+	// then to rune boundaries in case an end sits inside punctuation.
+	for _, w := range words {
+		if w.start < from && w.end > from {
+			from = w.start
+		}
+		if w.start < to && w.end > to {
+			to = w.end
+		}
+	}
+	for from > 0 && !utf8.RuneStart(text[from]) {
+		from--
+	}
+	for to < len(text) && !utf8.RuneStart(text[to]) {
+		to++
+	}
+
+	var b strings.Builder
+	if from > 0 {
+		b.WriteString("&hellip; ")
+	}
+	pos := from
+	for _, w := range words {
+		if w.start < from || w.end > to {
+			continue
+		}
+		if !want[strings.ToLower(text[w.start:w.end])] {
+			continue
+		}
+		b.WriteString(html.EscapeString(text[pos:w.start]))
+		b.WriteString("<mark>" + html.EscapeString(text[w.start:w.end]) + "</mark>")
+		pos = w.end
+	}
+	b.WriteString(html.EscapeString(text[pos:to]))
+	if to < len(text) {
+		b.WriteString(" &hellip;")
+	}
+	return template.HTML(b.String())
+}
+
+// PanelFingerprint summarises the fields that change when an article is
+// published or goes live, so Refresh (and the page cache watcher in app.go)
+// can tell whether anything needs rebuilding.
+func PanelFingerprint(panels []Article) string {
+	var b strings.Builder
+	for _, panel := range panels {
+		b.WriteString(strconv.Itoa(panel.PostID))
+		b.WriteString("\x00" + panel.PostTitle)
+		b.WriteString("\x00" + panel.ModifiedDate)
+		b.WriteString("\x00" + strings.Join(categoryNames(panel), ","))
+		b.WriteString("\x00" + string(panel.Excerpt))
+		b.WriteString("\x00" + panel.PostType + "\x01")
+	}
+	return b.String()
+}
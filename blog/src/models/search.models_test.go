@@ -0,0 +1,114 @@
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testSearchStore holds three articles written so each query below has one
+// clear best match, plus a quote the index must ignore.
+func testSearchStore() *MemoryArticleStore {
+	return NewMemoryArticleStore(
+		Item{
+			Categories:   "Distributed Systems",
+			Excerpt:      "A REST-accessible graph store.",
+			HTMLHold:     "<p>Each replica stores the graph.</p><p>Raft keeps the replicas consistent.</p>",
+			ModifiedDate: "August 10th, 2019",
+			PostID:       0,
+			PostTitle:    "Fault Tolerant Graph Store",
+			PostType:     "standard",
+		},
+		Item{
+			Categories:   "Cloud Services,Distributed Systems",
+			Excerpt:      "Running Spark &amp; Hadoop on AWS.",
+			HTMLHold:     "<div><script>var graph = 1;</script><p>EMR clusters scale out.</p></div>",
+			ModifiedDate: "November 26th, 2020",
+			PostID:       4,
+			PostTitle:    "AWS's Elastic Map Reduce Offering",
+			PostType:     "standard",
+		},
+		Item{
+			Categories:   "Disciplines",
+			Excerpt:      "Lessons from the SRE book.",
+			HTMLHold:     "<p>" + strings.Repeat("Toil is bad. ", 40) + "Consistent on-call rotations matter.</p>",
+			ModifiedDate: "March 18th, 2022",
+			PostID:       2,
+			PostTitle:    "Site Reliability Engineering",
+			PostType:     "standard",
+		},
+		Item{
+			Author:   "Leslie Lamport",
+			Excerpt:  "A distributed system is one in which the failure of a computer you didn't even know existed can render your own computer unusable.",
+			PostID:   3,
+			PostType: "quote",
+		},
+	)
+}
+
+func searchIDs(results []Article) []int {
+	ids := []int{}
+	for _, result := range results {
+		ids = append(ids, result.PostID)
+	}
+	return ids
+}
+
+func TestSearchIndex_Ranking(t *testing.T) {
+	silenceLogrus(t)
+	index := NewSearchIndex(testSearchStore())
+
+	assert.Equal(t, []int{0}, searchIDs(index.Search("graph")), "Title hits should match; script contents should not be indexed")
+	assert.Equal(t, []int{4, 0}, searchIDs(index.Search("Distributed Systems")), "Category hits should match, ties broken newest first")
+	assert.Equal(t, []int{2, 0}, searchIDs(index.Search("consistent")), "A repeated body term outranks a single mention")
+	assert.Equal(t, []int{0, 2}, searchIDs(index.Search("raft consistent")), "Articles matching every term should rank first")
+	assert.Equal(t, []int{4}, searchIDs(index.Search("HADOOP")), "Excerpts should be indexed, case-insensitively")
+	assert.Empty(t, index.Search("computer"), "Quotes should not be indexed")
+	assert.Empty(t, index.Search("the"), "Stop words alone should match nothing")
+	assert.Empty(t, index.Search("   "))
+}
+
+func TestSearchIndex_Snippet(t *testing.T) {
+	silenceLogrus(t)
+	index := NewSearchIndex(testSearchStore())
+
+	results := index.Search("raft")
+	require.Len(t, results, 1)
+	assert.Equal(t, "Each replica stores the graph. <mark>Raft</mark> keeps the replicas consistent.", string(results[0].Excerpt))
+
+	results = index.Search("rotations")
+	require.Len(t, results, 1)
+	snippet := string(results[0].Excerpt)
+	assert.True(t, strings.HasPrefix(snippet, "&hellip; "), "Long bodies should be trimmed around the hit, got %q", snippet)
+	assert.Contains(t, snippet, "<mark>rotations</mark>")
+	assert.Less(t, len(snippet), 400)
+}
+
+func TestHighlightSnippet_Escapes(t *testing.T) {
+	out := highlightSnippet("Use <b> & grep", []string{"grep"})
+
+	assert.Equal(t, "Use &lt;b&gt; &amp; <mark>grep</mark>", string(out))
+}
+
+func TestSearchIndex_Refresh(t *testing.T) {
+	silenceLogrus(t)
+	store := testSearchStore()
+	index := NewSearchIndex(store)
+
+	assert.True(t, index.Refresh(), "The first Refresh should build the index")
+	assert.False(t, index.Refresh(), "Refresh should be a no-op when nothing changed")
+	assert.Empty(t, index.Search("kubernetes"))
+
+	store.Put(Item{
+		HTMLHold:     "<p>Pods and nodes.</p>",
+		ModifiedDate: "October 1st, 2026",
+		PostID:       9,
+		PostTitle:    "Kubernetes",
+		PostType:     "standard",
+	})
+
+	assert.True(t, index.Refresh(), "A new article should trigger a rebuild")
+	assert.Equal(t, []int{9}, searchIDs(index.Search("kubernetes")))
+}
@@ -0,0 +1,86 @@
+package models
+
+import (
+	"encoding/xml"
+	"net/url"
+	"sort"
+	"time"
+)
+
+// URLSet : a sitemaps.org <urlset> document.
+type URLSet struct {
+	XMLName xml.Name     `xml:"http://www.sitemaps.org/schemas/sitemap/0.9 urlset"`
+	URLs    []SitemapURL `xml:"url"`
+}
+
+// SitemapURL : one <url> entry in a sitemap.
+type SitemapURL struct {
+	Loc      string `xml:"loc"`
+	LastMod  string `xml:"lastmod,omitempty"`
+	Priority string `xml:"priority,omitempty"`
+}
+
+// BuildSitemap lists the pages worth crawling, derived from the article
+// panels: the home page, /posts, every article page, every category any
+// article belongs to, and the remaining static pages. Quotes (and posts
+// with no type) are left out, the same posts ArticlePage refuses to render.
+func BuildSitemap(panels []Article) URLSet {
+	site := SiteURL()
+
+	var latest time.Time
+	articles := []SitemapURL{}
+	categories := map[string]time.Time{}
+	for _, panel := range panels {
+		if panel.PostType == "quote" || panel.PostType == "" {
+			continue
+		}
+		modified := articleUpdated(panel)
+		if modified.After(latest) {
+			latest = modified
+		}
+		articles = append(articles, SitemapURL{
+			Loc:      site + "/posts/" + panel.Slug,
+			LastMod:  sitemapDate(modified),
+			Priority: "0.64",
+		})
+		for _, name := range categoryNames(panel) {
+			if seen, ok := categories[name]; !ok || modified.After(seen) {
+				categories[name] = modified
+			}
+		}
+	}
+
+	names := make([]string, 0, len(categories))
+	for name := range categories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	urls := []SitemapURL{
+		{Loc: site + "/", LastMod: sitemapDate(latest), Priority: "1.00"},
+		{Loc: site + "/posts", LastMod: sitemapDate(latest), Priority: "0.80"},
+	}
+	for _, name := range names {
+		urls = append(urls, SitemapURL{
+			Loc:      site + "/category/" + url.PathEscape(name),
+			LastMod:  sitemapDate(categories[name]),
+			Priority: "0.80",
+		})
+	}
+	urls = append(urls, articles...)
+	urls = append(urls,
+		SitemapURL{Loc: site + "/contact", Priority: "0.51"},
+		SitemapURL{Loc: site + "/realtor", Priority: "0.51"},
+	)
+
+	return URLSet{URLs: urls}
+}
+
+// sitemapDate formats t as a W3C date, or "" (omitting <lastmod>) when the
+// article's dates didn't parse.
+func sitemapDate(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.Format("2006-01-02")
+}
@@ -0,0 +1,45 @@
+package models
+
+import (
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildSitemap(t *testing.T) {
+	t.Setenv("SITE_URL", "https://example.com")
+	store := NewMemoryArticleStore(testArticleItems()...)
+
+	sitemap := BuildSitemap(store.GetArticlePanels())
+
+	byLoc := map[string]SitemapURL{}
+	for _, u := range sitemap.URLs {
+		byLoc[u.Loc] = u
+	}
+
+	assert.Equal(t, SitemapURL{Loc: "https://example.com/", LastMod: "2022-05-03", Priority: "1.00"}, byLoc["https://example.com/"])
+	assert.Equal(t, "2022-05-03", byLoc["https://example.com/posts"].LastMod)
+
+	assert.Equal(t, "2019-08-10", byLoc["https://example.com/posts/fault-tolerant-graph-store-api"].LastMod, "Articles should carry their ModifiedDate")
+	assert.Contains(t, byLoc, "https://example.com/posts/infrastructure-as-code")
+	assert.Len(t, byLoc, 2+5+5+2, "Home and /posts, five articles (the quote excluded), five categories, contact and realtor")
+
+	assert.Equal(t, "2022-05-03", byLoc["https://example.com/category/Distributed%20Systems"].LastMod, "Categories should carry their newest post's date")
+	assert.Equal(t, "2019-08-10", byLoc["https://example.com/category/My%20Projects"].LastMod)
+	assert.Contains(t, byLoc, "https://example.com/category/Site%20Reliability")
+	assert.NotContains(t, byLoc, "https://example.com/category/Quotes", "Categories only quotes use should be excluded")
+
+	raw, err := xml.Marshal(sitemap)
+	require.NoError(t, err)
+	assert.Contains(t, string(raw), `<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">`)
+}
+
+func TestBuildSitemap_UnparseableDates(t *testing.T) {
+	sitemap := BuildSitemap([]Article{{PostID: 9, PostType: "standard", ModifiedDate: "someday"}})
+
+	for _, u := range sitemap.URLs {
+		assert.Empty(t, u.LastMod, "%s: lastmod should be omitted when the date doesn't parse", u.Loc)
+	}
+}
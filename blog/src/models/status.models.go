@@ -0,0 +1,116 @@
+package models
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Publication statuses an Item can carry in its status attribute. An item
+// with no status is treated as published, so rows written before statuses
+// existed stay public.
+const (
+	// StatusDraft : never public; readable only through a signed preview link.
+	StatusDraft = "draft"
+	// StatusScheduled : a draft until PublishAt, then treated as published.
+	StatusScheduled = "scheduled"
+	// StatusPublished : listed everywhere and readable at its URL.
+	StatusPublished = "published"
+	// StatusArchived : dropped from the listings, feeds, sitemap and search
+	// but still readable at its URL so existing links keep working.
+	StatusArchived = "archived"
+)
+
+// ValidStatus reports whether status is one of the publication statuses
+// (or empty, meaning published).
+func ValidStatus(status string) bool {
+	switch status {
+	case "", StatusDraft, StatusScheduled, StatusPublished, StatusArchived:
+		return true
+	}
+	return false
+}
+
+// ParsePublishAt parses a scheduled post's publish-at attribute, an RFC 3339
+// timestamp such as "2026-11-01T09:00:00-07:00".
+func ParsePublishAt(s string) (time.Time, error) {
+	return time.Parse(time.RFC3339, s)
+}
+
+// Listed reports whether the article belongs on the listing pages, feeds,
+// sitemap and search index at now: it is published, or scheduled and its
+// PublishAt has passed.
+func (a Article) Listed(now time.Time) bool {
+	switch a.Status {
+	case "", StatusPublished:
+		return true
+	case StatusScheduled:
+		at, err := ParsePublishAt(a.PublishAt)
+		return err == nil && !now.Before(at)
+	}
+	return false
+}
+
+// Public reports whether the article may be served at its URL at now
+// without a preview link: it is Listed, or archived.
+func (a Article) Public(now time.Time) bool {
+	return a.Listed(now) || a.Status == StatusArchived
+}
+
+// listedPanels filters panels down to those Listed at now.
+func listedPanels(panels []Article, now time.Time) []Article {
+	listed := []Article{}
+	for _, panel := range panels {
+		if panel.Listed(now) {
+			listed = append(listed, panel)
+		}
+	}
+	return listed
+}
+
+// PreviewSecret is the key preview links are signed with, from the
+// PREVIEW_SECRET environment variable. Previews are disabled when it's unset.
+func PreviewSecret() string {
+	return os.Getenv("PREVIEW_SECRET")
+}
+
+// PreviewSignature is the hex HMAC-SHA256 of the post id and expiry time
+// under secret.
+func PreviewSignature(secret string, id int, expires time.Time) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d:%d", id, expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// PreviewURL returns a link that shows the article with the given id,
+// whatever its status, until expires.
+func PreviewURL(site, secret string, id int, expires time.Time) string {
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires.Unix(), 10))
+	query.Set("signature", PreviewSignature(secret, id, expires))
+	return fmt.Sprintf("%s/preview/%d?%s", site, id, query.Encode())
+}
+
+// ValidPreview reports whether signature is a PreviewSignature for id and
+// the Unix time expires under secret, and that it hasn't expired at now. An
+// empty secret never validates.
+func ValidPreview(secret string, id int, expires string, signature string, now time.Time) bool {
+	if secret == "" {
+		return false
+	}
+	unix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return false
+	}
+	at := time.Unix(unix, 0)
+	if now.After(at) {
+		return false
+	}
+	want := PreviewSignature(secret, id, at)
+	return hmac.Equal([]byte(signature), []byte(want))
+}
@@ -0,0 +1,98 @@
+package models
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidStatus(t *testing.T) {
+	for _, status := range []string{"", StatusDraft, StatusScheduled, StatusPublished, StatusArchived} {
+		assert.True(t, ValidStatus(status), status)
+	}
+	assert.False(t, ValidStatus("live"))
+	assert.False(t, ValidStatus("Draft"))
+}
+
+func TestArticleListedAndPublic(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		name           string
+		article        Article
+		listed, public bool
+	}{
+		{"NoStatus", Article{}, true, true},
+		{"Published", Article{Status: StatusPublished}, true, true},
+		{"Draft", Article{Status: StatusDraft}, false, false},
+		{"Archived", Article{Status: StatusArchived}, false, true},
+		{"ScheduledFuture", Article{Status: StatusScheduled, PublishAt: "2026-10-18T12:00:01Z"}, false, false},
+		{"ScheduledNow", Article{Status: StatusScheduled, PublishAt: "2026-10-18T12:00:00Z"}, true, true},
+		{"ScheduledPastOffset", Article{Status: StatusScheduled, PublishAt: "2026-10-18T04:59:00-07:00"}, true, true},
+		{"ScheduledUnparseable", Article{Status: StatusScheduled, PublishAt: "October 1st, 2026"}, false, false},
+		{"Unknown", Article{Status: "live"}, false, false},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.listed, tc.article.Listed(now))
+			assert.Equal(t, tc.public, tc.article.Public(now))
+		})
+	}
+}
+
+func TestMemoryArticleStore_HonoursStatus(t *testing.T) {
+	items := testArticleItems()
+	items[0].Status = StatusDraft
+	items[1].Status = StatusArchived
+	items[2].Status = StatusScheduled
+	items[2].PublishAt = time.Now().Add(time.Hour).Format(time.RFC3339)
+	items[4].Status = StatusScheduled
+	items[4].PublishAt = time.Now().Add(-time.Hour).Format(time.RFC3339)
+	store := NewMemoryArticleStore(items...)
+
+	ids := []int{}
+	for _, panel := range store.GetArticlePanels() {
+		ids = append(ids, panel.PostID)
+	}
+	assert.Equal(t, []int{5, 4, 3}, ids, "Drafts, archived and future posts should be left off the front page")
+
+	ids = []int{}
+	for _, panel := range store.GetCategoryPageArticlePanels("Distributed Systems") {
+		ids = append(ids, panel.PostID)
+	}
+	assert.Equal(t, []int{4, 5}, ids)
+
+	draft, err := store.GetArticleByID(0)
+	require.NoError(t, err, "Direct lookups still return drafts so previews work")
+	assert.Equal(t, StatusDraft, draft.Status)
+
+	archived, err := store.GetArticleBySlug("google-sre")
+	require.NoError(t, err)
+	assert.True(t, archived.Public(time.Now()))
+}
+
+func TestPreviewURL(t *testing.T) {
+	now := time.Date(2026, time.October, 18, 12, 0, 0, 0, time.UTC)
+	expires := now.Add(24 * time.Hour)
+
+	link := PreviewURL("https://example.com", "s3cret", 7, expires)
+	require.True(t, strings.HasPrefix(link, "https://example.com/preview/7?"), link)
+
+	parsed, err := url.Parse(link)
+	require.NoError(t, err)
+	query := parsed.Query()
+	assert.Equal(t, strconv.FormatInt(expires.Unix(), 10), query.Get("expires"))
+
+	assert.True(t, ValidPreview("s3cret", 7, query.Get("expires"), query.Get("signature"), now))
+	assert.False(t, ValidPreview("s3cret", 8, query.Get("expires"), query.Get("signature"), now), "Signature is bound to the post id")
+	assert.False(t, ValidPreview("other", 7, query.Get("expires"), query.Get("signature"), now), "Signature is bound to the secret")
+	assert.False(t, ValidPreview("s3cret", 7, strconv.FormatInt(expires.Unix()+1, 10), query.Get("signature"), now), "Signature is bound to the expiry")
+	assert.False(t, ValidPreview("s3cret", 7, query.Get("expires"), query.Get("signature"), expires.Add(time.Second)), "Links expire")
+	assert.False(t, ValidPreview("s3cret", 7, "soon", query.Get("signature"), now))
+	assert.False(t, ValidPreview("", 7, query.Get("expires"), PreviewSignature("", 7, expires), now), "Previews are off without a secret")
+}
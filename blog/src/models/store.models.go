@@ -0,0 +1,275 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// MemoryArticleStore : ArticleStore that keeps Items in a map. Used by tests
+// in place of a live DynamoDB table; safe for concurrent use.
+type MemoryArticleStore struct {
+	mu        sync.RWMutex
+	items     map[int]Item
+	revisions map[int]History
+}
+
+// NewMemoryArticleStore returns a MemoryArticleStore seeded with items.
+func NewMemoryArticleStore(items ...Item) *MemoryArticleStore {
+	s := &MemoryArticleStore{items: map[int]Item{}, revisions: map[int]History{}}
+	for _, item := range items {
+		s.items[item.PostID] = item
+	}
+	return s
+}
+
+// Put inserts or replaces the Item with the same PostID.
+func (s *MemoryArticleStore) Put(item Item) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.items[item.PostID] = item
+}
+
+// GetArticlePanels Return a list of all the article panels for the Front Page
+func (s *MemoryArticleStore) GetArticlePanels() []Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return articlePanels(s.snapshot())
+}
+
+// GetAllArticlePanels returns every article's panel in memory, newest first
+func (s *MemoryArticleStore) GetAllArticlePanels() []Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return allArticlePanels(s.snapshot())
+}
+
+// GetCategoryPageArticlePanels Return a list of all the article panels for the Category Pages
+func (s *MemoryArticleStore) GetCategoryPageArticlePanels(category string) []Article {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return categoryPageArticlePanels(s.snapshot(), category)
+}
+
+// GetArticleByID gets an article from memory by id number
+func (s *MemoryArticleStore) GetArticleByID(id int) (*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	item, ok := s.items[id]
+	if !ok {
+		return nil, fmt.Errorf("article with ID %d not found", id)
+	}
+	article := item.Article()
+	return &article, nil
+}
+
+// GetArticleBySlug gets an article from memory by slug
+func (s *MemoryArticleStore) GetArticleBySlug(slug string) (*Article, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return articleBySlug(s.snapshot(), slug)
+}
+
+// snapshot copies the items out of the map; callers must hold s.mu.
+func (s *MemoryArticleStore) snapshot() []Item {
+	items := make([]Item, 0, len(s.items))
+	for _, item := range s.items {
+		items = append(items, item)
+	}
+	return items
+}
+
+// ArticleManifestFile is the metadata file an HTML article directory carries.
+const ArticleManifestFile = "article.json"
+
+// ArticleMarkdownFile is the single file a Markdown article directory
+// carries: YAML front matter for the metadata, then the Markdown body.
+const ArticleMarkdownFile = "article.md"
+
+// Content files that, when present next to the manifest, supply the HTML
+// fields the manifest leaves empty — so the markup lives in real .html files
+// rather than escaped JSON strings.
+const (
+	ArticleBodyFile    = "article.html"
+	ArticlePictureFile = "articlePicture.html"
+	PanelPictureFile   = "panelPicture.html"
+)
+
+// FileArticleStore : ArticleStore backed by a directory of article
+// directories (<root>/<name>/article.json plus optional content files, or
+// <root>/<name>/article.md), for local development without AWS. The tree is
+// re-read on every call so edits show up on the next page load.
+type FileArticleStore struct {
+	root string
+}
+
+// NewFileArticleStore returns a FileArticleStore rooted at root.
+func NewFileArticleStore(root string) *FileArticleStore {
+	return &FileArticleStore{root: root}
+}
+
+// GetArticlePanels Return a list of all the article panels for the Front Page
+func (s *FileArticleStore) GetArticlePanels() []Article {
+	return articlePanels(s.load())
+}
+
+// GetAllArticlePanels returns every article's panel in the tree, newest first
+func (s *FileArticleStore) GetAllArticlePanels() []Article {
+	return allArticlePanels(s.load())
+}
+
+// GetCategoryPageArticlePanels Return a list of all the article panels for the Category Pages
+func (s *FileArticleStore) GetCategoryPageArticlePanels(category string) []Article {
+	return categoryPageArticlePanels(s.load(), category)
+}
+
+// GetArticleByID gets an article from the directory tree by id number
+func (s *FileArticleStore) GetArticleByID(id int) (*Article, error) {
+	for _, item := range s.load() {
+		if item.PostID == id {
+			article := item.Article()
+			return &article, nil
+		}
+	}
+	return nil, fmt.Errorf("article with ID %d not found", id)
+}
+
+// GetArticleBySlug gets an article from the directory tree by slug
+func (s *FileArticleStore) GetArticleBySlug(slug string) (*Article, error) {
+	return articleBySlug(s.load(), slug)
+}
+
+// load reads every article directory under root. A directory that fails to
+// load is logged and skipped so one broken draft doesn't blank the site.
+func (s *FileArticleStore) load() []Item {
+	entries, err := os.ReadDir(s.root)
+	if err != nil {
+		log.Error("Unable to read article directory:", err)
+		return []Item{}
+	}
+
+	items := []Item{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		item, err := LoadArticleDir(filepath.Join(s.root, entry.Name()))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			log.WithError(err).WithField("dir", entry.Name()).Error("Skipping unreadable article directory")
+			continue
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+// LoadArticleDir reads a single article directory: either the
+// ArticleManifestFile plus any content files for fields the manifest leaves
+// empty, or an ArticleMarkdownFile. Dates may be written by hand; they come
+// back as RFC 3339. The returned error wraps os.ErrNotExist when dir holds
+// neither.
+func LoadArticleDir(dir string) (Item, error) {
+	item := Item{}
+
+	raw, err := os.ReadFile(filepath.Join(dir, ArticleManifestFile))
+	if errors.Is(err, os.ErrNotExist) {
+		src, mdErr := os.ReadFile(filepath.Join(dir, ArticleMarkdownFile))
+		if mdErr != nil {
+			return item, mdErr
+		}
+		item, mdErr = ParseMarkdownArticle(src)
+		if mdErr != nil {
+			return item, fmt.Errorf("%s: %v", ArticleMarkdownFile, mdErr)
+		}
+		return item.WithNormalizedDates()
+	}
+	if err != nil {
+		return item, err
+	}
+	if err := json.Unmarshal(raw, &item); err != nil {
+		return item, fmt.Errorf("%s: %v", ArticleManifestFile, err)
+	}
+
+	for name, field := range map[string]*string{
+		ArticleBodyFile:    &item.HTMLHold,
+		ArticlePictureFile: &item.ArticlePicture,
+		PanelPictureFile:   &item.PanelPicture,
+	} {
+		if *field != "" {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return item, err
+		}
+		*field = string(data)
+	}
+
+	return item.WithNormalizedDates()
+}
+
+// articleBySlug finds the item whose ResolvedSlug is slug.
+func articleBySlug(items []Item, slug string) (*Article, error) {
+	for _, item := range items {
+		if slug != "" && item.ResolvedSlug() == slug {
+			article := item.Article()
+			return &article, nil
+		}
+	}
+	return nil, fmt.Errorf("article with slug %q not found", slug)
+}
+
+// articlePanels applies GetArticlePanels' filter and ordering to an
+// in-process item set.
+func articlePanels(items []Item) []Article {
+	return listedPanels(allArticlePanels(items), time.Now())
+}
+
+// allArticlePanels applies GetAllArticlePanels' filter and ordering to an
+// in-process item set.
+func allArticlePanels(items []Item) []Article {
+	articles := []Article{}
+	for _, item := range items {
+		if item.PostID >= 0 {
+			articles = append(articles, item.Panel())
+		}
+	}
+
+	slices.SortFunc(articles, compareNewestFirst)
+
+	return articles
+}
+
+// categoryPageArticlePanels applies GetCategoryPageArticlePanels' filter and
+// ordering to an in-process item set. The match is membership of the item's
+// category set — the same semantics as the DynamoDB category index — so
+// every backend returns the same panels for the same data.
+func categoryPageArticlePanels(items []Item, category string) []Article {
+	unescapedCategory := html.UnescapeString(category)
+
+	now := time.Now()
+	articles := []Article{}
+	for _, item := range items {
+		if panel := item.Panel(); slices.Contains(item.CategoryNames(), unescapedCategory) && panel.Listed(now) {
+			articles = append(articles, panel)
+		}
+	}
+
+	slices.SortFunc(articles, func(a, b Article) int { return compareNewestFirst(b, a) })
+
+	return articles
+}
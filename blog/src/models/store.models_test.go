@@ -0,0 +1,121 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// writeArticleDir lays item out the way FileArticleStore expects: the
+// manifest plus whichever content files are given.
+func writeArticleDir(t *testing.T, root, name string, item Item, files map[string]string) {
+	t.Helper()
+	dir := filepath.Join(root, name)
+	require.NoError(t, os.MkdirAll(dir, 0755))
+	raw, err := json.Marshal(item)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, ArticleManifestFile), raw, 0644))
+	for file, content := range files {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, file), []byte(content), 0644))
+	}
+}
+
+func TestLoadArticleDir_ContentFiles(t *testing.T) {
+	root := t.TempDir()
+	writeArticleDir(t, root, "graphStore", Item{
+		PostID:     0,
+		PostTitle:  "Graph Store",
+		Categories: "Distributed Systems",
+		PostType:   "standard",
+		Excerpt:    "inline excerpt",
+	}, map[string]string{
+		ArticleBodyFile:    "<p>body from file</p>",
+		ArticlePictureFile: "<img src=\"/a.png\">",
+		PanelPictureFile:   "<img src=\"/p.png\">",
+	})
+
+	item, err := LoadArticleDir(filepath.Join(root, "graphStore"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "Graph Store", item.PostTitle)
+	assert.Equal(t, "inline excerpt", item.Excerpt)
+	assert.Equal(t, "<p>body from file</p>", item.HTMLHold)
+	assert.Equal(t, "<img src=\"/a.png\">", item.ArticlePicture)
+	assert.Equal(t, "<img src=\"/p.png\">", item.PanelPicture)
+}
+
+func TestLoadArticleDir_ManifestWins(t *testing.T) {
+	root := t.TempDir()
+	writeArticleDir(t, root, "inline", Item{PostID: 1, HTMLHold: "<p>inline body</p>"}, map[string]string{
+		ArticleBodyFile: "<p>ignored</p>",
+	})
+
+	item, err := LoadArticleDir(filepath.Join(root, "inline"))
+
+	require.NoError(t, err)
+	assert.Equal(t, "<p>inline body</p>", item.HTMLHold, "A non-empty manifest field should not be overwritten by a content file")
+}
+
+func TestLoadArticleDir_Errors(t *testing.T) {
+	root := t.TempDir()
+
+	_, err := LoadArticleDir(filepath.Join(root, "missing"))
+	assert.True(t, errors.Is(err, os.ErrNotExist), "A directory without a manifest should report os.ErrNotExist, got %v", err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "broken"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "broken", ArticleManifestFile), []byte("{not json"), 0644))
+	_, err = LoadArticleDir(filepath.Join(root, "broken"))
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), ArticleManifestFile)
+}
+
+func TestFileArticleStore(t *testing.T) {
+	silenceLogrus(t)
+	root := t.TempDir()
+	for i, item := range testArticleItems() {
+		writeArticleDir(t, root, "article"+string(rune('a'+i)), item, nil)
+	}
+	// Noise the store must tolerate: a stray file, a directory with no
+	// manifest, and a directory with a corrupt one.
+	require.NoError(t, os.WriteFile(filepath.Join(root, "README.md"), []byte("# articles"), 0644))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "assets"), 0755))
+	require.NoError(t, os.MkdirAll(filepath.Join(root, "broken"), 0755))
+	require.NoError(t, os.WriteFile(filepath.Join(root, "broken", ArticleManifestFile), []byte("{"), 0644))
+
+	store := NewFileArticleStore(root)
+
+	panels := store.GetArticlePanels()
+	require.Len(t, panels, len(testArticleItems()))
+	assert.Equal(t, 5, panels[0].PostID, "Panels should be sorted by PostID descending")
+
+	category := store.GetCategoryPageArticlePanels("Distributed Systems")
+	ids := []int{}
+	for _, panel := range category {
+		ids = append(ids, panel.PostID)
+	}
+	assert.Equal(t, []int{0, 2, 4, 5}, ids)
+
+	article, err := store.GetArticleByID(0)
+	require.NoError(t, err)
+	assert.Equal(t, "Fault Tolerant Graph Store API", article.ShortTitle)
+	assert.Equal(t, "<p>graph store</p>", string(article.HTMLHold))
+
+	_, err = store.GetArticleByID(42)
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "not found")
+}
+
+func TestFileArticleStore_MissingRoot(t *testing.T) {
+	silenceLogrus(t)
+	store := NewFileArticleStore(filepath.Join(t.TempDir(), "does-not-exist"))
+
+	panels := store.GetArticlePanels()
+
+	assert.NotNil(t, panels, "GetArticlePanels should never return nil")
+	assert.Len(t, panels, 0)
+}
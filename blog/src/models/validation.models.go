@@ -0,0 +1,317 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+// Amount is one of a listing's numeric fields. It's written as a number, in
+// JSON and in DynamoDB, but reads the strings the realtor form posts and
+// listings saved before the fields were typed still hold (see ParseAmount).
+type Amount float64
+
+// squareFeetPerAcre converts lot sizes given in acres.
+const squareFeetPerAcre = 43560
+
+var amountPattern = regexp.MustCompile(`^\$?\s*(\d[\d,]*(?:\.\d+)?|\.\d+)\s*([a-z][a-z. ²]*)?$`)
+
+// ParseAmount reads a number written the way people write them in a
+// listing: "503,000", "$503,000", "1200 sqft", "2 car". A trailing unit is
+// ignored, except that acres ("0.25 acres") are converted to square feet.
+func ParseAmount(s string) (Amount, error) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	m := amountPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(m[1], ",", ""), 64)
+	if err != nil {
+		return 0, fmt.Errorf("%q is not a number", s)
+	}
+	switch strings.TrimRight(m[2], ". ") {
+	case "ac", "acre", "acres":
+		f *= squareFeetPerAcre
+	}
+	return Amount(f), nil
+}
+
+// UnmarshalJSON reads a JSON number or a string ParseAmount understands. A
+// null or blank string is zero.
+func (n *Amount) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, []byte("null")) {
+		return nil
+	}
+	if len(data) > 0 && data[0] == '"' {
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if strings.TrimSpace(s) == "" {
+			*n = 0
+			return nil
+		}
+		parsed, err := ParseAmount(s)
+		if err != nil {
+			return err
+		}
+		*n = parsed
+		return nil
+	}
+	var f float64
+	if err := json.Unmarshal(data, &f); err != nil {
+		return fmt.Errorf("%s is not a number", data)
+	}
+	*n = Amount(f)
+	return nil
+}
+
+// UnmarshalDynamoDBAttributeValue reads a number, or the string an untyped
+// listing holds. A string that doesn't parse reads as zero rather than making
+// the whole listing unreadable; `daemon migrate` reports those.
+func (n *Amount) UnmarshalDynamoDBAttributeValue(av types.AttributeValue) error {
+	switch v := av.(type) {
+	case *types.AttributeValueMemberN:
+		f, err := strconv.ParseFloat(v.Value, 64)
+		if err != nil {
+			return err
+		}
+		*n = Amount(f)
+	case *types.AttributeValueMemberS:
+		*n, _ = ParseAmount(v.Value)
+	case *types.AttributeValueMemberNULL:
+		*n = 0
+	default:
+		return fmt.Errorf("cannot read %T as a number", av)
+	}
+	return nil
+}
+
+// FieldError is one problem with one field of a listing, named as it is in
+// the listing's JSON.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ValidationErrors is every problem found with a listing, in field order.
+type ValidationErrors []FieldError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, e := range errs {
+		messages = append(messages, e.Field+" "+e.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (errs *ValidationErrors) add(field, format string, args ...any) {
+	*errs = append(*errs, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// listingAmounts are the JSON names of a listing's numeric fields, and
+// whether each is required.
+var listingAmounts = []struct {
+	name     string
+	required bool
+}{
+	{"Sales Price", true},
+	{"Bedrooms", true},
+	{"Bathrooms", true},
+	{"Garage Size", false},
+	{"Square Feet", true},
+	{"Lot Size", false},
+}
+
+// DecodeListing reads a listing from a request body. Numeric fields may be
+// numbers or strings ParseAmount understands. A required number that's
+// missing, a number that doesn't parse and a field of the wrong JSON type are
+// returned together as ValidationErrors; malformed JSON is a plain error.
+// State is upper-cased. Call Validate on the result for the rest of the
+// checks.
+func DecodeListing(body []byte) (Listing, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return Listing{}, err
+	}
+	if fields == nil {
+		return Listing{}, errors.New("expected a JSON object")
+	}
+
+	var problems ValidationErrors
+	for _, amount := range listingAmounts {
+		raw, ok := fields[amount.name]
+		if !ok || bytes.Equal(raw, []byte("null")) || bytes.Equal(raw, []byte(`""`)) {
+			if amount.required {
+				problems.add(amount.name, "is required")
+			}
+			continue
+		}
+		var n Amount
+		if err := n.UnmarshalJSON(raw); err != nil {
+			problems.add(amount.name, "must be a number, not %s", raw)
+			delete(fields, amount.name)
+		}
+	}
+
+	// The numbers that didn't parse are gone, so any error now is a field
+	// of the wrong type.
+	rest, err := json.Marshal(fields)
+	if err != nil {
+		return Listing{}, err
+	}
+	var listing Listing
+	var typeErr *json.UnmarshalTypeError
+	if err := json.Unmarshal(rest, &listing); errors.As(err, &typeErr) {
+		problems.add(typeErr.Field, "must be a JSON %s, not %s", jsonKind(typeErr.Type), typeErr.Value)
+	} else if err != nil {
+		return Listing{}, err
+	}
+
+	if len(problems) > 0 {
+		return Listing{}, problems
+	}
+	listing.State = strings.ToUpper(strings.TrimSpace(listing.State))
+	listing.ZipCode = strings.TrimSpace(listing.ZipCode)
+	return listing, nil
+}
+
+func jsonKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	case reflect.Float32, reflect.Float64, reflect.Int, reflect.Int64:
+		return "number"
+	}
+	return "string"
+}
+
+// Limits on a listing's free-text fields, in characters.
+const (
+	MaxListingDescription = 5000
+	maxListingText        = 100
+	maxListingURL         = 2048
+	// MaxListingPhotos is how many photos a listing's Photo Array may hold.
+	MaxListingPhotos = 50
+)
+
+// usStates are the two-letter postal codes of the states, DC and the
+// inhabited territories.
+var usStates = []string{
+	"AK", "AL", "AR", "AS", "AZ", "CA", "CO", "CT", "DC", "DE", "FL", "GA", "GU", "HI", "IA", "ID",
+	"IL", "IN", "KS", "KY", "LA", "MA", "MD", "ME", "MI", "MN", "MO", "MP", "MS", "MT", "NC", "ND",
+	"NE", "NH", "NJ", "NM", "NV", "NY", "OH", "OK", "OR", "PA", "PR", "RI", "SC", "SD", "TN", "TX",
+	"UT", "VA", "VI", "VT", "WA", "WI", "WV", "WY",
+}
+
+var zipPattern = regexp.MustCompile(`^\d{5}(-\d{4})?$`)
+
+// Validate checks a listing before it's saved, returning every problem as
+// ValidationErrors.
+func (listing Listing) Validate() error {
+	var problems ValidationErrors
+
+	for _, field := range []struct {
+		name, value string
+		required    bool
+		max         int
+	}{
+		{"MLS", listing.MLS, true, 64},
+		{"Street1", listing.Street1, true, maxListingText},
+		{"Street2", listing.Street2, false, maxListingText},
+		{"City", listing.City, true, maxListingText},
+		{"Neighborhood", listing.Neighborhood, false, maxListingText},
+		{"Description", listing.Description, false, MaxListingDescription},
+	} {
+		switch {
+		case field.required && strings.TrimSpace(field.value) == "":
+			problems.add(field.name, "is required")
+		case utf8.RuneCountInString(field.value) > field.max:
+			problems.add(field.name, "must be at most %d characters", field.max)
+		}
+	}
+
+	if !slices.Contains(usStates, listing.State) {
+		problems.add("State", "must be a two-letter US state code like OR, got %q", listing.State)
+	}
+	if !zipPattern.MatchString(listing.ZipCode) {
+		problems.add("Zip Code", "must be a 5-digit ZIP code or ZIP+4 like 97701-1234, got %q", listing.ZipCode)
+	}
+
+	for _, field := range []struct {
+		name     string
+		value    Amount
+		positive bool
+		whole    bool
+	}{
+		{"Sales Price", listing.SalesPrice, true, false},
+		{"Bedrooms", listing.Bedrooms, false, true},
+		{"Bathrooms", listing.Bathrooms, false, false},
+		{"Garage Size", listing.GarageSize, false, false},
+		{"Square Feet", listing.SquareFeet, true, false},
+		{"Lot Size", listing.LotSize, false, false},
+	} {
+		f := float64(field.value)
+		switch {
+		case math.IsNaN(f) || math.IsInf(f, 0):
+			problems.add(field.name, "must be a number")
+		case field.positive && f <= 0:
+			problems.add(field.name, "must be greater than 0")
+		case f < 0:
+			problems.add(field.name, "must not be negative")
+		case field.whole && f != math.Trunc(f):
+			problems.add(field.name, "must be a whole number")
+		}
+	}
+
+	if !(listing.Latitude >= -90 && listing.Latitude <= 90) {
+		problems.add("Latitude", "must be from -90 to 90")
+	}
+	if !(listing.Longitude >= -180 && listing.Longitude <= 180) {
+		problems.add("Longitude", "must be from -180 to 180")
+	}
+
+	if listing.ListPhoto != "" && !validPhotoURL(listing.ListPhoto) {
+		problems.add("List Photo", "must be an http or https URL")
+	}
+	if len(listing.PhotoArray) > MaxListingPhotos {
+		problems.add("Photo Array", "must hold at most %d photos", MaxListingPhotos)
+	}
+	for i, photo := range listing.PhotoArray {
+		if !validPhotoURL(photo) {
+			problems.add(fmt.Sprintf("Photo Array[%d]", i), "must be an http or https URL")
+		}
+	}
+
+	switch listing.Deleted {
+	case "", "true", "false":
+	default:
+		problems.add("deleted", `must be "true" or "false"`)
+	}
+
+	if len(problems) > 0 {
+		return problems
+	}
+	return nil
+}
+
+func validPhotoURL(s string) bool {
+	if len(s) > maxListingURL {
+		return false
+	}
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
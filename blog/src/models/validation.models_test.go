@@ -0,0 +1,169 @@
+package models
+
+import (
+	"encoding/json"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	"github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAmount(t *testing.T) {
+	testCases := map[string]Amount{
+		"503000":      503000,
+		"503,000":     503000,
+		"$503,000":    503000,
+		"1200 sqft":   1200,
+		"1,200 sq ft": 1200,
+		"2 car":       2,
+		"2.5":         2.5,
+		".5":          0.5,
+		"0.25 acres":  10890,
+		"1 Acre":      43560,
+	}
+	for in, want := range testCases {
+		got, err := ParseAmount(in)
+		require.NoError(t, err, in)
+		assert.Equal(t, want, got, in)
+	}
+
+	for _, in := range []string{"", "a lot", "-5", "3 or 4", "1.2.3"} {
+		_, err := ParseAmount(in)
+		assert.Error(t, err, in)
+	}
+}
+
+func TestAmountUnmarshalJSON(t *testing.T) {
+	var listing Listing
+	require.NoError(t, json.Unmarshal([]byte(`{"Sales Price": "503,000", "Bedrooms": 3, "Bathrooms": "1.5",
+		"Garage Size": "", "Lot Size": null}`), &listing))
+	assert.Equal(t, Amount(503000), listing.SalesPrice)
+	assert.Equal(t, Amount(3), listing.Bedrooms)
+	assert.Equal(t, Amount(1.5), listing.Bathrooms)
+	assert.Zero(t, listing.GarageSize)
+	assert.Zero(t, listing.LotSize)
+
+	out, err := json.Marshal(listing)
+	require.NoError(t, err)
+	assert.Contains(t, string(out), `"Sales Price":503000`)
+	assert.Contains(t, string(out), `"Bathrooms":1.5`)
+}
+
+func TestAmountReadsUntypedListings(t *testing.T) {
+	listings := unmarshalListings([]map[string]types.AttributeValue{
+		{
+			"MLS":         &types.AttributeValueMemberS{Value: "legacy"},
+			"Sales Price": &types.AttributeValueMemberS{Value: "503,000"},
+			"Square Feet": &types.AttributeValueMemberS{Value: "1200 sqft"},
+			"Garage Size": &types.AttributeValueMemberS{Value: "plenty"},
+		},
+		{
+			"MLS":         &types.AttributeValueMemberS{Value: "typed"},
+			"Sales Price": &types.AttributeValueMemberN{Value: "425000"},
+		},
+	})
+	require.Len(t, listings, 2, "Untyped listings stay readable")
+	byMLS := map[string]Listing{}
+	for _, listing := range listings {
+		byMLS[listing.MLS] = listing
+	}
+	assert.Equal(t, Amount(503000), byMLS["legacy"].SalesPrice)
+	assert.Equal(t, Amount(1200), byMLS["legacy"].SquareFeet)
+	assert.Zero(t, byMLS["legacy"].GarageSize, "A string that isn't a number reads as zero")
+	assert.Equal(t, Amount(425000), byMLS["typed"].SalesPrice)
+
+	av, err := attributevalue.MarshalMap(byMLS["legacy"])
+	require.NoError(t, err)
+	assert.Equal(t, &types.AttributeValueMemberN{Value: "503000"}, av["Sales Price"], "and are written back as numbers")
+}
+
+func validListing() Listing {
+	return Listing{
+		MLS:         "1234567890",
+		Street1:     "123 Real Avenue",
+		City:        "Bend",
+		State:       "OR",
+		ZipCode:     "97701",
+		SalesPrice:  503000,
+		Bedrooms:    3,
+		Bathrooms:   1.5,
+		SquareFeet:  1200,
+		ListPhoto:   "https://files.mitchelletzel.com/full-house.jpg",
+		PhotoArray:  []string{"https://files.mitchelletzel.com/inside-one.jpeg"},
+		Description: "Lorem ipsum",
+		Deleted:     "false",
+	}
+}
+
+func TestListingValidate(t *testing.T) {
+	require.NoError(t, validListing().Validate())
+
+	zip4 := validListing()
+	zip4.ZipCode = "97701-1234"
+	studio := validListing()
+	studio.Bedrooms = 0
+	assert.NoError(t, zip4.Validate())
+	assert.NoError(t, studio.Validate())
+
+	testCases := map[string]func(*Listing){
+		"MLS":            func(l *Listing) { l.MLS = "" },
+		"Street1":        func(l *Listing) { l.Street1 = "   " },
+		"City":           func(l *Listing) { l.City = strings.Repeat("x", 101) },
+		"State":          func(l *Listing) { l.State = "Oregon" },
+		"Zip Code":       func(l *Listing) { l.ZipCode = "9770" },
+		"Sales Price":    func(l *Listing) { l.SalesPrice = 0 },
+		"Bedrooms":       func(l *Listing) { l.Bedrooms = 2.5 },
+		"Bathrooms":      func(l *Listing) { l.Bathrooms = -1 },
+		"Square Feet":    func(l *Listing) { l.SquareFeet = 0 },
+		"Latitude":       func(l *Listing) { l.Latitude = 91 },
+		"Longitude":      func(l *Listing) { l.Longitude = math.NaN() },
+		"List Photo":     func(l *Listing) { l.ListPhoto = "javascript:alert(1)" },
+		"Photo Array[1]": func(l *Listing) { l.PhotoArray = append(l.PhotoArray, "/media/relative.jpg") },
+		"Description":    func(l *Listing) { l.Description = strings.Repeat("é", MaxListingDescription+1) },
+		"deleted":        func(l *Listing) { l.Deleted = "maybe" },
+	}
+	for field, breakIt := range testCases {
+		listing := validListing()
+		breakIt(&listing)
+		var problems ValidationErrors
+		require.ErrorAs(t, listing.Validate(), &problems, field)
+		require.Len(t, problems, 1, field)
+		assert.Equal(t, field, problems[0].Field)
+	}
+
+	var problems ValidationErrors
+	require.ErrorAs(t, Listing{}.Validate(), &problems)
+	assert.Len(t, problems, 7, "Every problem is reported: %v", problems)
+}
+
+func TestDecodeListing(t *testing.T) {
+	listing, err := DecodeListing([]byte(`{"MLS": "1", "State": " or ", "Zip Code": "97701 ", "Sales Price": "$503,000",
+		"Bedrooms": "3", "Bathrooms": 2, "Square Feet": "1200 sqft", "Garage Size": "1 car"}`))
+	require.NoError(t, err)
+	assert.Equal(t, "OR", listing.State)
+	assert.Equal(t, "97701", listing.ZipCode)
+	assert.Equal(t, Amount(503000), listing.SalesPrice)
+	assert.Equal(t, Amount(1), listing.GarageSize)
+
+	_, err = DecodeListing([]byte(`{"MLS": 7, "Sales Price": "lots", "Bedrooms": true, "Bathrooms": null}`))
+	var problems ValidationErrors
+	require.ErrorAs(t, err, &problems)
+	assert.Equal(t, ValidationErrors{
+		{Field: "Sales Price", Message: `must be a number, not "lots"`},
+		{Field: "Bedrooms", Message: "must be a number, not true"},
+		{Field: "Bathrooms", Message: "is required"},
+		{Field: "Square Feet", Message: "is required"},
+		{Field: "MLS", Message: "must be a JSON string, not number"},
+	}, problems)
+	assert.Contains(t, err.Error(), "Sales Price must be a number")
+
+	for _, body := range []string{``, `{"MLS": `, `null`, `[]`} {
+		_, err := DecodeListing([]byte(body))
+		assert.Error(t, err, body)
+		assert.NotErrorAs(t, err, &problems, "%q is malformed, not invalid", body)
+	}
+}
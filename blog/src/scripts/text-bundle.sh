@@ -82,17 +82,26 @@ GO_FILES=(
     "blog/src/handlers/auth.handlers_test.go"
     "blog/src/handlers/realtor.handlers.go"
     "blog/src/handlers/realtor.handlers_test.go"
-    "daemon/articles/awsEMR/awsEMR.go"
-    "daemon/articles/awsEMR/awsEMR.html"
-    "daemon/articles/infraCode/infraCode.go"
-    "daemon/articles/infraCode/infraCode.html"
-    "daemon/articles/graphStore/graphStore.go"
-    "daemon/articles/graphStore/graphStore.html"
-    "daemon/articles/reactRealtor/reactRealtor.go"
-    "daemon/articles/reactRealtor/reactRealtor.html"
-    "daemon/articles/googleSRE/googleSRE.go"
-    "daemon/articles/googleSRE/googleSRE.html"
+    "daemon/articles/awsEMR/article.json"
+    "daemon/articles/awsEMR/article.html"
+    "daemon/articles/infraCode/article.json"
+    "daemon/articles/infraCode/article.html"
+    "daemon/articles/graphStore/article.json"
+    "daemon/articles/graphStore/article.html"
+    "daemon/articles/reactRealtor/article.json"
+    "daemon/articles/reactRealtor/article.html"
+    "daemon/articles/googleSRE/article.json"
+    "daemon/articles/googleSRE/article.html"
     "daemon/app.go"
+    "daemon/publish.go"
+    "daemon/revisions.go"
+    "daemon/preview.go"
+    "daemon/purge.go"
+    "daemon/migrate.go"
+    "daemon/seed.go"
+    "daemon/tables.go"
+    "daemon/tables.json"
+    "daemon/backup.go"
     "daemon/README.md"
     "README.md"
 )
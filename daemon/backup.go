@@ -0,0 +1,586 @@
+package main
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/etzelm/blog-in-golang/src/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// backupFormat is written to every manifest; import refuses any other.
+const backupFormat = 1
+
+const backupManifestFile = "manifest.json"
+
+// backupManifest is manifest.json, the first file of a backup tarball. The
+// other files are one JSON Lines file per table.
+type backupManifest struct {
+	Format   int           `json:"format"`
+	Created  string        `json:"created"`
+	Region   string        `json:"region"`
+	Endpoint string        `json:"endpoint,omitempty"`
+	Tables   []backupTable `json:"tables"`
+}
+
+type backupTable struct {
+	Kind   string `json:"kind"`
+	Table  string `json:"table"`
+	File   string `json:"file"`
+	Items  int    `json:"items"`
+	SHA256 string `json:"sha256"`
+}
+
+// backupKind is one kind of table in a backup. Items go through the model
+// the blog reads them with, so a line is the model's JSON and the derived
+// index attributes are rebuilt on import rather than copied.
+type backupKind struct {
+	name   string // also the file name, <name>.jsonl
+	key    string // hash key attribute, for conflict checks
+	encode func(av map[string]*dynamodb.AttributeValue) ([]byte, error)
+	decode func(line []byte) (map[string]*dynamodb.AttributeValue, any, error)
+}
+
+func modelKind[T any](name, key string, indexed func(T) T) backupKind {
+	return backupKind{
+		name: name,
+		key:  key,
+		encode: func(av map[string]*dynamodb.AttributeValue) ([]byte, error) {
+			var value T
+			if err := dynamodbattribute.UnmarshalMap(av, &value); err != nil {
+				return nil, err
+			}
+			return json.Marshal(value)
+		},
+		decode: func(line []byte) (map[string]*dynamodb.AttributeValue, any, error) {
+			var value T
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.DisallowUnknownFields()
+			if err := dec.Decode(&value); err != nil {
+				return nil, nil, err
+			}
+			av, err := dynamodbattribute.MarshalMap(indexed(value))
+			if err != nil {
+				return nil, nil, err
+			}
+			if av[key] == nil {
+				return nil, nil, fmt.Errorf("no %s, the table's key", key)
+			}
+			return av, value, nil
+		},
+	}
+}
+
+// reading returns the kind with every scanned item passed through fix
+// before it's encoded.
+func (kind backupKind) reading(fix func(av map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue) backupKind {
+	encode := kind.encode
+	kind.encode = func(av map[string]*dynamodb.AttributeValue) ([]byte, error) {
+		return encode(fix(av))
+	}
+	return kind
+}
+
+var backupKinds = []backupKind{
+	modelKind("articles", "post-id", models.Item.WithIndexAttributes),
+	modelKind("listings", "MLS", models.Listing.WithIndexKeys).reading(readableListing),
+	modelKind("auth", "email", func(form models.AuthForm) models.AuthForm { return form }),
+	modelKind("contact", "Email", func(form models.ContactForm) models.ContactForm { return form }),
+}
+
+// readableListing converts the number fields a listing still holds as
+// strings (see migrateListingNumbers) to numbers, since this SDK, unlike the
+// blog's, won't read a string into a models.Amount. A string ParseAmount
+// can't read is dropped, so it exports as zero, which is how the blog shows
+// it, rather than failing the whole table.
+func readableListing(av map[string]*dynamodb.AttributeValue) map[string]*dynamodb.AttributeValue {
+	fixed := map[string]*dynamodb.AttributeValue{}
+	for name, v := range av {
+		fixed[name] = v
+	}
+	mls := ""
+	if av["MLS"] != nil {
+		mls = aws.StringValue(av["MLS"].S)
+	}
+	for _, field := range listingNumberFields {
+		if av[field] == nil || av[field].S == nil {
+			continue
+		}
+		amount, err := models.ParseAmount(*av[field].S)
+		if err != nil {
+			if strings.TrimSpace(*av[field].S) != "" {
+				log.WithError(err).WithFields(log.Fields{"MLS": mls, "field": field}).Warn("Exporting unreadable listing number as zero")
+			}
+			delete(fixed, field)
+			continue
+		}
+		fixed[field] = &dynamodb.AttributeValue{N: aws.String(strconv.FormatFloat(float64(amount), 'f', -1, 64))}
+	}
+	return fixed
+}
+
+// backupFlags registers the flags export and import share: the table each
+// kind lives in, and --only to pick kinds.
+func backupFlags(flags *flag.FlagSet) (tables map[string]*string, only *string) {
+	articles := os.Getenv("ARTICLES")
+	if articles == "" {
+		articles = "Test-Articles"
+	}
+	tables = map[string]*string{
+		"articles": flags.String("articles", articles, "articles table"),
+		"listings": flags.String("listings", models.ListingsTable, "listings table"),
+		"auth":     flags.String("auth", "Auth", "auth table"),
+		"contact":  flags.String("contact", "Contact", "contact form table"),
+	}
+	only = flags.String("only", "", "comma-separated kinds to include (articles, listings, auth, contact); default all")
+	return tables, only
+}
+
+func selectKinds(only string) ([]backupKind, error) {
+	if only == "" {
+		return backupKinds, nil
+	}
+	kinds := []backupKind{}
+	for _, name := range strings.Split(only, ",") {
+		i := slices.IndexFunc(backupKinds, func(kind backupKind) bool { return kind.name == strings.TrimSpace(name) })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown kind %q; pick from articles, listings, auth, contact", name)
+		}
+		kinds = append(kinds, backupKinds[i])
+	}
+	return kinds, nil
+}
+
+// export scans every table into a gzipped tarball: manifest.json, with the
+// item count and SHA-256 of each file, then one <kind>.jsonl per table. The
+// tarball holds password hashes and contact details, so it is written
+// readable by its owner only.
+//
+//	daemon export [--out backup.tar.gz] [--only articles,listings] [--articles Live-Articles]
+func export(args []string) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	out := flags.String("out", "backup-"+time.Now().UTC().Format("20060102T150405Z")+".tar.gz", "tarball to write")
+	tables, only := backupFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	kinds, err := selectKinds(*only)
+	if err != nil {
+		return fmt.Errorf("export: %v", err)
+	}
+
+	sess, opts, err := awsSession()
+	if err != nil {
+		return err
+	}
+	dbSvc := dynamodb.New(sess, endpointConfig(opts.DynamoDBEndpoint))
+
+	manifest := backupManifest{
+		Format:   backupFormat,
+		Created:  time.Now().UTC().Format(time.RFC3339),
+		Region:   opts.Region,
+		Endpoint: opts.DynamoDBEndpoint,
+	}
+	// Each table is spooled to a temp file first: a tar header needs the
+	// size, and the manifest, which goes first, needs every checksum.
+	spools := []*os.File{}
+	defer func() {
+		for _, spool := range spools {
+			spool.Close()
+			os.Remove(spool.Name())
+		}
+	}()
+	for _, kind := range kinds {
+		spool, err := os.CreateTemp("", "daemon-export-*.jsonl")
+		if err != nil {
+			return err
+		}
+		spools = append(spools, spool)
+		table := *tables[kind.name]
+		entry, err := exportTable(dbSvc, kind, table, spool)
+		if err != nil {
+			return fmt.Errorf("%s: %v", table, err)
+		}
+		log.WithFields(log.Fields{"table": table, "items": entry.Items}).Info("Exported table")
+		manifest.Tables = append(manifest.Tables, entry)
+	}
+
+	f, err := os.OpenFile(*out, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return err
+	}
+	if err := writeBackup(f, manifest, spools); err != nil {
+		f.Close()
+		os.Remove(*out)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	log.Infof("Wrote %s", *out)
+	return nil
+}
+
+func exportTable(dbSvc *dynamodb.DynamoDB, kind backupKind, table string, w io.Writer) (backupTable, error) {
+	entry := backupTable{Kind: kind.name, Table: table, File: kind.name + ".jsonl"}
+	sum := sha256.New()
+	out := bufio.NewWriter(io.MultiWriter(w, sum))
+
+	var writeErr error
+	err := dbSvc.ScanPages(&dynamodb.ScanInput{TableName: aws.String(table)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, av := range page.Items {
+				line, err := kind.encode(av)
+				if err != nil {
+					writeErr = err
+					return false
+				}
+				out.Write(line)
+				if writeErr = out.WriteByte('\n'); writeErr != nil {
+					return false
+				}
+				entry.Items++
+			}
+			return true
+		})
+	if err != nil {
+		return entry, err
+	}
+	if writeErr != nil {
+		return entry, writeErr
+	}
+	if err := out.Flush(); err != nil {
+		return entry, err
+	}
+	entry.SHA256 = hex.EncodeToString(sum.Sum(nil))
+	return entry, nil
+}
+
+func writeBackup(w io.Writer, manifest backupManifest, spools []*os.File) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return err
+	}
+	modTime := time.Now()
+	if err := tw.WriteHeader(&tar.Header{Name: backupManifestFile, Mode: 0o600, Size: int64(len(data)), ModTime: modTime}); err != nil {
+		return err
+	}
+	if _, err := tw.Write(data); err != nil {
+		return err
+	}
+	for i, spool := range spools {
+		info, err := spool.Stat()
+		if err != nil {
+			return err
+		}
+		if _, err := spool.Seek(0, io.SeekStart); err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{Name: manifest.Tables[i].File, Mode: 0o600, Size: info.Size(), ModTime: modTime}); err != nil {
+			return err
+		}
+		if _, err := io.Copy(tw, spool); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Conflict modes for import, for items whose key is already in the table.
+const (
+	conflictSkip      = "skip"
+	conflictOverwrite = "overwrite"
+	conflictFail      = "fail"
+)
+
+// importBackup restores a tarball written by export into the tables named
+// by the flags, on whatever endpoint the environment points at, so prod
+// content can be cloned into Test-Articles or DynamoDB Local. The whole
+// tarball is checked against its manifest before anything is written.
+// --conflict decides what happens to items whose key already exists: skip
+// them (the default), overwrite them, or fail before writing anything. As
+// with publish, --dry-run reports what would happen and --confirm does it.
+//
+//	daemon import --dry-run backup.tar.gz
+//	DEPLOYMENT=local daemon import --confirm --conflict overwrite --only articles,listings backup.tar.gz
+func importBackup(args []string) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	conflict := flags.String("conflict", conflictSkip, "what to do with items that already exist: skip, overwrite or fail")
+	dryRun := flags.Bool("dry-run", false, "check the tarball and report conflicts without writing")
+	confirm := flags.Bool("confirm", false, "write the items")
+	tables, only := backupFlags(flags)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dryRun == *confirm {
+		return errors.New("import: pass exactly one of --dry-run or --confirm")
+	}
+	if !slices.Contains([]string{conflictSkip, conflictOverwrite, conflictFail}, *conflict) {
+		return fmt.Errorf("import: --conflict must be skip, overwrite or fail, got %q", *conflict)
+	}
+	if flags.NArg() != 1 {
+		return errors.New("import: pass exactly one backup tarball")
+	}
+	kinds, err := selectKinds(*only)
+	if err != nil {
+		return fmt.Errorf("import: %v", err)
+	}
+
+	dir, err := os.MkdirTemp("", "daemon-import-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	manifest, err := unpackBackup(flags.Arg(0), dir)
+	if err != nil {
+		return fmt.Errorf("%s: %v", flags.Arg(0), err)
+	}
+
+	dbSvc, err := articlesClient()
+	if err != nil {
+		return err
+	}
+
+	type restore struct {
+		kind  backupKind
+		table string
+		items []map[string]*dynamodb.AttributeValue
+		model []any
+	}
+	restores := []restore{}
+	conflicts := 0
+	for _, kind := range kinds {
+		i := slices.IndexFunc(manifest.Tables, func(entry backupTable) bool { return entry.Kind == kind.name })
+		if i < 0 {
+			if *only != "" {
+				return fmt.Errorf("import: the backup has no %s", kind.name)
+			}
+			continue
+		}
+		entry := manifest.Tables[i]
+		r := restore{kind: kind, table: *tables[kind.name]}
+		if err := readBackupLines(filepath.Join(dir, entry.File), func(line []byte) error {
+			av, model, err := kind.decode(line)
+			if err != nil {
+				return err
+			}
+			r.items = append(r.items, av)
+			r.model = append(r.model, model)
+			return nil
+		}); err != nil {
+			return fmt.Errorf("%s: %v", entry.File, err)
+		}
+
+		existing, err := countExisting(dbSvc, r.table, kind.key, r.items)
+		if err != nil {
+			return err
+		}
+		conflicts += existing
+		fmt.Printf("=== %s (%s from %s): %d item(s), %d already in %s\n", kind.name, entry.File, entry.Table, len(r.items), existing, r.table)
+		restores = append(restores, r)
+	}
+
+	if *conflict == conflictFail && conflicts > 0 {
+		return fmt.Errorf("import: %d item(s) already exist; nothing written (use --conflict skip or overwrite)", conflicts)
+	}
+	if *dryRun {
+		log.Infof("Dry run: nothing written (--conflict %s)", *conflict)
+		return nil
+	}
+
+	for _, r := range restores {
+		written, skipped := 0, 0
+		for i, av := range r.items {
+			if item, ok := r.model[i].(models.Item); ok {
+				// Articles go through putArticle so the import is recorded
+				// in their history.
+				var live *models.Item
+				if *conflict == conflictOverwrite {
+					if live, err = liveArticle(dbSvc, r.table, item.PostID); err != nil {
+						return fmt.Errorf("%s: %v", r.table, err)
+					}
+				}
+				item, err = putArticle(dbSvc, r.table, live, item, models.RevisionSourceImport)
+				if errors.Is(err, errArticleChanged) {
+					if *conflict != conflictSkip {
+						return fmt.Errorf("%s: post-id %d changed during the import; stopped after writing %d", r.table, item.PostID, written)
+					}
+					skipped++
+					continue
+				}
+				if err != nil {
+					return fmt.Errorf("%s: %v", r.table, err)
+				}
+				written++
+				if err := syncCategoryIndex(dbSvc, r.table, live, item); err != nil {
+					return err
+				}
+				continue
+			}
+
+			input := &dynamodb.PutItemInput{Item: av, TableName: aws.String(r.table)}
+			if *conflict != conflictOverwrite {
+				input.ConditionExpression = aws.String("attribute_not_exists(#key)")
+				input.ExpressionAttributeNames = map[string]*string{"#key": aws.String(r.kind.key)}
+			}
+			_, err := dbSvc.PutItem(input)
+			if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeConditionalCheckFailedException {
+				if *conflict == conflictFail {
+					return fmt.Errorf("%s: an item appeared after the conflict check; stopped after writing %d", r.table, written)
+				}
+				skipped++
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("%s: PutItem: %v", r.table, err)
+			}
+			written++
+		}
+		log.WithFields(log.Fields{"table": r.table, "written": written, "skipped": skipped}).Info("Imported table")
+	}
+	return nil
+}
+
+// unpackBackup extracts a tarball into dir, checking that it starts with a
+// manifest this daemon understands and that every file it lists is there
+// with the right item count and checksum.
+func unpackBackup(path, dir string) (backupManifest, error) {
+	manifest := backupManifest{}
+	f, err := os.Open(path)
+	if err != nil {
+		return manifest, err
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return manifest, err
+	}
+	tr := tar.NewReader(gz)
+
+	header, err := tr.Next()
+	if err != nil || header.Name != backupManifestFile {
+		return manifest, fmt.Errorf("not a backup: it must start with %s", backupManifestFile)
+	}
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return manifest, fmt.Errorf("%s: %v", backupManifestFile, err)
+	}
+	if manifest.Format != backupFormat {
+		return manifest, fmt.Errorf("backup format %d; this daemon reads format %d", manifest.Format, backupFormat)
+	}
+
+	sums := map[string]string{}
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return manifest, err
+		}
+		i := slices.IndexFunc(manifest.Tables, func(entry backupTable) bool { return entry.File == header.Name })
+		if i < 0 {
+			return manifest, fmt.Errorf("%s is not in the manifest", header.Name)
+		}
+		sum, err := copyFile(filepath.Join(dir, filepath.Base(header.Name)), tr, sha256.New())
+		if err != nil {
+			return manifest, err
+		}
+		sums[header.Name] = sum
+	}
+
+	for _, entry := range manifest.Tables {
+		sum, ok := sums[entry.File]
+		if !ok {
+			return manifest, fmt.Errorf("%s is missing", entry.File)
+		}
+		if sum != entry.SHA256 {
+			return manifest, fmt.Errorf("%s: checksum %s, manifest says %s", entry.File, sum, entry.SHA256)
+		}
+		lines := 0
+		if err := readBackupLines(filepath.Join(dir, entry.File), func([]byte) error { lines++; return nil }); err != nil {
+			return manifest, err
+		}
+		if lines != entry.Items {
+			return manifest, fmt.Errorf("%s: %d item(s), manifest says %d", entry.File, lines, entry.Items)
+		}
+	}
+	return manifest, nil
+}
+
+func copyFile(path string, r io.Reader, sum hash.Hash) (string, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o600)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	if _, err := io.Copy(io.MultiWriter(f, sum), r); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+func readBackupLines(path string, fn func(line []byte) error) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	// Article bodies make for long lines.
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		if err := fn(scanner.Bytes()); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// countExisting reports how many of items already have their key in table.
+// A missing table has none.
+func countExisting(dbSvc *dynamodb.DynamoDB, table, key string, items []map[string]*dynamodb.AttributeValue) (int, error) {
+	existing := 0
+	for _, av := range items {
+		out, err := dbSvc.GetItem(&dynamodb.GetItemInput{
+			TableName:                aws.String(table),
+			Key:                      map[string]*dynamodb.AttributeValue{key: av[key]},
+			ProjectionExpression:     aws.String("#key"),
+			ExpressionAttributeNames: map[string]*string{"#key": aws.String(key)},
+		})
+		if isNotFound(err) {
+			return 0, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("%s: GetItem: %v", table, err)
+		}
+		if out.Item != nil {
+			existing++
+		}
+	}
+	return existing, nil
+}
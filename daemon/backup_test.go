@@ -0,0 +1,302 @@
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// backupSamples are one item of each kind, as the blog writes them.
+func backupSamples() map[string]any {
+	return map[string]any{
+		"articles": models.Item{
+			PostID: 7, PostTitle: "Hello, World", ShortTitle: "Hello", Author: "Mitchell Etzel",
+			Categories: "Go,AWS", CreatedDate: "2024-01-02T03:04:05Z", ModifiedDate: "2024-01-03T03:04:05Z",
+			Excerpt: "An excerpt", HTMLHold: "<p>Hello</p>", ArticlePicture: "<img src=a.png>",
+			PanelPicture: "<img src=p.png>", PostType: "blog", Version: 3,
+		}.WithIndexAttributes(),
+		"listings": models.Listing{
+			MLS: "1234567890", Street1: "1 Main St", Street2: "*", City: "Bend", State: "OR",
+			ZipCode: "97701", Neighborhood: "Old Town", SalesPrice: 350000, Bedrooms: 3, Bathrooms: 2,
+			GarageSize: 2, SquareFeet: 1800, LotSize: 6000, DateListed: "2024-01-02T03:04:05Z",
+			LastModified: "2024-01-03T03:04:05Z", ListPhoto: "https://example.com/a.jpg",
+			PhotoArray: []string{"https://example.com/a.jpg"}, Description: "A house",
+			User: "seller@example.com", Deleted: "false", Latitude: 44.0582, Longitude: -121.3153,
+		}.WithIndexKeys(),
+		"auth":    models.AuthForm{Email: "admin@example.com", Password: "$2a$10$hash", UserToken: "token"},
+		"contact": models.ContactForm{Name: "Reader", Email: "reader@example.com", Website: "https://example.com", Message: "Hi", RobotCheck: 4, RobotNum: 4},
+	}
+}
+
+var backupTables = map[string]string{
+	"articles": "T-Articles",
+	"listings": "T-Listings",
+	"auth":     "T-Auth",
+	"contact":  "T-Contact",
+}
+
+func backupTableFlags() []string {
+	args := []string{}
+	for kind, table := range backupTables {
+		args = append(args, "--"+kind, table)
+	}
+	return args
+}
+
+// stubBackupTables answers a Scan of each backup table with the items in
+// stored, and every other call as if the tables were empty.
+func stubBackupTables(t *testing.T, stored map[string][]map[string]any) *dynamoStub {
+	return stubDynamoDB(t, func(op string, body map[string]any) any {
+		switch op {
+		case "Scan":
+			items := stored[body["TableName"].(string)]
+			return map[string]any{"Items": items, "Count": len(items)}
+		case "Query":
+			return map[string]any{"Items": []any{}, "Count": 0}
+		}
+		return nil
+	})
+}
+
+// jsonValue is v as it reads back from JSON, for comparing with the
+// request bodies the stub recorded.
+func jsonValue(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	require.NoError(t, err)
+	var out any
+	require.NoError(t, json.Unmarshal(data, &out))
+	return out
+}
+
+func TestBackupRoundTrip(t *testing.T) {
+	silenceLogrus(t)
+	samples := backupSamples()
+	stored := map[string][]map[string]any{}
+	for kind, sample := range samples {
+		stored[backupTables[kind]] = []map[string]any{storedItem(t, sample)}
+	}
+	stub := stubBackupTables(t, stored)
+	out := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	require.NoError(t, export(append(backupTableFlags(), "--out", out)))
+	require.NoError(t, importBackup(append(backupTableFlags(), "--dry-run", out)))
+
+	keys := map[string]any{}
+	for _, get := range stub.Calls("GetItem") {
+		keys[get["TableName"].(string)] = get["Key"]
+	}
+	for _, kind := range backupKinds {
+		table := backupTables[kind.name]
+		want := jsonValue(t, stored[table][0][kind.key])
+		require.NotNil(t, want, "%s: the sample has no %s", kind.name, kind.key)
+		if kind.name != "articles" {
+			assert.Equal(t, map[string]any{kind.key: want}, keys[table], "%s: the conflict check looks the item up by its key", kind.name)
+		}
+	}
+
+	require.NoError(t, importBackup(append(backupTableFlags(), "--confirm", out)))
+	written := map[string]any{}
+	for _, put := range stub.Calls("PutItem") {
+		written[put["TableName"].(string)] = put["Item"]
+	}
+	for _, tx := range stub.Calls("TransactWriteItems") {
+		put := tx["TransactItems"].([]any)[0].(map[string]any)["Put"].(map[string]any)
+		written[put["TableName"].(string)] = put["Item"]
+	}
+	for _, kind := range backupKinds {
+		table := backupTables[kind.name]
+		want := jsonValue(t, stored[table][0]).(map[string]any)
+		got, ok := written[table].(map[string]any)
+		require.True(t, ok, "%s: nothing was written to %s", kind.name, table)
+		assert.Equal(t, want[kind.key], got[kind.key], "%s: the item keeps its %s", kind.name, kind.key)
+		if kind.name == "articles" {
+			// The import is a new version of the article.
+			delete(want, "version")
+			delete(got, "version")
+		}
+		assert.Equal(t, want, got, "%s: the item is restored as the blog stored it", kind.name)
+	}
+}
+
+// writeTarball writes a gzipped tarball of files, in order, to a temp file.
+func writeTarball(t *testing.T, files ...[2]string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+	for _, file := range files {
+		require.NoError(t, tw.WriteHeader(&tar.Header{Name: file[0], Mode: 0o600, Size: int64(len(file[1]))}))
+		_, err := tw.Write([]byte(file[1]))
+		require.NoError(t, err)
+	}
+	require.NoError(t, tw.Close())
+	require.NoError(t, gz.Close())
+	return path
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestUnpackBackup(t *testing.T) {
+	const auth = `{"email":"a@example.com","password":"x"}` + "\n" + `{"email":"b@example.com","password":"y"}` + "\n"
+	entry := backupTable{Kind: "auth", Table: "Auth", File: "auth.jsonl", Items: 2, SHA256: sha256Hex(auth)}
+	manifest := func(edit func(*backupManifest)) [2]string {
+		m := backupManifest{Format: backupFormat, Created: "2024-01-02T03:04:05Z", Region: "us-west-2", Tables: []backupTable{entry}}
+		if edit != nil {
+			edit(&m)
+		}
+		data, err := json.Marshal(m)
+		require.NoError(t, err)
+		return [2]string{backupManifestFile, string(data)}
+	}
+
+	tests := []struct {
+		name  string
+		files [][2]string
+		err   string
+	}{
+		{
+			name:  "Valid",
+			files: [][2]string{manifest(nil), {"auth.jsonl", auth}},
+		},
+		{
+			name:  "NoManifest",
+			files: [][2]string{{"auth.jsonl", auth}, manifest(nil)},
+			err:   "not a backup: it must start with manifest.json",
+		},
+		{
+			name:  "WrongFormat",
+			files: [][2]string{manifest(func(m *backupManifest) { m.Format = backupFormat + 1 }), {"auth.jsonl", auth}},
+			err:   "backup format 2; this daemon reads format 1",
+		},
+		{
+			name:  "TamperedChecksum",
+			files: [][2]string{manifest(nil), {"auth.jsonl", auth[:len(auth)-2] + "z\n"}},
+			err:   "auth.jsonl: checksum",
+		},
+		{
+			name:  "MissingFile",
+			files: [][2]string{manifest(nil)},
+			err:   "auth.jsonl is missing",
+		},
+		{
+			name:  "ExtraFile",
+			files: [][2]string{manifest(nil), {"auth.jsonl", auth}, {"contact.jsonl", "{}\n"}},
+			err:   "contact.jsonl is not in the manifest",
+		},
+		{
+			name:  "ItemCount",
+			files: [][2]string{manifest(func(m *backupManifest) { m.Tables[0].Items = 3 }), {"auth.jsonl", auth}},
+			err:   "auth.jsonl: 2 item(s), manifest says 3",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			got, err := unpackBackup(writeTarball(t, tt.files...), dir)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, []backupTable{entry}, got.Tables)
+			data, err := os.ReadFile(filepath.Join(dir, "auth.jsonl"))
+			require.NoError(t, err)
+			assert.Equal(t, auth, string(data))
+		})
+	}
+}
+
+func TestImportBackupRefusesTamperedTarball(t *testing.T) {
+	silenceLogrus(t)
+	samples := backupSamples()
+	stored := map[string][]map[string]any{backupTables["auth"]: {storedItem(t, samples["auth"])}}
+	stub := stubBackupTables(t, stored)
+	out := filepath.Join(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, export(append(backupTableFlags(), "--only", "auth", "--out", out)))
+
+	// Re-pack the backup with one byte of the auth file changed.
+	dir := t.TempDir()
+	manifest, err := unpackBackup(out, dir)
+	require.NoError(t, err)
+	data, err := json.Marshal(manifest)
+	require.NoError(t, err)
+	auth, err := os.ReadFile(filepath.Join(dir, "auth.jsonl"))
+	require.NoError(t, err)
+	auth[len(auth)-2] = ' '
+	tampered := writeTarball(t, [2]string{backupManifestFile, string(data)}, [2]string{"auth.jsonl", string(auth)})
+
+	err = importBackup(append(backupTableFlags(), "--confirm", "--conflict", "overwrite", tampered))
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "checksum")
+	assert.Empty(t, stub.Calls("PutItem"), "Nothing is written from a tarball that fails its checks")
+}
+
+func TestSelectKinds(t *testing.T) {
+	tests := []struct {
+		only string
+		want []string
+		err  string
+	}{
+		{only: "", want: []string{"articles", "listings", "auth", "contact"}},
+		{only: "contact", want: []string{"contact"}},
+		{only: "listings, auth", want: []string{"listings", "auth"}},
+		{only: "articles,comments", err: `unknown kind "comments"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.only, func(t *testing.T) {
+			kinds, err := selectKinds(tt.only)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			names := []string{}
+			for _, kind := range kinds {
+				names = append(names, kind.name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+}
+
+func TestExportLegacyListingNumbers(t *testing.T) {
+	silenceLogrus(t)
+	listing := storedItem(t, backupSamples()["listings"])
+	// Saved before listing numbers were typed, and never migrated.
+	listing["Sales Price"] = map[string]any{"S": "$350,000"}
+	listing["Bedrooms"] = map[string]any{"S": "3"}
+	listing["Lot Size"] = map[string]any{"S": "call agent"}
+	listing["Garage Size"] = map[string]any{"S": ""}
+	stub := stubBackupTables(t, map[string][]map[string]any{backupTables["listings"]: {listing}})
+	out := filepath.Join(t.TempDir(), "backup.tar.gz")
+
+	require.NoError(t, export(append(backupTableFlags(), "--only", "listings", "--out", out)))
+	require.NoError(t, importBackup(append(backupTableFlags(), "--only", "listings", "--confirm", out)))
+
+	puts := stub.Calls("PutItem")
+	require.Len(t, puts, 1)
+	item := puts[0]["Item"].(map[string]any)
+	assert.Equal(t, map[string]any{"N": "350000"}, item["Sales Price"])
+	assert.Equal(t, map[string]any{"N": "3"}, item["Bedrooms"])
+	assert.Equal(t, map[string]any{"N": "0"}, item["Lot Size"], "An unreadable number exports as zero")
+	assert.Equal(t, map[string]any{"N": "0"}, item["Garage Size"])
+	assert.Equal(t, map[string]any{"N": "1800"}, item["Square Feet"], "Numbers stored as numbers are untouched")
+}
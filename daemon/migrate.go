@@ -0,0 +1,801 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/etzelm/blog-in-golang/src/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// migrate brings existing tables up to the data model the blog's indexed
+// reads need, and is safe to re-run:
+//
+//   - the category index table for ARTICLES is created if missing;
+//   - every article gets a category-set matching its categories, and the
+//     index gets exactly one (category, post-id) entry per pair;
+//   - every article gets a slug-key matching its ResolvedSlug, and the
+//     articles table gets its slug-key GSI;
+//   - every article's created-date and modified-date, and every listing's
+//     Date Listed and Last Modified, are rewritten in RFC 3339 (see
+//     models.ParseDate for what's read); a date that doesn't parse is
+//     reported and left alone;
+//   - every listing gets user-key and city-key, and the Listings table gets
+//     its user-key and city-key GSIs;
+//   - every listing's numeric fields (Sales Price, Bedrooms and so on) that
+//     are still strings are rewritten as numbers (see models.ParseAmount);
+//     a blank one is removed, and one that doesn't parse is reported and left
+//     alone;
+//   - every listing without coordinates is placed by its ZIP code, from the
+//     table at --zip-centroids (see models.LoadZIPCentroids), when one is
+//     given; one whose ZIP isn't there is reported and left unplaced. Every placed listing gets
+//     geo-cell and geohash, and the Listings table gets its geo-cell GSI.
+//
+// Articles are rewritten through putArticle, conditional on the version
+// scanned, so each change gets a "migrate" revision and none overwrites a
+// save made since the scan.
+//
+// As with publish, --dry-run reports what would change and --confirm does it.
+//
+//	daemon migrate --dry-run
+//	daemon migrate --confirm [--listings Listings] [--zip-centroids 2023_Gaz_zcta_national.txt]
+func migrate(args []string) error {
+	flags := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	dryRun := flags.Bool("dry-run", false, "print what would change without writing")
+	confirm := flags.Bool("confirm", false, "write the changes")
+	listings := flags.String("listings", models.ListingsTable, "name of the listings table")
+	zipPath := zipCentroidsFlag(flags, "")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dryRun == *confirm {
+		return errors.New("migrate: pass exactly one of --dry-run or --confirm")
+	}
+	geocoder, err := loadGeocoder(*zipPath)
+	if err != nil {
+		return err
+	}
+
+	table := os.Getenv("ARTICLES")
+	if table == "" {
+		return errors.New("migrate: ARTICLES is not set")
+	}
+
+	dbSvc, err := articlesClient()
+	if err != nil {
+		return err
+	}
+
+	if err := migrateArticles(dbSvc, table, *dryRun); err != nil {
+		return err
+	}
+	if err := migrateArticleDates(dbSvc, table, *dryRun); err != nil {
+		return err
+	}
+	if err := migrateListings(dbSvc, *listings, *dryRun); err != nil {
+		return err
+	}
+	if err := migrateListingNumbers(dbSvc, *listings, *dryRun); err != nil {
+		return err
+	}
+	return migrateListingLocations(dbSvc, *listings, geocoder, *dryRun)
+}
+
+// zipCentroidsFlag adds --zip-centroids, the ZIP table listings are placed
+// with, defaulting to def.
+func zipCentroidsFlag(flags *flag.FlagSet, def string) *string {
+	return flags.String("zip-centroids", def,
+		"ZIP table to geocode listings with, in the Census ZCTA gazetteer format; empty to skip geocoding")
+}
+
+// loadGeocoder loads the ZIP table at path, or returns nil, with a warning,
+// if path is empty.
+func loadGeocoder(path string) (models.Geocoder, error) {
+	if path == "" {
+		log.Warn("No --zip-centroids; listings won't be geocoded")
+		return nil, nil
+	}
+	zips, err := models.OpenZIPCentroids(path)
+	if err != nil {
+		return nil, fmt.Errorf("loading the ZIP table: %v", err)
+	}
+	return zips, nil
+}
+
+// categoryEntry is one item of the category index table.
+type categoryEntry struct {
+	Category string `dynamodbav:"category"`
+	PostID   int    `dynamodbav:"post-id"`
+}
+
+func migrateArticles(dbSvc *dynamodb.DynamoDB, table string, dryRun bool) error {
+	index := models.CategoryIndexTable(table)
+	exists, err := tableExists(dbSvc, index)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		fmt.Printf("=== %s: create category index table\n", index)
+		if !dryRun {
+			if err := createCategoryIndex(dbSvc, index); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Whole items are read, as putArticle writes whole items.
+	var items []models.Item
+	err = dbSvc.ScanPages(&dynamodb.ScanInput{TableName: aws.String(table)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, av := range page.Items {
+				item := models.Item{}
+				if err := dynamodbattribute.UnmarshalMap(av, &item); err != nil {
+					log.WithError(err).Warn("Skipping unreadable article")
+					continue
+				}
+				items = append(items, item)
+			}
+			return true
+		})
+	if err != nil {
+		return fmt.Errorf("%s: %v", table, err)
+	}
+
+	want := map[categoryEntry]bool{}
+	updated := 0
+	for _, item := range items {
+		names := item.CategoryNames()
+		slug := item.ResolvedSlug()
+		if !sameSet(item.CategorySet, names) || item.SlugKey != slug {
+			updated++
+			fmt.Printf("=== %s post-id %d: category-set %q -> %q, slug-key %q -> %q\n", table, item.PostID,
+				item.CategorySet, names, item.SlugKey, slug)
+			if !dryRun {
+				latest, err := setArticleIndexAttributes(dbSvc, table, item)
+				if err != nil {
+					return err
+				}
+				if latest == nil {
+					continue
+				}
+				names = latest.CategoryNames()
+			}
+		}
+		for _, name := range names {
+			want[categoryEntry{name, item.PostID}] = true
+		}
+	}
+
+	have := map[categoryEntry]bool{}
+	if exists {
+		err = dbSvc.ScanPages(&dynamodb.ScanInput{TableName: aws.String(index)},
+			func(page *dynamodb.ScanOutput, lastPage bool) bool {
+				for _, av := range page.Items {
+					entry := categoryEntry{}
+					if err := dynamodbattribute.UnmarshalMap(av, &entry); err == nil {
+						have[entry] = true
+					}
+				}
+				return true
+			})
+		if err != nil {
+			return fmt.Errorf("%s: %v", index, err)
+		}
+	}
+
+	added, removed := 0, 0
+	for entry := range want {
+		if have[entry] {
+			continue
+		}
+		added++
+		if !dryRun {
+			if err := putCategoryEntry(dbSvc, index, entry); err != nil {
+				return err
+			}
+		}
+	}
+	for entry := range have {
+		if want[entry] {
+			continue
+		}
+		removed++
+		if !dryRun {
+			if err := deleteCategoryEntry(dbSvc, index, entry); err != nil {
+				return err
+			}
+		}
+	}
+
+	verb := "Migrated"
+	if dryRun {
+		verb = "Dry run: would migrate"
+	}
+	log.Infof("%s %d article(s) in %s; %d index entries to add and %d to remove in %s", verb, updated, table, added, removed, index)
+
+	return ensureIndex(dbSvc, table, models.ArticlesSlugIndex, "slug-key", dynamodb.ProjectionTypeKeysOnly, dryRun)
+}
+
+// migrateArticleDates rewrites article dates in the stored format. Like the
+// category pass it writes whole items, through putArticle, so it reads whole
+// items too.
+func migrateArticleDates(dbSvc *dynamodb.DynamoDB, table string, dryRun bool) error {
+	var items []models.Item
+	err := dbSvc.ScanPages(&dynamodb.ScanInput{TableName: aws.String(table)},
+		func(page *dynamodb.ScanOutput, lastPage bool) bool {
+			for _, av := range page.Items {
+				item := models.Item{}
+				if err := dynamodbattribute.UnmarshalMap(av, &item); err != nil {
+					log.WithError(err).Warn("Skipping unreadable article")
+					continue
+				}
+				items = append(items, item)
+			}
+			return true
+		})
+	if err != nil {
+		return fmt.Errorf("%s: %v", table, err)
+	}
+
+	updated := 0
+	for _, item := range items {
+		migrated, err := item.WithNormalizedDates()
+		if err != nil {
+			log.WithError(err).WithField("post-id", item.PostID).Warn("Skipping article with an unreadable date")
+			continue
+		}
+		if migrated.CreatedDate == item.CreatedDate && migrated.ModifiedDate == item.ModifiedDate {
+			continue
+		}
+		updated++
+		fmt.Printf("=== %s post-id %d: created-date %q -> %q, modified-date %q -> %q\n", table, item.PostID,
+			item.CreatedDate, migrated.CreatedDate, item.ModifiedDate, migrated.ModifiedDate)
+		if dryRun {
+			continue
+		}
+		if _, err := putArticle(dbSvc, table, &item, migrated, models.RevisionSourceMigrate); err != nil {
+			return fmt.Errorf("post-id %d: %v", item.PostID, err)
+		}
+	}
+
+	verb := "Migrated"
+	if dryRun {
+		verb = "Dry run: would migrate"
+	}
+	log.Infof("%s dates of %d article(s) in %s", verb, updated, table)
+	return nil
+}
+
+// setArticleIndexAttributes stores an article's category-set and slug-key
+// (derived by WithIndexAttributes) through putArticle, so the write is
+// conditional on the version scanned and recorded as a "migrate" revision.
+// If the article was saved since the scan, that save stored its own, and the
+// article as it is now is returned instead, nil if it has been deleted.
+func setArticleIndexAttributes(dbSvc *dynamodb.DynamoDB, table string, item models.Item) (*models.Item, error) {
+	stored, err := putArticle(dbSvc, table, &item, item, models.RevisionSourceMigrate)
+	if errors.Is(err, errArticleChanged) {
+		log.WithField("post-id", item.PostID).Info("Article changed since the scan; keeping its index attributes")
+		live, err := liveArticle(dbSvc, table, item.PostID)
+		if err != nil {
+			return nil, fmt.Errorf("post-id %d: %v", item.PostID, err)
+		}
+		return live, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("post-id %d: %v", item.PostID, err)
+	}
+	return &stored, nil
+}
+
+// syncCategoryIndex brings the category index in line with a published
+// article: entries for the categories it has, none for the ones live had.
+// A missing index table is only a warning so publishing still works before
+// migrate has been run.
+func syncCategoryIndex(dbSvc *dynamodb.DynamoDB, table string, live *models.Item, item models.Item) error {
+	index := models.CategoryIndexTable(table)
+	names := item.CategoryNames()
+	for _, name := range names {
+		err := putCategoryEntry(dbSvc, index, categoryEntry{name, item.PostID})
+		if isNotFound(err) {
+			log.WithField("table", index).Warn("No category index table; run `daemon migrate --confirm` to create it")
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if live == nil {
+		return nil
+	}
+	for _, name := range live.CategoryNames() {
+		if slices.Contains(names, name) {
+			continue
+		}
+		if err := deleteCategoryEntry(dbSvc, index, categoryEntry{name, item.PostID}); err != nil && !isNotFound(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+func putCategoryEntry(dbSvc *dynamodb.DynamoDB, index string, entry categoryEntry) error {
+	av, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return err
+	}
+	_, err = dbSvc.PutItem(&dynamodb.PutItemInput{Item: av, TableName: aws.String(index)})
+	return err
+}
+
+func deleteCategoryEntry(dbSvc *dynamodb.DynamoDB, index string, entry categoryEntry) error {
+	key, err := dynamodbattribute.MarshalMap(entry)
+	if err != nil {
+		return err
+	}
+	_, err = dbSvc.DeleteItem(&dynamodb.DeleteItemInput{Key: key, TableName: aws.String(index)})
+	return err
+}
+
+func createCategoryIndex(dbSvc *dynamodb.DynamoDB, index string) error {
+	_, err := dbSvc.CreateTable(categoryIndexInput(index))
+	if err != nil {
+		return fmt.Errorf("%s: CreateTable: %v", index, err)
+	}
+	log.WithField("table", index).Info("Waiting for category index table")
+	return dbSvc.WaitUntilTableExists(&dynamodb.DescribeTableInput{TableName: aws.String(index)})
+}
+
+func categoryIndexInput(index string) *dynamodb.CreateTableInput {
+	return &dynamodb.CreateTableInput{
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{
+			{AttributeName: aws.String("category"), AttributeType: aws.String("S")},
+			{AttributeName: aws.String("post-id"), AttributeType: aws.String("N")},
+		},
+		KeySchema: []*dynamodb.KeySchemaElement{
+			{AttributeName: aws.String("category"), KeyType: aws.String("HASH")},
+			{AttributeName: aws.String("post-id"), KeyType: aws.String("RANGE")},
+		},
+		BillingMode: aws.String(dynamodb.BillingModePayPerRequest),
+		TableName:   aws.String(index),
+	}
+}
+
+func migrateListings(dbSvc *dynamodb.DynamoDB, table string, dryRun bool) error {
+	updated := 0
+	var writeErr error
+	err := dbSvc.ScanPages(&dynamodb.ScanInput{
+		TableName:            aws.String(table),
+		ProjectionExpression: aws.String("MLS, #user, City, #ukey, #ckey, #listed, #modified"),
+		ExpressionAttributeNames: map[string]*string{
+			"#user":     aws.String("User"),
+			"#ukey":     aws.String("user-key"),
+			"#ckey":     aws.String("city-key"),
+			"#listed":   aws.String("Date Listed"),
+			"#modified": aws.String("Last Modified"),
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, av := range page.Items {
+			listing := models.Listing{}
+			if err := dynamodbattribute.UnmarshalMap(av, &listing); err != nil {
+				log.WithError(err).Warn("Skipping unreadable listing")
+				continue
+			}
+			keyed := listing.WithIndexKeys()
+			rekey := keyed.UserKey != listing.UserKey || keyed.CityKey != listing.CityKey
+			dated, err := listing.WithNormalizedDates()
+			if err != nil {
+				log.WithError(err).WithField("MLS", listing.MLS).Warn("Leaving unreadable listing dates alone")
+				dated = listing
+			}
+			redate := dated.DateListed != listing.DateListed || dated.LastModified != listing.LastModified
+			if !rekey && !redate {
+				continue
+			}
+			updated++
+			if rekey {
+				fmt.Printf("=== %s MLS %s: user-key %q, city-key %q\n", table, listing.MLS, keyed.UserKey, keyed.CityKey)
+			}
+			if redate {
+				fmt.Printf("=== %s MLS %s: Date Listed %q -> %q, Last Modified %q -> %q\n", table, listing.MLS,
+					listing.DateListed, dated.DateListed, listing.LastModified, dated.LastModified)
+			}
+			if dryRun {
+				continue
+			}
+			if rekey {
+				if writeErr = setListingKeys(dbSvc, table, keyed); writeErr != nil {
+					return false
+				}
+			}
+			if redate {
+				if writeErr = setListingDates(dbSvc, table, dated); writeErr != nil {
+					return false
+				}
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", table, err)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	verb := "Migrated"
+	if dryRun {
+		verb = "Dry run: would migrate"
+	}
+	log.Infof("%s %d listing(s) in %s", verb, updated, table)
+
+	for _, index := range []struct{ name, key string }{
+		{models.ListingsUserIndex, "user-key"},
+		{models.ListingsCityIndex, "city-key"},
+		{models.ListingsGeoIndex, "geo-cell"},
+	} {
+		if err := ensureIndex(dbSvc, table, index.name, index.key, dynamodb.ProjectionTypeAll, dryRun); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setListingKeys writes a listing's user-key and city-key, removing either
+// one that is empty.
+func setListingKeys(dbSvc *dynamodb.DynamoDB, table string, listing models.Listing) error {
+	names := map[string]*string{"#ukey": aws.String("user-key"), "#ckey": aws.String("city-key")}
+	values := map[string]*dynamodb.AttributeValue{}
+	set, remove := []string{}, []string{}
+	for _, attr := range []struct{ name, value string }{{"#ukey", listing.UserKey}, {"#ckey", listing.CityKey}} {
+		if attr.value == "" {
+			remove = append(remove, attr.name)
+			continue
+		}
+		values[":"+attr.name[1:]] = &dynamodb.AttributeValue{S: aws.String(attr.value)}
+		set = append(set, attr.name+" = :"+attr.name[1:])
+	}
+
+	update := ""
+	if len(set) > 0 {
+		update = "SET " + strings.Join(set, ", ")
+	}
+	if len(remove) > 0 {
+		update += " REMOVE " + strings.Join(remove, ", ")
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName:                aws.String(table),
+		Key:                      map[string]*dynamodb.AttributeValue{"MLS": {S: aws.String(listing.MLS)}},
+		ExpressionAttributeNames: names,
+		UpdateExpression:         aws.String(update),
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+	if _, err := dbSvc.UpdateItem(input); err != nil {
+		return fmt.Errorf("MLS %s: UpdateItem: %v", listing.MLS, err)
+	}
+	return nil
+}
+
+// setListingDates writes a listing's Date Listed and Last Modified. Only
+// dates that were already set are rewritten, so neither is ever empty.
+func setListingDates(dbSvc *dynamodb.DynamoDB, table string, listing models.Listing) error {
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	set := []string{}
+	for _, attr := range []struct{ key, name, value string }{
+		{"listed", "Date Listed", listing.DateListed},
+		{"modified", "Last Modified", listing.LastModified},
+	} {
+		if attr.value == "" {
+			continue
+		}
+		names["#"+attr.key] = aws.String(attr.name)
+		values[":"+attr.key] = &dynamodb.AttributeValue{S: aws.String(attr.value)}
+		set = append(set, "#"+attr.key+" = :"+attr.key)
+	}
+	_, err := dbSvc.UpdateItem(&dynamodb.UpdateItemInput{
+		TableName:                 aws.String(table),
+		Key:                       map[string]*dynamodb.AttributeValue{"MLS": {S: aws.String(listing.MLS)}},
+		ExpressionAttributeNames:  names,
+		ExpressionAttributeValues: values,
+		UpdateExpression:          aws.String("SET " + strings.Join(set, ", ")),
+	})
+	if err != nil {
+		return fmt.Errorf("MLS %s: UpdateItem: %v", listing.MLS, err)
+	}
+	return nil
+}
+
+// listingNumberFields are the attributes a models.Listing holds as Amounts.
+var listingNumberFields = []string{"Sales Price", "Bedrooms", "Bathrooms", "Garage Size", "Square Feet", "Lot Size"}
+
+// migrateListingNumbers rewrites the numeric fields listings saved before
+// they were typed still hold as strings. It works on raw attributes, since
+// the SDK won't read a string into a number.
+func migrateListingNumbers(dbSvc *dynamodb.DynamoDB, table string, dryRun bool) error {
+	names := map[string]*string{"#mls": aws.String("MLS")}
+	projection := []string{"#mls"}
+	for i, field := range listingNumberFields {
+		key := "#n" + strconv.Itoa(i)
+		names[key] = aws.String(field)
+		projection = append(projection, key)
+	}
+
+	updated := 0
+	var writeErr error
+	err := dbSvc.ScanPages(&dynamodb.ScanInput{
+		TableName:                aws.String(table),
+		ProjectionExpression:     aws.String(strings.Join(projection, ", ")),
+		ExpressionAttributeNames: names,
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, av := range page.Items {
+			if av["MLS"] == nil || av["MLS"].S == nil {
+				continue
+			}
+			mls := *av["MLS"].S
+			numbers := map[string]*string{}
+			for _, field := range listingNumberFields {
+				if av[field] == nil || av[field].S == nil {
+					continue
+				}
+				text := *av[field].S
+				if strings.TrimSpace(text) == "" {
+					numbers[field] = nil
+					continue
+				}
+				amount, err := models.ParseAmount(text)
+				if err != nil {
+					log.WithError(err).WithFields(log.Fields{"MLS": mls, "field": field}).Warn("Leaving unreadable listing number alone")
+					continue
+				}
+				numbers[field] = aws.String(strconv.FormatFloat(float64(amount), 'f', -1, 64))
+			}
+			if len(numbers) == 0 {
+				continue
+			}
+			updated++
+			for _, field := range listingNumberFields {
+				if n, ok := numbers[field]; ok {
+					fmt.Printf("=== %s MLS %s: %s %q -> %s\n", table, mls, field, *av[field].S, aws.StringValue(n))
+				}
+			}
+			if dryRun {
+				continue
+			}
+			if writeErr = setListingNumbers(dbSvc, table, mls, numbers); writeErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", table, err)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	verb := "Converted"
+	if dryRun {
+		verb = "Dry run: would convert"
+	}
+	log.Infof("%s the numbers of %d listing(s) in %s", verb, updated, table)
+	return nil
+}
+
+// setListingNumbers writes numbers, by attribute name, to a listing,
+// removing the attributes whose number is nil.
+func setListingNumbers(dbSvc *dynamodb.DynamoDB, table, mls string, numbers map[string]*string) error {
+	names := map[string]*string{}
+	values := map[string]*dynamodb.AttributeValue{}
+	set, remove := []string{}, []string{}
+	for i, field := range listingNumberFields {
+		n, ok := numbers[field]
+		if !ok {
+			continue
+		}
+		key := "n" + strconv.Itoa(i)
+		names["#"+key] = aws.String(field)
+		if n == nil {
+			remove = append(remove, "#"+key)
+			continue
+		}
+		values[":"+key] = &dynamodb.AttributeValue{N: n}
+		set = append(set, "#"+key+" = :"+key)
+	}
+
+	update := ""
+	if len(set) > 0 {
+		update = "SET " + strings.Join(set, ", ")
+	}
+	if len(remove) > 0 {
+		update += " REMOVE " + strings.Join(remove, ", ")
+	}
+	input := &dynamodb.UpdateItemInput{
+		TableName:                aws.String(table),
+		Key:                      map[string]*dynamodb.AttributeValue{"MLS": {S: aws.String(mls)}},
+		ExpressionAttributeNames: names,
+		UpdateExpression:         aws.String(update),
+	}
+	if len(values) > 0 {
+		input.ExpressionAttributeValues = values
+	}
+	if _, err := dbSvc.UpdateItem(input); err != nil {
+		return fmt.Errorf("MLS %s: UpdateItem: %v", mls, err)
+	}
+	return nil
+}
+
+// migrateListingLocations places the listings without coordinates with
+// geocoder, if there is one, and brings every listing's geo-cell and geohash
+// in line with its coordinates.
+func migrateListingLocations(dbSvc *dynamodb.DynamoDB, table string, geocoder models.Geocoder, dryRun bool) error {
+	updated, unplaced := 0, 0
+	var writeErr error
+	err := dbSvc.ScanPages(&dynamodb.ScanInput{
+		TableName:            aws.String(table),
+		ProjectionExpression: aws.String("MLS, #zip, Latitude, Longitude, #cell, #hash"),
+		ExpressionAttributeNames: map[string]*string{
+			"#zip":  aws.String("Zip Code"),
+			"#cell": aws.String("geo-cell"),
+			"#hash": aws.String("geohash"),
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, av := range page.Items {
+			listing := models.Listing{}
+			if err := dynamodbattribute.UnmarshalMap(av, &listing); err != nil {
+				log.WithError(err).Warn("Skipping unreadable listing")
+				continue
+			}
+			placed := listing
+			if _, ok := listing.Location(); !ok && geocoder != nil {
+				var err error
+				if placed, err = listing.WithLocation(context.Background(), geocoder); err != nil {
+					log.WithError(err).WithField("MLS", listing.MLS).Warn("Leaving listing unplaced")
+					unplaced++
+				}
+			}
+			keyed := placed.WithIndexKeys()
+			if keyed.Latitude == listing.Latitude && keyed.Longitude == listing.Longitude &&
+				keyed.GeoCell == listing.GeoCell && keyed.Geohash == listing.Geohash {
+				continue
+			}
+			updated++
+			fmt.Printf("=== %s MLS %s: %v,%v, geo-cell %q\n", table, listing.MLS, keyed.Latitude, keyed.Longitude, keyed.GeoCell)
+			if dryRun {
+				continue
+			}
+			if writeErr = setListingLocation(dbSvc, table, keyed); writeErr != nil {
+				return false
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("%s: %v", table, err)
+	}
+	if writeErr != nil {
+		return writeErr
+	}
+
+	verb := "Placed"
+	if dryRun {
+		verb = "Dry run: would place"
+	}
+	log.Infof("%s %d listing(s) in %s; %d couldn't be geocoded", verb, updated, table, unplaced)
+	return nil
+}
+
+// setListingLocation writes a listing's coordinates, geo-cell and geohash,
+// or removes the geo-cell and geohash of a listing without a location.
+func setListingLocation(dbSvc *dynamodb.DynamoDB, table string, listing models.Listing) error {
+	input := &dynamodb.UpdateItemInput{
+		TableName: aws.String(table),
+		Key:       map[string]*dynamodb.AttributeValue{"MLS": {S: aws.String(listing.MLS)}},
+		ExpressionAttributeNames: map[string]*string{
+			"#cell": aws.String("geo-cell"),
+			"#hash": aws.String("geohash"),
+		},
+		UpdateExpression: aws.String("REMOVE #cell, #hash"),
+	}
+	if _, ok := listing.Location(); ok {
+		input.UpdateExpression = aws.String("SET Latitude = :lat, Longitude = :lng, #cell = :cell, #hash = :hash")
+		input.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":lat":  {N: aws.String(strconv.FormatFloat(listing.Latitude, 'f', -1, 64))},
+			":lng":  {N: aws.String(strconv.FormatFloat(listing.Longitude, 'f', -1, 64))},
+			":cell": {S: aws.String(listing.GeoCell)},
+			":hash": {S: aws.String(listing.Geohash)},
+		}
+	}
+	if _, err := dbSvc.UpdateItem(input); err != nil {
+		return fmt.Errorf("MLS %s: UpdateItem: %v", listing.MLS, err)
+	}
+	return nil
+}
+
+// ensureIndex adds a GSI partitioned on key with the given projection
+// (dynamodb.ProjectionTypeAll or KeysOnly), unless the table already has one
+// by that name. DynamoDB builds one new index at a time, so it waits for the
+// backfill to finish.
+func ensureIndex(dbSvc *dynamodb.DynamoDB, table, name, key, projection string, dryRun bool) error {
+	desc, err := dbSvc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if err != nil {
+		return fmt.Errorf("%s: DescribeTable: %v", table, err)
+	}
+	for _, gsi := range desc.Table.GlobalSecondaryIndexes {
+		if aws.StringValue(gsi.IndexName) == name {
+			return nil
+		}
+	}
+
+	fmt.Printf("=== %s: create GSI %s on %s\n", table, name, key)
+	if dryRun {
+		return nil
+	}
+
+	create := &dynamodb.CreateGlobalSecondaryIndexAction{
+		IndexName:  aws.String(name),
+		KeySchema:  []*dynamodb.KeySchemaElement{{AttributeName: aws.String(key), KeyType: aws.String("HASH")}},
+		Projection: &dynamodb.Projection{ProjectionType: aws.String(projection)},
+	}
+	billing := desc.Table.BillingModeSummary
+	if billing == nil || aws.StringValue(billing.BillingMode) != dynamodb.BillingModePayPerRequest {
+		create.ProvisionedThroughput = &dynamodb.ProvisionedThroughput{
+			ReadCapacityUnits:  desc.Table.ProvisionedThroughput.ReadCapacityUnits,
+			WriteCapacityUnits: desc.Table.ProvisionedThroughput.WriteCapacityUnits,
+		}
+	}
+	_, err = dbSvc.UpdateTable(&dynamodb.UpdateTableInput{
+		TableName:            aws.String(table),
+		AttributeDefinitions: []*dynamodb.AttributeDefinition{{AttributeName: aws.String(key), AttributeType: aws.String("S")}},
+		GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+			{Create: create},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: UpdateTable: %v", table, err)
+	}
+
+	log.WithFields(log.Fields{"table": table, "index": name}).Info("Waiting for index to build")
+	return waitForTable(dbSvc, table)
+}
+
+func tableExists(dbSvc *dynamodb.DynamoDB, table string) (bool, error) {
+	_, err := dbSvc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+	if isNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("%s: DescribeTable: %v", table, err)
+	}
+	return true, nil
+}
+
+func isNotFound(err error) bool {
+	var aerr awserr.Error
+	return errors.As(err, &aerr) && aerr.Code() == dynamodb.ErrCodeResourceNotFoundException
+}
+
+// sameSet reports whether a and b hold the same strings, in any order.
+func sameSet(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, s := range b {
+		if !slices.Contains(a, s) {
+			return false
+		}
+	}
+	return true
+}
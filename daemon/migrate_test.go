@@ -0,0 +1,136 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMigrateArticles(t *testing.T) {
+	const table = "T-Articles"
+	// Written before category-set and slug-key existed.
+	article := models.Item{PostID: 7, PostTitle: "Hello", Categories: "Go,AWS", PostType: "blog", Version: 2}
+	index := []map[string]any{
+		storedItem(t, categoryEntry{"Go", 7}),
+		storedItem(t, categoryEntry{"Old", 7}),
+	}
+
+	stub := func(t *testing.T, stored models.Item, saved *models.Item, gsis ...any) *dynamoStub {
+		return stubDynamoDB(t, func(op string, body map[string]any) any {
+			switch op {
+			case "DescribeTable":
+				return map[string]any{"Table": map[string]any{"TableName": body["TableName"], "TableStatus": "ACTIVE",
+					"BillingModeSummary": map[string]any{"BillingMode": "PAY_PER_REQUEST"}, "GlobalSecondaryIndexes": gsis}}
+			case "Scan":
+				if body["TableName"] == table {
+					return map[string]any{"Items": []any{storedItem(t, stored)}, "Count": 1}
+				}
+				return map[string]any{"Items": index, "Count": len(index)}
+			case "Query":
+				return map[string]any{"Items": []any{}, "Count": 0}
+			case "TransactWriteItems":
+				if saved != nil {
+					return &dynamoError{Status: 400, Type: "TransactionCanceledException"}
+				}
+			case "GetItem":
+				if saved != nil {
+					return map[string]any{"Item": storedItem(t, saved.WithIndexAttributes())}
+				}
+			}
+			return nil
+		})
+	}
+	entries := func(calls []map[string]any, attr string) []any {
+		got := []any{}
+		for _, call := range calls {
+			got = append(got, call[attr])
+		}
+		return got
+	}
+
+	t.Run("Migrated", func(t *testing.T) {
+		silenceLogrus(t)
+		s := stub(t, article, nil)
+		require.NoError(t, migrateArticles(s.client(t), table, false))
+
+		assert.Empty(t, s.Calls("UpdateItem"), "Articles are written through putArticle")
+		txs := s.Calls("TransactWriteItems")
+		require.Len(t, txs, 1)
+		puts := txs[0]["TransactItems"].([]any)
+		put := puts[0].(map[string]any)["Put"].(map[string]any)
+		assert.Equal(t, "#v = :v", put["ConditionExpression"], "The write is conditional on the version scanned")
+		assert.Equal(t, map[string]any{":v": map[string]any{"N": "2"}}, put["ExpressionAttributeValues"])
+		item := put["Item"].(map[string]any)
+		assert.Equal(t, map[string]any{"SS": []any{"Go", "AWS"}}, item["category-set"])
+		assert.Equal(t, map[string]any{"S": "hello"}, item["slug-key"])
+		assert.Equal(t, map[string]any{"N": "3"}, item["version"])
+		rev := puts[1].(map[string]any)["Put"].(map[string]any)
+		assert.Equal(t, "T-Articles-Revisions", rev["TableName"])
+		assert.Equal(t, map[string]any{"S": models.RevisionSourceMigrate}, rev["Item"].(map[string]any)["source"])
+
+		assert.Equal(t, []any{jsonValue(t, storedItem(t, categoryEntry{"AWS", 7}))}, entries(s.Calls("PutItem"), "Item"))
+		assert.Equal(t, []any{jsonValue(t, storedItem(t, categoryEntry{"Old", 7}))}, entries(s.Calls("DeleteItem"), "Key"))
+
+		updates := s.Calls("UpdateTable")
+		require.Len(t, updates, 1)
+		gsi := updates[0]["GlobalSecondaryIndexUpdates"].([]any)[0].(map[string]any)["Create"].(map[string]any)
+		assert.Equal(t, models.ArticlesSlugIndex, gsi["IndexName"])
+		assert.Equal(t, map[string]any{"ProjectionType": "KEYS_ONLY"}, gsi["Projection"])
+	})
+
+	t.Run("InSync", func(t *testing.T) {
+		silenceLogrus(t)
+		migrated := article.WithIndexAttributes()
+		migrated.Categories = "Go,Old"
+		s := stub(t, migrated.WithIndexAttributes(), nil,
+			map[string]any{"IndexName": models.ArticlesSlugIndex, "IndexStatus": "ACTIVE"})
+		require.NoError(t, migrateArticles(s.client(t), table, false))
+		assert.Empty(t, s.Calls("TransactWriteItems"))
+		assert.Empty(t, s.Calls("PutItem"))
+		assert.Empty(t, s.Calls("DeleteItem"))
+		assert.Empty(t, s.Calls("UpdateTable"))
+	})
+
+	t.Run("SlugChanged", func(t *testing.T) {
+		silenceLogrus(t)
+		renamed := article.WithIndexAttributes()
+		renamed.Slug = "hi"
+		renamed.Categories = "Go,Old"
+		renamed.CategorySet = []string{"Go", "Old"}
+		s := stub(t, renamed, nil, map[string]any{"IndexName": models.ArticlesSlugIndex, "IndexStatus": "ACTIVE"})
+		require.NoError(t, migrateArticles(s.client(t), table, false))
+		txs := s.Calls("TransactWriteItems")
+		require.Len(t, txs, 1, "A stale slug-key is rewritten even when the category-set is current")
+		put := txs[0]["TransactItems"].([]any)[0].(map[string]any)["Put"].(map[string]any)
+		assert.Equal(t, map[string]any{"S": "hi"}, put["Item"].(map[string]any)["slug-key"])
+	})
+
+	t.Run("SavedSinceScan", func(t *testing.T) {
+		silenceLogrus(t)
+		saved := article
+		saved.Categories, saved.Version = "Rust", 3
+		s := stub(t, article, &saved)
+		require.NoError(t, migrateArticles(s.client(t), table, false))
+
+		assert.Len(t, s.Calls("TransactWriteItems"), 1)
+		assert.Empty(t, s.Calls("UpdateItem"), "The stale category-set isn't written")
+		assert.Equal(t, []any{jsonValue(t, storedItem(t, categoryEntry{"Rust", 7}))}, entries(s.Calls("PutItem"), "Item"),
+			"The index follows the article as it was saved")
+		assert.ElementsMatch(t, []any{
+			jsonValue(t, storedItem(t, categoryEntry{"Go", 7})),
+			jsonValue(t, storedItem(t, categoryEntry{"Old", 7})),
+		}, entries(s.Calls("DeleteItem"), "Key"))
+	})
+
+	t.Run("DryRun", func(t *testing.T) {
+		silenceLogrus(t)
+		s := stub(t, article, nil)
+		require.NoError(t, migrateArticles(s.client(t), table, true))
+		assert.Empty(t, s.Calls("TransactWriteItems"))
+		assert.Empty(t, s.Calls("UpdateTable"))
+		assert.Empty(t, s.Calls("PutItem"))
+		assert.Empty(t, s.Calls("DeleteItem"))
+	})
+}
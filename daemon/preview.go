@@ -0,0 +1,51 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+)
+
+// preview prints a signed link that shows one article, whatever its status,
+// until the link expires. Links are signed with PREVIEW_SECRET, which must
+// match the blog's.
+//
+//	daemon preview [--ttl 72h] [--site https://mitchelletzel.com] articles/awsEMR
+//	daemon preview 4
+func preview(args []string) error {
+	flags := flag.NewFlagSet("preview", flag.ContinueOnError)
+	ttl := flags.Duration("ttl", 72*time.Hour, "how long the link stays valid")
+	site := flags.String("site", models.SiteURL(), "public origin of the blog")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New("preview: pass exactly one article directory or post-id")
+	}
+	if *ttl <= 0 {
+		return errors.New("preview: --ttl must be positive")
+	}
+
+	secret := models.PreviewSecret()
+	if secret == "" {
+		return errors.New("preview: PREVIEW_SECRET is not set")
+	}
+
+	id, err := strconv.Atoi(flags.Arg(0))
+	if err != nil {
+		item, err := models.LoadArticleDir(flags.Arg(0))
+		if err != nil {
+			return fmt.Errorf("%s: %v", flags.Arg(0), err)
+		}
+		id = item.PostID
+	}
+
+	expires := time.Now().Add(*ttl).Truncate(time.Second)
+	fmt.Println(models.PreviewURL(*site, secret, id, expires))
+	fmt.Printf("Valid until %s\n", expires.Format(time.RFC1123))
+	return nil
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/etzelm/blog-in-golang/src/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// publish loads one or more article directories, validates them and diffs
+// each against the item with the same post-id in the table named by
+// ARTICLES. With --dry-run it stops there; with --confirm it upserts every
+// article that differs. One of the two is required so nothing is written by
+// accident. With --purge, every blog listed there has its page cache emptied
+// once something has been written, so the change shows up immediately.
+// Writes keep the category index (see migrate) in step with each article,
+// and record a revision of it as the editor's saves do.
+//
+//	daemon publish --dry-run articles/awsEMR [articles/infraCode ...]
+//	daemon publish --confirm --all [--root articles] [--purge https://mitchelletzel.com]
+func publish(args []string) error {
+	flags := flag.NewFlagSet("publish", flag.ContinueOnError)
+	all := flags.Bool("all", false, "publish every article directory under --root")
+	root := flags.String("root", "articles", "directory holding one sub-directory per article")
+	dryRun := flags.Bool("dry-run", false, "print what would change without writing")
+	confirm := flags.Bool("confirm", false, "write the changes")
+	purgeList := flags.String("purge", "", "comma-separated blog origins whose page cache to purge after writing")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *dryRun == *confirm {
+		return errors.New("publish: pass exactly one of --dry-run or --confirm")
+	}
+	purgeTo := splitSites(*purgeList)
+	token := os.Getenv("ADMIN_TOKEN")
+	if len(purgeTo) > 0 && token == "" {
+		return errors.New("publish: --purge needs ADMIN_TOKEN")
+	}
+
+	dirs := flags.Args()
+	if *all {
+		if len(dirs) > 0 {
+			return errors.New("publish: pass either --all or article directories, not both")
+		}
+		found, err := articleDirs(*root)
+		if err != nil {
+			return err
+		}
+		dirs = found
+	}
+	if len(dirs) == 0 {
+		return errors.New("publish: no article directories given")
+	}
+
+	items, err := loadArticles(dirs)
+	if err != nil {
+		return err
+	}
+
+	table := os.Getenv("ARTICLES")
+	if table == "" {
+		return errors.New("publish: ARTICLES is not set")
+	}
+
+	dbSvc, err := articlesClient()
+	if err != nil {
+		return err
+	}
+
+	if err := checkLiveSlugs(dbSvc, table, items, dirs); err != nil {
+		return err
+	}
+
+	changed := 0
+	for i, item := range items {
+		live, err := liveArticle(dbSvc, table, item.PostID)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dirs[i], err)
+		}
+
+		diff := models.DiffItems(live, &item, "live", "local")
+		if diff == "" {
+			fmt.Printf("=== %s (post-id %d): no changes\n", dirs[i], item.PostID)
+			continue
+		}
+		changed++
+		state := "changed"
+		if live == nil {
+			state = "new"
+		}
+		fmt.Printf("=== %s (post-id %d): %s\n%s", dirs[i], item.PostID, state, diff)
+		if *dryRun {
+			continue
+		}
+
+		// Bump the version so an editor session holding the old one gets a
+		// conflict instead of overwriting this publish.
+		log.WithFields(log.Fields{"dir": dirs[i], "id": item.PostID, "table": table}).Info("Putting article into DDB")
+		item, err = putArticle(dbSvc, table, live, item, models.RevisionSourcePublish)
+		if err != nil {
+			return fmt.Errorf("%s: %v", dirs[i], err)
+		}
+		if err := syncCategoryIndex(dbSvc, table, live, item); err != nil {
+			return fmt.Errorf("%s: category index: %v", dirs[i], err)
+		}
+	}
+
+	if *dryRun {
+		log.Infof("Dry run: %d of %d article(s) would change in %s; re-run with --confirm to write", changed, len(items), table)
+		return nil
+	}
+	log.Infof("Published %d of %d article(s) to %s", changed, len(items), table)
+	if changed > 0 {
+		return purgeSites(purgeTo, token, purgeRequest{All: true})
+	}
+	return nil
+}
+
+// liveArticle fetches the item currently stored under id, or nil if there
+// is none.
+func liveArticle(dbSvc *dynamodb.DynamoDB, table string, id int) (*models.Item, error) {
+	result, err := dbSvc.GetItem(&dynamodb.GetItemInput{
+		TableName: aws.String(table),
+		Key: map[string]*dynamodb.AttributeValue{
+			"post-id": {N: aws.String(strconv.Itoa(id))},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("GetItem: %v", err)
+	}
+	if len(result.Item) == 0 {
+		return nil, nil
+	}
+
+	item := models.Item{}
+	if err := dynamodbattribute.UnmarshalMap(result.Item, &item); err != nil {
+		return nil, err
+	}
+	return &item, nil
+}
+
+// checkLiveSlugs makes sure no article already in the table, other than the
+// ones being published, holds a slug one of items resolves to. Slugs map
+// /posts/:slug to a single article, so a clash would hide one of them.
+func checkLiveSlugs(dbSvc *dynamodb.DynamoDB, table string, items []models.Item, dirs []string) error {
+	publishing := map[int]bool{}
+	for _, item := range items {
+		publishing[item.PostID] = true
+	}
+
+	taken := map[string]int{}
+	err := dbSvc.ScanPages(&dynamodb.ScanInput{
+		TableName:            aws.String(table),
+		ProjectionExpression: aws.String("#id, #type, #title, #short, #slug"),
+		ExpressionAttributeNames: map[string]*string{
+			"#id":    aws.String("post-id"),
+			"#type":  aws.String("post-type"),
+			"#title": aws.String("post-title"),
+			"#short": aws.String("short-title"),
+			"#slug":  aws.String("slug"),
+		},
+	}, func(page *dynamodb.ScanOutput, lastPage bool) bool {
+		for _, av := range page.Items {
+			live := models.Item{}
+			if err := dynamodbattribute.UnmarshalMap(av, &live); err != nil {
+				log.WithError(err).Warn("Skipping unreadable item while checking slugs")
+				continue
+			}
+			if live.PostType != "quote" && !publishing[live.PostID] {
+				taken[live.ResolvedSlug()] = live.PostID
+			}
+		}
+		return true
+	})
+	if err != nil {
+		return fmt.Errorf("Scan: %v", err)
+	}
+
+	for i, item := range items {
+		if item.PostType == "quote" {
+			continue
+		}
+		if id, ok := taken[item.ResolvedSlug()]; ok {
+			return fmt.Errorf("%s: slug %q is already used by post-id %d in %s", dirs[i], item.ResolvedSlug(), id, table)
+		}
+	}
+	return nil
+}
+
+// articleDirs lists the sub-directories of root that hold an article, in
+// name order. Directories without a manifest or Markdown file are skipped.
+func articleDirs(root string) ([]string, error) {
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, err
+	}
+
+	dirs := []string{}
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		dir := filepath.Join(root, entry.Name())
+		if hasArticle(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs, nil
+}
+
+func hasArticle(dir string) bool {
+	for _, name := range []string{models.ArticleManifestFile, models.ArticleMarkdownFile} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// loadArticles reads and validates every directory before anything is
+// written, so one bad article doesn't leave the table half published.
+func loadArticles(dirs []string) ([]models.Item, error) {
+	items := make([]models.Item, 0, len(dirs))
+	seen := map[int]string{}
+	slugs := map[string]string{}
+	for _, dir := range dirs {
+		item, err := models.LoadArticleDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, err)
+		}
+		if err := item.Validate(); err != nil {
+			return nil, fmt.Errorf("%s: %v", dir, err)
+		}
+		if other, ok := seen[item.PostID]; ok {
+			return nil, fmt.Errorf("%s: post-id %d is already used by %s", dir, item.PostID, other)
+		}
+		seen[item.PostID] = dir
+		if item.PostType != "quote" {
+			slug := item.ResolvedSlug()
+			if other, ok := slugs[slug]; ok {
+				return nil, fmt.Errorf("%s: slug %q is already used by %s", dir, slug, other)
+			}
+			slugs[slug] = dir
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// articlesClient connects to DynamoDB the way the blog does: the region,
+// endpoint, retries and timeout come from models.AWSOptionsFromEnv, so
+// DEPLOYMENT=local or DYNAMODB_ENDPOINT points the daemon at DynamoDB Local
+// too.
+func articlesClient() (*dynamodb.DynamoDB, error) {
+	sess, opts, err := awsSession()
+	if err != nil {
+		return nil, err
+	}
+	return dynamodb.New(sess, endpointConfig(opts.DynamoDBEndpoint)), nil
+}
+
+func awsSession() (*session.Session, models.AWSOptions, error) {
+	opts, err := models.AWSOptionsFromEnv()
+	if err != nil {
+		return nil, opts, err
+	}
+	id := os.Getenv("AWS_ACCESS_KEY_ID")
+	key := os.Getenv("AWS_SECRET_ACCESS_KEY")
+	if (id == "" || key == "") && opts.Local {
+		id, key = models.LocalAccessKeyID, models.LocalSecretAccessKey
+	}
+	var myCredentials = credentials.NewStaticCredentials(id, key, "")
+
+	sess, err := session.NewSession(&aws.Config{
+		Credentials: myCredentials,
+		Region:      aws.String(opts.Region),
+		MaxRetries:  aws.Int(opts.MaxAttempts - 1),
+		HTTPClient:  &http.Client{Timeout: opts.Timeout},
+	})
+	if err != nil {
+		return nil, opts, err
+	}
+	return sess, opts, nil
+}
+
+// endpointConfig overrides a service's endpoint when one is set.
+func endpointConfig(endpoint string) *aws.Config {
+	cfg := &aws.Config{}
+	if endpoint != "" {
+		cfg.Endpoint = aws.String(endpoint)
+	}
+	return cfg
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/etzelm/blog-in-golang/src/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// purgeRequest mirrors the blog's handlers.CachePurgeRequest.
+type purgeRequest struct {
+	URL    string `json:"url,omitempty"`
+	Prefix string `json:"prefix,omitempty"`
+	All    bool   `json:"all,omitempty"`
+}
+
+// purge asks one or more running blogs to drop pages from their page cache
+// through POST /manage/cache/purge, authenticated with ADMIN_TOKEN.
+//
+//	daemon purge --all [--site https://mitchelletzel.com,http://localhost:8080]
+//	daemon purge --url /posts
+//	daemon purge --prefix /category/
+func purge(args []string) error {
+	flags := flag.NewFlagSet("purge", flag.ContinueOnError)
+	sites := flags.String("site", models.SiteURL(), "comma-separated origins of the blogs to purge")
+	url := flags.String("url", "", "purge exactly this path (and query)")
+	prefix := flags.String("prefix", "", "purge every path starting with this")
+	all := flags.Bool("all", false, "purge every cached page")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	req := purgeRequest{URL: *url, Prefix: *prefix, All: *all}
+	set := 0
+	for _, given := range []bool{req.URL != "", req.Prefix != "", req.All} {
+		if given {
+			set++
+		}
+	}
+	if set != 1 {
+		return errors.New("purge: pass exactly one of --url, --prefix or --all")
+	}
+
+	token := os.Getenv("ADMIN_TOKEN")
+	if token == "" {
+		return errors.New("purge: ADMIN_TOKEN is not set")
+	}
+	return purgeSites(splitSites(*sites), token, req)
+}
+
+// purgeSites sends req to every site, stopping at the first failure.
+func purgeSites(sites []string, token string, req purgeRequest) error {
+	for _, site := range sites {
+		purged, err := purgeCache(site, token, req)
+		if err != nil {
+			return fmt.Errorf("purge %s: %v", site, err)
+		}
+		log.WithFields(log.Fields{"site": site, "purged": purged}).Info("Purged page cache")
+	}
+	return nil
+}
+
+// purgeCache sends one purge request and returns how many pages it dropped.
+func purgeCache(site, token string, req purgeRequest) (int, error) {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return 0, err
+	}
+	httpReq, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(site, "/")+"/manage/cache/purge", bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Authorization", "Bearer "+token)
+
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	var result struct {
+		Purged int    `json:"purged"`
+		Error  string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil && resp.StatusCode == http.StatusOK {
+		return 0, fmt.Errorf("unreadable response: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		if result.Error != "" {
+			return 0, fmt.Errorf("%s: %s", resp.Status, result.Error)
+		}
+		return 0, errors.New(resp.Status)
+	}
+	return result.Purged, nil
+}
+
+// splitSites splits a comma-separated --site list, dropping blanks.
+func splitSites(list string) []string {
+	sites := []string{}
+	for _, site := range strings.Split(list, ",") {
+		if site = strings.TrimSpace(site); site != "" {
+			sites = append(sites, site)
+		}
+	}
+	return sites
+}
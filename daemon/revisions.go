@@ -0,0 +1,127 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/etzelm/blog-in-golang/src/models"
+)
+
+// errArticleChanged means an article write lost a race: the item wasn't
+// what putArticle was told it was, or its revision had already been taken.
+var errArticleChanged = errors.New("the article changed while it was being written; re-run to pick up the change")
+
+// putArticle writes item over live (nil for a new article) and records a
+// revision of it in the same transaction, like the blog's editor does (see
+// models.ArticleEditor), so the daemon's writes show up in an article's
+// history too. The write is conditional on live: if the stored article is
+// no longer at live's version, nothing is written and errArticleChanged is
+// returned. It returns item as stored, one version past both live and the
+// newest revision of its post-id.
+func putArticle(dbSvc *dynamodb.DynamoDB, table string, live *models.Item, item models.Item, source string) (models.Item, error) {
+	revisions := models.RevisionTable(table)
+	latest, err := latestRevision(dbSvc, revisions, item.PostID)
+	if err != nil {
+		return item, err
+	}
+	item.Version = latest + 1
+	if live != nil && live.Version >= item.Version {
+		item.Version = live.Version + 1
+	}
+
+	av, err := dynamodbattribute.MarshalMap(item.WithIndexAttributes())
+	if err != nil {
+		return item, err
+	}
+	rev, err := dynamodbattribute.MarshalMap(models.NewRevision(item, models.Change{Author: revisionAuthor(), Source: source}, time.Now()))
+	if err != nil {
+		return item, err
+	}
+
+	put := &dynamodb.Put{
+		TableName:                aws.String(table),
+		Item:                     av,
+		ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("post-id")},
+	}
+	if live != nil && live.Version == 0 {
+		// Written before versions existed.
+		put.ConditionExpression = aws.String("attribute_exists(#id) AND attribute_not_exists(#v)")
+		put.ExpressionAttributeNames["#v"] = aws.String("version")
+	} else if live != nil {
+		put.ConditionExpression = aws.String("#v = :v")
+		put.ExpressionAttributeNames = map[string]*string{"#v": aws.String("version")}
+		put.ExpressionAttributeValues = map[string]*dynamodb.AttributeValue{
+			":v": {N: aws.String(strconv.Itoa(live.Version))},
+		}
+	}
+
+	_, err = dbSvc.TransactWriteItems(&dynamodb.TransactWriteItemsInput{
+		TransactItems: []*dynamodb.TransactWriteItem{
+			{Put: put},
+			{Put: &dynamodb.Put{
+				TableName:                aws.String(revisions),
+				Item:                     rev,
+				ConditionExpression:      aws.String("attribute_not_exists(#id)"),
+				ExpressionAttributeNames: map[string]*string{"#id": aws.String("post-id")},
+			}},
+		},
+	})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == dynamodb.ErrCodeTransactionCanceledException {
+		return item, errArticleChanged
+	}
+	if err != nil {
+		return item, fmt.Errorf("TransactWriteItems: %v", err)
+	}
+	return item, nil
+}
+
+// latestRevision returns the version of the newest revision of id in
+// revisions, or 0 if it has none.
+func latestRevision(dbSvc *dynamodb.DynamoDB, revisions string, id int) (int, error) {
+	result, err := dbSvc.Query(&dynamodb.QueryInput{
+		TableName:                aws.String(revisions),
+		KeyConditionExpression:   aws.String("#id = :id"),
+		ExpressionAttributeNames: map[string]*string{"#id": aws.String("post-id")},
+		ExpressionAttributeValues: map[string]*dynamodb.AttributeValue{
+			":id": {N: aws.String(strconv.Itoa(id))},
+		},
+		ScanIndexForward: aws.Bool(false),
+		Limit:            aws.Int64(1),
+		ConsistentRead:   aws.Bool(true),
+	})
+	if isNotFound(err) {
+		return 0, fmt.Errorf("no revision table %s; run `daemon tables apply --confirm` to create it", revisions)
+	}
+	if err != nil {
+		return 0, fmt.Errorf("%s: Query: %v", revisions, err)
+	}
+	if len(result.Items) == 0 {
+		return 0, nil
+	}
+	rev := models.Revision{}
+	if err := dynamodbattribute.UnmarshalMap(result.Items[0], &rev); err != nil {
+		return 0, err
+	}
+	return rev.Version, nil
+}
+
+// revisionAuthor attributes the daemon's writes to whoever is running it.
+func revisionAuthor() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		name = u.Username
+	}
+	if name == "" {
+		name = "unknown"
+	}
+	return "daemon:" + name
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	"github.com/aws/aws-sdk-go/service/dynamodb/dynamodbattribute"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/etzelm/blog-in-golang/src/models"
+	log "github.com/sirupsen/logrus"
+)
+
+// seed sets up a local copy of the site's data on DynamoDB Local and an
+// S3-compatible store, so the blog and the realtor app run without an AWS
+// account. It creates any table in the schema (see tables) that is missing,
+// and the image bucket, then writes every article under --articles into
+// ARTICLES (Test-Articles by default) and every listing JSON file under
+// --listings, placed by ZIP code as the blog would (see migrate's
+// --zip-centroids, which here defaults to the blog's Central Oregon sample,
+// where the fixture listings are). Re-running it overwrites the fixtures and leaves
+// anything else alone.
+//
+// It only runs against an endpoint override, so it can't write to AWS:
+//
+//	DEPLOYMENT=local daemon seed [--articles articles] [--listings ../realtor/test-data] [--schema tables.json] [--zip-centroids path]
+func seed(args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ContinueOnError)
+	articleRoot := flags.String("articles", "articles", "directory holding one sub-directory per article")
+	listingDir := flags.String("listings", "../realtor/test-data", "directory of listing JSON files")
+	schemaPath := flags.String("schema", "tables.json", "schema file declaring the tables")
+	zipPath := zipCentroidsFlag(flags, filepath.Join("../blog", models.SampleZIPCentroidsPath))
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	geocoder, err := loadGeocoder(*zipPath)
+	if err != nil {
+		return err
+	}
+	schema, err := loadSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+
+	sess, opts, err := awsSession()
+	if err != nil {
+		return err
+	}
+	if opts.DynamoDBEndpoint == "" {
+		return errors.New("seed: refusing to write to AWS; set DEPLOYMENT=local or DYNAMODB_ENDPOINT")
+	}
+	dbSvc := dynamodb.New(sess, endpointConfig(opts.DynamoDBEndpoint))
+
+	table := os.Getenv("ARTICLES")
+	if table == "" {
+		table = "Test-Articles"
+	}
+
+	dirs, err := articleDirs(*articleRoot)
+	if err != nil {
+		return err
+	}
+	items, err := loadArticles(dirs)
+	if err != nil {
+		return err
+	}
+	listings, err := loadListings(*listingDir)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range schema.Tables {
+		exists, err := tableExists(dbSvc, spec.Name)
+		if err != nil {
+			return err
+		}
+		if exists {
+			log.WithField("table", spec.Name).Info("Table already exists")
+			continue
+		}
+		log.WithField("table", spec.Name).Info("Creating table")
+		if err := createSpecTable(dbSvc, spec); err != nil {
+			return err
+		}
+	}
+
+	for _, item := range items {
+		live, err := liveArticle(dbSvc, table, item.PostID)
+		if err != nil {
+			return fmt.Errorf("%s: post-id %d: %v", table, item.PostID, err)
+		}
+		if item, err = putArticle(dbSvc, table, live, item, models.RevisionSourceSeed); err != nil {
+			return fmt.Errorf("%s: post-id %d: %v", table, item.PostID, err)
+		}
+		if err := syncCategoryIndex(dbSvc, table, live, item); err != nil {
+			return err
+		}
+	}
+	log.Infof("Seeded %d article(s) into %s", len(items), table)
+
+	for _, listing := range listings {
+		listing, err := listing.WithLocation(context.Background(), geocoder)
+		if err != nil {
+			log.WithError(err).WithField("MLS", listing.MLS).Warn("Seeding listing without a location")
+		}
+		av, err := dynamodbattribute.MarshalMap(listing.WithIndexKeys())
+		if err != nil {
+			return err
+		}
+		if _, err := dbSvc.PutItem(&dynamodb.PutItemInput{Item: av, TableName: aws.String(models.ListingsTable)}); err != nil {
+			return fmt.Errorf("%s: PutItem MLS %s: %v", models.ListingsTable, listing.MLS, err)
+		}
+	}
+	log.Infof("Seeded %d listing(s) into %s", len(listings), models.ListingsTable)
+
+	if opts.S3Endpoint == "" {
+		log.Warn("No S3 endpoint override; skipping the image bucket")
+		return nil
+	}
+	return ensureBucket(s3.New(sess, endpointConfig(opts.S3Endpoint), &aws.Config{S3ForcePathStyle: aws.Bool(true)}), models.ImageBucket)
+}
+
+// loadListings reads every *.json file in dir as one listing, in name order.
+func loadListings(dir string) ([]models.Listing, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(paths)
+
+	listings := make([]models.Listing, 0, len(paths))
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		listing := models.Listing{}
+		if err := json.Unmarshal(data, &listing); err != nil {
+			return nil, fmt.Errorf("%s: %v", path, err)
+		}
+		if listing.MLS == "" {
+			return nil, fmt.Errorf("%s: MLS is required", path)
+		}
+		listings = append(listings, listing)
+	}
+	return listings, nil
+}
+
+func ensureBucket(svc *s3.S3, bucket string) error {
+	_, err := svc.CreateBucket(&s3.CreateBucketInput{Bucket: aws.String(bucket)})
+	if aerr, ok := err.(awserr.Error); ok && aerr.Code() == s3.ErrCodeBucketAlreadyOwnedByYou {
+		log.WithField("bucket", bucket).Info("Bucket already exists")
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("%s: CreateBucket: %v", bucket, err)
+	}
+	log.WithField("bucket", bucket).Info("Created bucket")
+	return nil
+}
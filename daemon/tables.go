@@ -0,0 +1,698 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/dynamodb"
+	log "github.com/sirupsen/logrus"
+)
+
+// tables keeps the live DynamoDB tables in line with the schema file
+// (tables.json): their keys, GSIs, TTL attribute and billing mode.
+//
+//   - plan prints what apply would change and writes nothing;
+//   - apply makes those changes, creating missing tables and indexes, and is
+//     safe to re-run;
+//   - destroy deletes one table declared in the schema.
+//
+// Deleting a table or an index loses data or breaks the reads built on it,
+// so apply and destroy ask for the table's name to be typed out first. A key
+// schema can't be changed in place: plan reports the drift and apply refuses
+// until the table has been destroyed and recreated.
+//
+// Table names may use ${VAR} and ${VAR:-default}, so one file covers
+// Test-Articles and Live-Articles.
+//
+//	daemon tables plan [--schema tables.json]
+//	daemon tables apply [--schema tables.json]
+//	daemon tables destroy [--schema tables.json] Test-Articles
+func tables(args []string) error {
+	if len(args) == 0 {
+		return errors.New("tables: pass one of plan, apply or destroy")
+	}
+	command := args[0]
+	flags := flag.NewFlagSet("tables "+command, flag.ContinueOnError)
+	schemaPath := flags.String("schema", "tables.json", "schema file declaring the tables")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	schema, err := loadSchema(*schemaPath)
+	if err != nil {
+		return err
+	}
+	dbSvc, err := articlesClient()
+	if err != nil {
+		return err
+	}
+
+	switch command {
+	case "plan":
+		changes, err := planTables(dbSvc, schema)
+		printPlan(changes, err)
+		return err
+	case "apply":
+		return applyTables(dbSvc, schema, bufio.NewReader(os.Stdin))
+	case "destroy":
+		if flags.NArg() != 1 {
+			return errors.New("tables destroy: pass exactly one table name")
+		}
+		return destroyTable(dbSvc, schema, flags.Arg(0), bufio.NewReader(os.Stdin))
+	default:
+		return fmt.Errorf("tables: unknown command %q; pass one of plan, apply or destroy", command)
+	}
+}
+
+// tableSchema is the schema file: every table the blog and realtor app use.
+type tableSchema struct {
+	Tables []tableSpec `json:"tables"`
+}
+
+type keySpec struct {
+	Name string `json:"name"`
+	Type string `json:"type"` // S, N or B
+}
+
+type indexSpec struct {
+	Name          string   `json:"name"`
+	HashKey       keySpec  `json:"hash-key"`
+	RangeKey      *keySpec `json:"range-key,omitempty"`
+	Projection    string   `json:"projection,omitempty"` // ALL (default) or KEYS_ONLY
+	ReadCapacity  int64    `json:"read-capacity,omitempty"`
+	WriteCapacity int64    `json:"write-capacity,omitempty"`
+}
+
+type tableSpec struct {
+	Name          string      `json:"name"`
+	HashKey       keySpec     `json:"hash-key"`
+	RangeKey      *keySpec    `json:"range-key,omitempty"`
+	Billing       string      `json:"billing"` // PAY_PER_REQUEST or PROVISIONED
+	ReadCapacity  int64       `json:"read-capacity,omitempty"`
+	WriteCapacity int64       `json:"write-capacity,omitempty"`
+	Indexes       []indexSpec `json:"indexes,omitempty"`
+	TTL           string      `json:"ttl,omitempty"` // attribute holding the expiry, if any
+}
+
+// loadSchema reads and validates a schema file, expanding the table names.
+func loadSchema(path string) (tableSchema, error) {
+	schema := tableSchema{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return schema, err
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&schema); err != nil {
+		return schema, fmt.Errorf("%s: %v", path, err)
+	}
+
+	seen := map[string]bool{}
+	for i := range schema.Tables {
+		spec := &schema.Tables[i]
+		name, err := expandEnv(spec.Name)
+		if err != nil {
+			return schema, fmt.Errorf("%s: table %q: %v", path, spec.Name, err)
+		}
+		if name == "" {
+			return schema, fmt.Errorf("%s: table %q expands to an empty name", path, spec.Name)
+		}
+		if seen[name] {
+			return schema, fmt.Errorf("%s: table %s is declared twice", path, name)
+		}
+		seen[name] = true
+		spec.Name = name
+		if err := spec.validate(); err != nil {
+			return schema, fmt.Errorf("%s: %s: %v", path, name, err)
+		}
+	}
+	return schema, nil
+}
+
+func (t tableSpec) validate() error {
+	switch t.Billing {
+	case dynamodb.BillingModePayPerRequest:
+		if t.ReadCapacity != 0 || t.WriteCapacity != 0 {
+			return errors.New("read-capacity and write-capacity only apply to PROVISIONED tables")
+		}
+	case dynamodb.BillingModeProvisioned:
+		if t.ReadCapacity < 1 || t.WriteCapacity < 1 {
+			return errors.New("a PROVISIONED table needs read-capacity and write-capacity")
+		}
+	default:
+		return fmt.Errorf("billing must be PAY_PER_REQUEST or PROVISIONED, got %q", t.Billing)
+	}
+
+	indexes := map[string]bool{}
+	for i := range t.Indexes {
+		index := &t.Indexes[i]
+		if index.Name == "" || indexes[index.Name] {
+			return fmt.Errorf("index %d needs a unique name", i)
+		}
+		indexes[index.Name] = true
+		if index.Projection == "" {
+			index.Projection = dynamodb.ProjectionTypeAll
+		}
+		if index.Projection != dynamodb.ProjectionTypeAll && index.Projection != dynamodb.ProjectionTypeKeysOnly {
+			return fmt.Errorf("index %s: projection must be ALL or KEYS_ONLY, got %q", index.Name, index.Projection)
+		}
+		provisioned := index.ReadCapacity > 0 && index.WriteCapacity > 0
+		if provisioned != (t.Billing == dynamodb.BillingModeProvisioned) {
+			return fmt.Errorf("index %s: read-capacity and write-capacity are required for, and only for, PROVISIONED tables", index.Name)
+		}
+	}
+	_, err := t.attributeDefinitions(t.Indexes...)
+	return err
+}
+
+// attributeDefinitions lists the type of every key attribute of the table
+// and the given indexes, checking that no attribute has two types.
+func (t tableSpec) attributeDefinitions(indexes ...indexSpec) ([]*dynamodb.AttributeDefinition, error) {
+	keys := []*keySpec{&t.HashKey, t.RangeKey}
+	for i := range indexes {
+		keys = append(keys, &indexes[i].HashKey, indexes[i].RangeKey)
+	}
+
+	types := map[string]string{}
+	names := []string{}
+	for _, key := range keys {
+		if key == nil {
+			continue
+		}
+		if key.Name == "" {
+			return nil, errors.New("every key needs a name")
+		}
+		if key.Type != "S" && key.Type != "N" && key.Type != "B" {
+			return nil, fmt.Errorf("key %s: type must be S, N or B, got %q", key.Name, key.Type)
+		}
+		if other, ok := types[key.Name]; ok {
+			if other != key.Type {
+				return nil, fmt.Errorf("key %s is declared as both %s and %s", key.Name, other, key.Type)
+			}
+			continue
+		}
+		types[key.Name] = key.Type
+		names = append(names, key.Name)
+	}
+
+	defs := make([]*dynamodb.AttributeDefinition, 0, len(names))
+	for _, name := range names {
+		defs = append(defs, &dynamodb.AttributeDefinition{AttributeName: aws.String(name), AttributeType: aws.String(types[name])})
+	}
+	return defs, nil
+}
+
+func keySchema(hash keySpec, rangeKey *keySpec) []*dynamodb.KeySchemaElement {
+	elements := []*dynamodb.KeySchemaElement{{AttributeName: aws.String(hash.Name), KeyType: aws.String(dynamodb.KeyTypeHash)}}
+	if rangeKey != nil {
+		elements = append(elements, &dynamodb.KeySchemaElement{AttributeName: aws.String(rangeKey.Name), KeyType: aws.String(dynamodb.KeyTypeRange)})
+	}
+	return elements
+}
+
+func throughput(read, write int64) *dynamodb.ProvisionedThroughput {
+	if read == 0 && write == 0 {
+		return nil
+	}
+	return &dynamodb.ProvisionedThroughput{ReadCapacityUnits: aws.Int64(read), WriteCapacityUnits: aws.Int64(write)}
+}
+
+func (t tableSpec) createInput() *dynamodb.CreateTableInput {
+	defs, _ := t.attributeDefinitions(t.Indexes...)
+	input := &dynamodb.CreateTableInput{
+		AttributeDefinitions:  defs,
+		KeySchema:             keySchema(t.HashKey, t.RangeKey),
+		BillingMode:           aws.String(t.Billing),
+		ProvisionedThroughput: throughput(t.ReadCapacity, t.WriteCapacity),
+		TableName:             aws.String(t.Name),
+	}
+	for _, index := range t.Indexes {
+		input.GlobalSecondaryIndexes = append(input.GlobalSecondaryIndexes, &dynamodb.GlobalSecondaryIndex{
+			IndexName:             aws.String(index.Name),
+			KeySchema:             keySchema(index.HashKey, index.RangeKey),
+			Projection:            &dynamodb.Projection{ProjectionType: aws.String(index.Projection)},
+			ProvisionedThroughput: throughput(index.ReadCapacity, index.WriteCapacity),
+		})
+	}
+	return input
+}
+
+// tableChange is one step of bringing a live table in line with its spec.
+type tableChange struct {
+	table       string
+	summary     string
+	destructive bool
+	apply       func(dbSvc *dynamodb.DynamoDB) error
+}
+
+// planTables diffs every table in the schema against the live one. Drift
+// that apply can't fix (a changed key schema) is returned as an error
+// alongside the changes it can.
+func planTables(dbSvc *dynamodb.DynamoDB, schema tableSchema) ([]tableChange, error) {
+	changes := []tableChange{}
+	var drift []error
+	for _, spec := range schema.Tables {
+		planned, err := planTable(dbSvc, spec)
+		if err != nil && !errors.Is(err, errKeyDrift) {
+			return nil, err
+		}
+		if err != nil {
+			drift = append(drift, err)
+			continue
+		}
+		changes = append(changes, planned...)
+	}
+	return changes, errors.Join(drift...)
+}
+
+var errKeyDrift = errors.New("key schema differs; destroy the table and apply to recreate it")
+
+func planTable(dbSvc *dynamodb.DynamoDB, spec tableSpec) ([]tableChange, error) {
+	desc, err := dbSvc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(spec.Name)})
+	if isNotFound(err) {
+		return []tableChange{{
+			table:   spec.Name,
+			summary: fmt.Sprintf("create table %s (%s)", spec.Name, describeSpec(spec)),
+			apply:   func(dbSvc *dynamodb.DynamoDB) error { return createSpecTable(dbSvc, spec) },
+		}}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: DescribeTable: %v", spec.Name, err)
+	}
+	live := desc.Table
+	types := map[string]string{}
+	for _, def := range live.AttributeDefinitions {
+		types[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+	}
+
+	if got, want := describeKeys(live.KeySchema, types), describeKeys(keySchema(spec.HashKey, spec.RangeKey), specTypes(spec)); got != want {
+		return nil, fmt.Errorf("%s: live key %s, schema key %s: %w", spec.Name, got, want, errKeyDrift)
+	}
+
+	changes := []tableChange{}
+	liveIndexes := map[string]*dynamodb.GlobalSecondaryIndexDescription{}
+	for _, gsi := range live.GlobalSecondaryIndexes {
+		liveIndexes[aws.StringValue(gsi.IndexName)] = gsi
+	}
+	specIndexes := map[string]indexSpec{}
+	for _, index := range spec.Indexes {
+		specIndexes[index.Name] = index
+	}
+
+	// Indexes that are gone from the schema, or whose keys or projection
+	// changed, are dropped first so the billing update below only has to
+	// cover the indexes that stay.
+	names := make([]string, 0, len(liveIndexes))
+	for name := range liveIndexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	rebuild := map[string]bool{}
+	for _, name := range names {
+		gsi := liveIndexes[name]
+		index, declared := specIndexes[name]
+		reason := "not in the schema"
+		if declared {
+			want := describeKeys(keySchema(index.HashKey, index.RangeKey), specTypes(spec)) + " " + index.Projection
+			got := describeKeys(gsi.KeySchema, types) + " " + aws.StringValue(gsi.Projection.ProjectionType)
+			if got == want {
+				continue
+			}
+			reason = fmt.Sprintf("changing %s to %s", got, want)
+			rebuild[name] = true
+		}
+		changes = append(changes, tableChange{
+			table:       spec.Name,
+			summary:     fmt.Sprintf("delete index %s on %s (%s)", name, spec.Name, reason),
+			destructive: true,
+			apply: func(dbSvc *dynamodb.DynamoDB) error {
+				return updateTable(dbSvc, &dynamodb.UpdateTableInput{
+					TableName: aws.String(spec.Name),
+					GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{
+						{Delete: &dynamodb.DeleteGlobalSecondaryIndexAction{IndexName: aws.String(name)}},
+					},
+				})
+			},
+		})
+	}
+
+	liveBilling := dynamodb.BillingModeProvisioned
+	if live.BillingModeSummary != nil {
+		liveBilling = aws.StringValue(live.BillingModeSummary.BillingMode)
+	}
+	kept := []indexSpec{}
+	for _, index := range spec.Indexes {
+		if liveIndexes[index.Name] != nil && !rebuild[index.Name] {
+			kept = append(kept, index)
+		}
+	}
+	if liveBilling != spec.Billing || (spec.Billing == dynamodb.BillingModeProvisioned && !sameCapacity(live, spec, kept)) {
+		summary := fmt.Sprintf("set %s billing to %s", spec.Name, spec.Billing)
+		if spec.Billing == dynamodb.BillingModeProvisioned {
+			summary += fmt.Sprintf(" (read %d, write %d)", spec.ReadCapacity, spec.WriteCapacity)
+		}
+		if liveBilling != spec.Billing {
+			summary += ", was " + liveBilling
+		}
+		changes = append(changes, tableChange{
+			table:   spec.Name,
+			summary: summary,
+			apply: func(dbSvc *dynamodb.DynamoDB) error {
+				input := &dynamodb.UpdateTableInput{
+					TableName:             aws.String(spec.Name),
+					BillingMode:           aws.String(spec.Billing),
+					ProvisionedThroughput: throughput(spec.ReadCapacity, spec.WriteCapacity),
+				}
+				for _, index := range kept {
+					if index.ReadCapacity == 0 {
+						continue
+					}
+					input.GlobalSecondaryIndexUpdates = append(input.GlobalSecondaryIndexUpdates, &dynamodb.GlobalSecondaryIndexUpdate{
+						Update: &dynamodb.UpdateGlobalSecondaryIndexAction{
+							IndexName:             aws.String(index.Name),
+							ProvisionedThroughput: throughput(index.ReadCapacity, index.WriteCapacity),
+						},
+					})
+				}
+				return updateTable(dbSvc, input)
+			},
+		})
+	}
+
+	// DynamoDB builds one new index per UpdateTable call.
+	for _, index := range spec.Indexes {
+		if liveIndexes[index.Name] != nil && !rebuild[index.Name] {
+			continue
+		}
+		changes = append(changes, tableChange{
+			table:   spec.Name,
+			summary: fmt.Sprintf("create index %s on %s (%s %s)", index.Name, spec.Name, describeKeys(keySchema(index.HashKey, index.RangeKey), specTypes(spec)), index.Projection),
+			apply: func(dbSvc *dynamodb.DynamoDB) error {
+				defs, err := spec.attributeDefinitions(index)
+				if err != nil {
+					return err
+				}
+				return updateTable(dbSvc, &dynamodb.UpdateTableInput{
+					TableName:            aws.String(spec.Name),
+					AttributeDefinitions: defs,
+					GlobalSecondaryIndexUpdates: []*dynamodb.GlobalSecondaryIndexUpdate{{
+						Create: &dynamodb.CreateGlobalSecondaryIndexAction{
+							IndexName:             aws.String(index.Name),
+							KeySchema:             keySchema(index.HashKey, index.RangeKey),
+							Projection:            &dynamodb.Projection{ProjectionType: aws.String(index.Projection)},
+							ProvisionedThroughput: throughput(index.ReadCapacity, index.WriteCapacity),
+						},
+					}},
+				})
+			},
+		})
+	}
+
+	ttl, err := dbSvc.DescribeTimeToLive(&dynamodb.DescribeTimeToLiveInput{TableName: aws.String(spec.Name)})
+	if err != nil {
+		return nil, fmt.Errorf("%s: DescribeTimeToLive: %v", spec.Name, err)
+	}
+	liveTTL := ""
+	if d := ttl.TimeToLiveDescription; d != nil {
+		status := aws.StringValue(d.TimeToLiveStatus)
+		if status == dynamodb.TimeToLiveStatusEnabled || status == dynamodb.TimeToLiveStatusEnabling {
+			liveTTL = aws.StringValue(d.AttributeName)
+		}
+	}
+	if liveTTL != spec.TTL {
+		if liveTTL != "" {
+			changes = append(changes, tableChange{
+				table:   spec.Name,
+				summary: fmt.Sprintf("disable TTL on %s.%s", spec.Name, liveTTL),
+				apply:   func(dbSvc *dynamodb.DynamoDB) error { return setTTL(dbSvc, spec.Name, liveTTL, false) },
+			})
+		}
+		if spec.TTL != "" {
+			changes = append(changes, tableChange{
+				table:   spec.Name,
+				summary: fmt.Sprintf("enable TTL on %s.%s", spec.Name, spec.TTL),
+				apply:   func(dbSvc *dynamodb.DynamoDB) error { return setTTL(dbSvc, spec.Name, spec.TTL, true) },
+			})
+		}
+	}
+	return changes, nil
+}
+
+// sameCapacity reports whether a provisioned table, and the indexes it
+// keeps, already have the capacity the spec asks for.
+func sameCapacity(live *dynamodb.TableDescription, spec tableSpec, kept []indexSpec) bool {
+	matches := func(got *dynamodb.ProvisionedThroughputDescription, read, write int64) bool {
+		return got != nil && aws.Int64Value(got.ReadCapacityUnits) == read && aws.Int64Value(got.WriteCapacityUnits) == write
+	}
+	if !matches(live.ProvisionedThroughput, spec.ReadCapacity, spec.WriteCapacity) {
+		return false
+	}
+	for _, index := range kept {
+		for _, gsi := range live.GlobalSecondaryIndexes {
+			if aws.StringValue(gsi.IndexName) == index.Name && !matches(gsi.ProvisionedThroughput, index.ReadCapacity, index.WriteCapacity) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func specTypes(spec tableSpec) map[string]string {
+	defs, _ := spec.attributeDefinitions(spec.Indexes...)
+	types := map[string]string{}
+	for _, def := range defs {
+		types[aws.StringValue(def.AttributeName)] = aws.StringValue(def.AttributeType)
+	}
+	return types
+}
+
+// describeKeys renders a key schema as e.g. "category S HASH, post-id N RANGE".
+func describeKeys(elements []*dynamodb.KeySchemaElement, types map[string]string) string {
+	parts := make([]string, 0, len(elements))
+	for _, element := range elements {
+		name := aws.StringValue(element.AttributeName)
+		parts = append(parts, fmt.Sprintf("%s %s %s", name, types[name], aws.StringValue(element.KeyType)))
+	}
+	return strings.Join(parts, ", ")
+}
+
+func describeSpec(spec tableSpec) string {
+	parts := []string{describeKeys(keySchema(spec.HashKey, spec.RangeKey), specTypes(spec)), spec.Billing}
+	for _, index := range spec.Indexes {
+		parts = append(parts, "index "+index.Name)
+	}
+	if spec.TTL != "" {
+		parts = append(parts, "TTL "+spec.TTL)
+	}
+	return strings.Join(parts, "; ")
+}
+
+// printPlan lists the changes, then any drift apply can't fix. It prints
+// nothing if planning failed outright.
+func printPlan(changes []tableChange, drift error) {
+	if changes == nil {
+		return
+	}
+	if len(changes) == 0 && drift == nil {
+		fmt.Println("No changes: the live tables match the schema")
+		return
+	}
+	for _, change := range changes {
+		mark := "~"
+		switch {
+		case change.destructive:
+			mark = "-"
+		case strings.HasPrefix(change.summary, "create"), strings.HasPrefix(change.summary, "enable"):
+			mark = "+"
+		}
+		fmt.Printf("%s %s\n", mark, change.summary)
+	}
+	if drift != nil {
+		for _, line := range strings.Split(drift.Error(), "\n") {
+			fmt.Printf("! %s\n", line)
+		}
+	}
+}
+
+// applyTables plans, asks for the name of every table with a destructive
+// change, then applies the changes in order. Nothing is written unless
+// every confirmation matches.
+func applyTables(dbSvc *dynamodb.DynamoDB, schema tableSchema, in *bufio.Reader) error {
+	changes, err := planTables(dbSvc, schema)
+	printPlan(changes, err)
+	if err != nil {
+		return fmt.Errorf("tables apply: nothing changed: %w", err)
+	}
+	if len(changes) == 0 {
+		return nil
+	}
+
+	confirmed := []string{}
+	for _, change := range changes {
+		if !change.destructive || slices.Contains(confirmed, change.table) {
+			continue
+		}
+		if err := confirmTable(in, change.table, "apply the destructive changes to"); err != nil {
+			return err
+		}
+		confirmed = append(confirmed, change.table)
+	}
+
+	for _, change := range changes {
+		log.Info(change.summary)
+		if err := change.apply(dbSvc); err != nil {
+			return err
+		}
+	}
+	log.Infof("Applied %d change(s)", len(changes))
+	return nil
+}
+
+// destroyTable deletes a table declared in the schema once its name has
+// been typed back.
+func destroyTable(dbSvc *dynamodb.DynamoDB, schema tableSchema, table string, in *bufio.Reader) error {
+	if !slices.ContainsFunc(schema.Tables, func(spec tableSpec) bool { return spec.Name == table }) {
+		return fmt.Errorf("tables destroy: %s is not declared in the schema", table)
+	}
+	exists, err := tableExists(dbSvc, table)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("tables destroy: %s does not exist", table)
+	}
+
+	fmt.Printf("- delete table %s and every item in it\n", table)
+	if err := confirmTable(in, table, "delete"); err != nil {
+		return err
+	}
+	if _, err := dbSvc.DeleteTable(&dynamodb.DeleteTableInput{TableName: aws.String(table)}); err != nil {
+		return fmt.Errorf("%s: DeleteTable: %v", table, err)
+	}
+	log.WithField("table", table).Info("Waiting for table to be deleted")
+	return dbSvc.WaitUntilTableNotExists(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+}
+
+func confirmTable(in *bufio.Reader, table, action string) error {
+	fmt.Printf("Type the table name to %s %s: ", action, table)
+	line, err := in.ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	if strings.TrimSpace(line) != table {
+		return fmt.Errorf("%s: confirmation did not match; nothing changed", table)
+	}
+	return nil
+}
+
+// createSpecTable creates a table with its indexes and TTL and waits for it
+// to become active.
+func createSpecTable(dbSvc *dynamodb.DynamoDB, spec tableSpec) error {
+	if _, err := dbSvc.CreateTable(spec.createInput()); err != nil {
+		return fmt.Errorf("%s: CreateTable: %v", spec.Name, err)
+	}
+	if err := waitForTable(dbSvc, spec.Name); err != nil {
+		return err
+	}
+	if spec.TTL == "" {
+		return nil
+	}
+	return setTTL(dbSvc, spec.Name, spec.TTL, true)
+}
+
+func updateTable(dbSvc *dynamodb.DynamoDB, input *dynamodb.UpdateTableInput) error {
+	table := aws.StringValue(input.TableName)
+	if _, err := dbSvc.UpdateTable(input); err != nil {
+		return fmt.Errorf("%s: UpdateTable: %v", table, err)
+	}
+	return waitForTable(dbSvc, table)
+}
+
+func setTTL(dbSvc *dynamodb.DynamoDB, table, attribute string, enabled bool) error {
+	_, err := dbSvc.UpdateTimeToLive(&dynamodb.UpdateTimeToLiveInput{
+		TableName: aws.String(table),
+		TimeToLiveSpecification: &dynamodb.TimeToLiveSpecification{
+			AttributeName: aws.String(attribute),
+			Enabled:       aws.Bool(enabled),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%s: UpdateTimeToLive: %v", table, err)
+	}
+	return nil
+}
+
+// waitForTable polls until the table and all of its indexes are ACTIVE,
+// which on AWS can take minutes while a new index backfills.
+func waitForTable(dbSvc *dynamodb.DynamoDB, table string) error {
+	for logged := false; ; logged = true {
+		desc, err := dbSvc.DescribeTable(&dynamodb.DescribeTableInput{TableName: aws.String(table)})
+		if err != nil {
+			return fmt.Errorf("%s: DescribeTable: %v", table, err)
+		}
+		settled := aws.StringValue(desc.Table.TableStatus) == dynamodb.TableStatusActive
+		for _, gsi := range desc.Table.GlobalSecondaryIndexes {
+			settled = settled && aws.StringValue(gsi.IndexStatus) == dynamodb.IndexStatusActive
+		}
+		if settled {
+			return nil
+		}
+		if !logged {
+			log.WithField("table", table).Info("Waiting for table and indexes to become active")
+		}
+		time.Sleep(5 * time.Second)
+	}
+}
+
+// expandEnv replaces ${VAR} with the value of VAR, and ${VAR:-default} with
+// default when VAR is unset or empty. Defaults may nest.
+func expandEnv(s string) (string, error) {
+	var out strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			out.WriteString(s)
+			return out.String(), nil
+		}
+		out.WriteString(s[:start])
+
+		depth, end := 0, -1
+		for i := start; i < len(s) && end < 0; i++ {
+			switch {
+			case strings.HasPrefix(s[i:], "${"):
+				depth++
+			case s[i] == '}':
+				depth--
+				if depth == 0 {
+					end = i
+				}
+			}
+		}
+		if end < 0 {
+			return "", fmt.Errorf("unterminated ${ in %q", s)
+		}
+
+		name, fallback, hasDefault := strings.Cut(s[start+2:end], ":-")
+		value := os.Getenv(name)
+		if value == "" && hasDefault {
+			expanded, err := expandEnv(fallback)
+			if err != nil {
+				return "", err
+			}
+			value = expanded
+		}
+		out.WriteString(value)
+		s = s[end+1:]
+	}
+}
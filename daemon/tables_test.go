@@ -0,0 +1,477 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestExpandEnv(t *testing.T) {
+	t.Setenv("ARTICLES", "Live-Articles")
+	t.Setenv("ARTICLE_CATEGORIES", "")
+	t.Setenv("EMPTY", "")
+	os.Unsetenv("UNSET")
+
+	tests := []struct {
+		in   string
+		want string
+		err  string
+	}{
+		{in: "Listings", want: "Listings"},
+		{in: "${ARTICLES}", want: "Live-Articles"},
+		{in: "${UNSET}", want: ""},
+		{in: "${ARTICLES:-Test-Articles}", want: "Live-Articles"},
+		{in: "${UNSET:-Test-Articles}", want: "Test-Articles"},
+		{in: "${EMPTY:-Test-Articles}", want: "Test-Articles"},
+		{in: "${ARTICLES}-Revisions", want: "Live-Articles-Revisions"},
+		{in: "a-${ARTICLES}-b-${UNSET:-c}-d", want: "a-Live-Articles-b-c-d"},
+		{in: "${ARTICLE_CATEGORIES:-${ARTICLES:-Test-Articles}-Categories}", want: "Live-Articles-Categories"},
+		{in: "${ARTICLE_CATEGORIES:-${UNSET:-Test-Articles}-Categories}", want: "Test-Articles-Categories"},
+		{in: "${ARTICLES:-${UNSET}", err: "unterminated ${"},
+		{in: "${ARTICLES", err: "unterminated ${"},
+		{in: "ok-${UNSET:-${ARTICLES}", err: "unterminated ${"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := expandEnv(tt.in)
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestLoadSchema(t *testing.T) {
+	write := func(t *testing.T, schema string) string {
+		path := filepath.Join(t.TempDir(), "tables.json")
+		require.NoError(t, os.WriteFile(path, []byte(schema), 0o600))
+		return path
+	}
+
+	t.Run("Shipped", func(t *testing.T) {
+		t.Setenv("ARTICLES", "Live-Articles")
+		t.Setenv("ARTICLE_CATEGORIES", "")
+		t.Setenv("ARTICLE_REVISIONS", "")
+		schema, err := loadSchema("tables.json")
+		require.NoError(t, err)
+		names := []string{}
+		for _, spec := range schema.Tables {
+			names = append(names, spec.Name)
+		}
+		assert.Equal(t, []string{"Live-Articles", "Live-Articles-Categories", "Live-Articles-Revisions", "Listings", "Auth", "Contact"}, names)
+		for _, index := range schema.Tables[3].Indexes {
+			assert.Equal(t, "ALL", index.Projection, "index %s", index.Name)
+		}
+	})
+
+	tests := []struct {
+		name   string
+		schema string
+		err    string
+	}{
+		{
+			name:   "DefaultProjection",
+			schema: `{"tables": [{"name": "T", "hash-key": {"name": "id", "type": "S"}, "billing": "PAY_PER_REQUEST", "indexes": [{"name": "i", "hash-key": {"name": "k", "type": "S"}}]}]}`,
+		},
+		{
+			name:   "UnknownField",
+			schema: `{"tables": [{"name": "T", "hash-key": {"name": "id", "type": "S"}, "billing": "PAY_PER_REQUEST", "sort-key": {}}]}`,
+			err:    `unknown field "sort-key"`,
+		},
+		{
+			name:   "DeclaredTwice",
+			schema: `{"tables": [{"name": "T", "hash-key": {"name": "id", "type": "S"}, "billing": "PAY_PER_REQUEST"}, {"name": "${UNSET:-T}", "hash-key": {"name": "id", "type": "S"}, "billing": "PAY_PER_REQUEST"}]}`,
+			err:    "table T is declared twice",
+		},
+		{
+			name:   "EmptyName",
+			schema: `{"tables": [{"name": "${UNSET}", "hash-key": {"name": "id", "type": "S"}, "billing": "PAY_PER_REQUEST"}]}`,
+			err:    `table "${UNSET}" expands to an empty name`,
+		},
+		{
+			name:   "Unterminated",
+			schema: `{"tables": [{"name": "${UNSET", "hash-key": {"name": "id", "type": "S"}, "billing": "PAY_PER_REQUEST"}]}`,
+			err:    "unterminated ${",
+		},
+		{
+			name:   "Invalid",
+			schema: `{"tables": [{"name": "T", "hash-key": {"name": "id", "type": "S"}, "billing": "ON_DEMAND"}]}`,
+			err:    `: T: billing must be PAY_PER_REQUEST or PROVISIONED, got "ON_DEMAND"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Unsetenv("UNSET")
+			schema, err := loadSchema(write(t, tt.schema))
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Contains(t, err.Error(), tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "ALL", schema.Tables[0].Indexes[0].Projection)
+		})
+	}
+}
+
+func TestTableSpecValidate(t *testing.T) {
+	id := keySpec{Name: "id", Type: "S"}
+	tests := []struct {
+		name string
+		spec tableSpec
+		err  string
+	}{
+		{
+			name: "PayPerRequest",
+			spec: tableSpec{HashKey: id, Billing: "PAY_PER_REQUEST", Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"k", "N"}, Projection: "KEYS_ONLY"}}},
+		},
+		{
+			name: "Provisioned",
+			spec: tableSpec{HashKey: id, Billing: "PROVISIONED", ReadCapacity: 5, WriteCapacity: 5,
+				Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"k", "S"}, ReadCapacity: 1, WriteCapacity: 1}}},
+		},
+		{
+			name: "PayPerRequestWithCapacity",
+			spec: tableSpec{HashKey: id, Billing: "PAY_PER_REQUEST", ReadCapacity: 5},
+			err:  "read-capacity and write-capacity only apply to PROVISIONED tables",
+		},
+		{
+			name: "ProvisionedWithoutCapacity",
+			spec: tableSpec{HashKey: id, Billing: "PROVISIONED", ReadCapacity: 5},
+			err:  "a PROVISIONED table needs read-capacity and write-capacity",
+		},
+		{
+			name: "NoBilling",
+			spec: tableSpec{HashKey: id},
+			err:  `billing must be PAY_PER_REQUEST or PROVISIONED, got ""`,
+		},
+		{
+			name: "IndexCapacityOnPayPerRequest",
+			spec: tableSpec{HashKey: id, Billing: "PAY_PER_REQUEST", Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"k", "S"}, ReadCapacity: 1, WriteCapacity: 1}}},
+			err:  "index i: read-capacity and write-capacity are required for, and only for, PROVISIONED tables",
+		},
+		{
+			name: "ProvisionedIndexWithoutCapacity",
+			spec: tableSpec{HashKey: id, Billing: "PROVISIONED", ReadCapacity: 5, WriteCapacity: 5, Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"k", "S"}, ReadCapacity: 1}}},
+			err:  "index i: read-capacity and write-capacity are required for, and only for, PROVISIONED tables",
+		},
+		{
+			name: "DuplicateIndex",
+			spec: tableSpec{HashKey: id, Billing: "PAY_PER_REQUEST", Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"k", "S"}}, {Name: "i", HashKey: keySpec{"j", "S"}}}},
+			err:  "index 1 needs a unique name",
+		},
+		{
+			name: "UnnamedIndex",
+			spec: tableSpec{HashKey: id, Billing: "PAY_PER_REQUEST", Indexes: []indexSpec{{HashKey: keySpec{"k", "S"}}}},
+			err:  "index 0 needs a unique name",
+		},
+		{
+			name: "Projection",
+			spec: tableSpec{HashKey: id, Billing: "PAY_PER_REQUEST", Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"k", "S"}, Projection: "INCLUDE"}}},
+			err:  `index i: projection must be ALL or KEYS_ONLY, got "INCLUDE"`,
+		},
+		{
+			name: "ConflictingKeyTypes",
+			spec: tableSpec{HashKey: keySpec{"post-id", "N"}, Billing: "PAY_PER_REQUEST",
+				Indexes: []indexSpec{{Name: "i", HashKey: keySpec{"category", "S"}, RangeKey: &keySpec{"post-id", "S"}}}},
+			err: "key post-id is declared as both N and S",
+		},
+		{
+			name: "KeyType",
+			spec: tableSpec{HashKey: keySpec{"id", "BOOL"}, Billing: "PAY_PER_REQUEST"},
+			err:  `key id: type must be S, N or B, got "BOOL"`,
+		},
+		{
+			name: "UnnamedKey",
+			spec: tableSpec{HashKey: id, RangeKey: &keySpec{Type: "S"}, Billing: "PAY_PER_REQUEST"},
+			err:  "every key needs a name",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.spec.validate()
+			if tt.err != "" {
+				require.Error(t, err)
+				assert.Equal(t, tt.err, err.Error())
+				return
+			}
+			require.NoError(t, err)
+		})
+	}
+}
+
+// liveTable is DescribeTable's answer for a table created from spec.
+func liveTable(t *testing.T, spec tableSpec) map[string]any {
+	t.Helper()
+	require.NoError(t, spec.validate())
+	defs, err := spec.attributeDefinitions(spec.Indexes...)
+	require.NoError(t, err)
+	attributes := []any{}
+	for _, def := range defs {
+		attributes = append(attributes, map[string]any{"AttributeName": aws.StringValue(def.AttributeName), "AttributeType": aws.StringValue(def.AttributeType)})
+	}
+	keys := func(hash keySpec, rangeKey *keySpec) []any {
+		elements := []any{}
+		for _, element := range keySchema(hash, rangeKey) {
+			elements = append(elements, map[string]any{"AttributeName": aws.StringValue(element.AttributeName), "KeyType": aws.StringValue(element.KeyType)})
+		}
+		return elements
+	}
+	capacity := func(read, write int64) map[string]any {
+		return map[string]any{"ReadCapacityUnits": read, "WriteCapacityUnits": write}
+	}
+
+	table := map[string]any{
+		"TableName":             spec.Name,
+		"TableStatus":           "ACTIVE",
+		"AttributeDefinitions":  attributes,
+		"KeySchema":             keys(spec.HashKey, spec.RangeKey),
+		"BillingModeSummary":    map[string]any{"BillingMode": spec.Billing},
+		"ProvisionedThroughput": capacity(spec.ReadCapacity, spec.WriteCapacity),
+	}
+	indexes := []any{}
+	for _, index := range spec.Indexes {
+		indexes = append(indexes, map[string]any{
+			"IndexName":             index.Name,
+			"IndexStatus":           "ACTIVE",
+			"KeySchema":             keys(index.HashKey, index.RangeKey),
+			"Projection":            map[string]any{"ProjectionType": index.Projection},
+			"ProvisionedThroughput": capacity(index.ReadCapacity, index.WriteCapacity),
+		})
+	}
+	if len(indexes) > 0 {
+		table["GlobalSecondaryIndexes"] = indexes
+	}
+	return map[string]any{"Table": table}
+}
+
+// stubLiveTable answers DescribeTable with live (nil for no such table) and
+// DescribeTimeToLive with ttl (empty for none).
+func stubLiveTable(t *testing.T, live map[string]any, ttl string) *dynamoStub {
+	return stubDynamoDB(t, func(op string, body map[string]any) any {
+		switch op {
+		case "DescribeTable":
+			if live == nil {
+				return &dynamoError{Status: 400, Type: "ResourceNotFoundException"}
+			}
+			return live
+		case "DescribeTimeToLive":
+			if ttl == "" {
+				return map[string]any{"TimeToLiveDescription": map[string]any{"TimeToLiveStatus": "DISABLED"}}
+			}
+			return map[string]any{"TimeToLiveDescription": map[string]any{"TimeToLiveStatus": "ENABLED", "AttributeName": ttl}}
+		}
+		return nil
+	})
+}
+
+func TestPlanTable(t *testing.T) {
+	listings := func() tableSpec {
+		return tableSpec{
+			Name: "Listings", HashKey: keySpec{"MLS", "S"}, Billing: "PAY_PER_REQUEST",
+			Indexes: []indexSpec{
+				{Name: "user-key-index", HashKey: keySpec{"user-key", "S"}},
+				{Name: "city-key-index", HashKey: keySpec{"city-key", "S"}},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		live    func() tableSpec // nil for no such table
+		liveTTL string
+		spec    func() tableSpec
+		want    []string
+		destroy []bool
+		err     error
+	}{
+		{
+			name: "Missing",
+			spec: listings,
+			want: []string{"create table Listings (MLS S HASH; PAY_PER_REQUEST; index user-key-index; index city-key-index)"},
+		},
+		{
+			name: "InSync",
+			live: listings,
+			spec: listings,
+			want: []string{},
+		},
+		{
+			name: "KeyDrift",
+			live: listings,
+			spec: func() tableSpec { s := listings(); s.HashKey = keySpec{"MLS", "N"}; return s },
+			err:  errKeyDrift,
+		},
+		{
+			name: "Indexes",
+			live: listings,
+			spec: func() tableSpec {
+				s := listings()
+				// user-key-index is dropped, city-key-index rebuilt with
+				// a range key, and geo-cell-index added.
+				s.Indexes = []indexSpec{
+					{Name: "geo-cell-index", HashKey: keySpec{"geo-cell", "S"}},
+					{Name: "city-key-index", HashKey: keySpec{"city-key", "S"}, RangeKey: &keySpec{"MLS", "S"}},
+				}
+				return s
+			},
+			want: []string{
+				"delete index city-key-index on Listings (changing city-key S HASH ALL to city-key S HASH, MLS S RANGE ALL)",
+				"delete index user-key-index on Listings (not in the schema)",
+				"create index geo-cell-index on Listings (geo-cell S HASH ALL)",
+				"create index city-key-index on Listings (city-key S HASH, MLS S RANGE ALL)",
+			},
+			destroy: []bool{true, true, false, false},
+		},
+		{
+			name: "Projection",
+			live: listings,
+			spec: func() tableSpec { s := listings(); s.Indexes[1].Projection = "KEYS_ONLY"; return s },
+			want: []string{
+				"delete index city-key-index on Listings (changing city-key S HASH ALL to city-key S HASH KEYS_ONLY)",
+				"create index city-key-index on Listings (city-key S HASH KEYS_ONLY)",
+			},
+			destroy: []bool{true, false},
+		},
+		{
+			name: "Billing",
+			live: listings,
+			spec: func() tableSpec {
+				s := listings()
+				s.Billing, s.ReadCapacity, s.WriteCapacity = "PROVISIONED", 5, 5
+				for i := range s.Indexes {
+					s.Indexes[i].ReadCapacity, s.Indexes[i].WriteCapacity = 1, 1
+				}
+				return s
+			},
+			want: []string{"set Listings billing to PROVISIONED (read 5, write 5), was PAY_PER_REQUEST"},
+		},
+		{
+			name:    "EnableTTL",
+			live:    listings,
+			spec:    func() tableSpec { s := listings(); s.TTL = "expires"; return s },
+			want:    []string{"enable TTL on Listings.expires"},
+			destroy: []bool{false},
+		},
+		{
+			name:    "DisableTTL",
+			live:    listings,
+			liveTTL: "expires",
+			spec:    listings,
+			want:    []string{"disable TTL on Listings.expires"},
+		},
+		{
+			name:    "MoveTTL",
+			live:    listings,
+			liveTTL: "expires",
+			spec:    func() tableSpec { s := listings(); s.TTL = "expires-at"; return s },
+			want:    []string{"disable TTL on Listings.expires", "enable TTL on Listings.expires-at"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var live map[string]any
+			if tt.live != nil {
+				live = liveTable(t, tt.live())
+			}
+			stub := stubLiveTable(t, live, tt.liveTTL)
+			spec := tt.spec()
+			require.NoError(t, spec.validate())
+
+			changes, err := planTable(stub.client(t), spec)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			summaries := []string{}
+			destructive := []bool{}
+			for _, change := range changes {
+				summaries = append(summaries, change.summary)
+				destructive = append(destructive, change.destructive)
+			}
+			assert.Equal(t, tt.want, summaries)
+			if tt.destroy != nil {
+				assert.Equal(t, tt.destroy, destructive)
+			}
+		})
+	}
+}
+
+func TestPlanTableBillingCoversKeptIndexes(t *testing.T) {
+	silenceLogrus(t)
+	provisioned := func() tableSpec {
+		return tableSpec{
+			Name: "Listings", HashKey: keySpec{"MLS", "S"}, Billing: "PROVISIONED", ReadCapacity: 5, WriteCapacity: 5,
+			Indexes: []indexSpec{
+				{Name: "user-key-index", HashKey: keySpec{"user-key", "S"}, ReadCapacity: 1, WriteCapacity: 1},
+				{Name: "city-key-index", HashKey: keySpec{"city-key", "S"}, ReadCapacity: 1, WriteCapacity: 1},
+			},
+		}
+	}
+	spec := provisioned()
+	spec.ReadCapacity, spec.WriteCapacity = 10, 10
+	spec.Indexes[0].ReadCapacity, spec.Indexes[0].WriteCapacity = 2, 2
+	// Rebuilt, so it's created with its capacity rather than updated.
+	spec.Indexes[1].Projection = "KEYS_ONLY"
+	spec.Indexes[1].ReadCapacity, spec.Indexes[1].WriteCapacity = 3, 3
+	require.NoError(t, spec.validate())
+
+	stub := stubLiveTable(t, liveTable(t, provisioned()), "")
+	changes, err := planTable(stub.client(t), spec)
+	require.NoError(t, err)
+	summaries := []string{}
+	for _, change := range changes {
+		summaries = append(summaries, change.summary)
+		require.NoError(t, change.apply(stub.client(t)))
+	}
+	assert.Equal(t, []string{
+		"delete index city-key-index on Listings (changing city-key S HASH ALL to city-key S HASH KEYS_ONLY)",
+		"set Listings billing to PROVISIONED (read 10, write 10)",
+		"create index city-key-index on Listings (city-key S HASH KEYS_ONLY)",
+	}, summaries)
+
+	updates := stub.Calls("UpdateTable")
+	require.Len(t, updates, 3)
+	assert.Equal(t, []any{map[string]any{"Delete": map[string]any{"IndexName": "city-key-index"}}}, updates[0]["GlobalSecondaryIndexUpdates"])
+	assert.Equal(t, map[string]any{"ReadCapacityUnits": float64(10), "WriteCapacityUnits": float64(10)}, updates[1]["ProvisionedThroughput"])
+	assert.Equal(t, []any{map[string]any{"Update": map[string]any{
+		"IndexName":             "user-key-index",
+		"ProvisionedThroughput": map[string]any{"ReadCapacityUnits": float64(2), "WriteCapacityUnits": float64(2)},
+	}}}, updates[1]["GlobalSecondaryIndexUpdates"], "The billing update leaves the dropped index out")
+	create := updates[2]["GlobalSecondaryIndexUpdates"].([]any)[0].(map[string]any)["Create"].(map[string]any)
+	assert.Equal(t, "city-key-index", create["IndexName"])
+	assert.Equal(t, map[string]any{"ReadCapacityUnits": float64(3), "WriteCapacityUnits": float64(3)}, create["ProvisionedThroughput"])
+	assert.Equal(t, []any{map[string]any{"AttributeName": "MLS", "AttributeType": "S"}, map[string]any{"AttributeName": "city-key", "AttributeType": "S"}},
+		updates[2]["AttributeDefinitions"])
+}
+
+func TestPlanTableTTLApply(t *testing.T) {
+	silenceLogrus(t)
+	spec := tableSpec{Name: "Sessions", HashKey: keySpec{"id", "S"}, Billing: "PAY_PER_REQUEST", TTL: "expires-at"}
+	live := spec
+	live.TTL = ""
+	stub := stubLiveTable(t, liveTable(t, live), "expires")
+
+	changes, err := planTable(stub.client(t), spec)
+	require.NoError(t, err)
+	for _, change := range changes {
+		require.NoError(t, change.apply(stub.client(t)))
+	}
+	specs := []any{}
+	for _, update := range stub.Calls("UpdateTimeToLive") {
+		specs = append(specs, update["TimeToLiveSpecification"])
+	}
+	assert.Equal(t, []any{
+		map[string]any{"AttributeName": "expires", "Enabled": false},
+		map[string]any{"AttributeName": "expires-at", "Enabled": true},
+	}, specs, "The old TTL is disabled before the new one is enabled")
+}
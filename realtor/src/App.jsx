@@ -50,6 +50,24 @@ function App() {
       
       log('User signed in', { email });
       toast.success('Successfully signed in!', { autoClose: 3000, toastId: 'sign-in' });
+
+      // Trade the Google credential for the server session that listing
+      // writes and image uploads require; it comes back as a cookie.
+      fetch('/realtor/session', {
+        method: 'POST',
+        headers: { 'Content-Type': 'application/json' },
+        body: JSON.stringify({ credential: credentialResponse.credential }),
+      })
+        .then((response) => {
+          if (!response.ok) {
+            throw new Error(`Session request failed: ${response.status}`);
+          }
+          log('Server session started', { email });
+        })
+        .catch((error) => {
+          log('Server session failed', { error: error.message || 'Unknown error' });
+          toast.error('Signed in, but saving listings is unavailable right now.', { autoClose: 5000 });
+        });
     } catch (error) {
       log('Login processing failed', { error: error.message || 'Unknown error' });
       toast.error('Failed to process login.', { autoClose: 5000 });
@@ -75,6 +93,9 @@ function App() {
       setLoggedIn(false);
       localStorage.removeItem('user');
       localStorage.setItem('signedOut', 'true');
+      fetch('/realtor/session', { method: 'DELETE' }).catch((error) => {
+        log('Ending server session failed', { error: error.message || 'Unknown error' });
+      });
       
       log('Sign-out successful');
       toast.success('Successfully signed out.', { autoClose: 3000, toastId: 'sign-out' });
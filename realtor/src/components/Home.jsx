@@ -1,33 +1,57 @@
 import React from "react";
+import Button from 'react-bootstrap/Button';
 import TileDeck from "./TileDeck";
 
+// Listings are fetched a page at a time, newest first.
+const PAGE_LIMIT = 24;
+
 export default class Home extends React.Component {
   constructor(props) {
     super(props);
     this.state = {
-      cards: []
+      cards: [],
+      nextPage: null
     };
+    this.loadMore = this.loadMore.bind(this);
   }
 
   async componentDidMount() {
+    await this.fetchPage(null);
+  }
+
+  async fetchPage(pageToken) {
     try {
-      const response = await fetch('/listings');
+      let url = `/listings?limit=${PAGE_LIMIT}`;
+      if (pageToken) {
+        url += `&page_token=${encodeURIComponent(pageToken)}`;
+      }
+      const response = await fetch(url);
       if (!response.ok) {
         throw new Error(`Failed to fetch listings: ${response.status}`);
       }
       const data = await response.json();
       console.log('Raw data:', data);
+      const nextPage = (response.headers && response.headers.get('X-Next-Page-Token')) || null;
 
-      // Filter non-deleted listings and deduplicate by MLS
-      const listings = data.filter(card => card && card.deleted === "false");
+      // The server leaves out deleted listings; deduplicate by MLS
+      const listings = [...(pageToken ? this.state.cards : []), ...data]
+        .filter(card => card);
       const uniqueListings = Array.from(
         new Map(listings.map(item => [item.MLS, item])).values()
       );
-      console.log('Filtered listings:', uniqueListings.map(l => ({ MLS: l.MLS, deleted: l.deleted })));
-      this.setState({ cards: uniqueListings });
+      console.log('Listings:', uniqueListings.map(l => l.MLS));
+      this.setState({ cards: uniqueListings, nextPage });
     } catch (error) {
       console.error('Error fetching listings:', error);
-      this.setState({ cards: [] });
+      if (!pageToken) {
+        this.setState({ cards: [], nextPage: null });
+      }
+    }
+  }
+
+  loadMore() {
+    if (this.state.nextPage) {
+      this.fetchPage(this.state.nextPage);
     }
   }
 
@@ -37,7 +61,7 @@ export default class Home extends React.Component {
       backgroundColor: 'LightGray',
       margin: "0px",
       padding: "0px",
-      height: "240vh"
+      minHeight: "240vh"
     };
 
     return (
@@ -45,7 +69,14 @@ export default class Home extends React.Component {
         <br />
         <br />
         <TileDeck cards={this.state.cards} />
+        {this.state.nextPage && (
+          <div style={{ textAlign: 'center', padding: '20px' }}>
+            <Button data-testid="load-more" variant="secondary" onClick={this.loadMore}>
+              Load more
+            </Button>
+          </div>
+        )}
       </div>
     );
   }
-}
\ No newline at end of file
+}
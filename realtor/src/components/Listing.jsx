@@ -94,14 +94,14 @@ export default class Listing extends React.Component {
             addr = street + 
                         `${card['City']}, ${card['State']} ` + 
                         `${card['Zip Code']}`; 
-            desc1 = `Square Feet: ${card['Square Feet']} | ` + 
-                        `Lot Size: ${card['Lot Size']}`;
+            desc1 = `Square Feet: ${Tile.formatNumber(card['Square Feet'])} | ` + 
+                        `Lot Size: ${Tile.formatNumber(card['Lot Size'])}`;
             desc2 = `Beds: ${card['Bedrooms']} | ` + 
                         `Baths: ${card['Bathrooms']}`;
             const time = new Date().getTime();
-            ago = Tile.timeDifference(time, card['Last Modified']);
-            price = `Price: $${card['Sales Price']}`;
-            d.setUTCSeconds(card['Date Listed']/1000);
+            ago = Tile.timeDifference(time, Tile.parseDate(card['Last Modified']));
+            price = `Price: $${Tile.formatNumber(card['Sales Price'])}`;
+            d.setTime(Tile.parseDate(card['Date Listed']));
             listed = `First Listed: ${d.toString()}`;
             desc3 = `Garage Size: ${card['Garage Size']} | ` + 
                     `Neighborhood: ${card['Neighborhood']}`;
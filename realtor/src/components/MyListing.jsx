@@ -11,6 +11,14 @@ import { v4 as uuid } from 'uuid';
 import { toast, ToastContainer } from 'react-toastify';
 import 'react-toastify/dist/ReactToastify.css';
 
+// The upload API takes images as multipart/form-data in a "file" field and
+// stores them under the signed-in user's /media/ folder.
+const uploadForm = (file) => {
+  const form = new FormData();
+  form.append('file', file);
+  return form;
+};
+
 // Error Boundary Component
 class MyListingErrorBoundary extends React.Component {
   state = { error: null };
@@ -214,7 +222,7 @@ const MyListing = ({ loggedIn, user }) => {
     }
 
     const sanitizeInput = (value) => (typeof value === 'string' ? value.trim() : '');
-    const time = new Date().getTime();
+    const time = new Date().toISOString();
     const firstTime = card?.['Date Listed'] || time;
     const newUuid = card?.['MLS'] || uuid();
     const status = card?.['deleted'] || 'false';
@@ -266,7 +274,7 @@ const MyListing = ({ loggedIn, user }) => {
 
     try {
       log('Submitting listing', { instanceId, mls: newUuid, user });
-      const rawResponse = await fetch('/listings/add/HowMuchDoesSecurityCost', {
+      const rawResponse = await fetch('/listings', {
         method: 'POST',
         headers: {
           Accept: 'application/json',
@@ -276,6 +284,16 @@ const MyListing = ({ loggedIn, user }) => {
       });
       const responseData = await rawResponse.json();
       log('Submission response', { instanceId, responseData, status: rawResponse.status, ok: rawResponse.ok });
+      if (rawResponse.status === 422 && Array.isArray(responseData.fields)) {
+        const problems = responseData.fields.map((f) => `${f.field} ${f.message}`).join('; ');
+        log('Listing rejected', { instanceId, fields: responseData.fields });
+        toast.warning(`Please fix the listing: ${problems}`);
+        return;
+      }
+      if (rawResponse.status === 401) {
+        toast.warning('Your session has expired: Please sign in again');
+        return;
+      }
       if (rawResponse.ok) {
         toast.success('Success: Listing submitted');
         log('Submission successful', { instanceId, mls: newUuid });
@@ -302,9 +320,9 @@ const MyListing = ({ loggedIn, user }) => {
         newCard['List Photo'] = path;
         return { ...prev, card: newCard };
       });
-      fetch(`/upload/image/${user || ''}`, {
+      fetch('/upload/image', {
         method: 'POST',
-        body: file,
+        body: uploadForm(file),
       }).then((response) => {
         log('List photo upload response', { instanceId, status: response.status });
       }).catch((error) => {
@@ -328,9 +346,9 @@ const MyListing = ({ loggedIn, user }) => {
         newCard['Photo Array'] = photoArr;
         return { ...prev, card: newCard };
       });
-      fetch(`/upload/image/${user || ''}`, {
+      fetch('/upload/image', {
         method: 'POST',
-        body: file,
+        body: uploadForm(file),
       }).then((response) => {
         log('Array photo upload response', { instanceId, status: response.status });
       }).catch((error) => {
@@ -520,7 +538,7 @@ const MyListing = ({ loggedIn, user }) => {
               <Row>
                 <Form.Group as={Col} controlId="formGridPrice">
                   <Form.Label>Sales Price</Form.Label>
-                  <Form.Control type="text" name="Price" required defaultValue={state.card?.['Sales Price'] || ''} />
+                  <Form.Control type="text" name="Price" required defaultValue={state.card?.['Sales Price'] ?? ''} />
                 </Form.Group>
 
                 <Form.Group as={Col} controlId="formGridNeighborhood">
@@ -537,12 +555,12 @@ const MyListing = ({ loggedIn, user }) => {
               <Row>
                 <Form.Group as={Col} controlId="formGridBedrooms">
                   <Form.Label>Bedrooms</Form.Label>
-                  <Form.Control type="text" name="Bedrooms" required defaultValue={state.card?.Bedrooms || ''} />
+                  <Form.Control type="text" name="Bedrooms" required defaultValue={state.card?.Bedrooms ?? ''} />
                 </Form.Group>
 
                 <Form.Group as={Col} controlId="formGridBathrooms">
                   <Form.Label>Bathrooms</Form.Label>
-                  <Form.Control type="text" name="Bathrooms" required defaultValue={state.card?.Bathrooms || ''} />
+                  <Form.Control type="text" name="Bathrooms" required defaultValue={state.card?.Bathrooms ?? ''} />
                 </Form.Group>
               </Row>
 
@@ -553,13 +571,13 @@ const MyListing = ({ loggedIn, user }) => {
                     type="text"
                     name="SquareFeet"
                     required
-                    defaultValue={state.card?.['Square Feet'] || ''}
+                    defaultValue={state.card?.['Square Feet'] ?? ''}
                   />
                 </Form.Group>
 
                 <Form.Group as={Col} controlId="formGridLotSize">
                   <Form.Label>Lot Size</Form.Label>
-                  <Form.Control type="text" name="LotSize" required defaultValue={state.card?.['Lot Size'] || ''} />
+                  <Form.Control type="text" name="LotSize" required defaultValue={state.card?.['Lot Size'] ?? ''} />
                 </Form.Group>
 
                 <Form.Group as={Col} controlId="formGridGarageSize">
@@ -568,7 +586,7 @@ const MyListing = ({ loggedIn, user }) => {
                     type="text"
                     name="GarageSize"
                     required
-                    defaultValue={state.card?.['Garage Size'] || ''}
+                    defaultValue={state.card?.['Garage Size'] ?? ''}
                   />
                 </Form.Group>
               </Row>
@@ -8,7 +8,7 @@ const MyListings = (props) => {
     const fetchMyListings = async () => {
       if (props.loggedIn && props.user) {
         try {
-          const response = await fetch('/listings');
+          const response = await fetch(`/listings?user=${encodeURIComponent(props.user)}&include_deleted=true`);
           const data = await response.json();
 
           const myListings = data.filter(card => card.User === props.user);
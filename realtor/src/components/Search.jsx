@@ -14,7 +14,8 @@ export default class Search extends React.Component {
             user: props.user || null,
             cards: [],
             orgCards: [],
-            noResults: false
+            noResults: false,
+            searchError: false
         };
         
         // Create refs
@@ -25,6 +26,10 @@ export default class Search extends React.Component {
         this.bathroomsRef = React.createRef();
         this.mlsRef = React.createRef();
         this.squareFeetRef = React.createRef();
+        this.minPriceRef = React.createRef();
+        this.maxPriceRef = React.createRef();
+        this.keywordsRef = React.createRef();
+        this.sortRef = React.createRef();
     }
 
     async componentDidMount() {
@@ -32,79 +37,65 @@ export default class Search extends React.Component {
             const response = await fetch('/listings');
             const data = await response.json();
             console.log('Raw API response:', data);
-            const listings = data.filter(card => card.deleted === "false" || card.deleted === false);
-            const excludedListings = data.filter(card => !(card.deleted === "false" || card.deleted === false));
-            console.log('Filtered listings (deleted=false):', listings);
-            console.log('Excluded listings (deleted=true):', excludedListings);
+            // The server leaves out deleted listings.
             this.setState({ 
-                cards: listings,
-                orgCards: listings
+                cards: data,
+                orgCards: data
             });
         } catch (error) {
             console.error("Error fetching listings:", error);
         }
     }
 
+    // searchParams turns the form into the /listings search parameters,
+    // leaving out the fields that are blank.
+    searchParams = () => {
+        const fields = {
+            city: this.cityRef,
+            state: this.stateRef,
+            zip: this.zipCodeRef,
+            min_beds: this.bedroomsRef,
+            min_baths: this.bathroomsRef,
+            mls: this.mlsRef,
+            min_sqft: this.squareFeetRef,
+            min_price: this.minPriceRef,
+            max_price: this.maxPriceRef,
+            q: this.keywordsRef,
+            sort: this.sortRef,
+        };
+        const params = new URLSearchParams();
+        Object.entries(fields).forEach(([name, ref]) => {
+            const value = ref.current?.value?.trim();
+            if (value) {
+                params.append(name, value);
+            }
+        });
+        return params;
+    }
+
     onSubmit = async (event) => {
         event.preventDefault();
-        const { orgCards } = this.state;
-
-        // Get filter values and normalize them
-        const filters = {
-            City: this.cityRef.current?.value?.trim().toLowerCase() || null,
-            State: this.stateRef.current?.value?.trim().toLowerCase() || null,
-            "Zip Code": this.zipCodeRef.current?.value?.trim() || null,
-            Bedrooms: this.bedroomsRef.current?.value ? Number(this.bedroomsRef.current.value) : null,
-            Bathrooms: this.bathroomsRef.current?.value ? Number(this.bathroomsRef.current.value) : null,
-            MLS: this.mlsRef.current?.value?.trim() || null,
-            "Square Feet": this.squareFeetRef.current?.value ? Number(this.squareFeetRef.current.value) : null,
-        };
 
-        console.log('Filter values:', filters);
-
-        // Filter cards based on provided values
-        const filteredCards = orgCards.filter((card, index) => {
-            const result = Object.keys(filters).every((field) => {
-                // Skip if filter value is empty or null
-                if (!filters[field]) return true;
-
-                // Normalize card data for comparison
-                let cardValue = card[field];
-                let filterValue = filters[field];
-
-                // Handle string fields (City, State, MLS)
-                if (['City', 'State', 'MLS'].includes(field)) {
-                    cardValue = cardValue ? String(cardValue).toLowerCase() : '';
-                    filterValue = String(filterValue).toLowerCase();
-                }
-
-                // Handle Zip Code (strip extra characters like - for ZIP+4)
-                if (field === 'Zip Code') {
-                    cardValue = cardValue ? String(cardValue).replace(/[^0-9]/g, '') : '';
-                    filterValue = String(filterValue).replace(/[^0-9]/g, '');
-                }
-
-                // Handle numeric fields (Bedrooms, Bathrooms, Square Feet)
-                if (['Bedrooms', 'Bathrooms', 'Square Feet'].includes(field)) {
-                    cardValue = cardValue ? Number(cardValue) : null;
-                    filterValue = Number(filterValue);
-                }
-
-                const match = cardValue === filterValue;
-                if (!match) {
-                    console.log(`Card ${index} failed filter: ${field} (card: ${cardValue}, filter: ${filterValue})`);
-                }
-                return match;
-            });
+        const params = this.searchParams();
+        const url = params.toString() ? `/listings?${params}` : '/listings';
+        console.log('Search query:', url);
 
-            return result;
-        });
-
-        console.log('Filtered cards:', filteredCards);
-        this.setState({ 
-            cards: filteredCards,
-            noResults: filteredCards.length === 0
-        });
+        try {
+            const response = await fetch(url);
+            if (!response.ok) {
+                throw new Error(`Search failed: ${response.status}`);
+            }
+            const data = await response.json();
+            console.log('Search results:', data);
+            this.setState({
+                cards: data,
+                noResults: data.length === 0,
+                searchError: false
+            });
+        } catch (error) {
+            console.error("Error searching listings:", error);
+            this.setState({ searchError: true });
+        }
     }
 
     resetForm = () => {
@@ -116,10 +107,15 @@ export default class Search extends React.Component {
         this.bathroomsRef.current.value = '';
         this.mlsRef.current.value = '';
         this.squareFeetRef.current.value = '';
+        this.minPriceRef.current.value = '';
+        this.maxPriceRef.current.value = '';
+        this.keywordsRef.current.value = '';
+        this.sortRef.current.value = '';
         // Restore original cards
         this.setState({ 
             cards: this.state.orgCards,
-            noResults: false
+            noResults: false,
+            searchError: false
         });
         console.log('Form reset, restored original cards');
     }
@@ -178,12 +174,22 @@ export default class Search extends React.Component {
                         <Row className="mb-3">
                             <Form.Group as={Col} controlId="formGridBedrooms">
                                 <Form.Label>Bedrooms</Form.Label>
-                                <Form.Control type="number" ref={this.bedroomsRef} />
+                                <Form.Control type="number" placeholder="At least" ref={this.bedroomsRef} />
                             </Form.Group>
 
                             <Form.Group as={Col} controlId="formGridBathrooms">
                                 <Form.Label>Bathrooms</Form.Label>
-                                <Form.Control type="number" ref={this.bathroomsRef} />
+                                <Form.Control type="number" placeholder="At least" ref={this.bathroomsRef} />
+                            </Form.Group>
+
+                            <Form.Group as={Col} controlId="formGridMinPrice">
+                                <Form.Label>Min Price</Form.Label>
+                                <Form.Control type="text" ref={this.minPriceRef} />
+                            </Form.Group>
+
+                            <Form.Group as={Col} controlId="formGridMaxPrice">
+                                <Form.Label>Max Price</Form.Label>
+                                <Form.Control type="text" ref={this.maxPriceRef} />
                             </Form.Group>
                         </Row>
 
@@ -195,7 +201,22 @@ export default class Search extends React.Component {
 
                             <Form.Group as={Col} controlId="formGridSquareFeet">
                                 <Form.Label>Square Feet</Form.Label>
-                                <Form.Control type="number" ref={this.squareFeetRef} />
+                                <Form.Control type="number" placeholder="At least" ref={this.squareFeetRef} />
+                            </Form.Group>
+
+                            <Form.Group as={Col} controlId="formGridKeywords">
+                                <Form.Label>Keywords</Form.Label>
+                                <Form.Control type="text" placeholder="In the description" ref={this.keywordsRef} />
+                            </Form.Group>
+
+                            <Form.Group as={Col} controlId="formGridSort">
+                                <Form.Label>Sort By</Form.Label>
+                                <Form.Select ref={this.sortRef} defaultValue="">
+                                    <option value="">Recently updated</option>
+                                    <option value="listed">Newly listed</option>
+                                    <option value="price">Price</option>
+                                    <option value="size">Size</option>
+                                </Form.Select>
                             </Form.Group>
                         </Row>
 
@@ -217,6 +238,11 @@ export default class Search extends React.Component {
                         </div>
                     </Form>
                 </Card>
+                {this.state.searchError && (
+                    <div style={{ textAlign: 'center', marginTop: '20px', color: 'red' }}>
+                        Search failed. Please try again.
+                    </div>
+                )}
                 {this.state.noResults && (
                     <div style={{ textAlign: 'center', marginTop: '20px', color: 'red' }}>
                         No listings match your criteria.
@@ -15,6 +15,21 @@ export default class Tile extends React.Component {
     this.onImageLoad = this.onImageLoad.bind(this);
   }
 
+  // Listing dates are RFC 3339 strings; older listings hold epoch
+  // milliseconds. Returns milliseconds, or NaN if neither parses.
+  static parseDate(value) {
+    if (/^\d+$/.test(`${value}`)) {
+      return Number(value);
+    }
+    return Date.parse(value);
+  }
+
+  // Listing numbers are JSON numbers; older listings hold the strings they
+  // were typed as ("503,000"), which are shown as they are.
+  static formatNumber(value) {
+    return typeof value === 'number' ? value.toLocaleString('en-US') : value;
+  }
+
   static timeDifference(current, previous) {
     var msPerMinute = 60 * 1000;
     var msPerHour = msPerMinute * 60;
@@ -38,31 +53,27 @@ export default class Tile extends React.Component {
     }
   }
 
+  // Removing a listing soft-deletes it and publishing restores it; the
+  // server answers with the listing as stored.
   async onStateChange(event) {
     event.preventDefault();
-    console.log("submitted");
 
-    var newCard = this.props.card;
-    if (this.props.card['deleted'] === "true") {
-      newCard['deleted'] = "false";
-    } else {
-      newCard['deleted'] = "true";
-    }
-
-    const rawResponse = await fetch('/listings/add/HowMuchDoesSecurityCost', {
-      method: 'POST',
+    const mls = encodeURIComponent(this.state.card['MLS']);
+    const deleted = this.state.card['deleted'] === "true";
+    const rawResponse = await fetch(deleted ? `/listings/${mls}/restore` : `/listings/${mls}`, {
+      method: deleted ? 'POST' : 'DELETE',
       headers: {
-        'Accept': 'application/json',
-        'Content-Type': 'application/json'
-      },
-      body: JSON.stringify(newCard)
+        'Accept': 'application/json'
+      }
     });
     const content = await rawResponse.json();
-
-    console.log(content);
+    if (!rawResponse.ok) {
+      console.error("Error updating listing:", content.error);
+      return;
+    }
 
     this.setState({
-      card: newCard,
+      card: content,
     });
   }
 
@@ -93,11 +104,11 @@ export default class Tile extends React.Component {
       `${this.props.card['Zip Code']}`;
     const desc1 = `Beds: ${this.props.card['Bedrooms']} | ` + 
       `Baths: ${this.props.card['Bathrooms']}`; 
-    const desc2 = `Square Feet: ${this.props.card['Square Feet']} | ` + 
-      `Lot Size: ${this.props.card['Lot Size']}`;
+    const desc2 = `Square Feet: ${Tile.formatNumber(this.props.card['Square Feet'])} | ` + 
+      `Lot Size: ${Tile.formatNumber(this.props.card['Lot Size'])}`;
     const time = new Date().getTime();
-    const ago = Tile.timeDifference(time, this.props.card['Last Modified']);
-    const price = `Price: $${this.props.card['Sales Price']}`;
+    const ago = Tile.timeDifference(time, Tile.parseDate(this.props.card['Last Modified']));
+    const price = `Price: $${Tile.formatNumber(this.props.card['Sales Price'])}`;
     
     return (
       <a style={linkStyle} href={"/realtor/listing?MLS=" + this.props.card['MLS']} data-testid={`tile-${this.props.card['MLS']}`}>
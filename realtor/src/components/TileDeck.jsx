@@ -8,10 +8,10 @@ const TileDeck = ({ cards, user }) => {
   }
 
   const sortedCards = [...cards].sort((a, b) => {
-    const dateA = new Date(a['Last Modified']);
-    const dateB = new Date(b['Last Modified']);
-    if (isNaN(dateA.getTime())) return 1;
-    if (isNaN(dateB.getTime())) return -1;
+    const dateA = Tile.parseDate(a['Last Modified']);
+    const dateB = Tile.parseDate(b['Last Modified']);
+    if (isNaN(dateA)) return 1;
+    if (isNaN(dateB)) return -1;
     return dateB - dateA;
   });
 
@@ -1,5 +1,5 @@
 import { describe, it, expect, beforeEach } from 'vitest';
-import { render, screen, waitFor, cleanup } from '@testing-library/react';
+import { render, screen, waitFor, cleanup, fireEvent } from '@testing-library/react';
 import { BrowserRouter } from 'react-router';
 import Home from '../Home';
 import Tile from '../Tile';
@@ -29,7 +29,7 @@ describe('Home Component', () => {
   it('renders Home component without crashing', async () => {
     render(<BrowserRouter><Home /></BrowserRouter>);
     await waitFor(() => expect(fetchMock).toHaveBeenCalledTimes(1));
-    expect(fetchMock).toHaveBeenCalledWith('/listings');
+    expect(fetchMock).toHaveBeenCalledWith('/listings?limit=24');
     const tileDecks = screen.queryAllByTestId('tile-deck');
     expect(tileDecks.length).toBeGreaterThan(0);
     const tiles = screen.queryAllByTestId(/tile-\d+/);
@@ -47,7 +47,7 @@ describe('Home Component', () => {
 
     render(<BrowserRouter><Home /></BrowserRouter>);
     await waitFor(() => expect(fetchMock).toHaveBeenCalledTimes(1));
-    expect(fetchMock).toHaveBeenCalledWith('/listings');
+    expect(fetchMock).toHaveBeenCalledWith('/listings?limit=24');
     const tileDecks = screen.queryAllByTestId('tile-deck');
     expect(tileDecks.length).toBeGreaterThan(0);
     expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
@@ -59,16 +59,10 @@ describe('Home Component', () => {
     expect(tiles.length).toBe(5);
   });
 
-  it('filters out deleted listings', async () => {
+  it('shows the listings the server returns once each', async () => {
     const { listings } = await import('../../../test-data');
-    const modifiedListings = [
-      ...listings,
-      {
-        ...listings[0],
-        MLS: '9999999999',
-        deleted: 'true'
-      }
-    ];
+    // The server leaves out deleted listings, so Home shows all it gets.
+    const modifiedListings = [...listings, { ...listings[0] }];
     fetchMock.mockImplementation(() =>
       Promise.resolve({
         json: () => Promise.resolve(modifiedListings),
@@ -78,12 +72,12 @@ describe('Home Component', () => {
 
     render(<BrowserRouter><Home /></BrowserRouter>);
     await waitFor(() => expect(fetchMock).toHaveBeenCalledTimes(1));
+    expect(fetchMock.mock.calls[0][0]).not.toContain('include_deleted');
     const tileDecks = screen.queryAllByTestId('tile-deck');
     expect(tileDecks.length).toBeGreaterThan(0);
     const tiles = screen.getAllByTestId(/tile-\d+/);
     expect(tiles.length).toBe(5);
     expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
-    expect(screen.queryByTestId('tile-9999999999')).not.toBeInTheDocument();
   });
 
   it('renders correct listing details in Tile components', async () => {
@@ -124,6 +118,33 @@ describe('Home Component', () => {
     expect(tiles.length).toBe(0);
   });
 
+  it('loads the next page when Load more is clicked', async () => {
+    const { listings } = await import('../../../test-data');
+    fetchMock.mockImplementation((url) =>
+      Promise.resolve(url.includes('page_token=')
+        ? {
+          json: () => Promise.resolve(listings.slice(3)),
+          headers: { get: () => null },
+          ok: true,
+        }
+        : {
+          json: () => Promise.resolve(listings.slice(0, 3)),
+          headers: { get: (name) => (name === 'X-Next-Page-Token' ? 'next+token' : null) },
+          ok: true,
+        })
+    );
+
+    render(<BrowserRouter><Home /></BrowserRouter>);
+    await waitFor(() => expect(screen.getByTestId('load-more')).toBeInTheDocument());
+    expect(screen.getAllByTestId(/tile-\d+/).length).toBe(3);
+
+    fireEvent.click(screen.getByTestId('load-more'));
+    await waitFor(() => expect(fetchMock).toHaveBeenCalledTimes(2));
+    expect(fetchMock).toHaveBeenLastCalledWith('/listings?limit=24&page_token=next%2Btoken');
+    await waitFor(() => expect(screen.getAllByTestId(/tile-\d+/).length).toBe(5));
+    expect(screen.queryByTestId('load-more')).not.toBeInTheDocument();
+  });
+
   it('shows empty TileDeck before API response', async () => {
     const { listings } = await import('../../../test-data');
     fetchMock.mockImplementation(() =>
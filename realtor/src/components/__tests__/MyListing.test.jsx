@@ -231,7 +231,7 @@ describe('MyListing Component - Edit Mode', () => {
     // 4. Wait for the submission fetch call
     await waitFor(() => {
       expect(fetchMock).toHaveBeenCalledWith(
-        '/listings/add/HowMuchDoesSecurityCost',
+        '/listings',
         expect.objectContaining({
           method: 'POST',
           body: expect.stringContaining(`"MLS":"${mockListingData.MLS}"`),
@@ -588,7 +588,7 @@ describe('MyListing Component - Edit Mode', () => {
     });
 
     // Verify fetch was called
-    expect(fetchMock).toHaveBeenCalledWith('/listings/add/HowMuchDoesSecurityCost', expect.any(Object));
+    expect(fetchMock).toHaveBeenCalledWith('/listings', expect.any(Object));
   });
   // --- END OF SUBMISSION FAILURE TEST ---
 
@@ -753,7 +753,7 @@ describe('MyListing Component - Edit Mode', () => {
           json: () => Promise.resolve([mockDataWithPhotos]),
         });
       }
-      if (url.startsWith('/upload/image/')) {
+      if (url.startsWith('/upload/image')) {
         return Promise.resolve({ ok: true, status: 200 });
       }
       return Promise.resolve({ ok: true, json: () => Promise.resolve({}) });
@@ -808,9 +808,9 @@ describe('MyListing Component - Edit Mode', () => {
       onListDropSpy([testFile]);
       
       await waitFor(() => {
-        expect(fetchMock).toHaveBeenCalledWith(`/upload/image/${userEmail}`, {
-          method: 'POST', 
-          body: testFile,
+        expect(fetchMock).toHaveBeenCalledWith('/upload/image', {
+          method: 'POST',
+          body: expect.any(FormData),
         });
       });
     }
@@ -821,9 +821,9 @@ describe('MyListing Component - Edit Mode', () => {
       onArrayDropSpy([testFile2]);
       
       await waitFor(() => {
-        expect(fetchMock).toHaveBeenCalledWith(`/upload/image/${userEmail}`, {
+        expect(fetchMock).toHaveBeenCalledWith('/upload/image', {
           method: 'POST',
-          body: testFile2,
+          body: expect.any(FormData),
         });
       });
     }
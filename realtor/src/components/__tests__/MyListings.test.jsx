@@ -62,7 +62,7 @@ it('renders user listings when logged in with fetched data', async () => {
   );
 
   await waitFor(() => {
-    expect(fetchMock).toHaveBeenCalledWith('/listings');
+    expect(fetchMock).toHaveBeenCalledWith('/listings?user=test%40example.com&include_deleted=true');
     expect(screen.getByTestId('tile-deck')).toBeInTheDocument();
     expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
   });
@@ -59,16 +59,10 @@ describe('Home Component', () => {
     expect(tiles.length).toBe(5);
   });
 
-  it('filters out deleted listings', async () => {
+  it('shows the listings the server returns once each', async () => {
     const { listings } = await import('../../../test-data');
-    const modifiedListings = [
-      ...listings,
-      {
-        ...listings[0],
-        MLS: '9999999999',
-        deleted: 'true'
-      }
-    ];
+    // The server leaves out deleted listings, so Home shows all it gets.
+    const modifiedListings = [...listings, { ...listings[0] }];
     fetchMock.mockImplementation(() =>
       Promise.resolve({
         json: () => Promise.resolve(modifiedListings),
@@ -78,12 +72,12 @@ describe('Home Component', () => {
 
     render(<BrowserRouter><Home /></BrowserRouter>);
     await waitFor(() => expect(fetchMock).toHaveBeenCalledTimes(1));
+    expect(fetchMock.mock.calls[0][0]).not.toContain('include_deleted');
     const tileDecks = screen.queryAllByTestId('tile-deck');
     expect(tileDecks.length).toBeGreaterThan(0);
     const tiles = screen.getAllByTestId(/tile-\d+/);
     expect(tiles.length).toBe(5);
     expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
-    expect(screen.queryByTestId('tile-9999999999')).not.toBeInTheDocument();
   });
 
   it('renders correct listing details in Tile components', async () => {
@@ -205,7 +199,7 @@ describe('Search.jsx', () => {
     await waitFor(() => {
       expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
       // Optionally check for other original listings if needed
-      expect(screen.getAllByTestId(/tile-\d+/).length).toBe(listings.filter(l => l.deleted === 'false').length);
+      expect(screen.getAllByTestId(/tile-\d+/).length).toBe(listings.length);
     });
 
      // Check that console log for reset was called (covers line 221)
@@ -213,13 +207,22 @@ describe('Search.jsx', () => {
      expect(console.log).toHaveBeenCalledWith('Form reset, restored original cards');
   });
 
-  it('should filter listings when Submit button is clicked with criteria', async () => {
+  // mockSearch answers the initial /listings load with initial and any
+  // search (/listings?...) with results, as the server would after
+  // filtering.
+  const mockSearch = (initial, results) => {
+    fetchMock.mockImplementation((url) =>
+      Promise.resolve({
+        ok: true,
+        json: () => Promise.resolve(url === '/listings' ? initial : results),
+      })
+    );
+  };
+
+  it('should search the server when Submit button is clicked with criteria', async () => {
     const { listings } = await import('../../../test-data');
-    // Mock fetch to return initial data (includes listings in Bend and potentially others)
-    fetchMock.mockResolvedValue({
-      ok: true,
-      json: () => Promise.resolve(listings),
-    });
+    const bendListings = listings.filter(l => l.City.toLowerCase() === 'bend');
+    mockSearch(listings, bendListings.slice(0, 1));
 
     render(
       <MemoryRouter>
@@ -227,53 +230,56 @@ describe('Search.jsx', () => {
       </MemoryRouter>
     );
 
-    // Wait for initial data to load and tiles to render
     await waitFor(() => {
-      expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument(); // Assuming this is in Bend
+      expect(screen.getAllByTestId(/tile-\d+/).length).toBe(listings.length);
     });
 
-    // Find the City input and the Submit button
-    const cityInput = screen.getByLabelText('City');
-    const submitButton = screen.getByRole('button', { name: /submit/i });
-
-    // Simulate user typing 'Bend' in the City input
-    fireEvent.change(cityInput, { target: { value: 'Bend' } });
-    expect(cityInput).toHaveValue('Bend');
-
-    // Simulate clicking the Submit button
-    fireEvent.click(submitButton);
+    fireEvent.change(screen.getByLabelText('City'), { target: { value: ' Bend ' } });
+    fireEvent.change(screen.getByLabelText('Bedrooms'), { target: { value: '3' } });
+    fireEvent.click(screen.getByRole('button', { name: /submit/i }));
 
-    // Wait for the filtering logic to apply and component to re-render
+    // The server does the filtering; the page shows what it returns
     await waitFor(() => {
-      // Assert that only listings in Bend are now visible
-      // Check for a known Bend listing
-      expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
-      // Check that listings NOT in Bend (if any in test data) are GONE
-      // (Add specific checks here if your test data includes non-Bend listings)
-
-      // Count the tiles to ensure it matches the number of Bend listings
-      const expectedBendListings = listings.filter(
-        l => l.City.toLowerCase() === 'bend' && l.deleted === 'false'
-      ).length;
-      expect(screen.getAllByTestId(/tile-\d+/).length).toBe(expectedBendListings);
-
-      // Ensure the "No results" message is NOT shown
+      expect(fetchMock).toHaveBeenCalledWith('/listings?city=Bend&min_beds=3');
+      expect(screen.getAllByTestId(/tile-\d+/).length).toBe(1);
+      expect(screen.getByTestId(`tile-${bendListings[0].MLS}`)).toBeInTheDocument();
       expect(screen.queryByText('No listings match your criteria.')).not.toBeInTheDocument();
     });
+    expect(console.log).toHaveBeenCalledWith('Search query:', '/listings?city=Bend&min_beds=3');
+  });
+
+  it('should send every filled-in field and the sort order', async () => {
+    mockSearch([], []);
+
+    render(
+      <MemoryRouter>
+        <Search loggedIn={false} user={null} />
+      </MemoryRouter>
+    );
+    await waitFor(() => expect(fetchMock).toHaveBeenCalledWith('/listings'));
+
+    fireEvent.change(screen.getByLabelText('State'), { target: { value: 'OR' } });
+    fireEvent.change(screen.getByLabelText('Zip Code'), { target: { value: '97701' } });
+    fireEvent.change(screen.getByLabelText('Bathrooms'), { target: { value: '2' } });
+    fireEvent.change(screen.getByLabelText('MLS'), { target: { value: '1234567890' } });
+    fireEvent.change(screen.getByLabelText('Square Feet'), { target: { value: '1500' } });
+    fireEvent.change(screen.getByLabelText('Min Price'), { target: { value: '400,000' } });
+    fireEvent.change(screen.getByLabelText('Max Price'), { target: { value: '600000' } });
+    fireEvent.change(screen.getByLabelText('Keywords'), { target: { value: 'mountain views' } });
+    fireEvent.change(screen.getByLabelText('Sort By'), { target: { value: 'price' } });
+    fireEvent.click(screen.getByRole('button', { name: /submit/i }));
 
-    // Check console log for filter values (covers line 200)
-    expect(console.log).toHaveBeenCalledWith('Filter values:', expect.objectContaining({ City: 'bend' }));
-     // Check console log for filtered cards result (covers line 209)
-     expect(console.log).toHaveBeenCalledWith('Filtered cards:', expect.any(Array));
+    await waitFor(() => {
+      expect(fetchMock).toHaveBeenLastCalledWith(
+        '/listings?state=OR&zip=97701&min_baths=2&mls=1234567890&min_sqft=1500' +
+        '&min_price=400%2C000&max_price=600000&q=mountain+views&sort=price'
+      );
+    });
   });
 
   it('should return all listings when Submit is clicked with no criteria', async () => {
     const { listings } = await import('../../../test-data');
-    // Mock fetch to return initial data
-    fetchMock.mockResolvedValue({
-      ok: true,
-      json: () => Promise.resolve(listings),
-    });
+    mockSearch(listings, []);
 
     render(
       <MemoryRouter>
@@ -281,32 +287,20 @@ describe('Search.jsx', () => {
       </MemoryRouter>
     );
 
-    // 1. Wait for initial data to load and tiles to render
     let initialTiles;
     await waitFor(() => {
       initialTiles = screen.getAllByTestId(/tile-\d+/);
-      expect(initialTiles.length).toBe(listings.filter(l => l.deleted === 'false').length);
+      expect(initialTiles.length).toBe(listings.length);
     });
 
-    // 2. Simulate clicking the Submit button without changing any inputs
-    const submitButton = screen.getByRole('button', { name: /submit/i });
-    fireEvent.click(submitButton);
+    fireEvent.click(screen.getByRole('button', { name: /submit/i }));
 
-    // 3. Wait for filtering (which should do nothing) and assert all original tiles are still present
     await waitFor(() => {
-      const currentTiles = screen.getAllByTestId(/tile-\d+/);
-      // Expect the same number of tiles as initially loaded
-      expect(currentTiles.length).toBe(initialTiles.length);
-      // Check if a specific tile is still there
-      expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
-       // Ensure the "No results" message is NOT shown
+      expect(fetchMock).toHaveBeenCalledTimes(2);
+      expect(fetchMock).toHaveBeenLastCalledWith('/listings');
+      expect(screen.getAllByTestId(/tile-\d+/).length).toBe(initialTiles.length);
       expect(screen.queryByText('No listings match your criteria.')).not.toBeInTheDocument();
     });
-
-    // Check console log for filter values (all should be null or empty)
-    expect(console.log).toHaveBeenCalledWith('Filter values:', {
-      City: null, State: null, 'Zip Code': null, Bedrooms: null, Bathrooms: null, MLS: null, 'Square Feet': null
-    });
   });
 
   it('should handle error during initial listing fetch', async () => {
@@ -336,80 +330,33 @@ describe('Search.jsx', () => {
     expect(screen.getByLabelText('City')).toBeInTheDocument();
   });
 
-  // --- NEW TEST ADDED BELOW ---
-  it('should correctly filter out listings with missing data for filtered fields', async () => {
-    // Define mock listings with missing data
-    const mockListingsWithMissingData = [
-      // Valid listing matching filter
-      { MLS: 'valid-1', Street1: '1 Valid St', City: 'Bend', State: 'OR', Bedrooms: '3', deleted: 'false', 'List Photo': 'https://placehold.co/300x200/eee/aaa?text=Valid' },
-      // Listing with missing City
-      { MLS: 'missing-city', Street1: '2 Missing City St', City: null, State: 'OR', Bedrooms: '3', deleted: 'false', 'List Photo': 'https://placehold.co/300x200/eee/aaa?text=Missing+City' },
-      // Listing with missing Bedrooms
-      { MLS: 'missing-beds', Street1: '3 Missing Beds St', City: 'Bend', State: 'OR', Bedrooms: null, deleted: 'false', 'List Photo': 'https://placehold.co/300x200/eee/aaa?text=Missing+Beds' },
-      // Listing that doesn't match filter criteria
-      { MLS: 'other-data', Street1: '4 Other St', City: 'Redmond', State: 'OR', Bedrooms: '2', deleted: 'false', 'List Photo': 'https://placehold.co/300x200/eee/aaa?text=Other' },
-    ];
-
-    // Mock fetch to return these specific listings
-    fetchMock.mockResolvedValue({
-      ok: true,
-      json: () => Promise.resolve(mockListingsWithMissingData),
-    });
+  it('should show every listing the search returns', async () => {
+    const { listings } = await import('../../../test-data');
+    mockSearch(listings, [
+      { ...listings[0], MLS: 'first' },
+      { ...listings[0], MLS: 'second' },
+    ]);
 
     render(
       <MemoryRouter>
         <Search loggedIn={false} user={null} />
       </MemoryRouter>
     );
+    await waitFor(() => expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument());
 
-    // 1. Wait for initial data to load
-    await waitFor(() => {
-      expect(screen.getByTestId('tile-valid-1')).toBeInTheDocument();
-      expect(screen.getByTestId('tile-missing-city')).toBeInTheDocument();
-      expect(screen.getByTestId('tile-missing-beds')).toBeInTheDocument();
-      expect(screen.getByTestId('tile-other-data')).toBeInTheDocument();
-      expect(screen.getAllByTestId(/tile-\w+/).length).toBe(5); // All 4 initially
-    });
-
-    // 2. Find inputs and submit button
-    const cityInput = screen.getByLabelText('City');
-    const bedroomsInput = screen.getByLabelText('Bedrooms');
-    const submitButton = screen.getByRole('button', { name: /submit/i });
-
-    // 3. Simulate entering filter criteria ('Bend' and '3' bedrooms)
-    fireEvent.change(cityInput, { target: { value: 'Bend' } });
-    fireEvent.change(bedroomsInput, { target: { value: '3' } });
+    fireEvent.change(screen.getByLabelText('City'), { target: { value: 'Bend' } });
+    fireEvent.click(screen.getByRole('button', { name: /submit/i }));
 
-    // 4. Simulate clicking the Submit button
-    fireEvent.click(submitButton);
-
-    // 5. Wait for filtering and assert results
     await waitFor(() => {
-      // Only the valid listing should remain
-      expect(screen.getByTestId('tile-valid-1')).toBeInTheDocument();
-      // Listings with missing data for filtered fields should be gone
-      expect(screen.queryByTestId('tile-missing-city')).not.toBeInTheDocument();
-      expect(screen.queryByTestId('tile-missing-beds')).not.toBeInTheDocument();
-      // Listing that didn't match criteria should be gone
-      expect(screen.queryByTestId('tile-other-data')).not.toBeInTheDocument();
-      // Only 1 tile should remain
-      expect(screen.getAllByTestId(/tile-\w+/).length).toBe(2);
+      expect(fetchMock.mock.calls[1][0]).not.toContain('include_deleted');
+      expect(screen.getByTestId('tile-first')).toBeInTheDocument();
+      expect(screen.getByTestId('tile-second')).toBeInTheDocument();
     });
-
-     // Check console log for filter values
-     expect(console.log).toHaveBeenCalledWith('Filter values:', expect.objectContaining({ City: 'bend', Bedrooms: 3 }));
-     // Check console log for filtered cards result
-     expect(console.log).toHaveBeenCalledWith('Filtered cards:', expect.arrayContaining([expect.objectContaining({ MLS: 'valid-1' })]));
   });
-  // --- END OF NEW TEST ---
 
   it('should show "No listings match your criteria" when no results are found', async () => {
     const { listings } = await import('../../../test-data');
-    // Mock fetch to return listings
-    fetchMock.mockResolvedValue({
-      ok: true,
-      json: () => Promise.resolve(listings),
-    });
+    mockSearch(listings, []);
 
     render(
       <MemoryRouter>
@@ -417,150 +364,43 @@ describe('Search.jsx', () => {
       </MemoryRouter>
     );
 
-    // Wait for initial data to load
     await waitFor(() => {
       expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
     });
 
-    // Find inputs and submit button
-    const cityInput = screen.getByLabelText('City');
-    const submitButton = screen.getByRole('button', { name: /submit/i });
-
-    // Enter criteria that won't match any listings
-    fireEvent.change(cityInput, { target: { value: 'NonExistentCity' } });
-
-    // Submit the form
-    fireEvent.click(submitButton);
+    fireEvent.change(screen.getByLabelText('City'), { target: { value: 'NonExistentCity' } });
+    fireEvent.click(screen.getByRole('button', { name: /submit/i }));
 
-    // Wait for filtering and assert no results message appears
     await waitFor(() => {
       expect(screen.getByText('No listings match your criteria.')).toBeInTheDocument();
-      // Verify no tiles are present
       expect(screen.queryAllByTestId(/tile-\d+/).length).toBe(0);
     });
-
-    // Verify state.noResults was set to true by checking the UI
     expect(screen.getByText('No listings match your criteria.')).toBeVisible();
   });
 
-  it('should log filter mismatches for debugging', async () => {
-    const mockListings = [
-      {
-        MLS: 'test-1',
-        Street1: '1 Test St',
-        City: 'Portland', // This will NOT match our filter
-        State: 'OR',
-        Bedrooms: '2', // This will NOT match our filter
-        deleted: 'false',
-        'List Photo': 'https://example.com/photo.jpg'
-      }
-    ];
-
-    fetchMock.mockResolvedValue({
-      ok: true,
-      json: () => Promise.resolve(mockListings),
-    });
+  it('should report a failed search and keep the current results', async () => {
+    const { listings } = await import('../../../test-data');
+    fetchMock.mockImplementation((url) =>
+      Promise.resolve(url === '/listings'
+        ? { ok: true, json: () => Promise.resolve(listings) }
+        : { ok: false, status: 400, json: () => Promise.resolve({ error: 'min_price must be a number' }) })
+    );
 
     render(
       <MemoryRouter>
         <Search loggedIn={false} user={null} />
       </MemoryRouter>
     );
+    await waitFor(() => expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument());
 
-    // Wait for initial data to load
-    await waitFor(() => {
-      expect(screen.getByTestId('tile-test-1')).toBeInTheDocument();
-    });
-
-    // Find inputs and submit button
-    const cityInput = screen.getByLabelText('City');
-    const bedroomsInput = screen.getByLabelText('Bedrooms');
-    const submitButton = screen.getByRole('button', { name: /submit/i });
-
-    // Enter criteria that will cause mismatches (for debugging logs)
-    fireEvent.change(cityInput, { target: { value: 'Seattle' } }); // Won't match Portland
-    fireEvent.change(bedroomsInput, { target: { value: '3' } }); // Won't match 2
-
-    // Submit the form
-    fireEvent.click(submitButton);
-
-    // Wait for filtering and verify debug logs were called
-    await waitFor(() => {
-      // Check that the mismatch logging occurred (covers lines 83-85)
-      // The City filter fails first, so that's what we should see in the logs
-      expect(console.log).toHaveBeenCalledWith(
-        "Card 0 failed filter: City (card: portland, filter: seattle)"
-      );
-    });
-
-    // Verify no results message appears
-    expect(screen.getByText('No listings match your criteria.')).toBeInTheDocument();
-  });
-
-  it('should handle listings with null/missing numeric field values during filtering', async () => {
-    // Mock listings with missing/null numeric values
-    const mockListingsWithNulls = [
-      {
-        MLS: 'test-1',
-        Street1: '123 Test St',
-        Street2: '*',
-        City: 'portland',
-        State: 'OR',
-        'Zip Code': '97201',
-        Neighborhood: 'Test Hood',
-        'Sales Price': '500000',
-        Bedrooms: null, // Missing/null value
-        Bathrooms: '', // Empty value 
-        'Square Feet': undefined, // Undefined value
-        'Lot Size': '5000',
-        'Garage Size': '2 car',
-        Description: 'Test listing',
-        'Date Listed': '1589161257428',
-        'Last Modified': '1589161257428',
-        'List Photo': 'https://example.com/photo.jpg',
-        'Photo Array': ['https://example.com/photo1.jpg'],
-        User: 'test@example.com',
-        deleted: 'false',
-      }
-    ];
-
-    fetchMock.mockResolvedValueOnce({
-      json: () => Promise.resolve(mockListingsWithNulls),
-      ok: true,
-    });
-
-    render(
-      <MemoryRouter initialEntries={['/realtor/search']}>
-        <Search />
-      </MemoryRouter>
-    );
-
-    // Wait for initial data to load
-    await waitFor(() => {
-      expect(screen.getByTestId('tile-test-1')).toBeInTheDocument();
-    });
-
-    // Find numeric field inputs and submit button
-    const bedroomsInput = screen.getByLabelText('Bedrooms');
-    const bathroomsInput = screen.getByLabelText('Bathrooms');
-    const squareFeetInput = screen.getByLabelText('Square Feet');
-    const submitButton = screen.getByRole('button', { name: /submit/i });
+    fireEvent.change(screen.getByLabelText('Min Price'), { target: { value: 'lots' } });
+    fireEvent.click(screen.getByRole('button', { name: /submit/i }));
 
-    // Enter criteria for numeric fields
-    fireEvent.change(bedroomsInput, { target: { value: '3' } }); // Won't match null
-    fireEvent.change(bathroomsInput, { target: { value: '2' } }); // Won't match empty string
-    fireEvent.change(squareFeetInput, { target: { value: '1500' } }); // Won't match undefined
-
-    // Submit the form
-    fireEvent.click(submitButton);
-
-    // Wait for filtering to complete
     await waitFor(() => {
-      // Verify no results message appears (all filters failed due to null/empty/undefined values)
-      expect(screen.getByText('No listings match your criteria.')).toBeInTheDocument();
+      expect(screen.getByText('Search failed. Please try again.')).toBeInTheDocument();
+      expect(console.error).toHaveBeenCalledWith('Error searching listings:', expect.any(Error));
     });
-
-    // This test covers the branch where cardValue is falsy for numeric fields (line ~89 in Search.jsx)
-    // The cardValue ? Number(cardValue) : null branch is exercised for null, empty, and undefined values
+    expect(screen.getByTestId('tile-1234567890')).toBeInTheDocument();
+    expect(screen.queryByText('No listings match your criteria.')).not.toBeInTheDocument();
   });
 });
@@ -57,6 +57,17 @@ describe('Tile Component', () => {
   // --- New Test Added Below ---
 
   it('renders edit/remove buttons for logged-in user and handles state change on click', async () => {
+    // The server answers a delete or restore with the listing as stored.
+    fetchMock.mockImplementation((url, options) =>
+      Promise.resolve({
+        ok: true,
+        json: () => Promise.resolve({
+          ...mockListing,
+          deleted: options.method === 'DELETE' ? 'true' : 'false',
+        }),
+      })
+    );
+
     // Render the Tile with a user prop
     render(
       <BrowserRouter>
@@ -77,19 +88,11 @@ describe('Tile Component', () => {
 
     // Wait for the fetch call to complete and the component to re-render
     await waitFor(() => {
-      // Verify fetch was called correctly to update the listing status
+      // Verify the listing was soft-deleted through its own endpoint
       expect(fetchMock).toHaveBeenCalledTimes(1);
       expect(fetchMock).toHaveBeenCalledWith(
-        '/listings/add/HowMuchDoesSecurityCost', // Endpoint from Tile.jsx
-        expect.objectContaining({
-          method: 'POST',
-          headers: {
-            'Accept': 'application/json',
-            'Content-Type': 'application/json',
-          },
-          // Check that the body contains the updated 'deleted' status
-          body: expect.stringContaining(`"deleted":"true"`), // Should now be true
-        })
+        `/listings/${mockListing.MLS}`,
+        expect.objectContaining({ method: 'DELETE' })
       );
     });
 
@@ -101,20 +104,16 @@ describe('Tile Component', () => {
       expect(screen.queryByText('Remove Listing')).not.toBeInTheDocument();
     });
 
-    // Optional: Simulate clicking "Publish Listing" to toggle back
+    // Simulate clicking "Publish Listing" to restore it
     const publishButton = screen.getByText('Publish Listing');
     fireEvent.click(publishButton);
 
     // Wait for the second fetch call and re-render
     await waitFor(() => {
-      // Verify fetch was called again
       expect(fetchMock).toHaveBeenCalledTimes(2);
-       // Check that the body contains the updated 'deleted' status (back to false)
-       expect(fetchMock).toHaveBeenCalledWith(
-        '/listings/add/HowMuchDoesSecurityCost',
-        expect.objectContaining({
-          body: expect.stringContaining(`"deleted":"false"`),
-        })
+      expect(fetchMock).toHaveBeenCalledWith(
+        `/listings/${mockListing.MLS}/restore`,
+        expect.objectContaining({ method: 'POST' })
       );
     });
 
@@ -126,6 +125,28 @@ describe('Tile Component', () => {
     });
   });
 
+  it('keeps the listing as it was when the server refuses the change', async () => {
+    fetchMock.mockImplementation(() =>
+      Promise.resolve({
+        ok: false,
+        json: () => Promise.resolve({ error: 'listing belongs to another user' }),
+      })
+    );
+
+    render(
+      <BrowserRouter>
+        <Tile card={mockListing} user={mockUser} />
+      </BrowserRouter>
+    );
+
+    fireEvent.click(screen.getByText('Remove Listing'));
+
+    await waitFor(() => {
+      expect(console.error).toHaveBeenCalledWith('Error updating listing:', 'listing belongs to another user');
+    });
+    expect(screen.getByText('Remove Listing')).toBeInTheDocument();
+  });
+
   it('should display error message when image fails to load', async () => {
     render(
       <BrowserRouter>
@@ -167,6 +188,18 @@ describe('Tile Component', () => {
     expect(Tile.timeDifference(now, oneYearAgo)).toMatch(/years ago/);
   });
 
+  it('should parse RFC 3339 and epoch millisecond dates', () => {
+    expect(Tile.parseDate('2020-05-11T01:40:57Z')).toBe(1589161257000);
+    expect(Tile.parseDate('1589161257428')).toBe(1589161257428);
+    expect(Tile.parseDate('invalid-date')).toBeNaN();
+  });
+
+  it('should format typed listing numbers and leave legacy strings alone', () => {
+    expect(Tile.formatNumber(503000)).toBe('503,000');
+    expect(Tile.formatNumber(1.5)).toBe('1.5');
+    expect(Tile.formatNumber('1200 sqft')).toBe('1200 sqft');
+  });
+
   it('should handle image load event', () => {
     render(
       <BrowserRouter>